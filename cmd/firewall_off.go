@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/service"
+	"os"
+)
+
+var firewallOffCmd = &cobra.Command{
+	Use:   "firewall-off",
+	Short: "Disable firewall management",
+	Long:  "Disable firewall management and remove the firewall rules added by h-ui, use it when locked out by the panel allowlist.",
+	Run:   runFirewallOff,
+}
+
+func init() {
+	rootCmd.AddCommand(firewallOffCmd)
+}
+
+func runFirewallOff(cmd *cobra.Command, args []string) {
+	if err := dao.InitSqliteDB(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if err := dao.UpdateConfig([]string{constant.FirewallEnable}, map[string]interface{}{"value": "0"}); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if err := dao.CloseSqliteDB(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if err := service.CleanFirewall(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("firewall management disabled, the rules added by h-ui have been removed")
+}
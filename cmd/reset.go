@@ -30,14 +30,20 @@ func runReset(cmd *cobra.Command, args []string) {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
+	passHash, err := util.HashPassword(password)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
 	if err = dao.InitSqliteDB(); err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 	if err = dao.UpdateAccount([]int64{1}, map[string]interface{}{
-		"username": username,
-		"pass":     util.SHA224String(password),
-		"con_pass": fmt.Sprintf("%s.%s", username, password)}); err != nil {
+		"username":         username,
+		"pass":             passHash,
+		"con_pass":         fmt.Sprintf("%s.%s", username, password),
+		"must_change_pass": 1}); err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
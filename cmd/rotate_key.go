@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/util"
+	"os"
+)
+
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Rotate the master key of encrypted configs",
+	Long:  "Rotate the master key of encrypted configs. Stop h-ui before running this command.",
+	Run:   runRotateKey,
+}
+
+var newMasterKey string
+
+func init() {
+	rotateKeyCmd.Flags().StringVarP(&newMasterKey, "key", "k", "", "The new master key, 32 bytes encoded in base64 or hex, generated randomly if empty")
+	rootCmd.AddCommand(rotateKeyCmd)
+}
+
+func runRotateKey(cmd *cobra.Command, args []string) {
+	current, err := dao.LoadMasterKey(false)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if newMasterKey == "" {
+		if newMasterKey, err = util.GenerateMasterKey(); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	masterKey, err := util.ParseMasterKey(newMasterKey)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	target, err := util.NewEnvelope(masterKey)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	// 先保存新密钥，重新加密失败时旧密钥仍然可用
+	fromEnv := os.Getenv(constant.MasterKeyEnv) != ""
+	keyFilePath := dao.MasterKeyFilePath()
+	newKeyFilePath := keyFilePath + ".new"
+	if !fromEnv {
+		if err = dao.WriteMasterKeyFile(newKeyFilePath, newMasterKey); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if err = dao.InitSqliteDB(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	count, err := dao.RotateMasterKey(current, target)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if err = dao.CloseSqliteDB(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if fromEnv {
+		fmt.Println(fmt.Sprintf("%d secrets re-encrypted, set %s to the new master key before starting h-ui: %s",
+			count, constant.MasterKeyEnv, newMasterKey))
+		return
+	}
+	if err = os.Rename(newKeyFilePath, keyFilePath); err != nil {
+		fmt.Println(fmt.Sprintf("%d secrets re-encrypted, but the new master key could not be moved, rename %s to %s manually: %v",
+			count, newKeyFilePath, keyFilePath, err))
+		os.Exit(1)
+	}
+	fmt.Println(fmt.Sprintf("%d secrets re-encrypted, master key %s -> %s", count, current.KeyId(), target.KeyId()))
+}
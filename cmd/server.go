@@ -32,31 +32,33 @@ func runServer(port string) error {
 	if err := service.InitHysteria2(); err != nil {
 		return err
 	}
-	if err := service.InitTableAndChain(); err != nil {
+	if err := service.InitPortHopping(); err != nil {
 		logrus.Errorf(err.Error())
 	}
-	if err := service.InitPortHopping(); err != nil {
+	if err := service.InitFirewall(); err != nil {
 		logrus.Errorf(err.Error())
 	}
 	if err := service.InitTelegramBot(); err != nil {
 		logrus.Errorf(err.Error())
 	}
-
-	config, err := dao.GetConfig("key = ?", constant.HUIWebContext)
-	if err != nil {
+	if err := service.InitPanelAcme(); err != nil {
 		return err
 	}
 
-	r := gin.Default()
-	router.Router(r, config.Value)
-
-	serverPort, crtPath, keyPath, err := service.GetServerPortAndCert()
-	if err != nil {
-		return err
-	}
-
-	service.InitServer(fmt.Sprintf(":%d", serverPort), r)
-	if err := service.StartServer(crtPath, keyPath); err != nil && err != http.ErrServerClosed {
+	service.InitServer(func(webContext *string) http.Handler {
+		r := gin.Default()
+		// 只信任配置的反向代理传入的 X-Forwarded-For，避免伪造来源 IP
+		proxies, err := service.TrustedProxies()
+		if err != nil {
+			logrus.Errorf(err.Error())
+		}
+		if err = r.SetTrustedProxies(proxies); err != nil {
+			logrus.Errorf("set trusted proxies err: %v", err)
+		}
+		router.Router(r, webContext)
+		return r
+	})
+	if err := service.StartServer(); err != nil && err != http.ErrServerClosed {
 		logrus.Errorf("start server err: %v", err)
 		return errors.New("start server err")
 	}
@@ -70,7 +72,7 @@ func releaseResource() {
 	if err := service.ReleaseAllNodes(); err != nil {
 		logrus.Errorf(err.Error())
 	}
-	if err := service.RemoveByComment(); err != nil {
+	if err := service.RemovePortHopping(); err != nil {
 		logrus.Errorf(err.Error())
 	}
 }
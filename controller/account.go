@@ -11,6 +11,7 @@ import (
 	"h-ui/service"
 	"h-ui/util"
 	"io"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -21,21 +22,30 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	clientIP := c.ClientIP()
+	if err = service.LoginAllowed(*loginDto.Username, clientIP); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+
 	if !service.ExistAccountUsername(*loginDto.Username, 0) {
+		service.LoginFailed(*loginDto.Username, clientIP)
 		vo.Fail("account not exist", c)
 		return
 	}
 
-	token, err := service.Login(*loginDto.Username, util.SHA224String(*loginDto.Pass))
+	token, err := service.Login(*loginDto.Username, *loginDto.Pass)
 	if err != nil {
+		service.LoginFailed(*loginDto.Username, clientIP)
 		vo.Fail(err.Error(), c)
 		return
 	}
+	service.LoginSucceeded(*loginDto.Username, clientIP)
 	jwtVo := vo.JwtVo{
 		TokenType:   constant.TokenType,
 		AccessToken: token,
 	}
-	service.TelegramLoginRemind(*loginDto.Username, c.ClientIP())
+	service.TelegramLoginRemind(*loginDto.Username, clientIP)
 	vo.Success(jwtVo, c)
 }
 
@@ -56,6 +66,16 @@ func PageAccount(c *gin.Context) {
 		return
 	}
 
+	var ids []int64
+	for _, item := range accounts {
+		ids = append(ids, *item.Id)
+	}
+	tagsMap, err := service.AccountTagsMap(ids)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+
 	var accountVos []vo.AccountVo
 	for _, item := range accounts {
 		accountVo := vo.AccountVo{
@@ -75,6 +95,8 @@ func PageAccount(c *gin.Context) {
 			},
 			LoginAt: *item.LoginAt,
 			ConAt:   *item.ConAt,
+			PlanId:  *item.PlanId,
+			Tags:    tagsMap[*item.Id],
 		}
 		if value, exists := onlineUsers[*item.Username]; exists {
 			accountVo.Online = true
@@ -111,7 +133,15 @@ func SaveAccount(c *gin.Context) {
 		nodeAccess = *accountSaveDto.NodeAccess
 	}
 
-	passEncrypt := util.SHA224String(*accountSaveDto.Pass)
+	if err = service.ValidateAccountPass(0, *accountSaveDto.Username, *accountSaveDto.Pass); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	passEncrypt, err := util.HashPassword(*accountSaveDto.Pass)
+	if err != nil {
+		vo.Fail(constant.SysError, c)
+		return
+	}
 	conPass := fmt.Sprintf("%s.%s", *accountSaveDto.Username, *accountSaveDto.ConPass)
 	account := entity.Account{
 		Username:   accountSaveDto.Username,
@@ -158,6 +188,10 @@ func UpdateAccount(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	if !service.ApiKeyAccountAllowed(c, *accountUpdateDto.Id, accountUpdateDto.Pass != nil && *accountUpdateDto.Pass != "") {
+		vo.Fail(constant.ForbiddenError, c)
+		return
+	}
 
 	if accountUpdateDto.Username != nil && *accountUpdateDto.Username != "" && service.ExistAccountUsername(*accountUpdateDto.Username, *accountUpdateDto.Id) {
 		vo.Fail(fmt.Sprintf("username %s already exists", *accountUpdateDto.Username), c)
@@ -186,8 +220,25 @@ func UpdateAccount(c *gin.Context) {
 
 	var passEncrypt *string
 	if accountUpdateDto.Pass != nil && *accountUpdateDto.Pass != "" {
-		passEncryptSha224 := util.SHA224String(*accountUpdateDto.Pass)
-		passEncrypt = &passEncryptSha224
+		account, err := service.GetAccount(*accountUpdateDto.Id)
+		if err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+		username := *account.Username
+		if accountUpdateDto.Username != nil && *accountUpdateDto.Username != "" {
+			username = *accountUpdateDto.Username
+		}
+		if err = service.ValidateAccountPass(*accountUpdateDto.Id, username, *accountUpdateDto.Pass); err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+		passHash, err := util.HashPassword(*accountUpdateDto.Pass)
+		if err != nil {
+			vo.Fail(constant.SysError, c)
+			return
+		}
+		passEncrypt = &passHash
 	}
 
 	account := entity.Account{
@@ -264,6 +315,10 @@ func GetAccount(c *gin.Context) {
 		Role:       *account.Role,
 		NodeAccess: *account.NodeAccess,
 		Deleted:    *account.Deleted,
+		PlanId:     *account.PlanId,
+	}
+	if tagsMap, err := service.AccountTagsMap([]int64{*account.Id}); err == nil {
+		accountVo.Tags = tagsMap[*account.Id]
 	}
 	vo.Success(accountVo, c)
 }
@@ -279,11 +334,22 @@ func ImportAccount(c *gin.Context) {
 		vo.Fail("the file is too big", c)
 		return
 	}
-	// 文件后缀.json
-	if !strings.HasSuffix(header.Filename, ".json") {
+	// 文件后缀.json .csv .xlsx
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), "."))
+	if format != "json" && format != "csv" && format != "xlsx" {
 		vo.Fail("file format not supported", c)
 		return
 	}
+	var accountImportDto dto.AccountImportDto
+	_ = c.ShouldBind(&accountImportDto)
+	if err = validate.Struct(&accountImportDto); err != nil {
+		vo.Fail(constant.InvalidError, c)
+		return
+	}
+	if format != "json" {
+		importAccountTable(c, file, format, accountImportDto)
+		return
+	}
 	content, err := io.ReadAll(file)
 	if err != nil {
 		vo.Fail("json file read err", c)
@@ -294,26 +360,66 @@ func ImportAccount(c *gin.Context) {
 		vo.Fail("content Unmarshal err", c)
 		return
 	}
-	if err = service.UpsertAccount(accounts); err != nil {
+	accountImportVo, err := service.ImportAccountJson(accounts, accountImportDto)
+	if err != nil {
 		vo.Fail(err.Error(), c)
 		return
 	}
-	vo.Success(nil, c)
+	vo.Success(accountImportVo, c)
 }
 
-func ExportAccount(c *gin.Context) {
-	accountExports, err := service.ListExportAccount()
+func importAccountTable(c *gin.Context, file io.Reader, format string, accountImportDto dto.AccountImportDto) {
+	rows, err := util.ReadTable(file, format)
 	if err != nil {
 		vo.Fail(err.Error(), c)
 		return
 	}
+	accountImportVo, err := service.ImportAccountTable(rows, accountImportDto)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(accountImportVo, c)
+}
+
+func ExportAccount(c *gin.Context) {
+	accountExportDto, err := validateField(c, dto.AccountExportDto{})
+	if err != nil {
+		return
+	}
 
-	fileName := fmt.Sprintf("AccountExport-%s.json", time.Now().Format("20060102150405"))
+	format := "json"
+	if accountExportDto.Format != nil {
+		format = *accountExportDto.Format
+	}
+	fileName := fmt.Sprintf("AccountExport-%s.%s", time.Now().Format("20060102150405"), format)
 	filePath := constant.ExportPathDir + fileName
 
-	if err = util.ExportFile(filePath, accountExports, 0); err != nil {
-		vo.Fail(err.Error(), c)
-		return
+	if format == "json" {
+		accountExports, err := service.ListExportAccount()
+		if err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+		if err = util.ExportFile(filePath, accountExports, 0); err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+	} else {
+		header, rows, err := service.ListAccountTable(accountExportDto)
+		if err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+		if format == "csv" {
+			err = util.ExportCsv(filePath, header, rows)
+		} else {
+			err = util.ExportXlsx(filePath, header, rows)
+		}
+		if err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
 	}
 
 	// 下载
@@ -350,5 +456,159 @@ func VerifyDefaultPass(c *gin.Context) {
 		vo.Fail(err.Error(), c)
 		return
 	}
-	vo.Success(account.Pass != nil && *account.Pass == "02f382b76ca1ab7aa06ab03345c7712fd5b971fb0c0f2aef98bac9cd", c)
+	vo.Success(account.MustChangePass != nil && *account.MustChangePass == 1, c)
+}
+
+func ChangePass(c *gin.Context) {
+	changePassDto, err := validateField(c, dto.ChangePassDto{})
+	if err != nil {
+		return
+	}
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	if err = service.ChangePass(accountBo.Id, *changePassDto.OldPass, *changePassDto.NewPass); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func GenerateTelegramBindCode(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	bindCodeVo, err := service.GenerateTelegramBindCode(*idDto.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(bindCodeVo, c)
+}
+
+func UnbindTelegram(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UnbindTelegram(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func GenerateUserLoginLink(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	loginLinkVo, err := service.GenerateUserLoginLink(*idDto.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(loginLinkVo, c)
+}
+
+func SaveAccountFromPlan(c *gin.Context) {
+	accountSaveFromPlanDto, err := validateField(c, dto.AccountSaveFromPlanDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SaveAccountFromPlan(accountSaveFromPlanDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func RenewAccount(c *gin.Context) {
+	accountRenewDto, err := validateField(c, dto.AccountRenewDto{})
+	if err != nil {
+		return
+	}
+	var periods int64
+	if accountRenewDto.Periods != nil {
+		periods = *accountRenewDto.Periods
+	}
+	if err = service.RenewAccount(*accountRenewDto.Id, periods); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func ChangeAccountPlan(c *gin.Context) {
+	accountChangePlanDto, err := validateField(c, dto.AccountChangePlanDto{})
+	if err != nil {
+		return
+	}
+	if err = service.ChangeAccountPlan(*accountChangePlanDto.Id, *accountChangePlanDto.PlanId); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func ApplyPlan(c *gin.Context) {
+	accountApplyPlanDto, err := validateField(c, dto.AccountApplyPlanDto{})
+	if err != nil {
+		return
+	}
+	if err = service.ApplyPlan(accountApplyPlanDto.Ids, *accountApplyPlanDto.PlanId); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func ListAccountPlanHistory(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	historyVos, err := service.ListAccountPlanHistory(*idDto.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(historyVos, c)
+}
+
+func BulkAccount(c *gin.Context) {
+	accountBulkDto, err := validateField(c, dto.AccountBulkDto{})
+	if err != nil {
+		return
+	}
+	accountBulkVo, err := service.BulkAccount(accountBulkDto)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(accountBulkVo, c)
+}
+
+func SetAccountTags(c *gin.Context) {
+	accountTagsDto, err := validateField(c, dto.AccountTagsDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SetAccountTags(*accountTagsDto.Id, accountTagsDto.Tags); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func ListTag(c *gin.Context) {
+	tagVos, err := service.ListTag()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(tagVos, c)
 }
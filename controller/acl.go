@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListAclProfile(c *gin.Context) {
+	aclProfileVos, err := service.ListAclProfile()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(aclProfileVos, c)
+}
+
+func SaveAclProfile(c *gin.Context) {
+	aclProfileSaveDto, err := validateField(c, dto.AclProfileSaveDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SaveAclProfile(aclProfileSaveDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func UpdateAclProfile(c *gin.Context) {
+	aclProfileUpdateDto, err := validateField(c, dto.AclProfileUpdateDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UpdateAclProfile(aclProfileUpdateDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func DeleteAclProfile(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeleteAclProfile(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func ValidateAclRules(c *gin.Context) {
+	aclRulesValidateDto, err := validateField(c, dto.AclRulesValidateDto{})
+	if err != nil {
+		return
+	}
+	outbounds := ""
+	if aclRulesValidateDto.Outbounds != nil {
+		outbounds = *aclRulesValidateDto.Outbounds
+	}
+	vo.Success(service.ValidateAclRules(*aclRulesValidateDto.Rules, outbounds), c)
+}
+
+func SetAccountAclProfile(c *gin.Context) {
+	accountAclProfileDto, err := validateField(c, dto.AccountAclProfileDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SetAccountAclProfile(accountAclProfileDto.Ids, *accountAclProfileDto.AclProfileId); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
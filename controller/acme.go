@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func GetPanelAcme(c *gin.Context) {
+	panelAcmeVo, err := service.GetPanelAcme()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(panelAcmeVo, c)
+}
+
+// RenewPanelAcme 立即重新申请面板证书，不检查到期时间
+func RenewPanelAcme(c *gin.Context) {
+	if err := service.RenewPanelAcme(true); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListAlertRule(c *gin.Context) {
+	alertRuleVos, err := service.ListAlertRule()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(alertRuleVos, c)
+}
+
+func SaveAlertRule(c *gin.Context) {
+	alertRuleSaveDto, err := validateField(c, dto.AlertRuleSaveDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SaveAlertRule(alertRuleSaveDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func UpdateAlertRule(c *gin.Context) {
+	alertRuleUpdateDto, err := validateField(c, dto.AlertRuleUpdateDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UpdateAlertRule(alertRuleUpdateDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func DeleteAlertRule(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeleteAlertRule(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func TestAlertRule(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.TestAlertRule(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func ListAlertState(c *gin.Context) {
+	alertStateVos, err := service.ListAlertState()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(alertStateVos, c)
+}
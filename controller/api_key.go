@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListApiKey(c *gin.Context) {
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	apiKeyVos, err := service.ListApiKey(accountBo.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(apiKeyVos, c)
+}
+
+func SaveApiKey(c *gin.Context) {
+	apiKeySaveDto, err := validateField(c, dto.ApiKeySaveDto{})
+	if err != nil {
+		return
+	}
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	var expireTime int64 = 0
+	if apiKeySaveDto.ExpireTime != nil {
+		expireTime = *apiKeySaveDto.ExpireTime
+	}
+	apiKeySaveVo, err := service.SaveApiKey(accountBo.Id, *apiKeySaveDto.Name, apiKeySaveDto.Scopes, expireTime)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(apiKeySaveVo, c)
+}
+
+func DeleteApiKey(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	if err = service.DeleteApiKey(accountBo.Id, *idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
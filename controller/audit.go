@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/service"
+	"h-ui/util"
+	"strconv"
+	"time"
+)
+
+func PageAudit(c *gin.Context) {
+	auditPageDto, err := validateField(c, dto.AuditPageDto{})
+	if err != nil {
+		return
+	}
+	audits, total, err := service.PageAudit(auditPageDto)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	auditPageVo := vo.AuditPageVo{
+		AuditVos: toAuditVos(audits),
+		Total:    total,
+	}
+	vo.Success(auditPageVo, c)
+}
+
+func ExportAudit(c *gin.Context) {
+	auditExportDto, err := validateField(c, dto.AuditExportDto{})
+	if err != nil {
+		return
+	}
+	audits, err := service.ListAudit(auditExportDto.AuditQueryDto)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	auditVos := toAuditVos(audits)
+
+	fileName := fmt.Sprintf("AuditExport-%s.%s", time.Now().Format("20060102150405"), *auditExportDto.Format)
+	filePath := constant.ExportPathDir + fileName
+
+	if *auditExportDto.Format == "csv" {
+		header := []string{"id", "createTime", "actorId", "actor", "action", "targetIds", "ip", "result", "message", "beforeValue", "afterValue"}
+		var rows [][]string
+		for _, item := range auditVos {
+			rows = append(rows, []string{
+				strconv.FormatInt(item.Id, 10),
+				item.CreateTime.Format("2006-01-02 15:04:05"),
+				strconv.FormatInt(item.ActorId, 10),
+				item.Actor,
+				item.Action,
+				item.TargetIds,
+				item.Ip,
+				item.Result,
+				item.Message,
+				item.BeforeValue,
+				item.AfterValue,
+			})
+		}
+		err = util.ExportCsv(filePath, header, rows)
+	} else {
+		err = util.ExportFile(filePath, auditVos, 0)
+	}
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+
+	if !util.Exists(filePath) {
+		vo.Fail("file not exist", c)
+		return
+	}
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.File(filePath)
+}
+
+func toAuditVos(audits []entity.Audit) []vo.AuditVo {
+	auditVos := make([]vo.AuditVo, 0, len(audits))
+	for _, item := range audits {
+		auditVos = append(auditVos, vo.AuditVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			ActorId:     *item.ActorId,
+			Actor:       *item.Actor,
+			Action:      *item.Action,
+			TargetIds:   *item.TargetIds,
+			BeforeValue: *item.BeforeValue,
+			AfterValue:  *item.AfterValue,
+			Ip:          *item.Ip,
+			Result:      *item.Result,
+			Message:     *item.Message,
+		})
+	}
+	return auditVos
+}
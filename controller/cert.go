@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListCert(c *gin.Context) {
+	certVos, err := service.ListCert()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(certVos, c)
+}
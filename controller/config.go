@@ -15,6 +15,7 @@ import (
 	"h-ui/service"
 	"h-ui/util"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -23,6 +24,36 @@ import (
 	"time"
 )
 
+// telegramConfigKeys 修改后需要重启机器人的配置
+var telegramConfigKeys = []string{
+	constant.TelegramEnable,
+	constant.TelegramToken,
+	constant.TelegramChatId,
+	constant.TelegramUserEnable,
+	constant.TelegramMode,
+	constant.TelegramWebhookSecret,
+	constant.HUIPublicUrl,
+}
+
+// portHoppingConfigKeys 修改后需要重新应用端口跳跃规则的配置
+var portHoppingConfigKeys = []string{
+	constant.Hysteria2ConfigPortHopping,
+	constant.Hysteria2ConfigPortHoppingIfaces,
+	constant.Hysteria2Node2PortHopping,
+	constant.Hysteria2Node2PortHoppingIfaces,
+}
+
+// serverConfigKeys 面板监听相关的配置，切换失败时恢复原来的值
+var serverConfigKeys = []string{
+	constant.HUIWebPort,
+	constant.HUICrtPath,
+	constant.HUIKeyPath,
+	constant.HUIWebRoutes,
+	constant.HUIWebContext,
+	constant.HUITrustedProxies,
+	constant.PanelAcmeEnable,
+}
+
 func UpdateConfigs(c *gin.Context) {
 	configsUpdateDto, err := validateField(c, dto.ConfigsUpdateDto{})
 	if err != nil {
@@ -36,7 +67,42 @@ func UpdateConfigs(c *gin.Context) {
 	}
 
 	needResetPortHopping := false
+	needResetFirewall := false
 	needRestart := false
+	needReloadServer := false
+	needInitPanelAcme := false
+	needRestartTelegram := false
+	needRenewPanelAcme := false
+	var outboundKeys []string
+	var changedKeys []string
+	previousServerConfigs := map[string]string{}
+
+	updates := map[string]string{}
+	for _, item := range configsUpdateDto.ConfigUpdateDtos {
+		updates[*item.Key] = *item.Value
+	}
+	if err = service.CheckPanelAcme(updates); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	// 证书和私钥可能分开修改，按修改后的一对检查
+	newCrtPath, crtOk := updates[constant.HUICrtPath]
+	newKeyPath, keyOk := updates[constant.HUIKeyPath]
+	if crtOk || keyOk {
+		if !crtOk {
+			newCrtPath = crtPath
+		}
+		if !keyOk {
+			newKeyPath = keyPath
+		}
+		if newCrtPath != "" && newKeyPath != "" && (newCrtPath != crtPath || newKeyPath != keyPath) &&
+			util.Exists(newCrtPath) && util.Exists(newKeyPath) {
+			if err = service.CheckPanelCert(newCrtPath, newKeyPath); err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+		}
+	}
 
 	for _, item := range configsUpdateDto.ConfigUpdateDtos {
 		key := *item.Key
@@ -52,21 +118,53 @@ func UpdateConfigs(c *gin.Context) {
 				vo.Fail(fmt.Sprintf("port: %s is used", value), c)
 				return
 			}
-			needRestart = true
+			needReloadServer = true
 		}
 		if key == constant.HUICrtPath && crtPath != value {
 			if value != "" && !util.Exists(value) {
 				vo.Fail(fmt.Sprintf("crt path: %s is not exist", value), c)
 				return
 			}
-			needRestart = true
+			needReloadServer = true
 		}
 		if key == constant.HUIKeyPath && keyPath != value {
 			if value != "" && !util.Exists(value) {
 				vo.Fail(fmt.Sprintf("key path: %s is not exist", value), c)
 				return
 			}
-			needRestart = true
+			needReloadServer = true
+		}
+
+		if key == constant.HUIWebRoutes {
+			if err := service.CheckWebRoutes(value); err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			webRoutes, err := service.GetConfig(constant.HUIWebRoutes)
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			// 是否开放面板路由决定面板端口是否使用白名单
+			if *webRoutes.Value != value {
+				needReloadServer = true
+				needResetFirewall = true
+			}
+		}
+
+		if key == constant.HUITrustedProxies {
+			if _, err := service.ParseTrustedProxies(value); err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			trustedProxies, err := service.GetConfig(constant.HUITrustedProxies)
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			if *trustedProxies.Value != value {
+				needReloadServer = true
+			}
 		}
 
 		if key == constant.HUIWebContext {
@@ -76,26 +174,99 @@ func UpdateConfigs(c *gin.Context) {
 				return
 			}
 			if *huiWebContext.Value != value {
-				needRestart = true
+				needReloadServer = true
 			}
 		}
 
-		if key == constant.Hysteria2ConfigPortHopping {
+		if key == constant.Hysteria2ConfigPortHopping || key == constant.Hysteria2Node2PortHopping {
 			re := regexp.MustCompile("^\\d+(?:-\\d+)?(?:,\\d+(?:-\\d+)?)*$")
 			if value != "" && !re.MatchString(value) {
 				vo.Fail(fmt.Sprintf("port hopping: %s is invalid", value), c)
 				return
 			}
-			hysteria2ConfigPortHopping, err := service.GetConfig(constant.Hysteria2ConfigPortHopping)
+		}
+		if key == constant.Hysteria2ConfigPortHoppingIfaces || key == constant.Hysteria2Node2PortHoppingIfaces {
+			if _, err := service.ParsePortHoppingInterfaces(value); err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+		}
+		if util.ArrContain(portHoppingConfigKeys, key) {
+			portHoppingConfig, err := service.GetConfig(key)
 			if err != nil {
 				vo.Fail(err.Error(), c)
 				return
 			}
-			if *hysteria2ConfigPortHopping.Value != value {
+			if *portHoppingConfig.Value != value {
 				needResetPortHopping = true
 			}
 		}
 
+		if key == constant.FirewallEnable && value != "0" && value != "1" {
+			vo.Fail(fmt.Sprintf("firewall enable: %s is invalid", value), c)
+			return
+		}
+		if key == constant.FirewallPanelAllowlist {
+			allowlist, err := service.ParseFirewallAllowlist(value)
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			if !service.FirewallAllowlistContains(allowlist, c.RemoteIP()) {
+				vo.Fail(fmt.Sprintf("your ip %s is not in the panel allowlist", c.RemoteIP()), c)
+				return
+			}
+		}
+		if key == constant.FirewallEnable || key == constant.FirewallPanelAllowlist || key == constant.HUIWebPort {
+			firewallConfig, err := service.GetConfig(key)
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			if *firewallConfig.Value != value {
+				needResetFirewall = true
+			}
+		}
+
+		if key == constant.Hysteria2Outbounds || key == constant.Hysteria2Node2Outbounds {
+			if err := service.CheckNodeOutbounds(value); err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			outboundConfig, err := service.GetConfig(key)
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			if *outboundConfig.Value != value {
+				outboundKeys = append(outboundKeys, key)
+			}
+		}
+		if key == constant.OutboundCheckUrl || key == constant.GeoIPUrl || key == constant.GeoSiteUrl {
+			configUrl, err := url.Parse(value)
+			if err != nil || (configUrl.Scheme != "http" && configUrl.Scheme != "https") || configUrl.Host == "" {
+				vo.Fail(fmt.Sprintf("%s: %s is invalid", key, value), c)
+				return
+			}
+		}
+
+		// 开关变化需要切换面板的证书，其他配置变化只需要重新申请
+		if service.IsPanelAcmeConfigKey(key) {
+			panelAcmeConfig, err := service.GetConfig(key)
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			if *panelAcmeConfig.Value != value {
+				if key == constant.PanelAcmeEnable {
+					needInitPanelAcme = true
+					needReloadServer = true
+				} else {
+					needRenewPanelAcme = true
+				}
+			}
+		}
+
 		if key == constant.ResetTrafficCron {
 			resetTrafficCron, err := service.GetConfig(constant.ResetTrafficCron)
 			if err != nil {
@@ -107,22 +278,55 @@ func UpdateConfigs(c *gin.Context) {
 			}
 		}
 
-		if key == constant.TelegramEnable {
-			telegramEnable, err := service.GetConfig(constant.TelegramEnable)
+		if key == constant.PasswordMinLength || key == constant.PasswordHistory {
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || key == constant.PasswordMinLength && (number < 6 || number > 32) ||
+				key == constant.PasswordHistory && (number < 0 || number > 20) {
+				vo.Fail(fmt.Sprintf("%s: %s is invalid", key, value), c)
+				return
+			}
+		}
+
+		if util.ArrContain(telegramConfigKeys, key) {
+			if key == constant.TelegramMode && value != constant.TelegramModePolling && value != constant.TelegramModeWebhook {
+				vo.Fail(fmt.Sprintf("telegram mode: %s is invalid", value), c)
+				return
+			}
+			telegramConfig, err := service.GetConfig(key)
 			if err != nil {
 				vo.Fail(err.Error(), c)
 				return
 			}
-			if *telegramEnable.Value != value {
-				needRestart = true
+			if *telegramConfig.Value != value {
+				needRestartTelegram = true
 			}
 		}
 
+		if util.ArrContain(serverConfigKeys, key) {
+			serverConfig, err := service.GetConfig(key)
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				return
+			}
+			if _, ok := previousServerConfigs[key]; !ok {
+				previousServerConfigs[key] = *serverConfig.Value
+			}
+		}
 		if err = service.UpdateConfig(key, value); err != nil {
 			vo.Fail(err.Error(), c)
 			return
 		}
+		changedKeys = append(changedKeys, key)
 	}
+	// 面板没有切换成功时恢复原来的配置，避免下次启动失败
+	restoreServerConfigs := func() {
+		for key, value := range previousServerConfigs {
+			if err := service.UpdateConfig(key, value); err != nil {
+				logrus.Errorf("restore config %s err: %v", key, err)
+			}
+		}
+	}
+	service.EmitConfigWebhookEvent(changedKeys)
 
 	if needResetPortHopping {
 		if err := service.InitPortHopping(); err != nil {
@@ -130,6 +334,50 @@ func UpdateConfigs(c *gin.Context) {
 			return
 		}
 	}
+	for _, key := range outboundKeys {
+		if err := service.ApplyNodeOutbounds(key); err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+	}
+
+	// 机器人单独重启，不需要重启面板
+	if needRestartTelegram && !needRestart {
+		if err := service.StartTelegramBot(); err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+	}
+
+	// 面板只切换监听和路由，节点不受影响
+	if needInitPanelAcme && !needRestart {
+		if err := service.InitPanelAcme(); err != nil {
+			restoreServerConfigs()
+			vo.Fail(err.Error(), c)
+			return
+		}
+	}
+	// 需要重启时也先切换一次，确认新的监听可用
+	if needReloadServer {
+		if err := service.ReloadServer(); err != nil {
+			restoreServerConfigs()
+			vo.Fail(err.Error(), c)
+			return
+		}
+	}
+	// 面板切换成功后再放行新的端口，切换失败时防火墙仍对应原来的端口
+	if needResetPortHopping || needResetFirewall {
+		if err := service.InitFirewall(); err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
+	}
+
+	if needRenewPanelAcme && !needInitPanelAcme && !needRestart && service.PanelAcmeEnabled() {
+		go func() {
+			_ = service.RenewPanelAcme(true)
+		}()
+	}
 
 	if needRestart {
 		go func() {
@@ -227,12 +475,17 @@ func UpdateHysteria2Config(c *gin.Context) {
 		vo.Fail(err.Error(), c)
 		return
 	}
+	service.EmitConfigWebhookEvent([]string{constant.Hysteria2Config})
 
 	if needResetPortHopping {
 		if err := service.InitPortHopping(); err != nil {
 			vo.Fail(err.Error(), c)
 			return
 		}
+		if err := service.InitFirewall(); err != nil {
+			vo.Fail(err.Error(), c)
+			return
+		}
 	}
 
 	running := service.Hysteria2IsRunning()
@@ -379,6 +632,7 @@ func ImportHysteria2Config(c *gin.Context) {
 		vo.Fail(err.Error(), c)
 		return
 	}
+	service.EmitConfigWebhookEvent([]string{constant.Hysteria2Config})
 
 	running := service.Hysteria2IsRunning()
 	if running {
@@ -400,7 +654,7 @@ func ExportConfig(c *gin.Context) {
 	fileName := fmt.Sprintf("SystemConfig-%s.json", time.Now().Format("20060102150405"))
 	filePath := constant.ExportPathDir + fileName
 
-	if err = util.ExportFile(filePath, configs, 0); err != nil {
+	if err = util.ExportFile(filePath, service.RedactConfigs(configs), 0); err != nil {
 		vo.Fail(err.Error(), c)
 		return
 	}
@@ -434,15 +688,28 @@ func ImportConfig(c *gin.Context) {
 		vo.Fail("json file read err", c)
 		return
 	}
-	var configs []entity.Config
-	if err = json.Unmarshal(content, &configs); err != nil {
+	var importConfigs []entity.Config
+	if err = json.Unmarshal(content, &importConfigs); err != nil {
 		vo.Fail("content Unmarshal err", c)
 		return
 	}
+	// 导出时隐藏的敏感配置保持不变
+	configs, err := service.RestoreRedactedConfigs(importConfigs)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
 	if err = service.UpsertConfig(configs); err != nil {
 		vo.Fail(err.Error(), c)
 		return
 	}
+	var importKeys []string
+	for _, item := range configs {
+		if item.Key != nil {
+			importKeys = append(importKeys, *item.Key)
+		}
+	}
+	service.EmitConfigWebhookEvent(importKeys)
 	go func() {
 		_ = service.StopServer()
 	}()
@@ -512,6 +779,27 @@ func UploadCertFile(c *gin.Context) {
 	}
 	vo.Success(certPath, c)
 }
+
+// GetPortHoppingStatus 当前和期望的端口跳跃规则
+func GetPortHoppingStatus(c *gin.Context) {
+	status, err := service.PortHoppingStatus()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(status, c)
+}
+
+// GetFirewallStatus 当前和期望的防火墙规则
+func GetFirewallStatus(c *gin.Context) {
+	status, err := service.FirewallStatus()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(status, c)
+}
+
 // GetHysteria2Node2Config 获取第二节点配置
 func GetHysteria2Node2Config(c *gin.Context) {
 	config, err := service.GetHysteria2Node2Config()
@@ -533,6 +821,7 @@ func UpdateHysteria2Node2Config(c *gin.Context) {
 		vo.Fail(err.Error(), c)
 		return
 	}
+	service.EmitConfigWebhookEvent([]string{constant.Hysteria2Node2Config})
 
 	// 如果第二节点正在运行，重启它
 	if service.Hysteria2Node2IsRunning() {
@@ -551,7 +840,7 @@ func GetSocks5Config(c *gin.Context) {
 		vo.Fail(err.Error(), c)
 		return
 	}
-	
+
 	// 隐藏密码
 	configVo := vo.Socks5ConfigVo{
 		Addr:     config.Addr,
@@ -578,6 +867,7 @@ func UpdateSocks5Config(c *gin.Context) {
 		vo.Fail(err.Error(), c)
 		return
 	}
+	service.EmitConfigWebhookEvent([]string{constant.Hysteria2Socks5Addr, constant.Hysteria2Socks5User, constant.Hysteria2Socks5Pass})
 
 	// 如果第二节点正在运行，重启它以应用新的SOCKS5配置
 	if service.Hysteria2Node2IsRunning() {
@@ -648,6 +938,12 @@ func ToggleNode2(c *gin.Context) {
 		}
 	}
 
+	node2Keys := []string{constant.Hysteria2Node2Enable}
+	if node2ConfigDto.Remark != "" {
+		node2Keys = append(node2Keys, constant.Hysteria2Node2Remark)
+	}
+	service.EmitConfigWebhookEvent(node2Keys)
+
 	// 根据开关状态启动或停止第二节点
 	if node2ConfigDto.Enable {
 		// 检查SOCKS5配置是否完整
@@ -674,7 +970,7 @@ func ToggleNode2(c *gin.Context) {
 				return
 			}
 		}
-		
+
 		// 将所有双节点用户降级为单节点
 		if err = service.DowngradeUsersToSingleNode(); err != nil {
 			logrus.Errorf("failed to downgrade users to single node: %v", err)
@@ -682,6 +978,15 @@ func ToggleNode2(c *gin.Context) {
 		}
 	}
 
+	if err = service.InitPortHopping(); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	if err = service.InitFirewall(); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+
 	vo.Success(nil, c)
 }
 
@@ -689,8 +994,9 @@ func ToggleNode2(c *gin.Context) {
 func GetAllNodesStatus(c *gin.Context) {
 	status := service.GetNodesStatus()
 	vo.Success(status, c)
-}// 
-ExportNode2Config 导出第二节点配置
+}
+
+// ExportNode2Config 导出第二节点配置
 func ExportNode2Config(c *gin.Context) {
 	// 检查第二节点是否启用
 	enabled, err := service.IsNode2Enabled()
@@ -807,6 +1113,7 @@ func ImportNode2Config(c *gin.Context) {
 		vo.Fail(err.Error(), c)
 		return
 	}
+	service.EmitConfigWebhookEvent([]string{constant.Hysteria2Node2Config, constant.Hysteria2Socks5Addr, constant.Hysteria2Socks5User, constant.Hysteria2Socks5Pass})
 
 	vo.Success(nil, c)
 }
@@ -828,8 +1135,8 @@ func ExportFullConfig(c *gin.Context) {
 	}
 
 	exportData := map[string]interface{}{
-		"systemConfigs":   systemConfigs,
-		"hysteria2Config": hysteria2Config,
+		"systemConfigs":   service.RedactConfigs(systemConfigs),
+		"hysteria2Config": service.RedactHysteria2Config(hysteria2Config),
 		"exportTime":      time.Now().Format("2006-01-02 15:04:05"),
 		"version":         "1.0",
 	}
@@ -839,12 +1146,12 @@ func ExportFullConfig(c *gin.Context) {
 	if err == nil && enabled {
 		node2Config, err := service.GetHysteria2Node2Config()
 		if err == nil {
-			exportData["node2Config"] = node2Config
+			exportData["node2Config"] = service.RedactHysteria2Config(node2Config)
 		}
 
 		socks5Config, err := service.GetSocks5Config()
 		if err == nil {
-			exportData["socks5Config"] = socks5Config
+			exportData["socks5Config"] = service.RedactSocks5Config(socks5Config)
 		}
 	}
 
@@ -864,4 +1171,4 @@ func ExportFullConfig(c *gin.Context) {
 	c.Header("Content-Transfer-Encoding", "binary")
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
 	c.File(filePath)
-}
\ No newline at end of file
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+	"io"
+	"path/filepath"
+)
+
+func ListGeo(c *gin.Context) {
+	vo.Success(service.ListGeo(), c)
+}
+
+func ListGeoCategory(c *gin.Context) {
+	geoDto, err := validateField(c, dto.GeoDto{})
+	if err != nil {
+		return
+	}
+	geoCategoryVos, err := service.ListGeoCategory(*geoDto.Type)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(geoCategoryVos, c)
+}
+
+// UploadGeo multipart 表单：file、type，可选 sha256
+func UploadGeo(c *gin.Context) {
+	var geoUpdateDto dto.GeoUpdateDto
+	_ = c.ShouldBind(&geoUpdateDto)
+	if err := validate.Struct(&geoUpdateDto); err != nil {
+		vo.Fail(constant.InvalidError, c)
+		return
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		vo.Fail(constant.SysError, c)
+		return
+	}
+	defer file.Close()
+	if header.Size > 1024*1024*64 {
+		vo.Fail("the file is too big", c)
+		return
+	}
+	if filepath.Ext(header.Filename) != ".dat" {
+		vo.Fail("file format not supported", c)
+		return
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		vo.Fail("dat file read err", c)
+		return
+	}
+	expectSha256 := ""
+	if geoUpdateDto.Sha256 != nil {
+		expectSha256 = *geoUpdateDto.Sha256
+	}
+	if err = service.UploadGeo(*geoUpdateDto.Type, content, expectSha256); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func RefreshGeo(c *gin.Context) {
+	geoUpdateDto, err := validateField(c, dto.GeoUpdateDto{})
+	if err != nil {
+		return
+	}
+	expectSha256 := ""
+	if geoUpdateDto.Sha256 != nil {
+		expectSha256 = *geoUpdateDto.Sha256
+	}
+	if err = service.RefreshGeo(*geoUpdateDto.Type, expectSha256); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func RollbackGeo(c *gin.Context) {
+	geoDto, err := validateField(c, dto.GeoDto{})
+	if err != nil {
+		return
+	}
+	if err = service.RollbackGeo(*geoDto.Type); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
@@ -13,6 +13,7 @@ import (
 	"h-ui/util"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,7 +23,9 @@ func Hysteria2Auth(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	id, username, err := service.Hysteria2Auth(*hysteria2AuthDto.Auth)
+	// 规则集实例的认证地址带有 aclProfileId
+	aclProfileId, _ := strconv.ParseInt(c.Query("aclProfileId"), 10, 64)
+	id, username, err := service.Hysteria2Auth(*hysteria2AuthDto.Auth, aclProfileId)
 	if err != nil || username == "" {
 		vo.Hysteria2AuthFail("", c)
 		return
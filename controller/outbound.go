@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListOutbound(c *gin.Context) {
+	outboundVos, err := service.ListOutbound()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(outboundVos, c)
+}
+
+func SaveOutbound(c *gin.Context) {
+	outboundSaveDto, err := validateField(c, dto.OutboundSaveDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SaveOutbound(outboundSaveDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func UpdateOutbound(c *gin.Context) {
+	outboundUpdateDto, err := validateField(c, dto.OutboundUpdateDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UpdateOutbound(outboundUpdateDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func DeleteOutbound(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeleteOutbound(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func CheckOutbound(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	outbound, err := service.CheckOutbound(*idDto.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(vo.OutboundCheckVo{
+		Status:     *outbound.Status,
+		Latency:    *outbound.Latency,
+		FailCount:  *outbound.FailCount,
+		CheckError: *outbound.CheckError,
+	}, c)
+}
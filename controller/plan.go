@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListPlan(c *gin.Context) {
+	planVos, err := service.ListPlan()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(planVos, c)
+}
+
+func SavePlan(c *gin.Context) {
+	planSaveDto, err := validateField(c, dto.PlanSaveDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SavePlan(planSaveDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func UpdatePlan(c *gin.Context) {
+	planUpdateDto, err := validateField(c, dto.PlanUpdateDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UpdatePlan(planUpdateDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func DeletePlan(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeletePlan(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
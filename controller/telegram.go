@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+	"io"
+	"net/http"
+)
+
+// TelegramWebhook 接收 webhook 模式下 Telegram 推送的消息
+func TelegramWebhook(c *gin.Context) {
+	if !service.TelegramWebhookAuthorized(c.GetHeader(constant.TelegramSecretTokenHeader)) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if err = service.HandleTelegramWebhook(body); err != nil {
+		if errors.Is(err, service.ErrTelegramBusy) {
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func GetTelegramBotStatus(c *gin.Context) {
+	vo.Success(service.GetTelegramBotStatus(), c)
+}
+
+func ListTelegramChat(c *gin.Context) {
+	telegramChatVos, err := service.ListTelegramChat()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(telegramChatVos, c)
+}
+
+func SaveTelegramChat(c *gin.Context) {
+	telegramChatSaveDto, err := validateField(c, dto.TelegramChatSaveDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SaveTelegramChat(telegramChatSaveDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func UpdateTelegramChat(c *gin.Context) {
+	telegramChatUpdateDto, err := validateField(c, dto.TelegramChatUpdateDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UpdateTelegramChat(telegramChatUpdateDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func DeleteTelegramChat(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeleteTelegramChat(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
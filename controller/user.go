@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func UserLogin(c *gin.Context) {
+	loginDto, err := validateField(c, dto.LoginDto{})
+	if err != nil {
+		return
+	}
+
+	clientIP := c.ClientIP()
+	if err = service.LoginAllowed(*loginDto.Username, clientIP); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+
+	token, err := service.UserLogin(*loginDto.Username, *loginDto.Pass)
+	if err != nil {
+		service.LoginFailed(*loginDto.Username, clientIP)
+		vo.Fail(err.Error(), c)
+		return
+	}
+	service.LoginSucceeded(*loginDto.Username, clientIP)
+	vo.Success(vo.JwtVo{
+		TokenType:   constant.TokenType,
+		AccessToken: token,
+	}, c)
+}
+
+func UserLoginByToken(c *gin.Context) {
+	userLoginTokenDto, err := validateField(c, dto.UserLoginTokenDto{})
+	if err != nil {
+		return
+	}
+	token, err := service.UserLoginByToken(*userLoginTokenDto.Token)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(vo.JwtVo{
+		TokenType:   constant.TokenType,
+		AccessToken: token,
+	}, c)
+}
+
+func GetUserInfo(c *gin.Context) {
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	userInfoVo, err := service.GetUserInfo(accountBo.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(userInfoVo, c)
+}
+
+func ListUserTrafficDaily(c *gin.Context) {
+	userTrafficDailyDto, err := validateField(c, dto.UserTrafficDailyDto{})
+	if err != nil {
+		return
+	}
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	var days int64
+	if userTrafficDailyDto.Days != nil {
+		days = *userTrafficDailyDto.Days
+	}
+	trafficDailyVos, err := service.ListUserTrafficDaily(accountBo.Id, days)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(trafficDailyVos, c)
+}
+
+func GetUserSubscribe(c *gin.Context) {
+	userSubscribeDto, err := validateField(c, dto.UserSubscribeDto{})
+	if err != nil {
+		return
+	}
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	subscribeVo, err := service.GetUserSubscribe(accountBo.Id, *userSubscribeDto.Protocol, *userSubscribeDto.Host)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(subscribeVo, c)
+}
+
+func RotateUserConPass(c *gin.Context) {
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	conPass, err := service.RotateUserConPass(accountBo.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(vo.UserConPassVo{ConPass: conPass}, c)
+}
+
+func KickUser(c *gin.Context) {
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	if err = service.KickUserSessions(accountBo.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func RedeemUserVoucher(c *gin.Context) {
+	voucherCodeDto, err := validateField(c, dto.VoucherCodeDto{})
+	if err != nil {
+		return
+	}
+	accountBo, err := service.GetAccountBo(c)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	redemptionVo, err := service.RedeemVoucher(accountBo.Id, *voucherCodeDto.Code)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(redemptionVo, c)
+}
@@ -6,8 +6,8 @@ import (
 	"github.com/go-playground/validator/v10"
 	"h-ui/model/constant"
 	"h-ui/model/vo"
+	"h-ui/util"
 	"net/http"
-	"regexp"
 )
 
 var validate *validator.Validate
@@ -19,10 +19,7 @@ func init() {
 
 func validateStr(f validator.FieldLevel) bool {
 	field := f.Field().String()
-	// 字符串必须6-32位是字母或者数字或部分特殊字符的组合
-	reg := "^[a-zA-Z0-9!@#$%^&*()_+-=]{6,32}$"
-	compile := regexp.MustCompile(reg)
-	return field == "" || compile.MatchString(field)
+	return field == "" || util.ValidateStr(field)
 }
 
 func validateField[T interface{}](c *gin.Context, field T) (T, error) {
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+	"h-ui/util"
+	"strconv"
+)
+
+func GenerateVoucherBatch(c *gin.Context) {
+	voucherBatchSaveDto, err := validateField(c, dto.VoucherBatchSaveDto{})
+	if err != nil {
+		return
+	}
+	id, err := service.GenerateVoucherBatch(voucherBatchSaveDto)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(id, c)
+}
+
+func ListVoucherBatch(c *gin.Context) {
+	voucherBatchVos, err := service.ListVoucherBatch()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(voucherBatchVos, c)
+}
+
+func DeleteVoucherBatch(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeleteVoucherBatch(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func ListVoucher(c *gin.Context) {
+	voucherListDto, err := validateField(c, dto.VoucherListDto{})
+	if err != nil {
+		return
+	}
+	voucherVos, err := service.ListVoucher(*voucherListDto.BatchId)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(voucherVos, c)
+}
+
+func ExportVoucherBatch(c *gin.Context) {
+	voucherListDto, err := validateField(c, dto.VoucherListDto{})
+	if err != nil {
+		return
+	}
+	voucherBatch, err := service.GetVoucherBatch(*voucherListDto.BatchId)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	voucherVos, err := service.ListVoucher(*voucherBatch.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+
+	header := []string{"code", "batch", "planId", "quota", "duration", "maxUses", "used", "expireTime"}
+	var rows [][]string
+	for _, item := range voucherVos {
+		rows = append(rows, []string{
+			item.Code,
+			*voucherBatch.Name,
+			strconv.FormatInt(*voucherBatch.PlanId, 10),
+			strconv.FormatInt(*voucherBatch.Quota, 10),
+			strconv.FormatInt(*voucherBatch.Duration, 10),
+			strconv.FormatInt(item.MaxUses, 10),
+			strconv.FormatInt(item.Used, 10),
+			strconv.FormatInt(item.ExpireTime, 10),
+		})
+	}
+	fileName := fmt.Sprintf("VoucherExport-%d.csv", *voucherBatch.Id)
+	filePath := constant.ExportPathDir + fileName
+	if err = util.ExportCsv(filePath, header, rows); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+
+	if !util.Exists(filePath) {
+		vo.Fail("file not exist", c)
+		return
+	}
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.File(filePath)
+}
+
+func RedeemVoucher(c *gin.Context) {
+	voucherRedeemDto, err := validateField(c, dto.VoucherRedeemDto{})
+	if err != nil {
+		return
+	}
+	redemptionVo, err := service.RedeemVoucher(*voucherRedeemDto.AccountId, *voucherRedeemDto.Code)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(redemptionVo, c)
+}
+
+func PageVoucherRedemption(c *gin.Context) {
+	voucherRedemptionPageDto, err := validateField(c, dto.VoucherRedemptionPageDto{})
+	if err != nil {
+		return
+	}
+	redemptionPageVo, err := service.PageVoucherRedemption(voucherRedemptionPageDto)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(redemptionPageVo, c)
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListWebListener(c *gin.Context) {
+	webListenerVos, err := service.ListWebListener()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(webListenerVos, c)
+}
+
+func SaveWebListener(c *gin.Context) {
+	webListenerSaveDto, err := validateField(c, dto.WebListenerSaveDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SaveWebListener(webListenerSaveDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func UpdateWebListener(c *gin.Context) {
+	webListenerUpdateDto, err := validateField(c, dto.WebListenerUpdateDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UpdateWebListener(webListenerUpdateDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func DeleteWebListener(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeleteWebListener(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
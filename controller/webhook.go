@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"h-ui/service"
+)
+
+func ListWebhook(c *gin.Context) {
+	webhookVos, err := service.ListWebhook()
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(webhookVos, c)
+}
+
+func SaveWebhook(c *gin.Context) {
+	webhookSaveDto, err := validateField(c, dto.WebhookSaveDto{})
+	if err != nil {
+		return
+	}
+	if err = service.SaveWebhook(webhookSaveDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func UpdateWebhook(c *gin.Context) {
+	webhookUpdateDto, err := validateField(c, dto.WebhookUpdateDto{})
+	if err != nil {
+		return
+	}
+	if err = service.UpdateWebhook(webhookUpdateDto); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func DeleteWebhook(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	if err = service.DeleteWebhook(*idDto.Id); err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(nil, c)
+}
+
+func TestWebhook(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	deliveryId, err := service.TestWebhook(*idDto.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(deliveryId, c)
+}
+
+func RedeliverWebhook(c *gin.Context) {
+	idDto, err := validateField(c, dto.IdDto{})
+	if err != nil {
+		return
+	}
+	deliveryId, err := service.RedeliverWebhook(*idDto.Id)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	vo.Success(deliveryId, c)
+}
+
+func PageWebhookDelivery(c *gin.Context) {
+	deliveryPageDto, err := validateField(c, dto.WebhookDeliveryPageDto{})
+	if err != nil {
+		return
+	}
+	deliveries, total, err := service.PageWebhookDelivery(deliveryPageDto)
+	if err != nil {
+		vo.Fail(err.Error(), c)
+		return
+	}
+	deliveryVos := make([]vo.WebhookDeliveryVo, 0, len(deliveries))
+	for _, item := range deliveries {
+		deliveryVos = append(deliveryVos, vo.WebhookDeliveryVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			WebhookId:    *item.WebhookId,
+			Event:        *item.Event,
+			Payload:      *item.Payload,
+			Status:       *item.Status,
+			Attempts:     *item.Attempts,
+			ResponseCode: *item.ResponseCode,
+			Error:        *item.Error,
+			UpdateTime:   *item.UpdateTime,
+		})
+	}
+	vo.Success(vo.WebhookDeliveryPageVo{
+		WebhookDeliveryVos: deliveryVos,
+		Total:              total,
+	}, c)
+}
@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
+	"h-ui/model/bo"
 	"h-ui/model/constant"
 	"h-ui/model/dto"
 	"h-ui/model/entity"
+	"h-ui/util"
 	"time"
 )
 
@@ -28,6 +29,46 @@ func DeleteAccount(ids []int64) error {
 	return nil
 }
 
+// DeleteAccountCascade 在同一事务中删除账号及其关联数据
+func DeleteAccountCascade(ids []int64) error {
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		for _, value := range []interface{}{&entity.AccountTrafficDaily{}, &entity.TelegramBinding{},
+			&entity.AccountPlanHistory{}, &entity.AccountTag{}, &entity.AccountPassHistory{}} {
+			if err := tx.Where("account_id in ?", ids).Delete(value).Error; err != nil {
+				logrus.Errorf("%v", err)
+				return errors.New(constant.SysError)
+			}
+		}
+		if err := tx.Where("id in ?", ids).Delete(&entity.Account{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+// initDefaultAccountPass 新安装时为 sysadmin 生成默认密码的哈希，默认密码未修改前必须先修改密码
+func initDefaultAccountPass() error {
+	if tx := sqliteDB.Model(&entity.Account{}).
+		Where("id = 1 and pass in ?", []string{"", util.SHA224String(constant.DefaultAccountPass)}).
+		Update("must_change_pass", 1); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	pass, err := util.HashPassword(constant.DefaultAccountPass)
+	if err != nil {
+		logrus.Errorf("hash default password err: %v", err)
+		return errors.New(constant.SysError)
+	}
+	if tx := sqliteDB.Model(&entity.Account{}).
+		Where("id = 1 and pass = ''").
+		Update("pass", pass); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
 func UpdateAccount(ids []int64, updates map[string]interface{}) error {
 	if len(updates) > 0 {
 		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
@@ -41,15 +82,57 @@ func UpdateAccount(ids []int64, updates map[string]interface{}) error {
 	return nil
 }
 
-func UpsertAccount(accounts []entity.Account) error {
-	if tx := sqliteDB.Model(&entity.Account{}).Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "username"}},
-		DoUpdates: clause.AssignmentColumns([]string{"pass", "con_pass", "quota", "download", "upload", "expire_time", "kick_util_time", "device_no", "role", "deleted", "create_time", "update_time", "login_at", "con_at"}),
-	}).Create(accounts); tx.Error != nil {
-		logrus.Errorf("%v", tx.Error)
-		return errors.New(constant.SysError)
+// ImportAccount 在同一事务中新建、更新导入的账号，返回新建和更新的账号 id
+func ImportAccount(accountImports []bo.AccountImport) ([]int64, []int64, error) {
+	var createdIds, updatedIds []int64
+	err := sqliteDB.Transaction(func(tx *gorm.DB) error {
+		updateTime := time.Now().Format("2006-01-02 15:04:05")
+		for _, item := range accountImports {
+			var accountId int64
+			if item.Id == nil {
+				account := item.Account
+				if err := tx.Create(&account).Error; err != nil {
+					logrus.Errorf("%v", err)
+					return errors.New(constant.SysError)
+				}
+				accountId = *account.Id
+				createdIds = append(createdIds, accountId)
+			} else {
+				accountId = *item.Id
+				if len(item.Updates) > 0 {
+					item.Updates["update_time"] = updateTime
+					if err := tx.Model(&entity.Account{}).Where("id = ?", accountId).Updates(item.Updates).Error; err != nil {
+						logrus.Errorf("%v", err)
+						return errors.New(constant.SysError)
+					}
+				}
+				updatedIds = append(updatedIds, accountId)
+			}
+			if !item.SetTags {
+				continue
+			}
+			if err := tx.Where("account_id = ?", accountId).Delete(&entity.AccountTag{}).Error; err != nil {
+				logrus.Errorf("%v", err)
+				return errors.New(constant.SysError)
+			}
+			if len(item.Tags) == 0 {
+				continue
+			}
+			accountTags := make([]entity.AccountTag, 0, len(item.Tags))
+			for i := range item.Tags {
+				accountTags = append(accountTags, entity.AccountTag{AccountId: &accountId, Tag: &item.Tags[i]})
+			}
+			if err := tx.Create(&accountTags).Error; err != nil {
+				logrus.Errorf("%v", err)
+				return errors.New(constant.SysError)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil
+	return createdIds, updatedIds, nil
 }
 
 func UpdateAccountTraffic(username string, download int64, upload int64) error {
@@ -85,16 +168,66 @@ func GetAccount(query interface{}, args ...interface{}) (entity.Account, error)
 	return account, nil
 }
 
-func PageAccount(accountPageDto dto.AccountPageDto) ([]entity.Account, int64, error) {
+// accountQuery 账号筛选条件，onlineUsernames 为当前在线的用户名
+func accountQuery(accountQueryDto dto.AccountQueryDto, onlineUsernames []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		now := time.Now().UnixMilli()
+		if accountQueryDto.Username != nil && *accountQueryDto.Username != "" {
+			db = db.Where("username like ?", fmt.Sprintf("%%%s%%", *accountQueryDto.Username))
+		}
+		if accountQueryDto.Deleted != nil {
+			db = db.Where("deleted = ?", *accountQueryDto.Deleted)
+		}
+		if accountQueryDto.Tag != nil && *accountQueryDto.Tag != "" {
+			db = db.Where("id in (select account_id from account_tag where tag = ?)", *accountQueryDto.Tag)
+		}
+		if accountQueryDto.Role != nil {
+			db = db.Where("role = ?", *accountQueryDto.Role)
+		}
+		if accountQueryDto.Expired != nil {
+			if *accountQueryDto.Expired == 1 {
+				db = db.Where("expire_time <= ?", now)
+			} else {
+				db = db.Where("expire_time > ?", now)
+			}
+		}
+		// 与 hysteria2 认证的判断保持一致
+		if accountQueryDto.OverQuota != nil {
+			if *accountQueryDto.OverQuota == 1 {
+				db = db.Where("quota >= 0 and quota <= download + upload")
+			} else {
+				db = db.Where("(quota < 0 or quota > download + upload)")
+			}
+		}
+		if accountQueryDto.Online != nil {
+			if *accountQueryDto.Online == 1 {
+				if len(onlineUsernames) == 0 {
+					db = db.Where("1 = 0")
+				} else {
+					db = db.Where("username in ?", onlineUsernames)
+				}
+			} else if len(onlineUsernames) > 0 {
+				db = db.Where("username not in ?", onlineUsernames)
+			}
+		}
+		if accountQueryDto.NeverConnected != nil {
+			if *accountQueryDto.NeverConnected == 1 {
+				db = db.Where("con_at = 0")
+			} else {
+				db = db.Where("con_at > 0")
+			}
+		}
+		if accountQueryDto.InactiveDays != nil {
+			db = db.Where("con_at > 0 and con_at < ?", now-*accountQueryDto.InactiveDays*int64(24*time.Hour/time.Millisecond))
+		}
+		return db
+	}
+}
+
+func PageAccount(accountPageDto dto.AccountPageDto, onlineUsernames []string) ([]entity.Account, int64, error) {
 	var accounts []entity.Account
 	var total int64
-	tx := sqliteDB.Model(&entity.Account{})
-	if accountPageDto.Username != nil && *accountPageDto.Username != "" {
-		tx.Where("username like ?", fmt.Sprintf("%%%s%%", *accountPageDto.Username))
-	}
-	if accountPageDto.Deleted != nil {
-		tx.Where("deleted = ?", *accountPageDto.Deleted)
-	}
+	tx := sqliteDB.Model(&entity.Account{}).Scopes(accountQuery(accountPageDto.AccountQueryDto, onlineUsernames))
 	tx.Count(&total)
 	if tx.Scopes(Paginate(accountPageDto.PageNum, accountPageDto.PageSize)).
 		Order("role,create_time desc").
@@ -105,6 +238,18 @@ func PageAccount(accountPageDto dto.AccountPageDto) ([]entity.Account, int64, er
 	return accounts, total, nil
 }
 
+// ListAccountId 筛选结果的账号 id
+func ListAccountId(accountQueryDto dto.AccountQueryDto, onlineUsernames []string) ([]int64, error) {
+	var ids []int64
+	if tx := sqliteDB.Model(&entity.Account{}).
+		Scopes(accountQuery(accountQueryDto, onlineUsernames)).
+		Pluck("id", &ids); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return ids, errors.New(constant.SysError)
+	}
+	return ids, nil
+}
+
 func ListAccount(query interface{}, args ...interface{}) ([]entity.Account, error) {
 	var accounts []entity.Account
 	if tx := sqliteDB.Model(&entity.Account{}).
@@ -114,3 +259,17 @@ func ListAccount(query interface{}, args ...interface{}) ([]entity.Account, erro
 	}
 	return accounts, nil
 }
+
+// ExtendAccountExpireTime 到期时间叠加，已过期的账号从 now 开始计算
+func ExtendAccountExpireTime(ids []int64, now int64, duration int64) error {
+	return UpdateAccount(ids, map[string]interface{}{
+		"expire_time": gorm.Expr("max(expire_time, ?) + ?", now, duration),
+	})
+}
+
+// AddAccountQuota 增加额度，无限额度的账号不变
+func AddAccountQuota(ids []int64, quota int64) error {
+	return UpdateAccount(ids, map[string]interface{}{
+		"quota": gorm.Expr("case when quota < 0 then quota else quota + ? end", quota),
+	})
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+)
+
+// SaveAccountPassHistory 记录账号使用过的密码哈希，只保留最近 keep 条
+func SaveAccountPassHistory(accountId int64, pass string, keep int64) error {
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entity.AccountPassHistory{AccountId: &accountId, Pass: &pass}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if err := tx.Where("account_id = ? and id not in (?)", accountId,
+			tx.Model(&entity.AccountPassHistory{}).Select("id").
+				Where("account_id = ?", accountId).Order("id desc").Limit(int(keep))).
+			Delete(&entity.AccountPassHistory{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+// ListAccountPassHistory 账号最近使用过的 limit 个密码哈希
+func ListAccountPassHistory(accountId int64, limit int64) ([]entity.AccountPassHistory, error) {
+	var histories []entity.AccountPassHistory
+	if tx := sqliteDB.Model(&entity.AccountPassHistory{}).
+		Where("account_id = ?", accountId).
+		Order("id desc").Limit(int(limit)).
+		Find(&histories); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return histories, errors.New(constant.SysError)
+	}
+	return histories, nil
+}
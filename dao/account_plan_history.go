@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveAccountPlanHistory(history entity.AccountPlanHistory) error {
+	if tx := sqliteDB.Create(&history); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+// ApplyAccountPlan 更新账号并记录套餐变更历史
+func ApplyAccountPlan(accountId int64, updates map[string]interface{}, history entity.AccountPlanHistory) error {
+	updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Account{}).Where("id = ?", accountId).Updates(updates).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+func DeleteAccountPlanHistory(query interface{}, args ...interface{}) error {
+	if tx := sqliteDB.Where(query, args...).Delete(&entity.AccountPlanHistory{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func ListAccountPlanHistory(query interface{}, args ...interface{}) ([]entity.AccountPlanHistory, error) {
+	var histories []entity.AccountPlanHistory
+	if tx := sqliteDB.Model(&entity.AccountPlanHistory{}).
+		Where(query, args...).Order("id desc").Find(&histories); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return histories, errors.New(constant.SysError)
+	}
+	return histories, nil
+}
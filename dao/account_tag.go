@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+)
+
+// AddAccountTag 为账号添加标签，已有的标签忽略
+func AddAccountTag(accountIds []int64, tag string) error {
+	accountTags := make([]entity.AccountTag, 0, len(accountIds))
+	for i := range accountIds {
+		accountTags = append(accountTags, entity.AccountTag{AccountId: &accountIds[i], Tag: &tag})
+	}
+	if tx := sqliteDB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&accountTags, 500); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+// SetAccountTags 替换账号的全部标签
+func SetAccountTags(accountId int64, tags []string) error {
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", accountId).Delete(&entity.AccountTag{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		accountTags := make([]entity.AccountTag, 0, len(tags))
+		for i := range tags {
+			accountTags = append(accountTags, entity.AccountTag{AccountId: &accountId, Tag: &tags[i]})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&accountTags).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+func DeleteAccountTag(query interface{}, args ...interface{}) error {
+	if tx := sqliteDB.Where(query, args...).Delete(&entity.AccountTag{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func ListAccountTag(query interface{}, args ...interface{}) ([]entity.AccountTag, error) {
+	var accountTags []entity.AccountTag
+	if tx := sqliteDB.Model(&entity.AccountTag{}).
+		Where(query, args...).Order("tag asc").Find(&accountTags); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return accountTags, errors.New(constant.SysError)
+	}
+	return accountTags, nil
+}
+
+// ListTagCount 所有标签及使用的账号数
+func ListTagCount() ([]bo.AccountTagCount, error) {
+	var tagCounts []bo.AccountTagCount
+	if tx := sqliteDB.Model(&entity.AccountTag{}).
+		Select("tag, count(*) as count").
+		Group("tag").Order("tag asc").
+		Scan(&tagCounts); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return tagCounts, errors.New(constant.SysError)
+	}
+	return tagCounts, nil
+}
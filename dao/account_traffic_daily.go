@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+)
+
+// AddAccountTrafficDaily 累加账号当天的流量
+func AddAccountTrafficDaily(username string, day string, download int64, upload int64) error {
+	if download == 0 && upload == 0 {
+		return nil
+	}
+	if tx := sqliteDB.Exec("INSERT INTO account_traffic_daily (account_id, day, download, upload) "+
+		"SELECT id, ?, ?, ? FROM account WHERE username = ? "+
+		"ON CONFLICT (account_id, day) DO UPDATE SET download = download + excluded.download, upload = upload + excluded.upload, update_time = CURRENT_TIMESTAMP",
+		day, download, upload, username); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func ListAccountTrafficDaily(query interface{}, args ...interface{}) ([]entity.AccountTrafficDaily, error) {
+	var trafficDailies []entity.AccountTrafficDaily
+	if tx := sqliteDB.Model(&entity.AccountTrafficDaily{}).
+		Where(query, args...).Order("day asc").Find(&trafficDailies); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return trafficDailies, errors.New(constant.SysError)
+	}
+	return trafficDailies, nil
+}
+
+// TopAccountTrafficDaily 指定日期流量最多的账号
+func TopAccountTrafficDaily(day string, limit int) ([]bo.AccountTrafficTop, error) {
+	var tops []bo.AccountTrafficTop
+	if tx := sqliteDB.Table("account_traffic_daily t").
+		Select("t.account_id, a.username, t.download, t.upload").
+		Joins("JOIN account a ON a.id = t.account_id").
+		Where("t.day = ?", day).
+		Order("t.download + t.upload desc").
+		Limit(limit).
+		Scan(&tops); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return tops, errors.New(constant.SysError)
+	}
+	return tops, nil
+}
+
+func DeleteAccountTrafficDaily(query interface{}, args ...interface{}) error {
+	if tx := sqliteDB.Where(query, args...).Delete(&entity.AccountTrafficDaily{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveAclProfile(aclProfile entity.AclProfile) (int64, error) {
+	if tx := sqliteDB.Create(&aclProfile); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *aclProfile.Id, nil
+}
+
+// DeleteAclProfile 删除规则集，使用该规则集的账号恢复为默认
+func DeleteAclProfile(ids []int64) error {
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id in ?", ids).Delete(&entity.AclProfile{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if err := tx.Model(&entity.Account{}).Where("acl_profile_id in ?", ids).
+			Update("acl_profile_id", 0).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+func UpdateAclProfile(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.AclProfile{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetAclProfile(query interface{}, args ...interface{}) (entity.AclProfile, error) {
+	var aclProfile entity.AclProfile
+	if tx := sqliteDB.Model(&entity.AclProfile{}).
+		Where(query, args...).First(&aclProfile); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return aclProfile, errors.New("acl profile not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return aclProfile, errors.New(constant.SysError)
+	}
+	return aclProfile, nil
+}
+
+func ListAclProfile(query interface{}, args ...interface{}) ([]entity.AclProfile, error) {
+	var aclProfiles []entity.AclProfile
+	if tx := sqliteDB.Model(&entity.AclProfile{}).
+		Where(query, args...).Order("id asc").Find(&aclProfiles); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return aclProfiles, errors.New(constant.SysError)
+	}
+	return aclProfiles, nil
+}
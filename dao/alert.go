@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveAlertRule(alertRule entity.AlertRule) (int64, error) {
+	if tx := sqliteDB.Create(&alertRule); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *alertRule.Id, nil
+}
+
+func DeleteAlertRule(ids []int64) error {
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id in ?", ids).Delete(&entity.AlertRule{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if err := tx.Where("rule_id in ?", ids).Delete(&entity.AlertState{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+func UpdateAlertRule(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.AlertRule{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetAlertRule(query interface{}, args ...interface{}) (entity.AlertRule, error) {
+	var alertRule entity.AlertRule
+	if tx := sqliteDB.Model(&entity.AlertRule{}).
+		Where(query, args...).First(&alertRule); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return alertRule, errors.New("alert rule not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return alertRule, errors.New(constant.SysError)
+	}
+	return alertRule, nil
+}
+
+func ListAlertRule(query interface{}, args ...interface{}) ([]entity.AlertRule, error) {
+	var alertRules []entity.AlertRule
+	if tx := sqliteDB.Model(&entity.AlertRule{}).
+		Where(query, args...).Order("id asc").Find(&alertRules); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return alertRules, errors.New(constant.SysError)
+	}
+	return alertRules, nil
+}
+
+func SaveAlertState(alertState entity.AlertState) (int64, error) {
+	if tx := sqliteDB.Create(&alertState); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *alertState.Id, nil
+}
+
+func UpdateAlertState(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.AlertState{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func DeleteAlertState(ids []int64) error {
+	if tx := sqliteDB.Where("id in ?", ids).Delete(&entity.AlertState{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func ListAlertState(query interface{}, args ...interface{}) ([]entity.AlertState, error) {
+	var alertStates []entity.AlertState
+	if tx := sqliteDB.Model(&entity.AlertState{}).
+		Where(query, args...).Order("id asc").Find(&alertStates); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return alertStates, errors.New(constant.SysError)
+	}
+	return alertStates, nil
+}
@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveApiKey(apiKey entity.ApiKey) (int64, error) {
+	if tx := sqliteDB.Save(&apiKey); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *apiKey.Id, nil
+}
+
+func DeleteApiKey(query interface{}, args ...interface{}) error {
+	if tx := sqliteDB.Where(query, args...).Delete(&entity.ApiKey{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func UpdateApiKey(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.ApiKey{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetApiKey(query interface{}, args ...interface{}) (entity.ApiKey, error) {
+	var apiKey entity.ApiKey
+	if tx := sqliteDB.Model(&entity.ApiKey{}).
+		Where(query, args...).First(&apiKey); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return apiKey, errors.New(constant.IllegalTokenError)
+		}
+		logrus.Errorf("%v", tx.Error)
+		return apiKey, errors.New(constant.SysError)
+	}
+	return apiKey, nil
+}
+
+func ListApiKey(query interface{}, args ...interface{}) ([]entity.ApiKey, error) {
+	var apiKeys []entity.ApiKey
+	if tx := sqliteDB.Model(&entity.ApiKey{}).
+		Where(query, args...).Order("create_time desc").Find(&apiKeys); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return apiKeys, errors.New(constant.SysError)
+	}
+	return apiKeys, nil
+}
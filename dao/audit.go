@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveAudit(audit entity.Audit) (int64, error) {
+	if tx := sqliteDB.Create(&audit); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *audit.Id, nil
+}
+
+func DeleteAuditBefore(before time.Time) error {
+	if tx := sqliteDB.Where("create_time < ?", before.Format("2006-01-02 15:04:05")).
+		Delete(&entity.Audit{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func auditQuery(auditQueryDto dto.AuditQueryDto) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if auditQueryDto.Actor != nil && *auditQueryDto.Actor != "" {
+			db = db.Where("actor = ?", *auditQueryDto.Actor)
+		}
+		if auditQueryDto.Action != nil && *auditQueryDto.Action != "" {
+			db = db.Where("action like ?", fmt.Sprintf("%%%s%%", *auditQueryDto.Action))
+		}
+		if auditQueryDto.Result != nil && *auditQueryDto.Result != "" {
+			db = db.Where("result = ?", *auditQueryDto.Result)
+		}
+		if auditQueryDto.TargetId != nil {
+			db = db.Where("(',' || target_ids || ',') like ?", fmt.Sprintf("%%,%d,%%", *auditQueryDto.TargetId))
+		}
+		if auditQueryDto.StartTime != nil {
+			db = db.Where("create_time >= ?", time.UnixMilli(*auditQueryDto.StartTime).Format("2006-01-02 15:04:05"))
+		}
+		if auditQueryDto.EndTime != nil {
+			db = db.Where("create_time <= ?", time.UnixMilli(*auditQueryDto.EndTime).Format("2006-01-02 15:04:05"))
+		}
+		return db
+	}
+}
+
+func PageAudit(auditPageDto dto.AuditPageDto) ([]entity.Audit, int64, error) {
+	var audits []entity.Audit
+	var total int64
+	tx := sqliteDB.Model(&entity.Audit{}).Scopes(auditQuery(auditPageDto.AuditQueryDto))
+	tx.Count(&total)
+	if tx.Scopes(Paginate(auditPageDto.PageNum, auditPageDto.PageSize)).
+		Order("id desc").
+		Find(&audits); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return audits, 0, errors.New(constant.SysError)
+	}
+	return audits, total, nil
+}
+
+func ListAudit(auditQueryDto dto.AuditQueryDto) ([]entity.Audit, error) {
+	var audits []entity.Audit
+	if tx := sqliteDB.Model(&entity.Audit{}).
+		Scopes(auditQuery(auditQueryDto)).
+		Order("id desc").
+		Find(&audits); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return audits, errors.New(constant.SysError)
+	}
+	return audits, nil
+}
@@ -11,6 +11,13 @@ import (
 )
 
 func SaveConfig(config entity.Config) (int64, error) {
+	if config.Key != nil && config.Value != nil {
+		value, err := encryptConfigValue(*config.Key, *config.Value)
+		if err != nil {
+			return 0, err
+		}
+		config.Value = &value
+	}
 	if tx := sqliteDB.Save(&config); tx.Error != nil {
 		logrus.Errorf("%v", tx.Error)
 		return 0, errors.New(constant.SysError)
@@ -19,6 +26,26 @@ func SaveConfig(config entity.Config) (int64, error) {
 }
 
 func UpdateConfig(keys []string, updates map[string]interface{}) error {
+	// 敏感配置每个值单独加密
+	if _, ok := updates["value"].(string); ok && len(keys) > 1 {
+		for _, key := range keys {
+			keyUpdates := make(map[string]interface{}, len(updates))
+			for k, v := range updates {
+				keyUpdates[k] = v
+			}
+			if err := UpdateConfig([]string{key}, keyUpdates); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if value, ok := updates["value"].(string); ok && len(keys) == 1 {
+		encrypted, err := encryptConfigValue(keys[0], value)
+		if err != nil {
+			return err
+		}
+		updates["value"] = encrypted
+	}
 	if len(updates) > 0 {
 		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
 		if tx := sqliteDB.Model(&entity.Config{}).
@@ -41,6 +68,9 @@ func GetConfig(query interface{}, args ...interface{}) (entity.Config, error) {
 		logrus.Errorf("%v", tx.Error)
 		return config, errors.New(constant.SysError)
 	}
+	if err := decryptConfig(&config); err != nil {
+		return config, err
+	}
 	return config, nil
 }
 
@@ -51,10 +81,28 @@ func ListConfig(query interface{}, args ...interface{}) ([]entity.Config, error)
 		logrus.Errorf("%v", tx.Error)
 		return configs, errors.New(constant.SysError)
 	}
+	for i := range configs {
+		if err := decryptConfig(&configs[i]); err != nil {
+			return configs, err
+		}
+	}
 	return configs, nil
 }
 
 func UpsertConfig(configs []entity.Config) error {
+	if len(configs) == 0 {
+		return nil
+	}
+	for i, item := range configs {
+		if item.Key == nil || item.Value == nil {
+			continue
+		}
+		value, err := encryptConfigValue(*item.Key, *item.Value)
+		if err != nil {
+			return err
+		}
+		configs[i].Value = &value
+	}
 	if tx := sqliteDB.Model(&entity.Config{}).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "key"}},
 		DoUpdates: clause.AssignmentColumns([]string{"value", "remark", "create_time", "update_time"}),
@@ -0,0 +1,200 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/util"
+	"os"
+	"path/filepath"
+)
+
+// configEnvelope 加密配置使用的主密钥，未初始化时按明文读写
+var configEnvelope *util.Envelope
+
+// MasterKeyFilePath 主密钥文件，默认为 data/master.key
+func MasterKeyFilePath() string {
+	if path := os.Getenv(constant.MasterKeyFileEnv); path != "" {
+		return path
+	}
+	return os.Getenv("HUI_DATA") + constant.MasterKeyPath
+}
+
+// LoadMasterKey 优先使用环境变量 HUI_MASTER_KEY，其次读取主密钥文件，create 为 true 时文件不存在则自动生成
+func LoadMasterKey(create bool) (*util.Envelope, error) {
+	value := os.Getenv(constant.MasterKeyEnv)
+	if value == "" {
+		path := MasterKeyFilePath()
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) && create {
+			if value, err = util.GenerateMasterKey(); err != nil {
+				return nil, errors.New("generate master key err")
+			}
+			if err = WriteMasterKeyFile(path, value); err != nil {
+				return nil, err
+			}
+			logrus.Infof("master key generated: %s", path)
+		} else if err != nil {
+			logrus.Errorf("read master key err: %v", err)
+			return nil, fmt.Errorf("read master key file %s err", path)
+		} else {
+			value = string(content)
+		}
+	}
+	masterKey, err := util.ParseMasterKey(value)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewEnvelope(masterKey)
+}
+
+func WriteMasterKeyFile(path string, value string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		logrus.Errorf("write master key err: %v", err)
+		return errors.New("write master key err")
+	}
+	if err := os.WriteFile(path, []byte(value+"\n"), 0600); err != nil {
+		logrus.Errorf("write master key err: %v", err)
+		return errors.New("write master key err")
+	}
+	return nil
+}
+
+// initConfigEnvelope 加载主密钥，并加密旧版本以明文保存的配置
+func initConfigEnvelope() error {
+	envelope, err := LoadMasterKey(true)
+	if err != nil {
+		return err
+	}
+	configEnvelope = envelope
+
+	var configs []entity.Config
+	if tx := sqliteDB.Model(&entity.Config{}).
+		Where("key in ? and value != ''", constant.SecretConfigKeys).Find(&configs); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	for _, item := range configs {
+		if util.IsEnvelope(*item.Value) {
+			if _, err = configEnvelope.Decrypt(*item.Value); err != nil {
+				logrus.Errorf("config %s decrypt err: %v", *item.Key, err)
+				return fmt.Errorf("config %s cannot be decrypted, check %s or %s", *item.Key,
+					constant.MasterKeyEnv, MasterKeyFilePath())
+			}
+			continue
+		}
+		value, err := encryptConfigValue(*item.Key, *item.Value)
+		if err != nil {
+			return err
+		}
+		if tx := sqliteDB.Model(&entity.Config{}).Where("id = ?", *item.Id).Update("value", value); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return initOutboundEnvelope()
+}
+
+func isSecretConfigKey(key string) bool {
+	return util.ArrContain(constant.SecretConfigKeys, key)
+}
+
+// encryptConfigValue 敏感配置加密后保存，已加密的值必须能被当前主密钥解密
+func encryptConfigValue(key string, value string) (string, error) {
+	if !isSecretConfigKey(key) {
+		return value, nil
+	}
+	return encryptSecret("config "+key, value)
+}
+
+// encryptSecret 使用主密钥加密，name 用于日志和错误信息
+func encryptSecret(name string, value string) (string, error) {
+	if configEnvelope == nil || value == "" {
+		return value, nil
+	}
+	if util.IsEnvelope(value) {
+		if _, err := configEnvelope.Decrypt(value); err != nil {
+			return "", fmt.Errorf("%s cannot be decrypted with the current master key", name)
+		}
+		return value, nil
+	}
+	encrypted, err := configEnvelope.Encrypt(value)
+	if err != nil {
+		logrus.Errorf("%s encrypt err: %v", name, err)
+		return "", errors.New(constant.SysError)
+	}
+	return encrypted, nil
+}
+
+// decryptSecret 未加密的值原样返回
+func decryptSecret(name string, value *string) error {
+	if configEnvelope == nil || value == nil || !util.IsEnvelope(*value) {
+		return nil
+	}
+	decrypted, err := configEnvelope.Decrypt(*value)
+	if err != nil {
+		logrus.Errorf("%s decrypt err: %v", name, err)
+		return errors.New(constant.SysError)
+	}
+	*value = decrypted
+	return nil
+}
+
+func decryptConfig(config *entity.Config) error {
+	if config.Value == nil {
+		return nil
+	}
+	value := *config.Value
+	if err := decryptSecret("config "+*config.Key, &value); err != nil {
+		return err
+	}
+	config.Value = &value
+	return nil
+}
+
+// RotateMasterKey 在同一事务中使用新的主密钥重新加密所有数据密钥，返回处理的密文数
+func RotateMasterKey(current *util.Envelope, target *util.Envelope) (int, error) {
+	count := 0
+	err := sqliteDB.Transaction(func(tx *gorm.DB) error {
+		var configs []entity.Config
+		if err := tx.Model(&entity.Config{}).Where("value like ?", "enc:%").Find(&configs).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		for _, item := range configs {
+			value, err := current.Rewrap(*item.Value, target)
+			if err != nil {
+				return fmt.Errorf("config %s: %v", *item.Key, err)
+			}
+			if err = tx.Model(&entity.Config{}).Where("id = ?", *item.Id).Update("value", value).Error; err != nil {
+				logrus.Errorf("%v", err)
+				return errors.New(constant.SysError)
+			}
+			count++
+		}
+		if !tx.Migrator().HasTable(&entity.Outbound{}) {
+			return nil
+		}
+		var outbounds []entity.Outbound
+		if err := tx.Model(&entity.Outbound{}).Where("password like ?", "enc:%").Find(&outbounds).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		for _, item := range outbounds {
+			password, err := current.Rewrap(*item.Password, target)
+			if err != nil {
+				return fmt.Errorf("outbound %s: %v", *item.Name, err)
+			}
+			if err = tx.Model(&entity.Outbound{}).Where("id = ?", *item.Id).Update("password", password).Error; err != nil {
+				logrus.Errorf("%v", err)
+				return errors.New(constant.SysError)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,127 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+// initOutboundEnvelope 加密旧版本以明文保存的出站密码
+func initOutboundEnvelope() error {
+	var outbounds []entity.Outbound
+	if tx := sqliteDB.Model(&entity.Outbound{}).
+		Where("password != '' and password not like ?", "enc:%").Find(&outbounds); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	for _, item := range outbounds {
+		password, err := encryptOutboundPassword(*item.Name, *item.Password)
+		if err != nil {
+			return err
+		}
+		if tx := sqliteDB.Model(&entity.Outbound{}).Where("id = ?", *item.Id).Update("password", password); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+// encryptOutboundPassword socks5 密码与敏感配置使用同一个主密钥加密
+func encryptOutboundPassword(name string, password string) (string, error) {
+	return encryptSecret("outbound "+name+" password", password)
+}
+
+func decryptOutbound(outbound *entity.Outbound) error {
+	if outbound.Password == nil {
+		return nil
+	}
+	name := ""
+	if outbound.Name != nil {
+		name = *outbound.Name
+	}
+	password := *outbound.Password
+	if err := decryptSecret("outbound "+name+" password", &password); err != nil {
+		return err
+	}
+	outbound.Password = &password
+	return nil
+}
+
+func SaveOutbound(outbound entity.Outbound) (int64, error) {
+	if outbound.Password != nil {
+		password, err := encryptOutboundPassword(*outbound.Name, *outbound.Password)
+		if err != nil {
+			return 0, err
+		}
+		outbound.Password = &password
+	}
+	if tx := sqliteDB.Create(&outbound); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *outbound.Id, nil
+}
+
+func DeleteOutbound(ids []int64) error {
+	if tx := sqliteDB.Where("id in ?", ids).Delete(&entity.Outbound{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func UpdateOutbound(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		if password, ok := updates["password"].(string); ok {
+			encrypted, err := encryptOutboundPassword(fmt.Sprint(ids), password)
+			if err != nil {
+				return err
+			}
+			updates["password"] = encrypted
+		}
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.Outbound{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetOutbound(query interface{}, args ...interface{}) (entity.Outbound, error) {
+	var outbound entity.Outbound
+	if tx := sqliteDB.Model(&entity.Outbound{}).
+		Where(query, args...).First(&outbound); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return outbound, errors.New("outbound not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return outbound, errors.New(constant.SysError)
+	}
+	if err := decryptOutbound(&outbound); err != nil {
+		return outbound, err
+	}
+	return outbound, nil
+}
+
+func ListOutbound(query interface{}, args ...interface{}) ([]entity.Outbound, error) {
+	var outbounds []entity.Outbound
+	if tx := sqliteDB.Model(&entity.Outbound{}).
+		Where(query, args...).Order("id asc").Find(&outbounds); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return outbounds, errors.New(constant.SysError)
+	}
+	for i := range outbounds {
+		if err := decryptOutbound(&outbounds[i]); err != nil {
+			return nil, err
+		}
+	}
+	return outbounds, nil
+}
@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SavePlan(plan entity.Plan) (int64, error) {
+	if tx := sqliteDB.Create(&plan); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *plan.Id, nil
+}
+
+func DeletePlan(ids []int64) error {
+	if tx := sqliteDB.Where("id in ?", ids).Delete(&entity.Plan{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func UpdatePlan(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.Plan{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetPlan(query interface{}, args ...interface{}) (entity.Plan, error) {
+	var plan entity.Plan
+	if tx := sqliteDB.Model(&entity.Plan{}).
+		Where(query, args...).First(&plan); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return plan, errors.New("plan not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return plan, errors.New(constant.SysError)
+	}
+	return plan, nil
+}
+
+func ListPlan(query interface{}, args ...interface{}) ([]entity.Plan, error) {
+	var plans []entity.Plan
+	if tx := sqliteDB.Model(&entity.Plan{}).
+		Where(query, args...).Order("id asc").Find(&plans); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return plans, errors.New(constant.SysError)
+	}
+	return plans, nil
+}
@@ -15,7 +15,7 @@ import (
 	"time"
 )
 
-var sqlInitStr = "CREATE TABLE IF NOT EXISTS account\n(\n    id             INTEGER PRIMARY KEY AUTOINCREMENT,\n    username       TEXT    NOT NULL UNIQUE DEFAULT '',\n    pass           TEXT    NOT NULL        DEFAULT '',\n    con_pass       TEXT    NOT NULL        DEFAULT '',\n    quota          INTEGER NOT NULL        DEFAULT 0,\n    download       INTEGER NOT NULL        DEFAULT 0,\n    upload         INTEGER NOT NULL        DEFAULT 0,\n    expire_time    INTEGER NOT NULL        DEFAULT 0,\n    kick_util_time INTEGER NOT NULL        DEFAULT 0,\n    device_no      INTEGER NOT NULL        DEFAULT 3,\n    role           TEXT    NOT NULL        DEFAULT 'user',\n    deleted        INTEGER NOT NULL        DEFAULT 0,\n    create_time    TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time    TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nALTER TABLE account\n    ADD COLUMN login_at INTEGER NOT NULL DEFAULT 0;\nALTER TABLE account\n    ADD COLUMN con_at INTEGER NOT NULL DEFAULT 0;\nALTER TABLE account\n    ADD COLUMN node_access INTEGER NOT NULL DEFAULT 1;\nCREATE INDEX IF NOT EXISTS account_deleted_index ON account (deleted);\nCREATE INDEX IF NOT EXISTS account_username_index ON account (username);\nCREATE INDEX IF NOT EXISTS account_con_pass_index ON account (con_pass);\nCREATE INDEX IF NOT EXISTS account_pass_index ON account (pass);\nINSERT INTO account (id, username, pass, con_pass, quota, download, upload, expire_time, device_no, role)\nSELECT 1 ,'sysadmin', '02f382b76ca1ab7aa06ab03345c7712fd5b971fb0c0f2aef98bac9cd', 'sysadmin.sysadmin', -1, 0, 0, 253370736000000, 6, 'admin'\n    WHERE NOT EXISTS (SELECT 1 FROM account WHERE id = 1);\nCREATE TABLE IF NOT EXISTS config\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    key         TEXT NOT NULL UNIQUE DEFAULT '',\n    value       TEXT NOT NULL        DEFAULT '',\n    remark      TEXT NOT NULL        DEFAULT '',\n    create_time TIMESTAMP            DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP            DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS config_key_index ON config (key);\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_WEB_PORT', '8081', 'H UI Web Port'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_WEB_PORT');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_WEB_CONTEXT', '/', 'H UI Web Context'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_WEB_CONTEXT');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_CRT_PATH', '', 'H UI Crt File Path'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_CRT_PATH');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_KEY_PATH', '', 'H UI Key File Path'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_KEY_PATH');\nINSERT INTO config (key, value, remark)\nSELECT 'JWT_SECRET', hex(randomblob(10)), 'JWT Secret'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'JWT_SECRET');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_ENABLE', '0', 'Hysteria2 Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_CONFIG', '', 'Hysteria2 Config'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_CONFIG');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_TRAFFIC_TIME', '1', 'Hysteria2 Traffic Time'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_TRAFFIC_TIME');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_CONFIG_REMARK', '', 'Hysteria2 Config Remark'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_CONFIG_REMARK');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_CONFIG_PORT_HOPPING', '', 'Hysteria2 Config Port Hopping'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_CONFIG_PORT_HOPPING');\nINSERT INTO config (key, value, remark)\nSELECT 'RESET_TRAFFIC_CRON', '', 'Reset Traffic Cron'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'RESET_TRAFFIC_CRON');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_ENABLE', '0', 'Telegram Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_TOKEN', '', 'Telegram Token'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_TOKEN');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_CHAT_ID', '', 'Telegram ChatId'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_CHAT_ID');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_LOGIN_JOB_ENABLE', '0', 'TELEGRAM LOGIN Notification'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_LOGIN_JOB_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_LOGIN_JOB_TEXT', '[time], [username] logged into the panel, IP address is [ip]', 'TELEGRAM LOGIN Notification Text'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_LOGIN_JOB_TEXT');\nINSERT INTO config (key, value, remark)\nSELECT 'CLASH_EXTENSION', '', 'Clash Subscription Extension'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'CLASH_EXTENSION');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_ENABLE', '0', 'Hysteria2 Node2 Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_CONFIG', '', 'Hysteria2 Node2 Config'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_CONFIG');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_REMARK', 'Node2', 'Hysteria2 Node2 Remark'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_REMARK');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_SOCKS5_ADDR', '', 'Hysteria2 SOCKS5 Address'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_SOCKS5_ADDR');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_SOCKS5_USER', '', 'Hysteria2 SOCKS5 Username'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_SOCKS5_USER');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_SOCKS5_PASS', '', 'Hysteria2 SOCKS5 Password'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_SOCKS5_PASS');"
+var sqlInitStr = "CREATE TABLE IF NOT EXISTS account\n(\n    id             INTEGER PRIMARY KEY AUTOINCREMENT,\n    username       TEXT    NOT NULL UNIQUE DEFAULT '',\n    pass           TEXT    NOT NULL        DEFAULT '',\n    con_pass       TEXT    NOT NULL        DEFAULT '',\n    quota          INTEGER NOT NULL        DEFAULT 0,\n    download       INTEGER NOT NULL        DEFAULT 0,\n    upload         INTEGER NOT NULL        DEFAULT 0,\n    expire_time    INTEGER NOT NULL        DEFAULT 0,\n    kick_util_time INTEGER NOT NULL        DEFAULT 0,\n    device_no      INTEGER NOT NULL        DEFAULT 3,\n    role           TEXT    NOT NULL        DEFAULT 'user',\n    deleted        INTEGER NOT NULL        DEFAULT 0,\n    create_time    TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time    TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nALTER TABLE account\n    ADD COLUMN login_at INTEGER NOT NULL DEFAULT 0;\nALTER TABLE account\n    ADD COLUMN con_at INTEGER NOT NULL DEFAULT 0;\nALTER TABLE account\n    ADD COLUMN node_access INTEGER NOT NULL DEFAULT 1;\nCREATE INDEX IF NOT EXISTS account_deleted_index ON account (deleted);\nCREATE INDEX IF NOT EXISTS account_username_index ON account (username);\nCREATE INDEX IF NOT EXISTS account_con_pass_index ON account (con_pass);\nCREATE INDEX IF NOT EXISTS account_pass_index ON account (pass);\nINSERT INTO account (id, username, pass, con_pass, quota, download, upload, expire_time, device_no, role)\nSELECT 1 ,'sysadmin', '', 'sysadmin.sysadmin', -1, 0, 0, 253370736000000, 6, 'admin'\n    WHERE NOT EXISTS (SELECT 1 FROM account WHERE id = 1);\nCREATE TABLE IF NOT EXISTS config\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    key         TEXT NOT NULL UNIQUE DEFAULT '',\n    value       TEXT NOT NULL        DEFAULT '',\n    remark      TEXT NOT NULL        DEFAULT '',\n    create_time TIMESTAMP            DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP            DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS config_key_index ON config (key);\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_WEB_PORT', '8081', 'H UI Web Port'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_WEB_PORT');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_WEB_CONTEXT', '/', 'H UI Web Context'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_WEB_CONTEXT');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_CRT_PATH', '', 'H UI Crt File Path'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_CRT_PATH');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_KEY_PATH', '', 'H UI Key File Path'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_KEY_PATH');\nINSERT INTO config (key, value, remark)\nSELECT 'JWT_SECRET', hex(randomblob(10)), 'JWT Secret'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'JWT_SECRET');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_ENABLE', '0', 'Hysteria2 Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_CONFIG', '', 'Hysteria2 Config'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_CONFIG');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_TRAFFIC_TIME', '1', 'Hysteria2 Traffic Time'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_TRAFFIC_TIME');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_CONFIG_REMARK', '', 'Hysteria2 Config Remark'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_CONFIG_REMARK');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_CONFIG_PORT_HOPPING', '', 'Hysteria2 Config Port Hopping'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_CONFIG_PORT_HOPPING');\nINSERT INTO config (key, value, remark)\nSELECT 'RESET_TRAFFIC_CRON', '', 'Reset Traffic Cron'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'RESET_TRAFFIC_CRON');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_ENABLE', '0', 'Telegram Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_TOKEN', '', 'Telegram Token'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_TOKEN');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_CHAT_ID', '', 'Telegram ChatId'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_CHAT_ID');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_LOGIN_JOB_ENABLE', '0', 'TELEGRAM LOGIN Notification'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_LOGIN_JOB_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_LOGIN_JOB_TEXT', '[time], [username] logged into the panel, IP address is [ip]', 'TELEGRAM LOGIN Notification Text'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_LOGIN_JOB_TEXT');\nINSERT INTO config (key, value, remark)\nSELECT 'CLASH_EXTENSION', '', 'Clash Subscription Extension'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'CLASH_EXTENSION');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_ENABLE', '0', 'Hysteria2 Node2 Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_CONFIG', '', 'Hysteria2 Node2 Config'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_CONFIG');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_REMARK', 'Node2', 'Hysteria2 Node2 Remark'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_REMARK');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_SOCKS5_ADDR', '', 'Hysteria2 SOCKS5 Address'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_SOCKS5_ADDR');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_SOCKS5_USER', '', 'Hysteria2 SOCKS5 Username'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_SOCKS5_USER');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_SOCKS5_PASS', '', 'Hysteria2 SOCKS5 Password'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_SOCKS5_PASS');\nCREATE TABLE IF NOT EXISTS audit\n(\n    id           INTEGER PRIMARY KEY AUTOINCREMENT,\n    actor_id     INTEGER NOT NULL DEFAULT 0,\n    actor        TEXT    NOT NULL DEFAULT '',\n    action       TEXT    NOT NULL DEFAULT '',\n    target_ids   TEXT    NOT NULL DEFAULT '',\n    before_value TEXT    NOT NULL DEFAULT '',\n    after_value  TEXT    NOT NULL DEFAULT '',\n    ip           TEXT    NOT NULL DEFAULT '',\n    result       TEXT    NOT NULL DEFAULT '',\n    message      TEXT    NOT NULL DEFAULT '',\n    create_time  TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time  TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS audit_actor_index ON audit (actor);\nCREATE INDEX IF NOT EXISTS audit_action_index ON audit (action);\nCREATE INDEX IF NOT EXISTS audit_create_time_index ON audit (create_time);\nCREATE TABLE IF NOT EXISTS api_key\n(\n    id           INTEGER PRIMARY KEY AUTOINCREMENT,\n    account_id   INTEGER NOT NULL DEFAULT 0,\n    name         TEXT    NOT NULL DEFAULT '',\n    prefix       TEXT    NOT NULL UNIQUE DEFAULT '',\n    key_hash     TEXT    NOT NULL DEFAULT '',\n    scopes       TEXT    NOT NULL DEFAULT '',\n    expire_time  INTEGER NOT NULL DEFAULT 0,\n    last_used_at INTEGER NOT NULL DEFAULT 0,\n    create_time  TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time  TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS api_key_account_id_index ON api_key (account_id);\nCREATE INDEX IF NOT EXISTS api_key_prefix_index ON api_key (prefix);\nCREATE TABLE IF NOT EXISTS webhook\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    name        TEXT    NOT NULL DEFAULT '',\n    url         TEXT    NOT NULL DEFAULT '',\n    secret      TEXT    NOT NULL DEFAULT '',\n    events      TEXT    NOT NULL DEFAULT '',\n    enable      INTEGER NOT NULL DEFAULT 1,\n    create_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE TABLE IF NOT EXISTS webhook_delivery\n(\n    id            INTEGER PRIMARY KEY AUTOINCREMENT,\n    webhook_id    INTEGER NOT NULL DEFAULT 0,\n    event         TEXT    NOT NULL DEFAULT '',\n    payload       TEXT    NOT NULL DEFAULT '',\n    status        TEXT    NOT NULL DEFAULT '',\n    attempts      INTEGER NOT NULL DEFAULT 0,\n    response_code INTEGER NOT NULL DEFAULT 0,\n    error         TEXT    NOT NULL DEFAULT '',\n    create_time   TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time   TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS webhook_delivery_webhook_id_index ON webhook_delivery (webhook_id);\nCREATE INDEX IF NOT EXISTS webhook_delivery_create_time_index ON webhook_delivery (create_time);\nCREATE TABLE IF NOT EXISTS alert_rule\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    name        TEXT    NOT NULL DEFAULT '',\n    type        TEXT    NOT NULL DEFAULT '',\n    threshold   REAL    NOT NULL DEFAULT 0,\n    channels    TEXT    NOT NULL DEFAULT '',\n    template    TEXT    NOT NULL DEFAULT '',\n    cooldown    INTEGER NOT NULL DEFAULT 0,\n    enable      INTEGER NOT NULL DEFAULT 1,\n    create_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE TABLE IF NOT EXISTS alert_state\n(\n    id           INTEGER PRIMARY KEY AUTOINCREMENT,\n    rule_id      INTEGER NOT NULL DEFAULT 0,\n    subject      TEXT    NOT NULL DEFAULT '',\n    message      TEXT    NOT NULL DEFAULT '',\n    last_sent_at INTEGER NOT NULL DEFAULT 0,\n    create_time  TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time  TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    UNIQUE (rule_id, subject)\n);\nINSERT INTO config (key, value, remark)\nSELECT 'SMTP_HOST', '', 'SMTP Host'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'SMTP_HOST');\nINSERT INTO config (key, value, remark)\nSELECT 'SMTP_PORT', '587', 'SMTP Port'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'SMTP_PORT');\nINSERT INTO config (key, value, remark)\nSELECT 'SMTP_USERNAME', '', 'SMTP Username'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'SMTP_USERNAME');\nINSERT INTO config (key, value, remark)\nSELECT 'SMTP_PASSWORD', '', 'SMTP Password'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'SMTP_PASSWORD');\nINSERT INTO config (key, value, remark)\nSELECT 'SMTP_FROM', '', 'SMTP Sender Address'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'SMTP_FROM');\nINSERT INTO config (key, value, remark)\nSELECT 'SMTP_TO', '', 'Alert Email Recipients, comma separated'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'SMTP_TO');\nCREATE TABLE IF NOT EXISTS account_traffic_daily\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    account_id  INTEGER NOT NULL DEFAULT 0,\n    day         TEXT    NOT NULL DEFAULT '',\n    download    INTEGER NOT NULL DEFAULT 0,\n    upload      INTEGER NOT NULL DEFAULT 0,\n    create_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    UNIQUE (account_id, day)\n);\nCREATE INDEX IF NOT EXISTS account_traffic_daily_day_index ON account_traffic_daily (day);\nCREATE TABLE IF NOT EXISTS telegram_binding\n(\n    id            INTEGER PRIMARY KEY AUTOINCREMENT,\n    account_id    INTEGER NOT NULL UNIQUE DEFAULT 0,\n    chat_id       INTEGER NOT NULL UNIQUE DEFAULT 0,\n    quota_warned  INTEGER NOT NULL        DEFAULT 0,\n    expire_warned INTEGER NOT NULL        DEFAULT 0,\n    create_time   TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time   TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_USER_ENABLE', '0', 'Telegram User Self-service Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_USER_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_USER_QUOTA_WARN', '80', 'Telegram User Quota Warning Percent'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_USER_QUOTA_WARN');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_USER_EXPIRE_WARN', '3', 'Telegram User Expiry Warning Days'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_USER_EXPIRE_WARN');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_PUBLIC_URL', '', 'H UI Public Url, used for subscription links outside the panel'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_PUBLIC_URL');\nCREATE TABLE IF NOT EXISTS telegram_chat\n(\n    id            INTEGER PRIMARY KEY AUTOINCREMENT,\n    chat_id       INTEGER NOT NULL UNIQUE DEFAULT 0,\n    name          TEXT    NOT NULL        DEFAULT '',\n    subscriptions TEXT    NOT NULL        DEFAULT '',\n    enable        INTEGER NOT NULL        DEFAULT 1,\n    create_time   TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time   TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_MODE', 'polling', 'Telegram Update Mode, polling or webhook'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_MODE');\nINSERT INTO config (key, value, remark)\nSELECT 'TELEGRAM_WEBHOOK_SECRET', hex(randomblob(16)), 'Telegram Webhook Secret Token'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'TELEGRAM_WEBHOOK_SECRET');\nCREATE TABLE IF NOT EXISTS plan\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    name        TEXT    NOT NULL UNIQUE DEFAULT '',\n    quota       INTEGER NOT NULL        DEFAULT -1,\n    duration    INTEGER NOT NULL        DEFAULT 30,\n    device_no   INTEGER NOT NULL        DEFAULT 3,\n    node_access INTEGER NOT NULL        DEFAULT 1,\n    price       INTEGER NOT NULL        DEFAULT 0,\n    remark      TEXT    NOT NULL        DEFAULT '',\n    create_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nALTER TABLE account\n    ADD COLUMN plan_id INTEGER NOT NULL DEFAULT 0;\nCREATE TABLE IF NOT EXISTS account_plan_history\n(\n    id                 INTEGER PRIMARY KEY AUTOINCREMENT,\n    account_id         INTEGER NOT NULL DEFAULT 0,\n    plan_id            INTEGER NOT NULL DEFAULT 0,\n    plan_name          TEXT    NOT NULL DEFAULT '',\n    action             TEXT    NOT NULL DEFAULT '',\n    quota              INTEGER NOT NULL DEFAULT 0,\n    device_no          INTEGER NOT NULL DEFAULT 0,\n    node_access        INTEGER NOT NULL DEFAULT 0,\n    expire_time_before INTEGER NOT NULL DEFAULT 0,\n    expire_time_after  INTEGER NOT NULL DEFAULT 0,\n    create_time        TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time        TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS account_plan_history_account_id_index ON account_plan_history (account_id);\nCREATE TABLE IF NOT EXISTS voucher_batch\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    name        TEXT    NOT NULL DEFAULT '',\n    plan_id     INTEGER NOT NULL DEFAULT 0,\n    quota       INTEGER NOT NULL DEFAULT 0,\n    duration    INTEGER NOT NULL DEFAULT 0,\n    max_uses    INTEGER NOT NULL DEFAULT 1,\n    expire_time INTEGER NOT NULL DEFAULT 0,\n    count       INTEGER NOT NULL DEFAULT 0,\n    create_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE TABLE IF NOT EXISTS voucher\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    batch_id    INTEGER NOT NULL DEFAULT 0,\n    code        TEXT    NOT NULL UNIQUE DEFAULT '',\n    max_uses    INTEGER NOT NULL        DEFAULT 1,\n    used        INTEGER NOT NULL        DEFAULT 0,\n    expire_time INTEGER NOT NULL        DEFAULT 0,\n    create_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS voucher_batch_id_index ON voucher (batch_id);\nCREATE TABLE IF NOT EXISTS voucher_redemption\n(\n    id                 INTEGER PRIMARY KEY AUTOINCREMENT,\n    voucher_id         INTEGER NOT NULL DEFAULT 0,\n    batch_id           INTEGER NOT NULL DEFAULT 0,\n    code               TEXT    NOT NULL DEFAULT '',\n    account_id         INTEGER NOT NULL DEFAULT 0,\n    username           TEXT    NOT NULL DEFAULT '',\n    plan_id            INTEGER NOT NULL DEFAULT 0,\n    quota              INTEGER NOT NULL DEFAULT 0,\n    duration           INTEGER NOT NULL DEFAULT 0,\n    expire_time_before INTEGER NOT NULL DEFAULT 0,\n    expire_time_after  INTEGER NOT NULL DEFAULT 0,\n    create_time        TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time        TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    UNIQUE (voucher_id, account_id)\n);\nCREATE INDEX IF NOT EXISTS voucher_redemption_account_id_index ON voucher_redemption (account_id);\nCREATE INDEX IF NOT EXISTS voucher_redemption_batch_id_index ON voucher_redemption (batch_id);\nCREATE TABLE IF NOT EXISTS account_tag\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    account_id  INTEGER NOT NULL DEFAULT 0,\n    tag         TEXT    NOT NULL DEFAULT '',\n    create_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    UNIQUE (account_id, tag)\n);\nCREATE INDEX IF NOT EXISTS account_tag_tag_index ON account_tag (tag);\nALTER TABLE account\n    ADD COLUMN must_change_pass INTEGER NOT NULL DEFAULT 0;\nCREATE TABLE IF NOT EXISTS account_pass_history\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    account_id  INTEGER NOT NULL DEFAULT 0,\n    pass        TEXT    NOT NULL DEFAULT '',\n    create_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP        DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX IF NOT EXISTS account_pass_history_account_id_index ON account_pass_history (account_id);\nINSERT INTO config (key, value, remark)\nSELECT 'PASSWORD_MIN_LENGTH', '8', 'Panel Password Minimum Length'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PASSWORD_MIN_LENGTH');\nINSERT INTO config (key, value, remark)\nSELECT 'PASSWORD_HISTORY', '3', 'Number of Previous Panel Passwords That Cannot Be Reused'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PASSWORD_HISTORY');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_CONFIG_PORT_HOPPING_INTERFACES', 'all', 'Hysteria2 Port Hopping Interfaces, comma separated or all'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_CONFIG_PORT_HOPPING_INTERFACES');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_PORT_HOPPING', '', 'Hysteria2 Node2 Port Hopping'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_PORT_HOPPING');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_PORT_HOPPING_INTERFACES', 'all', 'Hysteria2 Node2 Port Hopping Interfaces, comma separated or all'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_PORT_HOPPING_INTERFACES');\nINSERT INTO config (key, value, remark)\nSELECT 'FIREWALL_ENABLE', '0', 'Firewall Management Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'FIREWALL_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'FIREWALL_PANEL_ALLOWLIST', '', 'Panel Port Allowlist, comma separated IP or CIDR, empty allows all'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'FIREWALL_PANEL_ALLOWLIST');\nCREATE TABLE IF NOT EXISTS acl_profile\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    name        TEXT    NOT NULL UNIQUE DEFAULT '',\n    port        INTEGER NOT NULL        DEFAULT 0,\n    rules       TEXT    NOT NULL        DEFAULT '',\n    outbounds   TEXT    NOT NULL        DEFAULT '',\n    tags        TEXT    NOT NULL        DEFAULT '',\n    enable      INTEGER NOT NULL        DEFAULT 1,\n    remark      TEXT    NOT NULL        DEFAULT '',\n    create_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nALTER TABLE account\n    ADD COLUMN acl_profile_id INTEGER NOT NULL DEFAULT 0;\nCREATE TABLE IF NOT EXISTS outbound\n(\n    id          INTEGER PRIMARY KEY AUTOINCREMENT,\n    name        TEXT    NOT NULL UNIQUE DEFAULT '',\n    type        TEXT    NOT NULL        DEFAULT '',\n    addr        TEXT    NOT NULL        DEFAULT '',\n    username    TEXT    NOT NULL        DEFAULT '',\n    password    TEXT    NOT NULL        DEFAULT '',\n    bind_ipv4   TEXT    NOT NULL        DEFAULT '',\n    bind_ipv6   TEXT    NOT NULL        DEFAULT '',\n    bind_device TEXT    NOT NULL        DEFAULT '',\n    insecure    INTEGER NOT NULL        DEFAULT 0,\n    enable      INTEGER NOT NULL        DEFAULT 1,\n    remark      TEXT    NOT NULL        DEFAULT '',\n    status      TEXT    NOT NULL        DEFAULT 'unknown',\n    latency     INTEGER NOT NULL        DEFAULT 0,\n    fail_count  INTEGER NOT NULL        DEFAULT 0,\n    check_at    INTEGER NOT NULL        DEFAULT 0,\n    check_error TEXT    NOT NULL        DEFAULT '',\n    create_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_OUTBOUNDS', '', 'Hysteria2 Outbounds, comma separated outbound ids in failover order'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_OUTBOUNDS');\nINSERT INTO config (key, value, remark)\nSELECT 'HYSTERIA2_NODE2_OUTBOUNDS', '', 'Hysteria2 Node2 Outbounds, comma separated outbound ids in failover order'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'HYSTERIA2_NODE2_OUTBOUNDS');\nINSERT INTO config (key, value, remark)\nSELECT 'OUTBOUND_CHECK_URL', 'http://cp.cloudflare.com/generate_204', 'Outbound Health Check Url'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'OUTBOUND_CHECK_URL');\nINSERT INTO config (key, value, remark)\nSELECT 'GEOIP_URL', 'https://github.com/Loyalsoldier/v2ray-rules-dat/releases/latest/download/geoip.dat', 'GeoIP Download Url, checksum is read from the same url with .sha256sum'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'GEOIP_URL');\nINSERT INTO config (key, value, remark)\nSELECT 'GEOSITE_URL', 'https://github.com/Loyalsoldier/v2ray-rules-dat/releases/latest/download/geosite.dat', 'GeoSite Download Url, checksum is read from the same url with .sha256sum'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'GEOSITE_URL');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_ENABLE', '0', 'Panel ACME Switch'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_ENABLE');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_DOMAINS', '', 'Panel ACME Domains, comma separated'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_DOMAINS');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_EMAIL', '', 'Panel ACME Account Email'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_EMAIL');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_CA', 'https://acme-v02.api.letsencrypt.org/directory', 'Panel ACME Directory Url'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_CA');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_CA_ROOT', '', 'Panel ACME Directory Root Certificate Path, for private CAs'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_CA_ROOT');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_CHALLENGE', 'http-01', 'Panel ACME Challenge, http-01, tls-alpn-01 or dns-01'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_CHALLENGE');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_HTTP_PORT', '80', 'Panel ACME HTTP-01 Challenge Port'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_HTTP_PORT');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_TLS_PORT', '443', 'Panel ACME TLS-ALPN-01 Challenge Port'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_TLS_PORT');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_DNS_PROVIDER', '', 'Panel ACME DNS-01 Provider, cloudflare or webhook'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_DNS_PROVIDER');\nINSERT INTO config (key, value, remark)\nSELECT 'PANEL_ACME_DNS_CONFIG', '', 'Panel ACME DNS-01 Provider Config, Cloudflare API token or webhook url'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'PANEL_ACME_DNS_CONFIG');\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_WEB_ROUTES', 'panel,subscribe,auth,telegram', 'H UI Web Port Route Groups, comma separated panel, subscribe, auth or telegram'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_WEB_ROUTES');\nCREATE TABLE IF NOT EXISTS web_listener\n(\n    id            INTEGER PRIMARY KEY AUTOINCREMENT,\n    name          TEXT    NOT NULL UNIQUE DEFAULT '',\n    network       TEXT    NOT NULL        DEFAULT 'tcp',\n    address       TEXT    NOT NULL        DEFAULT '',\n    tls           INTEGER NOT NULL        DEFAULT 0,\n    routes        TEXT    NOT NULL        DEFAULT '',\n    redirect_port INTEGER NOT NULL        DEFAULT 0,\n    enable        INTEGER NOT NULL        DEFAULT 1,\n    remark        TEXT    NOT NULL        DEFAULT '',\n    create_time   TIMESTAMP               DEFAULT CURRENT_TIMESTAMP,\n    update_time   TIMESTAMP               DEFAULT CURRENT_TIMESTAMP\n);\nINSERT INTO config (key, value, remark)\nSELECT 'H_UI_TRUSTED_PROXIES', '', 'Trusted Reverse Proxies, comma separated IP or CIDR, client IP is read from X-Forwarded-For only when the request comes from them'\n    WHERE NOT EXISTS (SELECT 1 FROM config WHERE key = 'H_UI_TRUSTED_PROXIES');\nALTER TABLE plan\n    ADD COLUMN speed_tier TEXT NOT NULL DEFAULT '';\nALTER TABLE acl_profile\n    ADD COLUMN bandwidth_up TEXT NOT NULL DEFAULT '';\nALTER TABLE acl_profile\n    ADD COLUMN bandwidth_down TEXT NOT NULL DEFAULT ''"
 
 var sqliteDB *gorm.DB
 
@@ -51,6 +51,12 @@ func InitSql(port string) error {
 	if err := sqliteInit(sqlInitStr); err != nil {
 		return err
 	}
+	if err := initConfigEnvelope(); err != nil {
+		return err
+	}
+	if err := initDefaultAccountPass(); err != nil {
+		return err
+	}
 	if port != "" {
 		var result string
 		db, err := sqliteDB.DB()
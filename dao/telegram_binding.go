@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+// SaveTelegramBinding 绑定账号，同一账号或同一会话的旧绑定会被替换
+func SaveTelegramBinding(accountId int64, chatId int64) error {
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ? or chat_id = ?", accountId, chatId).Delete(&entity.TelegramBinding{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if err := tx.Create(&entity.TelegramBinding{AccountId: &accountId, ChatId: &chatId}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+func DeleteTelegramBinding(query interface{}, args ...interface{}) error {
+	if tx := sqliteDB.Where(query, args...).Delete(&entity.TelegramBinding{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func UpdateTelegramBinding(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.TelegramBinding{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetTelegramBinding(query interface{}, args ...interface{}) (entity.TelegramBinding, error) {
+	var telegramBinding entity.TelegramBinding
+	if tx := sqliteDB.Model(&entity.TelegramBinding{}).
+		Where(query, args...).First(&telegramBinding); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return telegramBinding, errors.New("telegram binding not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return telegramBinding, errors.New(constant.SysError)
+	}
+	return telegramBinding, nil
+}
+
+func ListTelegramBinding(query interface{}, args ...interface{}) ([]entity.TelegramBinding, error) {
+	var telegramBindings []entity.TelegramBinding
+	if tx := sqliteDB.Model(&entity.TelegramBinding{}).
+		Where(query, args...).Find(&telegramBindings); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return telegramBindings, errors.New(constant.SysError)
+	}
+	return telegramBindings, nil
+}
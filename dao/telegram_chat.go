@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveTelegramChat(telegramChat entity.TelegramChat) (int64, error) {
+	if tx := sqliteDB.Create(&telegramChat); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *telegramChat.Id, nil
+}
+
+func DeleteTelegramChat(ids []int64) error {
+	if tx := sqliteDB.Where("id in ?", ids).Delete(&entity.TelegramChat{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func UpdateTelegramChat(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.TelegramChat{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetTelegramChat(query interface{}, args ...interface{}) (entity.TelegramChat, error) {
+	var telegramChat entity.TelegramChat
+	if tx := sqliteDB.Model(&entity.TelegramChat{}).
+		Where(query, args...).First(&telegramChat); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return telegramChat, errors.New("telegram chat not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return telegramChat, errors.New(constant.SysError)
+	}
+	return telegramChat, nil
+}
+
+func ListTelegramChat(query interface{}, args ...interface{}) ([]entity.TelegramChat, error) {
+	var telegramChats []entity.TelegramChat
+	if tx := sqliteDB.Model(&entity.TelegramChat{}).
+		Where(query, args...).Order("id asc").Find(&telegramChats); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return telegramChats, errors.New(constant.SysError)
+	}
+	return telegramChats, nil
+}
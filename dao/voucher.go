@@ -0,0 +1,170 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"time"
+)
+
+// SaveVoucherBatch 保存批次及其兑换码
+func SaveVoucherBatch(voucherBatch entity.VoucherBatch, codes []string) (int64, error) {
+	err := sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&voucherBatch).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		vouchers := make([]entity.Voucher, 0, len(codes))
+		for i := range codes {
+			vouchers = append(vouchers, entity.Voucher{
+				BatchId:    voucherBatch.Id,
+				Code:       &codes[i],
+				MaxUses:    voucherBatch.MaxUses,
+				ExpireTime: voucherBatch.ExpireTime,
+			})
+		}
+		if err := tx.CreateInBatches(&vouchers, 100).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return *voucherBatch.Id, nil
+}
+
+// DeleteVoucherBatch 删除批次及未使用的兑换码，兑换记录保留
+func DeleteVoucherBatch(ids []int64) error {
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("batch_id in ?", ids).Delete(&entity.Voucher{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if err := tx.Where("id in ?", ids).Delete(&entity.VoucherBatch{}).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		return nil
+	})
+}
+
+func GetVoucherBatch(query interface{}, args ...interface{}) (entity.VoucherBatch, error) {
+	var voucherBatch entity.VoucherBatch
+	if tx := sqliteDB.Model(&entity.VoucherBatch{}).
+		Where(query, args...).First(&voucherBatch); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return voucherBatch, errors.New("voucher batch not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return voucherBatch, errors.New(constant.SysError)
+	}
+	return voucherBatch, nil
+}
+
+func ListVoucherBatch(query interface{}, args ...interface{}) ([]entity.VoucherBatch, error) {
+	var voucherBatches []entity.VoucherBatch
+	if tx := sqliteDB.Model(&entity.VoucherBatch{}).
+		Where(query, args...).Order("id desc").Find(&voucherBatches); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return voucherBatches, errors.New(constant.SysError)
+	}
+	return voucherBatches, nil
+}
+
+func GetVoucher(query interface{}, args ...interface{}) (entity.Voucher, error) {
+	var voucher entity.Voucher
+	if tx := sqliteDB.Model(&entity.Voucher{}).
+		Where(query, args...).First(&voucher); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return voucher, errors.New("the voucher is invalid")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return voucher, errors.New(constant.SysError)
+	}
+	return voucher, nil
+}
+
+func ListVoucher(query interface{}, args ...interface{}) ([]entity.Voucher, error) {
+	var vouchers []entity.Voucher
+	if tx := sqliteDB.Model(&entity.Voucher{}).
+		Where(query, args...).Order("id asc").Find(&vouchers); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return vouchers, errors.New(constant.SysError)
+	}
+	return vouchers, nil
+}
+
+// RedeemVoucher 在同一事务中扣减次数、记录兑换并更新账号，次数用尽或过期时不做任何修改
+func RedeemVoucher(voucherId int64, now int64, accountId int64, updates map[string]interface{},
+	redemption *entity.VoucherRedemption, history *entity.AccountPlanHistory) error {
+	updateTime := time.Now().Format("2006-01-02 15:04:05")
+	updates["update_time"] = updateTime
+	return sqliteDB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Voucher{}).
+			Where("id = ? and used < max_uses and (expire_time = 0 or expire_time > ?)", voucherId, now).
+			Updates(map[string]interface{}{"used": gorm.Expr("used + 1"), "update_time": updateTime})
+		if result.Error != nil {
+			logrus.Errorf("%v", result.Error)
+			return errors.New(constant.SysError)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("the voucher has been used up or has expired")
+		}
+		if err := tx.Create(redemption).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if err := tx.Model(&entity.Account{}).Where("id = ?", accountId).Updates(updates).Error; err != nil {
+			logrus.Errorf("%v", err)
+			return errors.New(constant.SysError)
+		}
+		if history != nil {
+			if err := tx.Create(history).Error; err != nil {
+				logrus.Errorf("%v", err)
+				return errors.New(constant.SysError)
+			}
+		}
+		return nil
+	})
+}
+
+func voucherRedemptionQuery(voucherRedemptionQueryDto dto.VoucherRedemptionQueryDto) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if voucherRedemptionQueryDto.BatchId != nil {
+			db = db.Where("batch_id = ?", *voucherRedemptionQueryDto.BatchId)
+		}
+		if voucherRedemptionQueryDto.AccountId != nil {
+			db = db.Where("account_id = ?", *voucherRedemptionQueryDto.AccountId)
+		}
+		if voucherRedemptionQueryDto.Code != nil && *voucherRedemptionQueryDto.Code != "" {
+			db = db.Where("code = ?", *voucherRedemptionQueryDto.Code)
+		}
+		return db
+	}
+}
+
+func PageVoucherRedemption(voucherRedemptionPageDto dto.VoucherRedemptionPageDto) ([]entity.VoucherRedemption, int64, error) {
+	var redemptions []entity.VoucherRedemption
+	var total int64
+	tx := sqliteDB.Model(&entity.VoucherRedemption{}).Scopes(voucherRedemptionQuery(voucherRedemptionPageDto.VoucherRedemptionQueryDto))
+	tx.Count(&total)
+	if tx.Scopes(Paginate(voucherRedemptionPageDto.PageNum, voucherRedemptionPageDto.PageSize)).
+		Order("id desc").
+		Find(&redemptions); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return redemptions, 0, errors.New(constant.SysError)
+	}
+	return redemptions, total, nil
+}
+
+func ExistVoucherRedemption(voucherId int64, accountId int64) bool {
+	var count int64
+	sqliteDB.Model(&entity.VoucherRedemption{}).
+		Where("voucher_id = ? and account_id = ?", voucherId, accountId).Count(&count)
+	return count > 0
+}
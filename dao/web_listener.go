@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveWebListener(webListener entity.WebListener) (int64, error) {
+	if tx := sqliteDB.Create(&webListener); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *webListener.Id, nil
+}
+
+func DeleteWebListener(ids []int64) error {
+	if tx := sqliteDB.Where("id in ?", ids).Delete(&entity.WebListener{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func UpdateWebListener(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.WebListener{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetWebListener(query interface{}, args ...interface{}) (entity.WebListener, error) {
+	var webListener entity.WebListener
+	if tx := sqliteDB.Model(&entity.WebListener{}).
+		Where(query, args...).First(&webListener); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return webListener, errors.New("web listener not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return webListener, errors.New(constant.SysError)
+	}
+	return webListener, nil
+}
+
+func ListWebListener(query interface{}, args ...interface{}) ([]entity.WebListener, error) {
+	var webListeners []entity.WebListener
+	if tx := sqliteDB.Model(&entity.WebListener{}).
+		Where(query, args...).Order("id asc").Find(&webListeners); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return webListeners, errors.New(constant.SysError)
+	}
+	return webListeners, nil
+}
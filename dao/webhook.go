@@ -0,0 +1,130 @@
+package dao
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"time"
+)
+
+func SaveWebhook(webhook entity.Webhook) (int64, error) {
+	if tx := sqliteDB.Create(&webhook); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *webhook.Id, nil
+}
+
+func DeleteWebhook(ids []int64) error {
+	if tx := sqliteDB.Where("id in ?", ids).Delete(&entity.Webhook{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+func UpdateWebhook(ids []int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.Webhook{}).
+			Where("id in ?", ids).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetWebhook(query interface{}, args ...interface{}) (entity.Webhook, error) {
+	var webhook entity.Webhook
+	if tx := sqliteDB.Model(&entity.Webhook{}).
+		Where(query, args...).First(&webhook); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return webhook, errors.New("webhook not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return webhook, errors.New(constant.SysError)
+	}
+	return webhook, nil
+}
+
+func ListWebhook(query interface{}, args ...interface{}) ([]entity.Webhook, error) {
+	var webhooks []entity.Webhook
+	if tx := sqliteDB.Model(&entity.Webhook{}).
+		Where(query, args...).Order("id asc").Find(&webhooks); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return webhooks, errors.New(constant.SysError)
+	}
+	return webhooks, nil
+}
+
+func SaveWebhookDelivery(delivery entity.WebhookDelivery) (int64, error) {
+	if tx := sqliteDB.Create(&delivery); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return 0, errors.New(constant.SysError)
+	}
+	return *delivery.Id, nil
+}
+
+func UpdateWebhookDelivery(id int64, updates map[string]interface{}) error {
+	if len(updates) > 0 {
+		updates["update_time"] = time.Now().Format("2006-01-02 15:04:05")
+		if tx := sqliteDB.Model(&entity.WebhookDelivery{}).
+			Where("id = ?", id).
+			Updates(updates); tx.Error != nil {
+			logrus.Errorf("%v", tx.Error)
+			return errors.New(constant.SysError)
+		}
+	}
+	return nil
+}
+
+func GetWebhookDelivery(query interface{}, args ...interface{}) (entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	if tx := sqliteDB.Model(&entity.WebhookDelivery{}).
+		Where(query, args...).First(&delivery); tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			return delivery, errors.New("webhook delivery not found")
+		}
+		logrus.Errorf("%v", tx.Error)
+		return delivery, errors.New(constant.SysError)
+	}
+	return delivery, nil
+}
+
+func PageWebhookDelivery(deliveryPageDto dto.WebhookDeliveryPageDto) ([]entity.WebhookDelivery, int64, error) {
+	var deliveries []entity.WebhookDelivery
+	var total int64
+	tx := sqliteDB.Model(&entity.WebhookDelivery{})
+	if deliveryPageDto.WebhookId != nil {
+		tx = tx.Where("webhook_id = ?", *deliveryPageDto.WebhookId)
+	}
+	if deliveryPageDto.Event != nil && *deliveryPageDto.Event != "" {
+		tx = tx.Where("event = ?", *deliveryPageDto.Event)
+	}
+	if deliveryPageDto.Status != nil && *deliveryPageDto.Status != "" {
+		tx = tx.Where("status = ?", *deliveryPageDto.Status)
+	}
+	tx.Count(&total)
+	if tx.Scopes(Paginate(deliveryPageDto.PageNum, deliveryPageDto.PageSize)).
+		Order("id desc").
+		Find(&deliveries); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return deliveries, 0, errors.New(constant.SysError)
+	}
+	return deliveries, total, nil
+}
+
+// DeleteWebhookDeliveryBefore 清理早于指定时间的投递记录
+func DeleteWebhookDeliveryBefore(before time.Time) error {
+	if tx := sqliteDB.Where("create_time < ?", before.Format("2006-01-02 15:04:05")).
+		Delete(&entity.WebhookDelivery{}); tx.Error != nil {
+		logrus.Errorf("%v", tx.Error)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+import request from "@/utils/request";
+import { AxiosPromise } from "axios";
+import { AccountLoginDto, AccountLoginVo } from "@/api/account/types";
+import {
+  UserConPassVo,
+  UserInfoVo,
+  UserLoginTokenDto,
+  UserSubscribeDto,
+  UserSubscribeVo,
+  UserTrafficDailyDto,
+  UserTrafficDailyVo,
+} from "./types";
+
+// 用户自助页面使用单独保存的 token，不影响面板登录
+const portalTokenKey = "portalToken";
+
+export function getPortalToken(): string {
+  return localStorage.getItem(portalTokenKey) || "";
+}
+
+export function setPortalToken(token: string) {
+  if (token) {
+    localStorage.setItem(portalTokenKey, token);
+  } else {
+    localStorage.removeItem(portalTokenKey);
+  }
+}
+
+function portalHeaders() {
+  return { Authorization: getPortalToken() };
+}
+
+/**
+ * 用户名密码登录
+ */
+export function userLoginApi(
+  data: AccountLoginDto
+): AxiosPromise<AccountLoginVo> {
+  return request({
+    url: "/user/login",
+    method: "post",
+    data: data,
+  });
+}
+
+/**
+ * 登录链接登录
+ */
+export function userLoginByTokenApi(
+  data: UserLoginTokenDto
+): AxiosPromise<AccountLoginVo> {
+  return request({
+    url: "/user/loginByToken",
+    method: "post",
+    data: data,
+  });
+}
+
+export function getUserInfoApi(): AxiosPromise<UserInfoVo> {
+  return request({
+    url: "/user/getUserInfo",
+    method: "get",
+    headers: portalHeaders(),
+  });
+}
+
+export function listUserTrafficDailyApi(
+  data: UserTrafficDailyDto
+): AxiosPromise<UserTrafficDailyVo[]> {
+  return request({
+    url: "/user/listTrafficDaily",
+    method: "get",
+    params: data,
+    headers: portalHeaders(),
+  });
+}
+
+export function getUserSubscribeApi(
+  data: UserSubscribeDto
+): AxiosPromise<UserSubscribeVo> {
+  return request({
+    url: "/user/getSubscribe",
+    method: "get",
+    params: data,
+    headers: portalHeaders(),
+  });
+}
+
+export function rotateUserConPassApi(): AxiosPromise<UserConPassVo> {
+  return request({
+    url: "/user/rotateConPass",
+    method: "post",
+    headers: portalHeaders(),
+  });
+}
+
+export function kickUserApi(): AxiosPromise {
+  return request({
+    url: "/user/kick",
+    method: "post",
+    headers: portalHeaders(),
+  });
+}
@@ -0,0 +1,47 @@
+export interface UserLoginTokenDto {
+  token: string;
+}
+
+export interface UserTrafficDailyDto {
+  days?: number;
+}
+
+export interface UserSubscribeDto {
+  protocol: string;
+  host: string;
+}
+
+export interface UserInfoVo {
+  id: number;
+  username: string;
+  quota: number;
+  download: number;
+  upload: number;
+  expireTime: number;
+  deviceNo: number;
+  nodeAccess: number;
+  online: boolean;
+  device: number;
+}
+
+export interface UserTrafficDailyVo {
+  day: string;
+  download: number;
+  upload: number;
+}
+
+export interface UserNodeUrlVo {
+  nodeName: string;
+  url: string;
+  qrCode: string;
+}
+
+export interface UserSubscribeVo {
+  url: string;
+  qrCode: string;
+  nodes: UserNodeUrlVo[];
+}
+
+export interface UserConPassVo {
+  conPass: string;
+}
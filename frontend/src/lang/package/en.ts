@@ -28,6 +28,22 @@ export default {
   navbar: {
     logout: "Logout",
   },
+  portal: {
+    title: "User Portal",
+    online: "Online",
+    offline: "Offline",
+    used: "Used",
+    device: "Devices",
+    trafficDaily: "Daily Traffic",
+    node: "Node",
+    copy: "Copy",
+    rotateConPass: "Rotate Password",
+    rotateConPassTip:
+      "Rotate the connection password? Existing subscriptions need to be updated.",
+    newConPass: "New Connection Password",
+    kick: "Kick Sessions",
+    kickTip: "Disconnect all online devices?",
+  },
   common: {
     id: "ID",
     createTime: "CreateTime",
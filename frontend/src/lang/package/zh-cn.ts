@@ -28,6 +28,21 @@ export default {
   navbar: {
     logout: "注销",
   },
+  portal: {
+    title: "用户中心",
+    online: "在线",
+    offline: "离线",
+    used: "已用流量",
+    device: "在线设备",
+    trafficDaily: "每日流量",
+    node: "节点",
+    copy: "复制",
+    rotateConPass: "重置连接密码",
+    rotateConPassTip: "确定重置连接密码吗？重置后需要更新订阅",
+    newConPass: "新的连接密码",
+    kick: "下线所有设备",
+    kickTip: "确定断开所有在线设备吗？",
+  },
   common: {
     id: "编号",
     createTime: "创建时间",
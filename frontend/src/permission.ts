@@ -14,6 +14,11 @@ const whiteList = ["/login", "/register"];
 
 router.beforeEach(async (to, from, next) => {
   NProgress.start();
+  // 用户门户使用独立的 token，不走管理后台的鉴权流程
+  if (to.path === "/portal") {
+    next();
+    return;
+  }
   const hasToken = localStorage.getItem("accessToken");
   if (hasToken) {
     if (to.path === "/login") {
@@ -26,6 +26,11 @@ export const constantRoutes: RouteRecordRaw[] = [
     component: () => import("@/views/login/index.vue"),
     meta: { hidden: true },
   },
+  {
+    path: "/portal",
+    component: () => import("@/views/portal/index.vue"),
+    meta: { hidden: true },
+  },
   {
     path: "/",
     component: Layout,
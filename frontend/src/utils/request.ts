@@ -13,7 +13,7 @@ const service = axios.create({
 service.interceptors.request.use(
   (config: InternalAxiosRequestConfig) => {
     const accountStore = useAccountStoreHook();
-    if (accountStore.token) {
+    if (accountStore.token && !config.headers.Authorization) {
       config.headers.Authorization = accountStore.token;
     }
     return config;
@@ -1,26 +1,69 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"h-ui/model/constant"
 	"h-ui/model/vo"
 	"h-ui/service"
 	"h-ui/util"
+	"io"
+	"strconv"
 )
 
+// mustChangePassActions 使用默认密码的管理员只能访问这些接口
+var mustChangePassActions = []string{"account.getAccountInfo", "account.verifyDefaultPass", "account.changePass"}
+
 func AdminHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		myClaims, err := service.ParseToken(service.GetToken(c))
+		accountBo, err := service.GetAccountBo(c)
 		if err != nil {
 			vo.Fail(err.Error(), c)
 			c.Abort()
 			return
 		}
-		if !util.ArrContain(myClaims.AccountBo.Roles, "admin") {
+		if !util.ArrContain(accountBo.Roles, "admin") {
 			vo.Fail(constant.ForbiddenError, c)
 			c.Abort()
 			return
 		}
+		if scopes, ok := service.GetApiKeyScopes(c); ok && !service.ApiKeyAllowed(scopes, routeAction(c.FullPath()), c.Request.Method) {
+			vo.Fail(constant.ForbiddenError, c)
+			c.Abort()
+			return
+		}
+		if !mustChangePassAllowed(c, accountBo.Id) && service.AccountMustChangePass(accountBo.Id) {
+			vo.Fail("the default password must be changed first", c)
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
+
+// mustChangePassAllowed 除修改密码的接口外，还允许查看和修改自己的账号，修改时必须同时设置新密码
+func mustChangePassAllowed(c *gin.Context, accountId int64) bool {
+	action := routeAction(c.FullPath())
+	switch action {
+	case "account.getAccount":
+		return c.Query("id") == strconv.FormatInt(accountId, 10)
+	case "account.updateAccount":
+		if c.Request.Body == nil {
+			return false
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, auditRequestLimit+1))
+		c.Request.Body = auditBody{Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body), Closer: c.Request.Body}
+		// 超过大小限制的请求体不解析，直接拒绝
+		if err != nil || len(body) > auditRequestLimit {
+			return false
+		}
+		var request struct {
+			Id   *int64  `json:"id"`
+			Pass *string `json:"pass"`
+		}
+		return json.Unmarshal(body, &request) == nil && request.Id != nil && *request.Id == accountId &&
+			request.Pass != nil && *request.Pass != ""
+	}
+	return util.ArrContain(mustChangePassActions, action)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMustChangePassAllowedLimitsBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var allowed bool
+	var rest string
+	r := gin.New()
+	r.POST("/hui/account/updateAccount", func(c *gin.Context) {
+		allowed = mustChangePassAllowed(c, 3)
+		body, _ := io.ReadAll(c.Request.Body)
+		rest = string(body)
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		expect bool
+	}{
+		{"own account with new pass", `{"id":3,"pass":"New-Pass-123"}`, true},
+		{"other account", `{"id":4,"pass":"New-Pass-123"}`, false},
+		{"without pass", `{"id":3}`, false},
+		{"oversized body", `{"id":3,"pass":"New-Pass-123","padding":"` + strings.Repeat("a", auditRequestLimit) + `"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/hui/account/updateAccount", strings.NewReader(tt.body))
+			r.ServeHTTP(httptest.NewRecorder(), request)
+			if allowed != tt.expect {
+				t.Errorf("expected %v, got: %v", tt.expect, allowed)
+			}
+			if rest != tt.body {
+				t.Errorf("request body not restored, got %d bytes", len(rest))
+			}
+		})
+	}
+}
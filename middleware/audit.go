@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/service"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	auditBodyLimit    = 4096
+	auditRequestLimit = 1 << 20 // 超过该大小的请求体不解析操作对象
+)
+
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < auditBodyLimit {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	if w.body.Len() < auditBodyLimit {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+type auditBody struct {
+	io.Reader
+	io.Closer
+}
+
+type auditRequest struct {
+	Id               *int64  `json:"id"`
+	Ids              []int64 `json:"ids"`
+	AccountId        *int64  `json:"accountId"`
+	Username         *string `json:"username"`
+	ConfigUpdateDtos []struct {
+		Key *string `json:"key"`
+	} `json:"configUpdateDtos"`
+}
+
+type auditResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// AuditHandler 记录管理员的所有变更操作
+func AuditHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		action := routeAction(c.FullPath())
+		target := auditTarget(c)
+		before, _ := service.AuditSnapshot(action, target)
+
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		result := constant.AuditResultSuccess
+		message := ""
+		var response auditResponse
+		if err := json.Unmarshal(writer.body.Bytes(), &response); err == nil && response.Code != 0 {
+			if response.Code != constant.CodeSuccess {
+				result = constant.AuditResultFail
+				message = response.Message
+			}
+		} else if writer.Status() >= http.StatusBadRequest {
+			result = constant.AuditResultFail
+			message = http.StatusText(writer.Status())
+		}
+
+		after, ids := "", target.Ids
+		// 删除成功后账号已不存在，无需再生成快照
+		if action != "account.deleteAccount" || result != constant.AuditResultSuccess {
+			after, ids = service.AuditSnapshot(action, target)
+			if len(ids) == 0 {
+				ids = target.Ids
+			}
+		}
+		var targetIds []string
+		for _, id := range ids {
+			targetIds = append(targetIds, strconv.FormatInt(id, 10))
+		}
+
+		var actorId int64
+		actor := ""
+		if accountBo, err := service.GetAccountBo(c); err == nil {
+			actorId = accountBo.Id
+			actor = accountBo.Username
+		}
+		ip := c.ClientIP()
+		targetIdsStr := strings.Join(targetIds, ",")
+		service.SaveAudit(entity.Audit{
+			ActorId:     &actorId,
+			Actor:       &actor,
+			Action:      &action,
+			TargetIds:   &targetIdsStr,
+			BeforeValue: &before,
+			AfterValue:  &after,
+			Ip:          &ip,
+			Result:      &result,
+			Message:     &message,
+		})
+	}
+}
+
+// routeAction /hui/account/updateAccount => account.updateAccount
+func routeAction(fullPath string) string {
+	if index := strings.Index(fullPath, "/hui/"); index >= 0 {
+		fullPath = fullPath[index+len("/hui/"):]
+	}
+	return strings.ReplaceAll(strings.Trim(fullPath, "/"), "/", ".")
+}
+
+func auditTarget(c *gin.Context) bo.AuditTarget {
+	var target bo.AuditTarget
+	if !strings.HasPrefix(c.ContentType(), "application/json") || c.Request.Body == nil {
+		return target
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, auditRequestLimit+1))
+	// 读取过的部分与剩余请求体拼接后交还给后续处理
+	c.Request.Body = auditBody{Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body), Closer: c.Request.Body}
+	if err != nil || len(body) > auditRequestLimit {
+		return target
+	}
+
+	var request auditRequest
+	if err = json.Unmarshal(body, &request); err != nil {
+		return target
+	}
+	if request.Id != nil {
+		target.Ids = append(target.Ids, *request.Id)
+	}
+	if request.AccountId != nil {
+		target.Ids = append(target.Ids, *request.AccountId)
+	}
+	target.Ids = append(target.Ids, request.Ids...)
+	if request.Username != nil {
+		target.Username = *request.Username
+	}
+	for _, item := range request.ConfigUpdateDtos {
+		if item.Key != nil {
+			target.ConfigKeys = append(target.ConfigKeys, *item.Key)
+		}
+	}
+	return target
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/vo"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func initTestSqlite(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir() + "/"
+	if err := os.MkdirAll(dir+constant.SqliteDBDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HUI_DATA", dir)
+	t.Setenv(constant.MasterKeyEnv, "")
+	t.Setenv(constant.MasterKeyFileEnv, "")
+	if err := dao.InitSql(""); err != nil {
+		t.Fatalf("init sqlite err: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dao.CloseSqliteDB()
+	})
+}
+
+func TestAuditHandlerRecordsSnapshots(t *testing.T) {
+	initTestSqlite(t)
+	if err := dao.UpdateConfig([]string{constant.Hysteria2Socks5Pass}, map[string]interface{}{"value": "old-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AuditHandler())
+	router.POST("/hui/config/updateConfigs", func(c *gin.Context) {
+		_ = dao.UpdateConfig([]string{constant.HUIWebPort}, map[string]interface{}{"value": "9090"})
+		_ = dao.UpdateConfig([]string{constant.Hysteria2Socks5Pass}, map[string]interface{}{"value": "new-secret"})
+		vo.Success(nil, c)
+	})
+
+	body := `{"configUpdateDtos":[{"key":"H_UI_WEB_PORT","value":"9090"},{"key":"HYSTERIA2_SOCKS5_PASS","value":"new-secret"}]}`
+	request := httptest.NewRequest(http.MethodPost, "/hui/config/updateConfigs", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	audits, err := dao.ListAudit(dto.AuditQueryDto{})
+	if err != nil || len(audits) != 1 {
+		t.Fatalf("unexpected audits: %v %v", audits, err)
+	}
+	audit := audits[0]
+	if *audit.Action != "config.updateConfigs" || *audit.Result != constant.AuditResultSuccess {
+		t.Fatalf("unexpected audit: %s %s", *audit.Action, *audit.Result)
+	}
+	if !strings.Contains(*audit.BeforeValue, `"H_UI_WEB_PORT":"8081"`) || !strings.Contains(*audit.AfterValue, `"H_UI_WEB_PORT":"9090"`) {
+		t.Fatalf("unexpected snapshots, before: %s after: %s", *audit.BeforeValue, *audit.AfterValue)
+	}
+	for _, value := range []string{*audit.BeforeValue, *audit.AfterValue} {
+		if strings.Contains(value, "old-secret") || strings.Contains(value, "new-secret") {
+			t.Fatalf("secret not redacted: %s", value)
+		}
+	}
+}
+
+func TestAuditTargetLimitsBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"id":7,"padding":"` + strings.Repeat("a", auditRequestLimit) + `"}`
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/hui/account/updateAccount", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	if target := auditTarget(c); len(target.Ids) != 0 {
+		t.Fatalf("oversized body should not be parsed: %v", target.Ids)
+	}
+	rest, err := io.ReadAll(c.Request.Body)
+	if err != nil || !bytes.Equal(rest, []byte(body)) {
+		t.Fatalf("request body not restored, got %d bytes", len(rest))
+	}
+
+	c.Request = httptest.NewRequest(http.MethodPost, "/hui/account/updateAccount", strings.NewReader(`{"id":7}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if target := auditTarget(c); len(target.Ids) != 1 || target.Ids[0] != 7 {
+		t.Fatalf("unexpected target: %v", target.Ids)
+	}
+}
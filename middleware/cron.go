@@ -18,6 +18,46 @@ func InitCron() error {
 		logrus.Errorf("cron add func CronHandleAccount err: %v", err)
 		return errors.New("cron add func CronHandleAccount err")
 	}
+	_, err = c.AddFunc("@every 10m", service.CronTelegramUserRemind)
+	if err != nil {
+		logrus.Errorf("cron add func CronTelegramUserRemind err: %v", err)
+		return errors.New("cron add func CronTelegramUserRemind err")
+	}
+	_, err = c.AddFunc("@every 1m", service.CronPortHopping)
+	if err != nil {
+		logrus.Errorf("cron add func CronPortHopping err: %v", err)
+		return errors.New("cron add func CronPortHopping err")
+	}
+	_, err = c.AddFunc("@every 5m", service.CronFirewall)
+	if err != nil {
+		logrus.Errorf("cron add func CronFirewall err: %v", err)
+		return errors.New("cron add func CronFirewall err")
+	}
+	_, err = c.AddFunc("@every 1m", service.CronOutboundCheck)
+	if err != nil {
+		logrus.Errorf("cron add func CronOutboundCheck err: %v", err)
+		return errors.New("cron add func CronOutboundCheck err")
+	}
+	_, err = c.AddFunc("@every 12h", service.CronPanelAcme)
+	if err != nil {
+		logrus.Errorf("cron add func CronPanelAcme err: %v", err)
+		return errors.New("cron add func CronPanelAcme err")
+	}
+	_, err = c.AddFunc("@every 1m", service.CronPanelServer)
+	if err != nil {
+		logrus.Errorf("cron add func CronPanelServer err: %v", err)
+		return errors.New("cron add func CronPanelServer err")
+	}
+	_, err = c.AddFunc("@daily", service.CronCleanWebhookDelivery)
+	if err != nil {
+		logrus.Errorf("cron add func CronCleanWebhookDelivery err: %v", err)
+		return errors.New("cron add func CronCleanWebhookDelivery err")
+	}
+	_, err = c.AddFunc("@daily", service.CronCleanAudit)
+	if err != nil {
+		logrus.Errorf("cron add func CronCleanAudit err: %v", err)
+		return errors.New("cron add func CronCleanAudit err")
+	}
 	resetTrafficCron, err := dao.GetConfig("key = ?", constant.ResetTrafficCron)
 	if err != nil {
 		return err
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/gin-gonic/gin"
+	"h-ui/model/bo"
 	"h-ui/model/constant"
 	"h-ui/model/vo"
 	"h-ui/service"
@@ -17,22 +18,37 @@ func JWTHandler() gin.HandlerFunc {
 			return
 		}
 		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
+		if !(len(parts) == 2 && (parts[0] == constant.TokenType || parts[0] == constant.ApiKeyTokenType)) {
 			vo.Fail(constant.IllegalTokenError, c)
 			c.Abort()
 			return
 		}
-		myClaims, err := service.ParseToken(parts[1])
-		if err != nil {
-			vo.Fail(err.Error(), c)
-			c.Abort()
-			return
+		var accountBo bo.AccountBo
+		if parts[0] == constant.ApiKeyTokenType {
+			var scopes []string
+			var err error
+			accountBo, scopes, err = service.ParseApiKey(parts[1])
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				c.Abort()
+				return
+			}
+			service.SetApiKeyScopes(c, scopes)
+		} else {
+			myClaims, err := service.ParseToken(parts[1])
+			if err != nil {
+				vo.Fail(err.Error(), c)
+				c.Abort()
+				return
+			}
+			accountBo = myClaims.AccountBo
 		}
-		if myClaims.AccountBo.Deleted != 0 {
+		if accountBo.Deleted != 0 {
 			vo.Fail("this account has been disabled", c)
 			c.Abort()
 			return
 		}
+		service.SetAccountBo(c, accountBo)
 		c.Next()
 	}
 }
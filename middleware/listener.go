@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/service"
+	"net/http"
+)
+
+// ListenerHandler 监听没有开放的路由组返回 404
+func ListenerHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.ListenerAllowRoute(c.Request, c.FullPath()) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Next()
+	}
+}
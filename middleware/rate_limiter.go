@@ -4,7 +4,10 @@ import (
 	"github.com/didip/tollbooth"
 	"github.com/didip/tollbooth/limiter"
 	"github.com/gin-gonic/gin"
+	"h-ui/model/constant"
 	"h-ui/model/vo"
+	"h-ui/service"
+	"strings"
 )
 
 var limit *limiter.Limiter
@@ -15,6 +18,10 @@ func init() {
 
 func RateLimiterHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if isHysteria2AuthCallback(c) || isTelegramWebhook(c) {
+			c.Next()
+			return
+		}
 		httpError := tollbooth.LimitByRequest(limit, c.Writer, c.Request)
 		if httpError != nil {
 			vo.Fail("click too fast", c)
@@ -24,3 +31,15 @@ func RateLimiterHandler() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// isHysteria2AuthCallback hysteria2 通过本机回调认证接口，不参与限流
+// 本机反向代理转发的请求带有转发头，仍然限流
+func isHysteria2AuthCallback(c *gin.Context) bool {
+	return strings.HasSuffix(c.Request.URL.Path, "/hui/hysteria2/auth") && service.IsLocalRequest(c.Request)
+}
+
+// isTelegramWebhook Telegram 推送带有正确的 secret_token 时不参与限流
+func isTelegramWebhook(c *gin.Context) bool {
+	return strings.HasSuffix(c.Request.URL.Path, "/hui/telegram/webhook") &&
+		service.TelegramWebhookAuthorized(c.GetHeader(constant.TelegramSecretTokenHeader))
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/model/constant"
+	"h-ui/model/vo"
+	"h-ui/service"
+	"h-ui/util"
+)
+
+// UserHandler 用户自助接口只接受用户登录签发的 token，不接受 API Key
+func UserHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountBo, err := service.GetAccountBo(c)
+		if err != nil {
+			vo.Fail(err.Error(), c)
+			c.Abort()
+			return
+		}
+		if _, ok := service.GetApiKeyScopes(c); ok || !util.ArrContain(accountBo.Roles, "user") {
+			vo.Fail(constant.ForbiddenError, c)
+			c.Abort()
+			return
+		}
+		if !service.UserAccountAvailable(accountBo.Id) {
+			vo.Fail("this account has been disabled", c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
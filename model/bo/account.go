@@ -1,6 +1,9 @@
 package bo
 
-import "time"
+import (
+	"h-ui/model/entity"
+	"time"
+)
 
 type AccountBo struct {
 	Id       int64    `json:"id"`
@@ -28,3 +31,25 @@ type AccountExport struct {
 	LoginAt      int64     `json:"loginAt"`
 	ConAt        int64     `json:"conAt"`
 }
+
+type AccountTrafficTop struct {
+	AccountId int64  `json:"accountId"`
+	Username  string `json:"username"`
+	Download  int64  `json:"download"`
+	Upload    int64  `json:"upload"`
+}
+
+type AccountTagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// AccountImport Id 为空时新建 Account，否则按 Updates 更新；SetTags 为 true 时替换标签
+type AccountImport struct {
+	Id         *int64
+	Account    entity.Account
+	Updates    map[string]interface{}
+	SetTags    bool
+	Tags       []string
+	PassHashed bool // Account.Pass 已是哈希，来自 json 备份
+}
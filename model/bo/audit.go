@@ -0,0 +1,8 @@
+package bo
+
+// AuditTarget 审计对象，由请求参数解析得到
+type AuditTarget struct {
+	Ids        []int64
+	Username   string
+	ConfigKeys []string
+}
@@ -2,40 +2,40 @@ package bo
 
 type Hysteria2ServerConfig struct {
 	Listen                *string                     `yaml:"listen,omitempty" json:"listen" validate:"required"`
-	Obfs                  *serverConfigObfs           `yaml:"obfs,omitempty" json:"obfs" validate:"omitempty"`
-	TLS                   *serverConfigTLS            `yaml:"tls,omitempty" json:"tls" validate:"omitempty"`
-	ACME                  *serverConfigACME           `yaml:"acme,omitempty" json:"acme" validate:"omitempty"`
-	QUIC                  *serverConfigQUIC           `yaml:"quic,omitempty" json:"quic" validate:"omitempty"`
-	Bandwidth             *serverConfigBandwidth      `yaml:"bandwidth,omitempty" json:"bandwidth" validate:"omitempty"`
+	Obfs                  *ServerConfigObfs           `yaml:"obfs,omitempty" json:"obfs" validate:"omitempty"`
+	TLS                   *ServerConfigTLS            `yaml:"tls,omitempty" json:"tls" validate:"omitempty"`
+	ACME                  *ServerConfigACME           `yaml:"acme,omitempty" json:"acme" validate:"omitempty"`
+	QUIC                  *ServerConfigQUIC           `yaml:"quic,omitempty" json:"quic" validate:"omitempty"`
+	Bandwidth             *ServerConfigBandwidth      `yaml:"bandwidth,omitempty" json:"bandwidth" validate:"omitempty"`
 	IgnoreClientBandwidth *bool                       `yaml:"ignoreClientBandwidth,omitempty" json:"ignoreClientBandwidth" validate:"omitempty"`
 	SpeedTest             *bool                       `yaml:"speedTest,omitempty" json:"speedTest" validate:"omitempty"`
 	DisableUDP            *bool                       `yaml:"disableUDP,omitempty" json:"disableUDP" validate:"omitempty"`
 	UDPIdleTimeout        *string                     `yaml:"udpIdleTimeout,omitempty" json:"udpIdleTimeout" validate:"omitempty"`
 	Auth                  *ServerConfigAuth           `yaml:"auth,omitempty" json:"-" validate:"omitempty"`
-	Resolver              *serverConfigResolver       `yaml:"resolver,omitempty" json:"resolver" validate:"omitempty"`
-	Sniff                 *serverConfigSniff          `yaml:"sniff,omitempty" json:"sniff" validate:"omitempty"`
-	ACL                   *serverConfigACL            `yaml:"acl,omitempty" json:"acl" validate:"omitempty"`
-	Outbounds             []serverConfigOutboundEntry `yaml:"outbounds,omitempty" json:"outbounds" validate:"omitempty"`
+	Resolver              *ServerConfigResolver       `yaml:"resolver,omitempty" json:"resolver" validate:"omitempty"`
+	Sniff                 *ServerConfigSniff          `yaml:"sniff,omitempty" json:"sniff" validate:"omitempty"`
+	ACL                   *ServerConfigACL            `yaml:"acl,omitempty" json:"acl" validate:"omitempty"`
+	Outbounds             []ServerConfigOutboundEntry `yaml:"outbounds,omitempty" json:"outbounds" validate:"omitempty"`
 	TrafficStats          *ServerConfigTrafficStats   `yaml:"trafficStats,omitempty" json:"trafficStats" validate:"required"`
-	Masquerade            *serverConfigMasquerade     `yaml:"masquerade,omitempty" json:"masquerade" validate:"omitempty"`
+	Masquerade            *ServerConfigMasquerade     `yaml:"masquerade,omitempty" json:"masquerade" validate:"omitempty"`
 }
 
-type serverConfigObfsSalamander struct {
+type ServerConfigObfsSalamander struct {
 	Password *string `yaml:"password,omitempty" json:"password" validate:"required"`
 }
 
-type serverConfigObfs struct {
+type ServerConfigObfs struct {
 	Type       *string                     `yaml:"type,omitempty" json:"type" validate:"required"`
-	Salamander *serverConfigObfsSalamander `yaml:"salamander,omitempty" json:"salamander" validate:"required"`
+	Salamander *ServerConfigObfsSalamander `yaml:"salamander,omitempty" json:"salamander" validate:"required"`
 }
 
-type serverConfigTLS struct {
+type ServerConfigTLS struct {
 	Cert     *string `yaml:"cert,omitempty" json:"cert" validate:"required"`
 	Key      *string `yaml:"key,omitempty"  json:"key" validate:"required"`
 	SNIGuard *string `yaml:"sniGuard,omitempty" json:"sniGuard" validate:"omitempty"`
 }
 
-type serverConfigACME struct {
+type ServerConfigACME struct {
 	// Common fields
 	Domains    []string `yaml:"domains,omitempty" json:"domains" validate:"required"`
 	Email      *string  `yaml:"email,omitempty" json:"email" validate:"required"`
@@ -45,9 +45,9 @@ type serverConfigACME struct {
 
 	// Type selection
 	Type *string               `yaml:"type,omitempty" json:"type" validate:"omitempty"`
-	HTTP *serverConfigACMEHTTP `yaml:"http,omitempty" json:"http" validate:"omitempty"`
-	TLS  *serverConfigACMETLS  `yaml:"tls,omitempty" json:"tls" validate:"omitempty"`
-	DNS  *serverConfigACMEDNS  `yaml:"dns,omitempty" json:"dns" validate:"omitempty"`
+	HTTP *ServerConfigACMEHTTP `yaml:"http,omitempty" json:"http" validate:"omitempty"`
+	TLS  *ServerConfigACMETLS  `yaml:"tls,omitempty" json:"tls" validate:"omitempty"`
+	DNS  *ServerConfigACMEDNS  `yaml:"dns,omitempty" json:"dns" validate:"omitempty"`
 
 	// Legacy fields for backwards compatibility
 	// Only applicable when Type is empty
@@ -57,20 +57,20 @@ type serverConfigACME struct {
 	AltTLSALPNPort *int  `yaml:"altTLSALPNPort,omitempty" json:"altTLSALPNPort" validate:"required"`
 }
 
-type serverConfigACMEHTTP struct {
+type ServerConfigACMEHTTP struct {
 	AltPort *int `yaml:"altPort,omitempty" json:"altPort" validate:"required"`
 }
 
-type serverConfigACMETLS struct {
+type ServerConfigACMETLS struct {
 	AltPort *int `yaml:"altPort,omitempty" json:"altPort" validate:"required"`
 }
 
-type serverConfigACMEDNS struct {
+type ServerConfigACMEDNS struct {
 	Name   *string           `yaml:"name,omitempty" json:"name" validate:"required"`
 	Config map[string]string `yaml:"config,omitempty" json:"config" validate:"required"`
 }
 
-type serverConfigQUIC struct {
+type ServerConfigQUIC struct {
 	InitStreamReceiveWindow     *uint64 `yaml:"initStreamReceiveWindow,omitempty" json:"initStreamReceiveWindow" validate:"omitempty"`
 	MaxStreamReceiveWindow      *uint64 `yaml:"maxStreamReceiveWindow,omitempty" json:"maxStreamReceiveWindow" validate:"omitempty"`
 	InitConnectionReceiveWindow *uint64 `yaml:"initConnReceiveWindow,omitempty" json:"initConnReceiveWindow" validate:"omitempty"`
@@ -80,7 +80,7 @@ type serverConfigQUIC struct {
 	DisablePathMTUDiscovery     *bool   `yaml:"disablePathMTUDiscovery,omitempty" json:"disablePathMTUDiscovery" validate:"omitempty"`
 }
 
-type serverConfigBandwidth struct {
+type ServerConfigBandwidth struct {
 	Up   *string `yaml:"up,omitempty" json:"up" validate:"required"`
 	Down *string `yaml:"down,omitempty" json:"down" validate:"required"`
 }
@@ -98,39 +98,39 @@ type ServerConfigAuth struct {
 	Command  *string               `yaml:"command,omitempty" json:"command" validate:"omitempty"`
 }
 
-type serverConfigResolverTCP struct {
+type ServerConfigResolverTCP struct {
 	Addr    *string `yaml:"addr,omitempty" json:"addr" validate:"required"`
 	Timeout *string `yaml:"timeout,omitempty" json:"timeout" validate:"required"`
 }
 
-type serverConfigResolverUDP struct {
+type ServerConfigResolverUDP struct {
 	Addr    *string `yaml:"addr,omitempty" json:"addr" validate:"required"`
 	Timeout *string `yaml:"timeout,omitempty" json:"timeout" validate:"required"`
 }
 
-type serverConfigResolverTLS struct {
+type ServerConfigResolverTLS struct {
 	Addr     *string `yaml:"addr,omitempty" json:"addr" validate:"required"`
 	Timeout  *string `yaml:"timeout,omitempty" json:"timeout" validate:"required"`
 	SNI      *string `yaml:"sni,omitempty" json:"sni" validate:"required"`
 	Insecure *bool   `yaml:"insecure,omitempty" json:"insecure" validate:"required"`
 }
 
-type serverConfigResolverHTTPS struct {
+type ServerConfigResolverHTTPS struct {
 	Addr     *string `yaml:"addr,omitempty" json:"addr" validate:"required"`
 	Timeout  *string `yaml:"timeout,omitempty" json:"timeout" validate:"required"`
 	SNI      *string `yaml:"sni,omitempty" json:"sni"  validate:"required"`
 	Insecure *bool   `yaml:"insecure,omitempty" json:"insecure" validate:"required"`
 }
 
-type serverConfigResolver struct {
+type ServerConfigResolver struct {
 	Type  *string                    `yaml:"type,omitempty" json:"type" validate:"omitempty"`
-	TCP   *serverConfigResolverTCP   `yaml:"tcp,omitempty" json:"tcp" validate:"omitempty"`
-	UDP   *serverConfigResolverUDP   `yaml:"udp,omitempty" json:"udp" validate:"omitempty"`
-	TLS   *serverConfigResolverTLS   `yaml:"tls,omitempty" json:"tls" validate:"omitempty"`
-	HTTPS *serverConfigResolverHTTPS `yaml:"https,omitempty" json:"https" validate:"omitempty"`
+	TCP   *ServerConfigResolverTCP   `yaml:"tcp,omitempty" json:"tcp" validate:"omitempty"`
+	UDP   *ServerConfigResolverUDP   `yaml:"udp,omitempty" json:"udp" validate:"omitempty"`
+	TLS   *ServerConfigResolverTLS   `yaml:"tls,omitempty" json:"tls" validate:"omitempty"`
+	HTTPS *ServerConfigResolverHTTPS `yaml:"https,omitempty" json:"https" validate:"omitempty"`
 }
 
-type serverConfigSniff struct {
+type ServerConfigSniff struct {
 	Enable        *bool   `yaml:"enable,omitempty" json:"enable" validate:"required"`
 	Timeout       *string `yaml:"timeout,omitempty" json:"timeout" validate:"required"`
 	RewriteDomain *bool   `yaml:"rewriteDomain,omitempty" json:"rewriteDomain" validate:"required"`
@@ -138,7 +138,7 @@ type serverConfigSniff struct {
 	UDPPorts      *string `yaml:"udpPorts,omitempty" json:"udpPorts" validate:"omitempty"`
 }
 
-type serverConfigACL struct {
+type ServerConfigACL struct {
 	File              *string  `yaml:"file,omitempty" json:"file" validate:"omitempty"`
 	Inline            []string `yaml:"inline,omitempty" json:"inline" validate:"omitempty"`
 	GeoIP             *string  `yaml:"geoip,omitempty" json:"geoip" validate:"omitempty"`
@@ -146,7 +146,7 @@ type serverConfigACL struct {
 	GeoUpdateInterval *string  `yaml:"geoUpdateInterval,omitempty" json:"geoUpdateInterval" validate:"omitempty"`
 }
 
-type serverConfigOutboundDirect struct {
+type ServerConfigOutboundDirect struct {
 	Mode       *string `yaml:"mode,omitempty" json:"mode" validate:"required"`
 	BindIPv4   *string `yaml:"bindIPv4,omitempty" json:"bindIPv4" validate:"required"`
 	BindIPv6   *string `yaml:"bindIPv6,omitempty" json:"bindIPv6" validate:"required"`
@@ -154,23 +154,23 @@ type serverConfigOutboundDirect struct {
 	FastOpen   *bool   `yaml:"fastOpen,omitempty" json:"fastOpen" validate:"required"`
 }
 
-type serverConfigOutboundSOCKS5 struct {
+type ServerConfigOutboundSOCKS5 struct {
 	Addr     *string `yaml:"addr,omitempty" json:"addr" validate:"required"`
 	Username *string `yaml:"username,omitempty" json:"username" validate:"omitempty"`
 	Password *string `yaml:"password,omitempty" json:"password" validate:"omitempty"`
 }
 
-type serverConfigOutboundHTTP struct {
+type ServerConfigOutboundHTTP struct {
 	URL      *string `yaml:"url,omitempty" json:"url" validate:"required"`
 	Insecure *bool   `yaml:"insecure,omitempty" json:"insecure" validate:"required"`
 }
 
-type serverConfigOutboundEntry struct {
+type ServerConfigOutboundEntry struct {
 	Name   *string                     `yaml:"name,omitempty" json:"name" validate:"required"`
 	Type   *string                     `yaml:"type,omitempty" json:"type" validate:"omitempty"`
-	Direct *serverConfigOutboundDirect `yaml:"direct,omitempty" json:"direct" validate:"omitempty"`
-	SOCKS5 *serverConfigOutboundSOCKS5 `yaml:"socks5,omitempty" json:"socks5" validate:"omitempty"`
-	HTTP   *serverConfigOutboundHTTP   `yaml:"http,omitempty" json:"http" validate:"omitempty"`
+	Direct *ServerConfigOutboundDirect `yaml:"direct,omitempty" json:"direct" validate:"omitempty"`
+	SOCKS5 *ServerConfigOutboundSOCKS5 `yaml:"socks5,omitempty" json:"socks5" validate:"omitempty"`
+	HTTP   *ServerConfigOutboundHTTP   `yaml:"http,omitempty" json:"http" validate:"omitempty"`
 }
 
 type ServerConfigTrafficStats struct {
@@ -178,27 +178,27 @@ type ServerConfigTrafficStats struct {
 	Secret *string `yaml:"secret,omitempty" json:"-" validate:"omitempty"`
 }
 
-type serverConfigMasqueradeFile struct {
+type ServerConfigMasqueradeFile struct {
 	Dir *string `yaml:"dir,omitempty" json:"dir" validate:"required"`
 }
 
-type serverConfigMasqueradeProxy struct {
+type ServerConfigMasqueradeProxy struct {
 	URL         *string `yaml:"url,omitempty" json:"url" validate:"required"`
 	RewriteHost *bool   `yaml:"rewriteHost,omitempty" json:"rewriteHost" validate:"required"`
 	Insecure    *bool   `yaml:"insecure,omitempty" json:"insecure" validate:"required"`
 }
 
-type serverConfigMasqueradeString struct {
+type ServerConfigMasqueradeString struct {
 	Content    *string           `yaml:"content,omitempty" json:"content" validate:"required"`
 	Headers    map[string]string `yaml:"headers,omitempty" json:"headers" validate:"omitempty"`
 	StatusCode *int              `yaml:"statusCode,omitempty" json:"statusCode" validate:"omitempty"`
 }
 
-type serverConfigMasquerade struct {
+type ServerConfigMasquerade struct {
 	Type        *string                       `yaml:"type,omitempty" json:"type" validate:"omitempty"`
-	File        *serverConfigMasqueradeFile   `yaml:"file,omitempty" json:"file" validate:"omitempty"`
-	Proxy       *serverConfigMasqueradeProxy  `yaml:"proxy,omitempty" json:"proxy" validate:"omitempty"`
-	String      *serverConfigMasqueradeString `yaml:"string,omitempty" json:"string" validate:"omitempty"`
+	File        *ServerConfigMasqueradeFile   `yaml:"file,omitempty" json:"file" validate:"omitempty"`
+	Proxy       *ServerConfigMasqueradeProxy  `yaml:"proxy,omitempty" json:"proxy" validate:"omitempty"`
+	String      *ServerConfigMasqueradeString `yaml:"string,omitempty" json:"string" validate:"omitempty"`
 	ListenHTTP  *string                       `yaml:"listenHTTP,omitempty" json:"listenHTTP" validate:"omitempty"`
 	ListenHTTPS *string                       `yaml:"listenHTTPS,omitempty" json:"listenHTTPS" validate:"omitempty"`
 	ForceHTTPS  *bool                         `yaml:"forceHTTPS,omitempty" json:"forceHTTPS" validate:"omitempty"`
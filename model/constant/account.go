@@ -0,0 +1,19 @@
+package constant
+
+// DefaultAccountPass sysadmin 的初始密码，首次登录后必须修改
+const DefaultAccountPass = "sysadmin"
+
+// 账号导入冲突策略
+const (
+	AccountImportConflictSkip      = "skip"
+	AccountImportConflictOverwrite = "overwrite"
+	AccountImportConflictMerge     = "merge"
+)
+
+// 账号导入每行的处理结果
+const (
+	AccountImportActionCreate = "create"
+	AccountImportActionUpdate = "update"
+	AccountImportActionSkip   = "skip"
+	AccountImportActionError  = "error"
+)
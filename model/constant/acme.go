@@ -0,0 +1,15 @@
+package constant
+
+import "time"
+
+const (
+	AcmeChallengeHttp01    = "http-01"
+	AcmeChallengeTlsAlpn01 = "tls-alpn-01"
+	AcmeChallengeDns01     = "dns-01"
+
+	AcmeDnsProviderCloudflare = "cloudflare"
+	AcmeDnsProviderWebhook    = "webhook" // POST {action, fqdn, value} 到指定地址，由外部脚本修改解析
+
+	PanelAcmeRenewBefore = 30 * 24 * time.Hour // 到期前 30 天续期
+	PanelAcmeTimeout     = 5 * time.Minute
+)
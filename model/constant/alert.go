@@ -0,0 +1,15 @@
+package constant
+
+const (
+	AlertTypeAccountQuota  = "account_quota"  // 流量使用百分比
+	AlertTypeAccountExpire = "account_expire" // 距离到期天数
+	AlertTypeCpu           = "cpu"
+	AlertTypeMem           = "mem"
+	AlertTypeDisk          = "disk"
+	AlertTypeHysteria2Down = "hysteria2_down"
+	AlertTypeCertExpire    = "cert_expire" // 证书距离到期天数
+
+	AlertChannelTelegram = "telegram"
+	AlertChannelWebhook  = "webhook"
+	AlertChannelEmail    = "email"
+)
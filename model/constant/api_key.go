@@ -0,0 +1,15 @@
+package constant
+
+const (
+	ApiKeyTokenType = "ApiKey"
+	ApiKeyPrefix    = "hui_"
+
+	ScopeAccountsRead  = "accounts:read"
+	ScopeAccountsWrite = "accounts:write"
+	ScopeTrafficRead   = "traffic:read"
+	ScopeConfigRead    = "config:read"
+	ScopeConfigWrite   = "config:write"
+	ScopeLogsRead      = "logs:read"
+	ScopeAuditRead     = "audit:read"
+	ScopeAdmin         = "admin"
+)
@@ -0,0 +1,8 @@
+package constant
+
+const (
+	AuditResultSuccess = "success"
+	AuditResultFail    = "fail"
+
+	AuditActionLogin = "auth.login"
+)
@@ -1,28 +1,76 @@
 package constant
 
 const (
-	HUIWebPort                 = "H_UI_WEB_PORT"
-	HUIWebContext              = "H_UI_WEB_CONTEXT"
-	HUICrtPath                 = "H_UI_CRT_PATH"
-	HUIKeyPath                 = "H_UI_KEY_PATH"
-	JwtSecret                  = "JWT_SECRET"
-	Hysteria2Enable            = "HYSTERIA2_ENABLE"
-	Hysteria2Config            = "HYSTERIA2_CONFIG"
-	Hysteria2TrafficTime       = "HYSTERIA2_TRAFFIC_TIME"
-	Hysteria2ConfigRemark      = "HYSTERIA2_CONFIG_REMARK"
-	Hysteria2ConfigPortHopping = "HYSTERIA2_CONFIG_PORT_HOPPING"
-	Hysteria2Node2Enable       = "HYSTERIA2_NODE2_ENABLE"
-	Hysteria2Node2Config       = "HYSTERIA2_NODE2_CONFIG"
-	Hysteria2Node2Remark       = "HYSTERIA2_NODE2_REMARK"
-	Hysteria2Socks5Addr        = "HYSTERIA2_SOCKS5_ADDR"
-	Hysteria2Socks5User        = "HYSTERIA2_SOCKS5_USER"
-	Hysteria2Socks5Pass        = "HYSTERIA2_SOCKS5_PASS"
-	ResetTrafficCron           = "RESET_TRAFFIC_CRON"
-	TelegramEnable             = "TELEGRAM_ENABLE"
-	TelegramToken              = "TELEGRAM_TOKEN"
-	TelegramChatId             = "TELEGRAM_CHAT_ID"
-	TelegramDebug              = "TELEGRAM_DEBUG"
-	TelegramLoginJobEnable     = "TELEGRAM_LOGIN_JOB_ENABLE"
-	TelegramLoginJobText       = "TELEGRAM_LOGIN_JOB_TEXT"
-	ClashExtension             = "CLASH_EXTENSION"
+	HUIWebPort                       = "H_UI_WEB_PORT"
+	HUIWebContext                    = "H_UI_WEB_CONTEXT"
+	HUICrtPath                       = "H_UI_CRT_PATH"
+	HUIKeyPath                       = "H_UI_KEY_PATH"
+	HUIWebRoutes                     = "H_UI_WEB_ROUTES"
+	HUITrustedProxies                = "H_UI_TRUSTED_PROXIES"
+	JwtSecret                        = "JWT_SECRET"
+	Hysteria2Enable                  = "HYSTERIA2_ENABLE"
+	Hysteria2Config                  = "HYSTERIA2_CONFIG"
+	Hysteria2TrafficTime             = "HYSTERIA2_TRAFFIC_TIME"
+	Hysteria2ConfigRemark            = "HYSTERIA2_CONFIG_REMARK"
+	Hysteria2ConfigPortHopping       = "HYSTERIA2_CONFIG_PORT_HOPPING"
+	Hysteria2ConfigPortHoppingIfaces = "HYSTERIA2_CONFIG_PORT_HOPPING_INTERFACES"
+	Hysteria2Node2Enable             = "HYSTERIA2_NODE2_ENABLE"
+	Hysteria2Node2Config             = "HYSTERIA2_NODE2_CONFIG"
+	Hysteria2Node2Remark             = "HYSTERIA2_NODE2_REMARK"
+	Hysteria2Node2PortHopping        = "HYSTERIA2_NODE2_PORT_HOPPING"
+	Hysteria2Node2PortHoppingIfaces  = "HYSTERIA2_NODE2_PORT_HOPPING_INTERFACES"
+	Hysteria2Socks5Addr              = "HYSTERIA2_SOCKS5_ADDR"
+	Hysteria2Socks5User              = "HYSTERIA2_SOCKS5_USER"
+	Hysteria2Socks5Pass              = "HYSTERIA2_SOCKS5_PASS"
+	Hysteria2Outbounds               = "HYSTERIA2_OUTBOUNDS"
+	Hysteria2Node2Outbounds          = "HYSTERIA2_NODE2_OUTBOUNDS"
+	OutboundCheckUrl                 = "OUTBOUND_CHECK_URL"
+	GeoIPUrl                         = "GEOIP_URL"
+	GeoSiteUrl                       = "GEOSITE_URL"
+	ResetTrafficCron                 = "RESET_TRAFFIC_CRON"
+	TelegramEnable                   = "TELEGRAM_ENABLE"
+	TelegramToken                    = "TELEGRAM_TOKEN"
+	TelegramChatId                   = "TELEGRAM_CHAT_ID"
+	TelegramDebug                    = "TELEGRAM_DEBUG"
+	TelegramLoginJobEnable           = "TELEGRAM_LOGIN_JOB_ENABLE"
+	TelegramLoginJobText             = "TELEGRAM_LOGIN_JOB_TEXT"
+	TelegramUserEnable               = "TELEGRAM_USER_ENABLE"
+	TelegramUserQuotaWarn            = "TELEGRAM_USER_QUOTA_WARN"
+	TelegramUserExpireWarn           = "TELEGRAM_USER_EXPIRE_WARN"
+	TelegramMode                     = "TELEGRAM_MODE"
+	TelegramWebhookSecret            = "TELEGRAM_WEBHOOK_SECRET"
+	HUIPublicUrl                     = "H_UI_PUBLIC_URL"
+	ClashExtension                   = "CLASH_EXTENSION"
+	SmtpHost                         = "SMTP_HOST"
+	SmtpPort                         = "SMTP_PORT"
+	SmtpUsername                     = "SMTP_USERNAME"
+	SmtpPassword                     = "SMTP_PASSWORD"
+	SmtpFrom                         = "SMTP_FROM"
+	SmtpTo                           = "SMTP_TO"
+	PasswordMinLength                = "PASSWORD_MIN_LENGTH"
+	PasswordHistory                  = "PASSWORD_HISTORY"
+	FirewallEnable                   = "FIREWALL_ENABLE"
+	FirewallPanelAllowlist           = "FIREWALL_PANEL_ALLOWLIST"
+	PanelAcmeEnable                  = "PANEL_ACME_ENABLE"
+	PanelAcmeDomains                 = "PANEL_ACME_DOMAINS"
+	PanelAcmeEmail                   = "PANEL_ACME_EMAIL"
+	PanelAcmeCa                      = "PANEL_ACME_CA"
+	PanelAcmeCaRoot                  = "PANEL_ACME_CA_ROOT"
+	PanelAcmeChallenge               = "PANEL_ACME_CHALLENGE"
+	PanelAcmeHttpPort                = "PANEL_ACME_HTTP_PORT"
+	PanelAcmeTlsPort                 = "PANEL_ACME_TLS_PORT"
+	PanelAcmeDnsProvider             = "PANEL_ACME_DNS_PROVIDER"
+	PanelAcmeDnsConfig               = "PANEL_ACME_DNS_CONFIG"
 )
+
+// SecretConfigKeys 加密存储的配置，HYSTERIA2_CONFIG 中包含 obfs 密码等
+var SecretConfigKeys = []string{
+	JwtSecret,
+	TelegramToken,
+	TelegramWebhookSecret,
+	Hysteria2Socks5Pass,
+	SmtpPassword,
+	Hysteria2Config,
+	Hysteria2Node2Config,
+	PanelAcmeDnsConfig,
+}
@@ -0,0 +1,16 @@
+package constant
+
+// 监听可以开放的路由组
+const (
+	RouteGroupPanel     = "panel"     // 管理面板、用户中心及其接口
+	RouteGroupSubscribe = "subscribe" // 订阅链接
+	RouteGroupAuth      = "auth"      // hysteria2 认证回调
+	RouteGroupTelegram  = "telegram"  // Telegram webhook
+)
+
+var RouteGroups = []string{RouteGroupPanel, RouteGroupSubscribe, RouteGroupAuth, RouteGroupTelegram}
+
+const (
+	ListenerNetworkTcp  = "tcp"
+	ListenerNetworkUnix = "unix"
+)
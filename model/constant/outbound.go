@@ -0,0 +1,16 @@
+package constant
+
+import "time"
+
+const (
+	OutboundTypeDirect = "direct"
+	OutboundTypeSocks5 = "socks5"
+	OutboundTypeHttp   = "http"
+
+	OutboundStatusUnknown   = "unknown" // 尚未检查，按健康处理
+	OutboundStatusHealthy   = "healthy"
+	OutboundStatusUnhealthy = "unhealthy"
+
+	OutboundFailThreshold = 3 // 连续失败次数达到后标记为不健康
+	OutboundCheckTimeout  = 10 * time.Second
+)
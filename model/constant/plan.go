@@ -0,0 +1,8 @@
+package constant
+
+// 账号套餐变更类型
+const (
+	PlanActionCreate = "create"
+	PlanActionRenew  = "renew"
+	PlanActionChange = "change"
+)
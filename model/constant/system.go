@@ -6,10 +6,22 @@ const (
 	BinDir        = "bin/"
 	ExportPathDir = "export/"
 
-	SqliteDBPath = "data/h_ui.db"
+	SqliteDBPath  = "data/h_ui.db"
+	MasterKeyPath = "data/master.key"
+
+	PanelAcmeDir            = "data/acme/"
+	PanelAcmeAccountKeyPath = "data/acme/account.key"
+	PanelAcmeCrtPath        = "data/acme/panel.crt"
+	PanelAcmeKeyPath        = "data/acme/panel.key"
+
+	MasterKeyEnv     = "HUI_MASTER_KEY"
+	MasterKeyFileEnv = "HUI_MASTER_KEY_FILE"
 
 	Hysteria2ConfigPath     = "bin/hysteria2.yaml"
 	Hysteria2Node2ConfigPath = "bin/hysteria2-node2.yaml"
+	Hysteria2AclConfigPath   = "bin/hysteria2-acl-%d.yaml" // 每个 ACL 规则集一个实例
+	GeoIPPath                = "bin/geoip.dat"
+	GeoSitePath              = "bin/geosite.dat"
 
 	SystemLogPath    = "logs/h-ui.log"
 	Hysteria2LogPath = "logs/hysteria2.log"
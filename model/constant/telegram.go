@@ -0,0 +1,14 @@
+package constant
+
+const (
+	TelegramModePolling = "polling"
+	TelegramModeWebhook = "webhook"
+
+	// TelegramSecretTokenHeader webhook 模式下 Telegram 回传 secret_token 的请求头
+	TelegramSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+	TelegramNotifyAll       = "*"
+	TelegramNotifyLogin     = "login"
+	TelegramNotifyLoginLock = "login_lock"
+	TelegramNotifyAlert     = "alert"
+)
@@ -0,0 +1,30 @@
+package constant
+
+const (
+	WebhookEventAll = "*"
+
+	WebhookEventAccountCreated        = "account.created"
+	WebhookEventAccountUpdated        = "account.updated"
+	WebhookEventAccountDeleted        = "account.deleted"
+	WebhookEventAccountDisabled       = "account.disabled"
+	WebhookEventAccountQuotaExhausted = "account.quota_exhausted"
+	WebhookEventAccountExpired        = "account.expired"
+	WebhookEventAccountKicked         = "account.kicked"
+	WebhookEventAccountTrafficReset   = "account.traffic_reset"
+	WebhookEventNodeStarted           = "node.started"
+	WebhookEventNodeStopped           = "node.stopped"
+	WebhookEventNodeCrashed           = "node.crashed"
+	WebhookEventConfigChanged         = "config.changed"
+	WebhookEventOutboundFailover      = "outbound.failover"
+	WebhookEventAlert                 = "alert.fired"
+	WebhookEventPing                  = "ping"
+
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFail    = "fail"
+
+	WebhookSignatureHeader = "X-HUI-Signature"
+	WebhookEventHeader     = "X-HUI-Event"
+	WebhookDeliveryHeader  = "X-HUI-Delivery"
+	WebhookTimestampHeader = "X-HUI-Timestamp"
+)
@@ -1,9 +1,40 @@
 package dto
 
+type AccountQueryDto struct {
+	Username       *string `json:"username" form:"username" validate:"omitempty,min=1,max=32"`
+	Deleted        *int64  `json:"deleted" form:"deleted" validate:"omitempty,oneof=0 1"`
+	Tag            *string `json:"tag" form:"tag" validate:"omitempty,min=1,max=32"`
+	Role           *string `json:"role" form:"role" validate:"omitempty,oneof=admin user"`
+	Expired        *int64  `json:"expired" form:"expired" validate:"omitempty,oneof=0 1"`
+	OverQuota      *int64  `json:"overQuota" form:"overQuota" validate:"omitempty,oneof=0 1"`
+	Online         *int64  `json:"online" form:"online" validate:"omitempty,oneof=0 1"`
+	NeverConnected *int64  `json:"neverConnected" form:"neverConnected" validate:"omitempty,oneof=0 1"`
+	InactiveDays   *int64  `json:"inactiveDays" form:"inactiveDays" validate:"omitempty,min=1"` // 最近一次连接早于 N 天前
+}
+
 type AccountPageDto struct {
 	BaseDto
-	Username *string `json:"username" form:"username" validate:"omitempty,min=1,max=32"`
-	Deleted  *int64  `json:"deleted" form:"deleted" validate:"omitempty,oneof=0 1"`
+	AccountQueryDto
+}
+
+// AccountBulkDto ids 与 filter 二选一，ids 优先
+type AccountBulkDto struct {
+	Ids          []int64          `json:"ids" form:"ids" validate:"omitempty,max=10000"`
+	Filter       *AccountQueryDto `json:"filter" form:"filter" validate:"omitempty"`
+	Action       *string          `json:"action" form:"action" validate:"required,oneof=update extend addQuota resetTraffic kick disable enable delete addTag removeTag"`
+	Quota        *int64           `json:"quota" form:"quota" validate:"omitempty,min=-1"`
+	ExpireTime   *int64           `json:"expireTime" form:"expireTime" validate:"omitempty,min=0"`
+	DeviceNo     *int64           `json:"deviceNo" form:"deviceNo" validate:"omitempty,min=1"`
+	NodeAccess   *int64           `json:"nodeAccess" form:"nodeAccess" validate:"omitempty,oneof=1 2"`
+	Days         *int64           `json:"days" form:"days" validate:"omitempty,min=1,max=3650"`  // extend
+	AddQuota     *int64           `json:"addQuota" form:"addQuota" validate:"omitempty,min=1"`   // addQuota
+	KickUtilTime *int64           `json:"kickUtilTime" form:"kickUtilTime" validate:"omitempty"` // kick
+	Tag          *string          `json:"tag" form:"tag" validate:"omitempty,min=1,max=32"`      // addTag removeTag
+}
+
+type AccountTagsDto struct {
+	IdDto
+	Tags []string `json:"tags" form:"tags" validate:"omitempty,max=20,dive,min=1,max=32"`
 }
 
 type LoginDto struct {
@@ -11,6 +42,11 @@ type LoginDto struct {
 	Pass     *string `json:"pass" form:"pass" validate:"required,min=6,max=32,validateStr"`
 }
 
+type ChangePassDto struct {
+	OldPass *string `json:"oldPass" form:"oldPass" validate:"required,min=6,max=32,validateStr"`
+	NewPass *string `json:"newPass" form:"newPass" validate:"required,min=6,max=32,validateStr"`
+}
+
 type AccountSaveDto struct {
 	Username   *string `json:"username" form:"username" validate:"required,min=6,max=32,validateStr"`
 	Pass       *string `json:"pass" form:"pass" validate:"required,min=6,max=32,validateStr"`
@@ -33,3 +69,17 @@ type AccountUpdateDto struct {
 	NodeAccess *int64  `json:"nodeAccess" form:"nodeAccess" validate:"omitempty,oneof=1 2"`
 	Deleted    *int64  `json:"deleted" form:"deleted" validate:"omitempty,oneof=0 1"`
 }
+
+// AccountImportDto csv/xlsx 导入参数，以 multipart 表单提交
+type AccountImportDto struct {
+	DryRun   *int64  `json:"dryRun" form:"dryRun" validate:"omitempty,oneof=0 1"`
+	Conflict *string `json:"conflict" form:"conflict" validate:"omitempty,oneof=skip overwrite merge"`
+	Mapping  *string `json:"mapping" form:"mapping" validate:"omitempty,max=4096"` // JSON 对象：导入字段 -> 文件表头
+}
+
+// AccountExportDto format 为空或 json 时导出完整备份，csv/xlsx 可选择列和筛选条件
+type AccountExportDto struct {
+	Format  *string          `json:"format" form:"format" validate:"omitempty,oneof=json csv xlsx"`
+	Columns []string         `json:"columns" form:"columns" validate:"omitempty,max=32,dive,min=1,max=32"`
+	Filter  *AccountQueryDto `json:"filter" form:"filter" validate:"omitempty"`
+}
@@ -0,0 +1,38 @@
+package dto
+
+type AclProfileSaveDto struct {
+	Name          *string `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Port          *int64  `json:"port" form:"port" validate:"required,min=1,max=65535"`
+	Rules         *string `json:"rules" form:"rules" validate:"required,max=65536"`
+	Outbounds     *string `json:"outbounds" form:"outbounds" validate:"omitempty,max=65536"`
+	Tags          *string `json:"tags" form:"tags" validate:"omitempty,max=256"`
+	BandwidthUp   *string `json:"bandwidthUp" form:"bandwidthUp" validate:"omitempty,max=32"`
+	BandwidthDown *string `json:"bandwidthDown" form:"bandwidthDown" validate:"omitempty,max=32"`
+	Enable        *int64  `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+	Remark        *string `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
+
+type AclProfileUpdateDto struct {
+	IdDto
+	Name          *string `json:"name" form:"name" validate:"omitempty,min=1,max=32"`
+	Port          *int64  `json:"port" form:"port" validate:"omitempty,min=1,max=65535"`
+	Rules         *string `json:"rules" form:"rules" validate:"omitempty,max=65536"`
+	Outbounds     *string `json:"outbounds" form:"outbounds" validate:"omitempty,max=65536"`
+	Tags          *string `json:"tags" form:"tags" validate:"omitempty,max=256"`
+	BandwidthUp   *string `json:"bandwidthUp" form:"bandwidthUp" validate:"omitempty,max=32"`
+	BandwidthDown *string `json:"bandwidthDown" form:"bandwidthDown" validate:"omitempty,max=32"`
+	Enable        *int64  `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+	Remark        *string `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
+
+// AclRulesValidateDto 规则编辑器保存前的语法检查
+type AclRulesValidateDto struct {
+	Rules     *string `json:"rules" form:"rules" validate:"required,max=65536"`
+	Outbounds *string `json:"outbounds" form:"outbounds" validate:"omitempty,max=65536"`
+}
+
+// AccountAclProfileDto aclProfileId 为 0 时按标签匹配或使用节点默认规则
+type AccountAclProfileDto struct {
+	Ids          []int64 `json:"ids" form:"ids" validate:"required,min=1,max=10000"`
+	AclProfileId *int64  `json:"aclProfileId" form:"aclProfileId" validate:"required,min=0"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+type AlertRuleSaveDto struct {
+	Name      *string  `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Type      *string  `json:"type" form:"type" validate:"required,oneof=account_quota account_expire cpu mem disk hysteria2_down cert_expire"`
+	Threshold *float64 `json:"threshold" form:"threshold" validate:"required,min=0"` // 百分比或天数
+	Channels  []string `json:"channels" form:"channels" validate:"required,min=1,dive,oneof=telegram webhook email"`
+	Template  *string  `json:"template" form:"template" validate:"omitempty,max=512"` // 为空时使用默认模板
+	Cooldown  *int64   `json:"cooldown" form:"cooldown" validate:"required,min=0"`    // 持续触发时重复提醒的间隔（秒），0 只提醒一次
+	Enable    *int64   `json:"enable" form:"enable" validate:"required,oneof=0 1"`
+}
+
+type AlertRuleUpdateDto struct {
+	IdDto
+	Name      *string  `json:"name" form:"name" validate:"omitempty,min=1,max=32"`
+	Threshold *float64 `json:"threshold" form:"threshold" validate:"omitempty,min=0"`
+	Channels  []string `json:"channels" form:"channels" validate:"omitempty,min=1,dive,oneof=telegram webhook email"`
+	Template  *string  `json:"template" form:"template" validate:"omitempty,max=512"`
+	Cooldown  *int64   `json:"cooldown" form:"cooldown" validate:"omitempty,min=0"`
+	Enable    *int64   `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type ApiKeySaveDto struct {
+	Name       *string  `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Scopes     []string `json:"scopes" form:"scopes" validate:"required,min=1,dive,oneof=accounts:read accounts:write traffic:read config:read config:write logs:read audit:read admin"`
+	ExpireTime *int64   `json:"expireTime" form:"expireTime" validate:"omitempty,min=0"` // 0 永不过期
+}
@@ -0,0 +1,21 @@
+package dto
+
+type AuditQueryDto struct {
+	Actor     *string `json:"actor" form:"actor" validate:"omitempty,min=1,max=32"`
+	Action    *string `json:"action" form:"action" validate:"omitempty,min=1,max=64"`
+	Result    *string `json:"result" form:"result" validate:"omitempty,oneof=success fail"`
+	TargetId  *int64  `json:"targetId" form:"targetId" validate:"omitempty,gt=0"`
+	StartTime *int64  `json:"startTime" form:"startTime" validate:"omitempty,gt=0"` // 开始时间
+	EndTime   *int64  `json:"endTime" form:"endTime" validate:"omitempty,gt=0"`     // 结束时间
+}
+
+type AuditPageDto struct {
+	PageNum  *int64 `json:"pageNum" form:"pageNum" validate:"required,gt=0"`   // 页号
+	PageSize *int64 `json:"pageSize" form:"pageSize" validate:"required,gt=0"` // 页大小
+	AuditQueryDto
+}
+
+type AuditExportDto struct {
+	Format *string `json:"format" form:"format" validate:"required,oneof=csv json"`
+	AuditQueryDto
+}
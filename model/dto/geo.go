@@ -0,0 +1,11 @@
+package dto
+
+type GeoDto struct {
+	Type *string `json:"type" form:"type" validate:"required,oneof=geoip geosite"`
+}
+
+// GeoUpdateDto 上传或重新下载 geo 文件，sha256 为空时下载会读取同一地址的 .sha256sum
+type GeoUpdateDto struct {
+	Type   *string `json:"type" form:"type" validate:"required,oneof=geoip geosite"`
+	Sha256 *string `json:"sha256" form:"sha256" validate:"omitempty,len=64,hexadecimal"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+type OutboundSaveDto struct {
+	Name       *string `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Type       *string `json:"type" form:"type" validate:"required,oneof=direct socks5 http"`
+	Addr       *string `json:"addr" form:"addr" validate:"omitempty,max=256"`
+	Username   *string `json:"username" form:"username" validate:"omitempty,max=64"`
+	Password   *string `json:"password" form:"password" validate:"omitempty,max=128"`
+	BindIPv4   *string `json:"bindIPv4" form:"bindIPv4" validate:"omitempty,ipv4"`
+	BindIPv6   *string `json:"bindIPv6" form:"bindIPv6" validate:"omitempty,ipv6"`
+	BindDevice *string `json:"bindDevice" form:"bindDevice" validate:"omitempty,max=15"`
+	Insecure   *int64  `json:"insecure" form:"insecure" validate:"omitempty,oneof=0 1"`
+	Enable     *int64  `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+	Remark     *string `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
+
+type OutboundUpdateDto struct {
+	IdDto
+	Name       *string `json:"name" form:"name" validate:"omitempty,min=1,max=32"`
+	Type       *string `json:"type" form:"type" validate:"omitempty,oneof=direct socks5 http"`
+	Addr       *string `json:"addr" form:"addr" validate:"omitempty,max=256"`
+	Username   *string `json:"username" form:"username" validate:"omitempty,max=64"`
+	Password   *string `json:"password" form:"password" validate:"omitempty,max=128"`
+	BindIPv4   *string `json:"bindIPv4" form:"bindIPv4" validate:"omitempty,ipv4"`
+	BindIPv6   *string `json:"bindIPv6" form:"bindIPv6" validate:"omitempty,ipv6"`
+	BindDevice *string `json:"bindDevice" form:"bindDevice" validate:"omitempty,max=15"`
+	Insecure   *int64  `json:"insecure" form:"insecure" validate:"omitempty,oneof=0 1"`
+	Enable     *int64  `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+	Remark     *string `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
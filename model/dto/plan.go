@@ -0,0 +1,46 @@
+package dto
+
+type PlanSaveDto struct {
+	Name       *string `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Quota      *int64  `json:"quota" form:"quota" validate:"required,min=-1"`
+	Duration   *int64  `json:"duration" form:"duration" validate:"required,min=1,max=3650"` // 天
+	DeviceNo   *int64  `json:"deviceNo" form:"deviceNo" validate:"required,min=1"`
+	NodeAccess *int64  `json:"nodeAccess" form:"nodeAccess" validate:"required,oneof=1 2"`
+	SpeedTier  *string `json:"speedTier" form:"speedTier" validate:"omitempty,max=32"`
+	Price      *int64  `json:"price" form:"price" validate:"omitempty,min=0"`
+	Remark     *string `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
+
+type PlanUpdateDto struct {
+	IdDto
+	Name       *string `json:"name" form:"name" validate:"omitempty,min=1,max=32"`
+	Quota      *int64  `json:"quota" form:"quota" validate:"omitempty,min=-1"`
+	Duration   *int64  `json:"duration" form:"duration" validate:"omitempty,min=1,max=3650"`
+	DeviceNo   *int64  `json:"deviceNo" form:"deviceNo" validate:"omitempty,min=1"`
+	NodeAccess *int64  `json:"nodeAccess" form:"nodeAccess" validate:"omitempty,oneof=1 2"`
+	SpeedTier  *string `json:"speedTier" form:"speedTier" validate:"omitempty,max=32"`
+	Price      *int64  `json:"price" form:"price" validate:"omitempty,min=0"`
+	Remark     *string `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
+
+type AccountSaveFromPlanDto struct {
+	Username *string `json:"username" form:"username" validate:"required,min=6,max=32,validateStr"`
+	Pass     *string `json:"pass" form:"pass" validate:"required,min=6,max=32,validateStr"`
+	ConPass  *string `json:"conPass" form:"conPass" validate:"required,min=6,max=32,validateStr"`
+	PlanId   *int64  `json:"planId" form:"planId" validate:"required,gt=0"`
+}
+
+type AccountRenewDto struct {
+	IdDto
+	Periods *int64 `json:"periods" form:"periods" validate:"omitempty,min=1,max=120"` // 续期周期数，默认 1
+}
+
+type AccountChangePlanDto struct {
+	IdDto
+	PlanId *int64 `json:"planId" form:"planId" validate:"required,gt=0"`
+}
+
+type AccountApplyPlanDto struct {
+	Ids    []int64 `json:"ids" form:"ids" validate:"required,min=1"`
+	PlanId *int64  `json:"planId" form:"planId" validate:"required,gt=0"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+type TelegramChatSaveDto struct {
+	ChatId        *int64   `json:"chatId" form:"chatId" validate:"required,ne=0"`
+	Name          *string  `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Subscriptions []string `json:"subscriptions" form:"subscriptions" validate:"omitempty,dive,oneof=* login login_lock alert"`
+	Enable        *int64   `json:"enable" form:"enable" validate:"required,oneof=0 1"`
+}
+
+type TelegramChatUpdateDto struct {
+	IdDto
+	ChatId        *int64   `json:"chatId" form:"chatId" validate:"omitempty,ne=0"`
+	Name          *string  `json:"name" form:"name" validate:"omitempty,min=1,max=32"`
+	Subscriptions []string `json:"subscriptions" form:"subscriptions" validate:"omitempty,dive,oneof=* login login_lock alert"`
+	Enable        *int64   `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+}
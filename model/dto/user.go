@@ -0,0 +1,14 @@
+package dto
+
+type UserLoginTokenDto struct {
+	Token *string `json:"token" form:"token" validate:"required,len=32,alphanum"`
+}
+
+type UserTrafficDailyDto struct {
+	Days *int64 `json:"days" form:"days" validate:"omitempty,min=1,max=90"` // 默认 30 天
+}
+
+type UserSubscribeDto struct {
+	Protocol *string `json:"protocol" form:"protocol" validate:"required,min=1,max=8"`
+	Host     *string `json:"host" form:"host" validate:"required,min=1,max=301"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+// VoucherBatchSaveDto planId 与 quota/duration 二选一
+type VoucherBatchSaveDto struct {
+	Name       *string `json:"name" form:"name" validate:"required,min=1,max=32"`
+	PlanId     *int64  `json:"planId" form:"planId" validate:"omitempty,gt=0"`
+	Quota      *int64  `json:"quota" form:"quota" validate:"omitempty,min=0"`
+	Duration   *int64  `json:"duration" form:"duration" validate:"omitempty,min=0,max=3650"` // 天
+	Count      *int64  `json:"count" form:"count" validate:"required,min=1,max=1000"`
+	MaxUses    *int64  `json:"maxUses" form:"maxUses" validate:"required,min=1"`
+	ExpireTime *int64  `json:"expireTime" form:"expireTime" validate:"omitempty,min=0"`
+}
+
+type VoucherListDto struct {
+	BatchId *int64 `json:"batchId" form:"batchId" validate:"required,gt=0"`
+}
+
+type VoucherRedeemDto struct {
+	AccountId *int64  `json:"accountId" form:"accountId" validate:"required,gt=0"`
+	Code      *string `json:"code" form:"code" validate:"required,min=1,max=32"`
+}
+
+type VoucherCodeDto struct {
+	Code *string `json:"code" form:"code" validate:"required,min=1,max=32"`
+}
+
+type VoucherRedemptionQueryDto struct {
+	BatchId   *int64  `json:"batchId" form:"batchId" validate:"omitempty,gt=0"`
+	AccountId *int64  `json:"accountId" form:"accountId" validate:"omitempty,gt=0"`
+	Code      *string `json:"code" form:"code" validate:"omitempty,max=32"`
+}
+
+type VoucherRedemptionPageDto struct {
+	PageNum  *int64 `json:"pageNum" form:"pageNum" validate:"required,gt=0"`   // 页号
+	PageSize *int64 `json:"pageSize" form:"pageSize" validate:"required,gt=0"` // 页大小
+	VoucherRedemptionQueryDto
+}
@@ -0,0 +1,24 @@
+package dto
+
+type WebListenerSaveDto struct {
+	Name         *string  `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Network      *string  `json:"network" form:"network" validate:"required,oneof=tcp unix"`
+	Address      *string  `json:"address" form:"address" validate:"required,min=1,max=256"`
+	Tls          *int64   `json:"tls" form:"tls" validate:"omitempty,oneof=0 1"`
+	Routes       []string `json:"routes" form:"routes" validate:"omitempty,dive,oneof=panel subscribe auth telegram"`
+	RedirectPort *int64   `json:"redirectPort" form:"redirectPort" validate:"omitempty,min=0,max=65535"`
+	Enable       *int64   `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+	Remark       *string  `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
+
+type WebListenerUpdateDto struct {
+	IdDto
+	Name         *string  `json:"name" form:"name" validate:"omitempty,min=1,max=32"`
+	Network      *string  `json:"network" form:"network" validate:"omitempty,oneof=tcp unix"`
+	Address      *string  `json:"address" form:"address" validate:"omitempty,min=1,max=256"`
+	Tls          *int64   `json:"tls" form:"tls" validate:"omitempty,oneof=0 1"`
+	Routes       []string `json:"routes" form:"routes" validate:"omitempty,dive,oneof=panel subscribe auth telegram"`
+	RedirectPort *int64   `json:"redirectPort" form:"redirectPort" validate:"omitempty,min=0,max=65535"`
+	Enable       *int64   `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+	Remark       *string  `json:"remark" form:"remark" validate:"omitempty,max=128"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+type WebhookSaveDto struct {
+	Name   *string  `json:"name" form:"name" validate:"required,min=1,max=32"`
+	Url    *string  `json:"url" form:"url" validate:"required,url,max=512"`
+	Secret *string  `json:"secret" form:"secret" validate:"required,min=16,max=128"`
+	Events []string `json:"events" form:"events" validate:"required,min=1,dive,oneof=* account.created account.updated account.deleted account.disabled account.quota_exhausted account.expired account.kicked account.traffic_reset node.started node.stopped node.crashed config.changed outbound.failover alert.fired"`
+	Enable *int64   `json:"enable" form:"enable" validate:"required,oneof=0 1"`
+}
+
+type WebhookUpdateDto struct {
+	IdDto
+	Name   *string  `json:"name" form:"name" validate:"omitempty,min=1,max=32"`
+	Url    *string  `json:"url" form:"url" validate:"omitempty,url,max=512"`
+	Secret *string  `json:"secret" form:"secret" validate:"omitempty,min=16,max=128"`
+	Events []string `json:"events" form:"events" validate:"omitempty,min=1,dive,oneof=* account.created account.updated account.deleted account.disabled account.quota_exhausted account.expired account.kicked account.traffic_reset node.started node.stopped node.crashed config.changed outbound.failover alert.fired"`
+	Enable *int64   `json:"enable" form:"enable" validate:"omitempty,oneof=0 1"`
+}
+
+type WebhookDeliveryPageDto struct {
+	PageNum   *int64  `json:"pageNum" form:"pageNum" validate:"required,gt=0"`   // 页号
+	PageSize  *int64  `json:"pageSize" form:"pageSize" validate:"required,gt=0"` // 页大小
+	WebhookId *int64  `json:"webhookId" form:"webhookId" validate:"omitempty,gt=0"`
+	Event     *string `json:"event" form:"event" validate:"omitempty,min=1,max=64"`
+	Status    *string `json:"status" form:"status" validate:"omitempty,oneof=pending success fail"`
+}
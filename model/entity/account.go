@@ -17,4 +17,10 @@ type Account struct {
 
 	LoginAt *int64 `gorm:"column:login_at;default:0" json:"loginAt"`
 	ConAt   *int64 `gorm:"column:con_at;default:0" json:"conAt"`
+
+	PlanId *int64 `gorm:"column:plan_id;default:0" json:"planId"`
+
+	MustChangePass *int64 `gorm:"column:must_change_pass;default:0" json:"mustChangePass"`
+
+	AclProfileId *int64 `gorm:"column:acl_profile_id;default:0" json:"aclProfileId"`
 }
@@ -0,0 +1,7 @@
+package entity
+
+type AccountPassHistory struct {
+	AccountId  *int64  `gorm:"column:account_id;default:0" json:"accountId"`
+	Pass       *string `gorm:"column:pass;default:''" json:"pass"`
+	BaseEntity `gorm:"embedded"`
+}
@@ -0,0 +1,14 @@
+package entity
+
+type AccountPlanHistory struct {
+	AccountId        *int64  `gorm:"column:account_id;default:0" json:"accountId"`
+	PlanId           *int64  `gorm:"column:plan_id;default:0" json:"planId"`
+	PlanName         *string `gorm:"column:plan_name;default:''" json:"planName"`
+	Action           *string `gorm:"column:action;default:''" json:"action"`
+	Quota            *int64  `gorm:"column:quota;default:0" json:"quota"`
+	DeviceNo         *int64  `gorm:"column:device_no;default:0" json:"deviceNo"`
+	NodeAccess       *int64  `gorm:"column:node_access;default:0" json:"nodeAccess"`
+	ExpireTimeBefore *int64  `gorm:"column:expire_time_before;default:0" json:"expireTimeBefore"`
+	ExpireTimeAfter  *int64  `gorm:"column:expire_time_after;default:0" json:"expireTimeAfter"`
+	BaseEntity       `gorm:"embedded"`
+}
@@ -0,0 +1,9 @@
+package entity
+
+type AccountTrafficDaily struct {
+	AccountId  *int64  `gorm:"column:account_id;default:0" json:"accountId"`
+	Day        *string `gorm:"column:day;default:''" json:"day"`
+	Download   *int64  `gorm:"column:download;default:0" json:"download"`
+	Upload     *int64  `gorm:"column:upload;default:0" json:"upload"`
+	BaseEntity `gorm:"embedded"`
+}
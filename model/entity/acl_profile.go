@@ -0,0 +1,14 @@
+package entity
+
+type AclProfile struct {
+	Name          *string `gorm:"column:name;default:''" json:"name"`
+	Port          *int64  `gorm:"column:port;default:0" json:"port"`                     // 独立 hysteria2 实例的监听端口
+	Rules         *string `gorm:"column:rules;default:''" json:"rules"`                  // hysteria2 ACL 规则，每行一条
+	Outbounds     *string `gorm:"column:outbounds;default:''" json:"outbounds"`          // hysteria2 outbounds 的 YAML 列表
+	Tags          *string `gorm:"column:tags;default:''" json:"tags"`                    // 逗号分隔，带有这些标签的账号使用该规则集
+	BandwidthUp   *string `gorm:"column:bandwidth_up;default:''" json:"bandwidthUp"`     // 每个客户端的上行限速，如 100 mbps，空表示不限速
+	BandwidthDown *string `gorm:"column:bandwidth_down;default:''" json:"bandwidthDown"` // 每个客户端的下行限速
+	Enable        *int64  `gorm:"column:enable;default:1" json:"enable"`
+	Remark        *string `gorm:"column:remark;default:''" json:"remark"`
+	BaseEntity    `gorm:"embedded"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+type AlertRule struct {
+	Name       *string  `gorm:"column:name;default:''" json:"name"`
+	Type       *string  `gorm:"column:type;default:''" json:"type"`
+	Threshold  *float64 `gorm:"column:threshold;default:0" json:"threshold"`
+	Channels   *string  `gorm:"column:channels;default:''" json:"channels"`
+	Template   *string  `gorm:"column:template;default:''" json:"template"`
+	Cooldown   *int64   `gorm:"column:cooldown;default:0" json:"cooldown"`
+	Enable     *int64   `gorm:"column:enable;default:1" json:"enable"`
+	BaseEntity `gorm:"embedded"`
+}
+
+type AlertState struct {
+	RuleId     *int64  `gorm:"column:rule_id;default:0" json:"ruleId"`
+	Subject    *string `gorm:"column:subject;default:''" json:"subject"`
+	Message    *string `gorm:"column:message;default:''" json:"message"`
+	LastSentAt *int64  `gorm:"column:last_sent_at;default:0" json:"lastSentAt"`
+	BaseEntity `gorm:"embedded"`
+}
@@ -0,0 +1,12 @@
+package entity
+
+type ApiKey struct {
+	AccountId  *int64  `gorm:"column:account_id;default:0" json:"accountId"`
+	Name       *string `gorm:"column:name;default:''" json:"name"`
+	Prefix     *string `gorm:"column:prefix;default:''" json:"prefix"`
+	KeyHash    *string `gorm:"column:key_hash;default:''" json:"keyHash"`
+	Scopes     *string `gorm:"column:scopes;default:''" json:"scopes"`
+	ExpireTime *int64  `gorm:"column:expire_time;default:0" json:"expireTime"`
+	LastUsedAt *int64  `gorm:"column:last_used_at;default:0" json:"lastUsedAt"`
+	BaseEntity `gorm:"embedded"`
+}
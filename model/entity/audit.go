@@ -0,0 +1,14 @@
+package entity
+
+type Audit struct {
+	ActorId     *int64  `gorm:"column:actor_id;default:0" json:"actorId"`
+	Actor       *string `gorm:"column:actor;default:''" json:"actor"`
+	Action      *string `gorm:"column:action;default:''" json:"action"`
+	TargetIds   *string `gorm:"column:target_ids;default:''" json:"targetIds"`
+	BeforeValue *string `gorm:"column:before_value;default:''" json:"beforeValue"`
+	AfterValue  *string `gorm:"column:after_value;default:''" json:"afterValue"`
+	Ip          *string `gorm:"column:ip;default:''" json:"ip"`
+	Result      *string `gorm:"column:result;default:''" json:"result"`
+	Message     *string `gorm:"column:message;default:''" json:"message"`
+	BaseEntity  `gorm:"embedded"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+type Outbound struct {
+	Name       *string `gorm:"column:name;default:''" json:"name"`
+	Type       *string `gorm:"column:type;default:''" json:"type"`              // direct、socks5、http
+	Addr       *string `gorm:"column:addr;default:''" json:"addr"`              // socks5 为 host:port，http 为代理 URL
+	Username   *string `gorm:"column:username;default:''" json:"username"`      // socks5 用户名
+	Password   *string `gorm:"column:password;default:''" json:"-"`             // socks5 密码
+	BindIPv4   *string `gorm:"column:bind_ipv4;default:''" json:"bindIPv4"`     // direct 绑定的 IPv4 地址
+	BindIPv6   *string `gorm:"column:bind_ipv6;default:''" json:"bindIPv6"`     // direct 绑定的 IPv6 地址
+	BindDevice *string `gorm:"column:bind_device;default:''" json:"bindDevice"` // direct 绑定的网卡
+	Insecure   *int64  `gorm:"column:insecure;default:0" json:"insecure"`       // http 代理跳过证书校验
+	Enable     *int64  `gorm:"column:enable;default:1" json:"enable"`
+	Remark     *string `gorm:"column:remark;default:''" json:"remark"`
+	Status     *string `gorm:"column:status;default:'unknown'" json:"status"`   // 健康检查状态
+	Latency    *int64  `gorm:"column:latency;default:0" json:"latency"`         // 最近一次检查的延迟，毫秒
+	FailCount  *int64  `gorm:"column:fail_count;default:0" json:"failCount"`    // 连续失败次数
+	CheckAt    *int64  `gorm:"column:check_at;default:0" json:"checkAt"`        // 最近一次检查的时间，毫秒
+	CheckError *string `gorm:"column:check_error;default:''" json:"checkError"` // 最近一次失败的原因
+	BaseEntity `gorm:"embedded"`
+}
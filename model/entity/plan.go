@@ -0,0 +1,13 @@
+package entity
+
+type Plan struct {
+	Name       *string `gorm:"column:name;default:''" json:"name"`
+	Quota      *int64  `gorm:"column:quota;default:-1" json:"quota"`
+	Duration   *int64  `gorm:"column:duration;default:30" json:"duration"` // 有效天数
+	DeviceNo   *int64  `gorm:"column:device_no;default:3" json:"deviceNo"`
+	NodeAccess *int64  `gorm:"column:node_access;default:1" json:"nodeAccess"`
+	SpeedTier  *string `gorm:"column:speed_tier;default:''" json:"speedTier"` // 限速规则集名称，空表示不限速
+	Price      *int64  `gorm:"column:price;default:0" json:"price"`           // 用于变更套餐时折算剩余时长
+	Remark     *string `gorm:"column:remark;default:''" json:"remark"`
+	BaseEntity `gorm:"embedded"`
+}
@@ -0,0 +1,9 @@
+package entity
+
+type TelegramChat struct {
+	ChatId        *int64  `gorm:"column:chat_id;default:0" json:"chatId"`
+	Name          *string `gorm:"column:name;default:''" json:"name"`
+	Subscriptions *string `gorm:"column:subscriptions;default:''" json:"subscriptions"`
+	Enable        *int64  `gorm:"column:enable;default:1" json:"enable"`
+	BaseEntity    `gorm:"embedded"`
+}
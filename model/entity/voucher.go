@@ -0,0 +1,35 @@
+package entity
+
+type VoucherBatch struct {
+	Name       *string `gorm:"column:name;default:''" json:"name"`
+	PlanId     *int64  `gorm:"column:plan_id;default:0" json:"planId"`
+	Quota      *int64  `gorm:"column:quota;default:0" json:"quota"`
+	Duration   *int64  `gorm:"column:duration;default:0" json:"duration"` // 天
+	MaxUses    *int64  `gorm:"column:max_uses;default:1" json:"maxUses"`
+	ExpireTime *int64  `gorm:"column:expire_time;default:0" json:"expireTime"` // 0 表示永不过期
+	Count      *int64  `gorm:"column:count;default:0" json:"count"`
+	BaseEntity `gorm:"embedded"`
+}
+
+type Voucher struct {
+	BatchId    *int64  `gorm:"column:batch_id;default:0" json:"batchId"`
+	Code       *string `gorm:"column:code;default:''" json:"code"`
+	MaxUses    *int64  `gorm:"column:max_uses;default:1" json:"maxUses"`
+	Used       *int64  `gorm:"column:used;default:0" json:"used"`
+	ExpireTime *int64  `gorm:"column:expire_time;default:0" json:"expireTime"`
+	BaseEntity `gorm:"embedded"`
+}
+
+type VoucherRedemption struct {
+	VoucherId        *int64  `gorm:"column:voucher_id;default:0" json:"voucherId"`
+	BatchId          *int64  `gorm:"column:batch_id;default:0" json:"batchId"`
+	Code             *string `gorm:"column:code;default:''" json:"code"`
+	AccountId        *int64  `gorm:"column:account_id;default:0" json:"accountId"`
+	Username         *string `gorm:"column:username;default:''" json:"username"`
+	PlanId           *int64  `gorm:"column:plan_id;default:0" json:"planId"`
+	Quota            *int64  `gorm:"column:quota;default:0" json:"quota"`
+	Duration         *int64  `gorm:"column:duration;default:0" json:"duration"`
+	ExpireTimeBefore *int64  `gorm:"column:expire_time_before;default:0" json:"expireTimeBefore"`
+	ExpireTimeAfter  *int64  `gorm:"column:expire_time_after;default:0" json:"expireTimeAfter"`
+	BaseEntity       `gorm:"embedded"`
+}
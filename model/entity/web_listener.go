@@ -0,0 +1,13 @@
+package entity
+
+type WebListener struct {
+	Name         *string `gorm:"column:name;default:''" json:"name"`
+	Network      *string `gorm:"column:network;default:'tcp'" json:"network"`        // tcp 或 unix
+	Address      *string `gorm:"column:address;default:''" json:"address"`           // tcp 为 host:port，unix 为 socket 文件路径
+	Tls          *int64  `gorm:"column:tls;default:0" json:"tls"`                    // 使用面板证书
+	Routes       *string `gorm:"column:routes;default:''" json:"routes"`             // 开放的路由组，逗号分隔
+	RedirectPort *int64  `gorm:"column:redirect_port;default:0" json:"redirectPort"` // 大于 0 时所有请求重定向到该端口的 https
+	Enable       *int64  `gorm:"column:enable;default:1" json:"enable"`
+	Remark       *string `gorm:"column:remark;default:''" json:"remark"`
+	BaseEntity   `gorm:"embedded"`
+}
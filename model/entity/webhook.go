@@ -0,0 +1,21 @@
+package entity
+
+type Webhook struct {
+	Name       *string `gorm:"column:name;default:''" json:"name"`
+	Url        *string `gorm:"column:url;default:''" json:"url"`
+	Secret     *string `gorm:"column:secret;default:''" json:"secret"`
+	Events     *string `gorm:"column:events;default:''" json:"events"`
+	Enable     *int64  `gorm:"column:enable;default:1" json:"enable"`
+	BaseEntity `gorm:"embedded"`
+}
+
+type WebhookDelivery struct {
+	WebhookId    *int64  `gorm:"column:webhook_id;default:0" json:"webhookId"`
+	Event        *string `gorm:"column:event;default:''" json:"event"`
+	Payload      *string `gorm:"column:payload;default:''" json:"payload"`
+	Status       *string `gorm:"column:status;default:''" json:"status"`
+	Attempts     *int64  `gorm:"column:attempts;default:0" json:"attempts"`
+	ResponseCode *int64  `gorm:"column:response_code;default:0" json:"responseCode"`
+	Error        *string `gorm:"column:error;default:''" json:"error"`
+	BaseEntity   `gorm:"embedded"`
+}
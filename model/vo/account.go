@@ -18,6 +18,11 @@ type AccountVo struct {
 
 	LoginAt int64 `json:"loginAt"`
 	ConAt   int64 `json:"conAt"`
+
+	PlanId int64    `json:"planId"`
+	Tags   []string `json:"tags"`
+
+	AclProfileId int64 `json:"aclProfileId"` // 0 表示按标签匹配或使用节点默认规则
 }
 type AccountPageVo struct {
 	AccountVos []AccountVo `json:"records"`
@@ -29,3 +34,37 @@ type AccountInfoVo struct {
 	Username string   `json:"username"`
 	Roles    []string `json:"roles"`
 }
+
+type TelegramBindCodeVo struct {
+	Code       string `json:"code"`
+	ExpireTime int64  `json:"expireTime"`
+	Link       string `json:"link"` // 机器人未启动时为空
+}
+
+type AccountBulkVo struct {
+	Affected int64 `json:"affected"`
+}
+
+type AccountTagVo struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+type AccountImportRowVo struct {
+	Row      int64    `json:"row"` // 文件中的行号，表头为第 1 行
+	Username string   `json:"username"`
+	Action   string   `json:"action"` // create update skip error
+	Fields   []string `json:"fields"` // 将写入的字段
+	Errors   []string `json:"errors"`
+}
+
+type AccountImportVo struct {
+	DryRun  bool                 `json:"dryRun"`
+	Applied bool                 `json:"applied"`
+	Total   int64                `json:"total"`
+	Created int64                `json:"created"`
+	Updated int64                `json:"updated"`
+	Skipped int64                `json:"skipped"`
+	Failed  int64                `json:"failed"`
+	Rows    []AccountImportRowVo `json:"rows"`
+}
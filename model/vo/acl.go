@@ -0,0 +1,26 @@
+package vo
+
+type AclProfileVo struct {
+	BaseVo
+	Name          string `json:"name"`
+	Port          int64  `json:"port"`
+	Rules         string `json:"rules"`
+	Outbounds     string `json:"outbounds"`
+	Tags          string `json:"tags"`
+	BandwidthUp   string `json:"bandwidthUp"`
+	BandwidthDown string `json:"bandwidthDown"`
+	Enable        int64  `json:"enable"`
+	Remark        string `json:"remark"`
+	Running       bool   `json:"running"`  // 对应的 hysteria2 实例是否在运行
+	Accounts      int64  `json:"accounts"` // 单独指定该规则集的账号数，不含按标签匹配的
+}
+
+type AclRuleErrorVo struct {
+	Line    int64  `json:"line"` // 从 1 开始，0 表示不属于某一行的错误
+	Message string `json:"message"`
+}
+
+type AclRulesValidateVo struct {
+	Valid  bool             `json:"valid"`
+	Errors []AclRuleErrorVo `json:"errors"`
+}
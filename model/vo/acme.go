@@ -0,0 +1,15 @@
+package vo
+
+type PanelAcmeVo struct {
+	Enable      bool     `json:"enable"`
+	Domains     []string `json:"domains"`
+	Challenge   string   `json:"challenge"`
+	CertDomains []string `json:"certDomains"` // 当前证书包含的域名
+	Issuer      string   `json:"issuer"`
+	NotBefore   int64    `json:"notBefore"`
+	NotAfter    int64    `json:"notAfter"`
+	SelfSigned  bool     `json:"selfSigned"` // 申请成功之前使用自签名证书
+	Renewing    bool     `json:"renewing"`
+	RenewAt     int64    `json:"renewAt"` // 最近一次申请的时间
+	LastError   string   `json:"lastError"`
+}
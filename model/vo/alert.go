@@ -0,0 +1,21 @@
+package vo
+
+type AlertRuleVo struct {
+	BaseVo
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Threshold float64  `json:"threshold"`
+	Channels  []string `json:"channels"`
+	Template  string   `json:"template"`
+	Cooldown  int64    `json:"cooldown"`
+	Enable    int64    `json:"enable"`
+}
+
+type AlertStateVo struct {
+	BaseVo
+	RuleId     int64  `json:"ruleId"`
+	RuleName   string `json:"ruleName"`
+	Subject    string `json:"subject"`
+	Message    string `json:"message"`
+	LastSentAt int64  `json:"lastSentAt"`
+}
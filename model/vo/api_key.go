@@ -0,0 +1,15 @@
+package vo
+
+type ApiKeyVo struct {
+	BaseVo
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpireTime int64    `json:"expireTime"`
+	LastUsedAt int64    `json:"lastUsedAt"`
+}
+
+type ApiKeySaveVo struct {
+	ApiKeyVo
+	Key string `json:"key"` // 明文仅在创建时返回一次
+}
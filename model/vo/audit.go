@@ -0,0 +1,19 @@
+package vo
+
+type AuditVo struct {
+	BaseVo
+	ActorId     int64  `json:"actorId"`
+	Actor       string `json:"actor"`
+	Action      string `json:"action"`
+	TargetIds   string `json:"targetIds"`
+	BeforeValue string `json:"beforeValue"`
+	AfterValue  string `json:"afterValue"`
+	Ip          string `json:"ip"`
+	Result      string `json:"result"`
+	Message     string `json:"message"`
+}
+
+type AuditPageVo struct {
+	AuditVos []AuditVo `json:"records"`
+	Total    int64     `json:"total"`
+}
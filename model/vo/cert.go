@@ -0,0 +1,18 @@
+package vo
+
+type CertVo struct {
+	Sources   []string `json:"sources"` // 使用该证书的位置
+	CertPath  string   `json:"certPath"`
+	KeyPath   string   `json:"keyPath"`
+	Subject   string   `json:"subject"`
+	Sans      []string `json:"sans"`
+	Issuer    string   `json:"issuer"`
+	NotBefore int64    `json:"notBefore"`
+	NotAfter  int64    `json:"notAfter"`
+	DaysLeft  int64    `json:"daysLeft"`
+	KeyMatch  bool     `json:"keyMatch"`
+	Names     []string `json:"names"`     // 客户端校验的域名
+	Uncovered []string `json:"uncovered"` // 证书未覆盖的域名
+	Warnings  []string `json:"warnings"`
+	Error     string   `json:"error"` // 证书无法读取时的错误
+}
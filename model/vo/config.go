@@ -18,4 +18,48 @@ type Socks5ConfigVo struct {
 	Addr     string `json:"addr"`
 	Username string `json:"username"`
 	Password string `json:"password,omitempty"` // 密码在返回时可能需要隐藏
-}
\ No newline at end of file
+}
+type PortHoppingRuleVo struct {
+	Node      string `json:"node"`
+	Interface string `json:"interface"`
+	Ports     string `json:"ports"`
+	Target    uint16 `json:"target"`
+}
+
+// PortHoppingBackendVo Missing 为期望但不存在的规则，Extra 为存在但不期望的规则
+type PortHoppingBackendVo struct {
+	Name    string              `json:"name"`
+	Current []PortHoppingRuleVo `json:"current"`
+	Missing []PortHoppingRuleVo `json:"missing"`
+	Extra   []PortHoppingRuleVo `json:"extra"`
+	InSync  bool                `json:"inSync"`
+}
+
+type PortHoppingStatusVo struct {
+	Desired    []PortHoppingRuleVo    `json:"desired"`
+	Backends   []PortHoppingBackendVo `json:"backends"`
+	Interfaces []string               `json:"interfaces"`
+	InSync     bool                   `json:"inSync"`
+}
+
+type FirewallRuleVo struct {
+	Protocol string `json:"protocol"`
+	Ports    string `json:"ports"`
+	Source   string `json:"source"`
+	Action   string `json:"action"`
+}
+
+type FirewallBackendVo struct {
+	Name    string           `json:"name"`
+	Current []FirewallRuleVo `json:"current"`
+	Missing []FirewallRuleVo `json:"missing"`
+	Extra   []FirewallRuleVo `json:"extra"`
+	InSync  bool             `json:"inSync"`
+}
+
+type FirewallStatusVo struct {
+	Enable   bool                `json:"enable"`
+	Desired  []FirewallRuleVo    `json:"desired"`
+	Backends []FirewallBackendVo `json:"backends"`
+	InSync   bool                `json:"inSync"`
+}
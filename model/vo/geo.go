@@ -0,0 +1,19 @@
+package vo
+
+type GeoVo struct {
+	Type             string `json:"type"`
+	Path             string `json:"path"`
+	Exists           bool   `json:"exists"`
+	Size             int64  `json:"size"`
+	Sha256           string `json:"sha256"`
+	UpdateTime       int64  `json:"updateTime"`
+	Categories       int64  `json:"categories"`
+	Backup           bool   `json:"backup"` // 是否有可以回滚的上一个版本
+	BackupSha256     string `json:"backupSha256"`
+	BackupUpdateTime int64  `json:"backupUpdateTime"`
+}
+
+type GeoCategoryVo struct {
+	Name       string   `json:"name"`
+	Attributes []string `json:"attributes"` // geosite 中可以用 @ 引用的属性
+}
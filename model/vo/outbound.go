@@ -0,0 +1,29 @@
+package vo
+
+type OutboundVo struct {
+	BaseVo
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Addr       string   `json:"addr"`
+	Username   string   `json:"username"`
+	BindIPv4   string   `json:"bindIPv4"`
+	BindIPv6   string   `json:"bindIPv6"`
+	BindDevice string   `json:"bindDevice"`
+	Insecure   int64    `json:"insecure"`
+	Enable     int64    `json:"enable"`
+	Remark     string   `json:"remark"`
+	Status     string   `json:"status"`
+	Latency    int64    `json:"latency"`
+	FailCount  int64    `json:"failCount"`
+	CheckAt    int64    `json:"checkAt"`
+	CheckError string   `json:"checkError"`
+	Nodes      []string `json:"nodes"`   // 引用该出站的节点
+	Primary    []string `json:"primary"` // 当前以该出站为默认出站的节点
+}
+
+type OutboundCheckVo struct {
+	Status     string `json:"status"`
+	Latency    int64  `json:"latency"`
+	FailCount  int64  `json:"failCount"`
+	CheckError string `json:"checkError"`
+}
@@ -0,0 +1,25 @@
+package vo
+
+type PlanVo struct {
+	BaseVo
+	Name       string `json:"name"`
+	Quota      int64  `json:"quota"`
+	Duration   int64  `json:"duration"`
+	DeviceNo   int64  `json:"deviceNo"`
+	NodeAccess int64  `json:"nodeAccess"`
+	SpeedTier  string `json:"speedTier"`
+	Price      int64  `json:"price"`
+	Remark     string `json:"remark"`
+}
+
+type AccountPlanHistoryVo struct {
+	BaseVo
+	PlanId           int64  `json:"planId"`
+	PlanName         string `json:"planName"`
+	Action           string `json:"action"`
+	Quota            int64  `json:"quota"`
+	DeviceNo         int64  `json:"deviceNo"`
+	NodeAccess       int64  `json:"nodeAccess"`
+	ExpireTimeBefore int64  `json:"expireTimeBefore"`
+	ExpireTimeAfter  int64  `json:"expireTimeAfter"`
+}
@@ -0,0 +1,15 @@
+package vo
+
+type TelegramChatVo struct {
+	BaseVo
+	ChatId        int64    `json:"chatId"`
+	Name          string   `json:"name"`
+	Subscriptions []string `json:"subscriptions"`
+	Enable        int64    `json:"enable"`
+}
+
+type TelegramBotStatusVo struct {
+	Running  bool   `json:"running"`
+	Mode     string `json:"mode"`
+	Username string `json:"username"`
+}
@@ -0,0 +1,37 @@
+package vo
+
+// UserInfoVo 用户自助页面的账号信息
+type UserInfoVo struct {
+	Id         int64  `json:"id"`
+	Username   string `json:"username"`
+	Quota      int64  `json:"quota"`
+	Download   int64  `json:"download"`
+	Upload     int64  `json:"upload"`
+	ExpireTime int64  `json:"expireTime"`
+	DeviceNo   int64  `json:"deviceNo"`
+	NodeAccess int64  `json:"nodeAccess"`
+	Online     bool   `json:"online"`
+	Device     int64  `json:"device"`
+}
+
+type UserTrafficDailyVo struct {
+	Day      string `json:"day"`
+	Download int64  `json:"download"`
+	Upload   int64  `json:"upload"`
+}
+
+type UserSubscribeVo struct {
+	Url    string               `json:"url"`
+	QrCode []byte               `json:"qrCode"`
+	Nodes  []Hysteria2NodeUrlVo `json:"nodes"`
+}
+
+type UserConPassVo struct {
+	ConPass string `json:"conPass"`
+}
+
+type UserLoginLinkVo struct {
+	Token      string `json:"token"`
+	Link       string `json:"link"` // 未配置 H_UI_PUBLIC_URL 时为空
+	ExpireTime int64  `json:"expireTime"`
+}
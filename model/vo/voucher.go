@@ -0,0 +1,39 @@
+package vo
+
+type VoucherBatchVo struct {
+	BaseVo
+	Name       string `json:"name"`
+	PlanId     int64  `json:"planId"`
+	Quota      int64  `json:"quota"`
+	Duration   int64  `json:"duration"`
+	MaxUses    int64  `json:"maxUses"`
+	ExpireTime int64  `json:"expireTime"`
+	Count      int64  `json:"count"`
+}
+
+type VoucherVo struct {
+	BaseVo
+	Code       string `json:"code"`
+	MaxUses    int64  `json:"maxUses"`
+	Used       int64  `json:"used"`
+	ExpireTime int64  `json:"expireTime"`
+}
+
+type VoucherRedemptionVo struct {
+	BaseVo
+	VoucherId        int64  `json:"voucherId"`
+	BatchId          int64  `json:"batchId"`
+	Code             string `json:"code"`
+	AccountId        int64  `json:"accountId"`
+	Username         string `json:"username"`
+	PlanId           int64  `json:"planId"`
+	Quota            int64  `json:"quota"`
+	Duration         int64  `json:"duration"`
+	ExpireTimeBefore int64  `json:"expireTimeBefore"`
+	ExpireTimeAfter  int64  `json:"expireTimeAfter"`
+}
+
+type VoucherRedemptionPageVo struct {
+	VoucherRedemptionVos []VoucherRedemptionVo `json:"records"`
+	Total                int64                 `json:"total"`
+}
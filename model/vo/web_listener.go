@@ -0,0 +1,14 @@
+package vo
+
+type WebListenerVo struct {
+	BaseVo
+	Name         string   `json:"name"`
+	Network      string   `json:"network"`
+	Address      string   `json:"address"`
+	Tls          int64    `json:"tls"`
+	Routes       []string `json:"routes"`
+	RedirectPort int64    `json:"redirectPort"`
+	Enable       int64    `json:"enable"`
+	Remark       string   `json:"remark"`
+	Listening    bool     `json:"listening"`
+}
@@ -0,0 +1,28 @@
+package vo
+
+import "time"
+
+type WebhookVo struct {
+	BaseVo
+	Name   string   `json:"name"`
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	Enable int64    `json:"enable"`
+}
+
+type WebhookDeliveryVo struct {
+	BaseVo
+	WebhookId    int64     `json:"webhookId"`
+	Event        string    `json:"event"`
+	Payload      string    `json:"payload"`
+	Status       string    `json:"status"`
+	Attempts     int64     `json:"attempts"`
+	ResponseCode int64     `json:"responseCode"`
+	Error        string    `json:"error"`
+	UpdateTime   time.Time `json:"updateTime"`
+}
+
+type WebhookDeliveryPageVo struct {
+	WebhookDeliveryVos []WebhookDeliveryVo `json:"records"`
+	Total              int64               `json:"total"`
+}
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"h-ui/model/constant"
 	"h-ui/util"
@@ -27,6 +28,13 @@ func NewHysteria2Instance() *Hysteria2Process {
 	return hysteria2Instance
 }
 
+// SetCrashHandler 设置进程异常退出时的回调
+func (h *Hysteria2Process) SetCrashHandler(handler func(err error)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.onCrash = handler
+}
+
 func (h *Hysteria2Process) IsRunning() bool {
 	return h.isRunning()
 }
@@ -72,4 +80,39 @@ func init() {
 
 func NewHysteria2Node2Instance() *Hysteria2Process {
 	return hysteria2Node2Instance
-}
\ No newline at end of file
+}
+
+// ACL 规则集实例，按规则集 id 创建
+var mutexHysteria2Acl sync.Mutex
+var hysteria2AclInstances = map[int64]*Hysteria2Process{}
+
+func NewHysteria2AclInstance(id int64) *Hysteria2Process {
+	mutexHysteria2Acl.Lock()
+	defer mutexHysteria2Acl.Unlock()
+	instance, ok := hysteria2AclInstances[id]
+	if !ok {
+		instance = &Hysteria2Process{
+			process{mutex: &sync.Mutex{}, cmd: &exec.Cmd{}},
+			util.GetHysteria2BinPath(),
+			fmt.Sprintf(constant.Hysteria2AclConfigPath, id),
+		}
+		hysteria2AclInstances[id] = instance
+	}
+	return instance
+}
+
+// ListHysteria2AclInstanceIds 已创建过的 ACL 实例
+func ListHysteria2AclInstanceIds() []int64 {
+	mutexHysteria2Acl.Lock()
+	defer mutexHysteria2Acl.Unlock()
+	ids := make([]int64, 0, len(hysteria2AclInstances))
+	for id := range hysteria2AclInstances {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ConfigPath 实例的配置文件路径
+func (h *Hysteria2Process) ConfigPath() string {
+	return h.configPath
+}
@@ -31,8 +31,9 @@ func init() {
 }
 
 type process struct {
-	mutex *sync.Mutex
-	cmd   *exec.Cmd
+	mutex   *sync.Mutex
+	cmd     *exec.Cmd
+	onCrash func(err error) // 进程非主动停止时回调
 }
 
 func (p *process) isRunning() bool {
@@ -75,7 +76,7 @@ func (p *process) start(name string, arg ...string) error {
 
 	p.cmd = cmd
 
-	go p.handleLogs(stdout, stderr)
+	go p.wait(cmd, stdout, stderr)
 
 	return nil
 }
@@ -118,6 +119,24 @@ func (p *process) release() error {
 	return nil
 }
 
+// wait 读取日志直到进程退出，非 stop/release 导致的退出视为崩溃
+func (p *process) wait(cmd *exec.Cmd, stdout, stderr io.ReadCloser) {
+	p.handleLogs(stdout, stderr)
+	err := cmd.Wait()
+
+	p.mutex.Lock()
+	crashed := p.cmd == cmd
+	onCrash := p.onCrash
+	p.mutex.Unlock()
+
+	if crashed {
+		logrus.Errorf("cmd exited unexpectedly: %v", err)
+		if onCrash != nil {
+			onCrash(err)
+		}
+	}
+}
+
 func (p *process) handleLogs(stdout, stderr io.ReadCloser) {
 	// 日志
 	stdoutChan := make(chan string)
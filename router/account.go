@@ -19,5 +19,17 @@ func initAccountAdminRouter(accountApi *gin.RouterGroup) {
 		account.POST("/exportAccount", controller.ExportAccount)
 		account.POST("/releaseKickAccount", controller.ReleaseKickAccount)
 		account.GET("/verifyDefaultPass", controller.VerifyDefaultPass)
+		account.POST("/changePass", controller.ChangePass)
+		account.POST("/generateTelegramBindCode", controller.GenerateTelegramBindCode)
+		account.POST("/unbindTelegram", controller.UnbindTelegram)
+		account.POST("/generateUserLoginLink", controller.GenerateUserLoginLink)
+		account.POST("/saveAccountFromPlan", controller.SaveAccountFromPlan)
+		account.POST("/renewAccount", controller.RenewAccount)
+		account.POST("/changeAccountPlan", controller.ChangeAccountPlan)
+		account.POST("/applyPlan", controller.ApplyPlan)
+		account.GET("/listAccountPlanHistory", controller.ListAccountPlanHistory)
+		account.POST("/bulkAccount", controller.BulkAccount)
+		account.POST("/setAccountTags", controller.SetAccountTags)
+		account.GET("/listTag", controller.ListTag)
 	}
 }
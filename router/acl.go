@@ -0,0 +1,18 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initAclRouter(aclApi *gin.RouterGroup) {
+	acl := aclApi.Group("/acl")
+	{
+		acl.GET("/listAclProfile", controller.ListAclProfile)
+		acl.POST("/saveAclProfile", controller.SaveAclProfile)
+		acl.POST("/updateAclProfile", controller.UpdateAclProfile)
+		acl.POST("/deleteAclProfile", controller.DeleteAclProfile)
+		acl.POST("/validateAclRules", controller.ValidateAclRules)
+		acl.POST("/setAccountAclProfile", controller.SetAccountAclProfile)
+	}
+}
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initAcmeRouter(acmeApi *gin.RouterGroup) {
+	acme := acmeApi.Group("/acme")
+	{
+		acme.GET("/getPanelAcme", controller.GetPanelAcme)
+		acme.POST("/renewPanelAcme", controller.RenewPanelAcme)
+	}
+}
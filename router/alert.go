@@ -0,0 +1,18 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initAlertRouter(alertApi *gin.RouterGroup) {
+	alert := alertApi.Group("/alert")
+	{
+		alert.GET("/listAlertRule", controller.ListAlertRule)
+		alert.POST("/saveAlertRule", controller.SaveAlertRule)
+		alert.POST("/updateAlertRule", controller.UpdateAlertRule)
+		alert.POST("/deleteAlertRule", controller.DeleteAlertRule)
+		alert.POST("/testAlertRule", controller.TestAlertRule)
+		alert.GET("/listAlertState", controller.ListAlertState)
+	}
+}
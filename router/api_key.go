@@ -0,0 +1,15 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initApiKeyRouter(apiKeyApi *gin.RouterGroup) {
+	apiKey := apiKeyApi.Group("/apiKey")
+	{
+		apiKey.GET("/listApiKey", controller.ListApiKey)
+		apiKey.POST("/saveApiKey", controller.SaveApiKey)
+		apiKey.POST("/deleteApiKey", controller.DeleteApiKey)
+	}
+}
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initAuditRouter(auditApi *gin.RouterGroup) {
+	audit := auditApi.Group("/audit")
+	{
+		audit.GET("/pageAudit", controller.PageAudit)
+		audit.POST("/exportAudit", controller.ExportAudit)
+	}
+}
@@ -0,0 +1,13 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initCertRouter(certApi *gin.RouterGroup) {
+	cert := certApi.Group("/cert")
+	{
+		cert.GET("/listCert", controller.ListCert)
+	}
+}
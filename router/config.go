@@ -22,6 +22,8 @@ func initConfigRouter(configApi *gin.RouterGroup) {
 		config.GET("/getNode2Status", controller.GetNode2Status)
 		config.POST("/toggleNode2", controller.ToggleNode2)
 		config.GET("/getAllNodesStatus", controller.GetAllNodesStatus)
+		config.GET("/getPortHoppingStatus", controller.GetPortHoppingStatus)
+		config.GET("/getFirewallStatus", controller.GetFirewallStatus)
 		config.POST("/exportNode2Config", controller.ExportNode2Config)
 		config.POST("/importNode2Config", controller.ImportNode2Config)
 		config.POST("/exportFullConfig", controller.ExportFullConfig)
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initGeoRouter(geoApi *gin.RouterGroup) {
+	geo := geoApi.Group("/geo")
+	{
+		geo.GET("/listGeo", controller.ListGeo)
+		geo.GET("/listGeoCategory", controller.ListGeoCategory)
+		geo.POST("/uploadGeo", controller.UploadGeo)
+		geo.POST("/refreshGeo", controller.RefreshGeo)
+		geo.POST("/rollbackGeo", controller.RollbackGeo)
+	}
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initOutboundRouter(outboundApi *gin.RouterGroup) {
+	outbound := outboundApi.Group("/outbound")
+	{
+		outbound.GET("/listOutbound", controller.ListOutbound)
+		outbound.POST("/saveOutbound", controller.SaveOutbound)
+		outbound.POST("/updateOutbound", controller.UpdateOutbound)
+		outbound.POST("/deleteOutbound", controller.DeleteOutbound)
+		outbound.POST("/checkOutbound", controller.CheckOutbound)
+	}
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initPlanRouter(planApi *gin.RouterGroup) {
+	plan := planApi.Group("/plan")
+	{
+		plan.GET("/listPlan", controller.ListPlan)
+		plan.POST("/savePlan", controller.SavePlan)
+		plan.POST("/updatePlan", controller.UpdatePlan)
+		plan.POST("/deletePlan", controller.DeletePlan)
+	}
+}
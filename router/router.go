@@ -13,6 +13,9 @@ func Router(router *gin.Engine, huiWebContext *string) {
 	if huiWebContext != nil && strings.HasPrefix(*huiWebContext, "/") {
 		relativePath = *huiWebContext
 	}
+	// 每个监听只开放配置的路由组
+	router.Use(middleware.ListenerHandler())
+
 	globalGroup := router.Group(relativePath)
 	{
 		globalGroup.Use(middleware.FilterHandler(), middleware.LogHandler(), middleware.RateLimiterHandler())
@@ -23,12 +26,21 @@ func Router(router *gin.Engine, huiWebContext *string) {
 		{
 			initAuthRouter(authApi)
 			initHysteria2AuthRouter(authApi)
+			initTelegramWebhookRouter(authApi)
+			initUserAuthRouter(authApi)
 		}
 
 		globalGroup.Use(middleware.JWTHandler())
 
+		huiUserApi := globalGroup.Group("/hui")
+		{
+			initUserRouter(huiUserApi)
+		}
+
 		globalGroup.Use(middleware.AdminHandler())
 
+		globalGroup.Use(middleware.AuditHandler())
+
 		huiAdminApi := globalGroup.Group("/hui")
 		{
 			initAccountAdminRouter(huiAdminApi)
@@ -36,6 +48,19 @@ func Router(router *gin.Engine, huiWebContext *string) {
 			initHysteria2Router(huiAdminApi)
 			initLogRouter(huiAdminApi)
 			initMonitorRouter(huiAdminApi)
+			initAuditRouter(huiAdminApi)
+			initApiKeyRouter(huiAdminApi)
+			initWebhookRouter(huiAdminApi)
+			initAlertRouter(huiAdminApi)
+			initTelegramRouter(huiAdminApi)
+			initPlanRouter(huiAdminApi)
+			initVoucherRouter(huiAdminApi)
+			initAclRouter(huiAdminApi)
+			initOutboundRouter(huiAdminApi)
+			initGeoRouter(huiAdminApi)
+			initAcmeRouter(huiAdminApi)
+			initCertRouter(huiAdminApi)
+			initWebListenerRouter(huiAdminApi)
 		}
 	}
 }
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/service"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func initTestSqlite(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir() + "/"
+	if err := os.MkdirAll(dir+constant.SqliteDBDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HUI_DATA", dir)
+	t.Setenv(constant.MasterKeyEnv, "")
+	t.Setenv(constant.MasterKeyFileEnv, "")
+	if err := dao.InitSql(""); err != nil {
+		t.Fatalf("init sqlite err: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dao.CloseSqliteDB()
+	})
+}
+
+func TestApiKeyCannotEscalateAccounts(t *testing.T) {
+	initTestSqlite(t)
+	if err := dao.UpdateAccount([]int64{1}, map[string]interface{}{"must_change_pass": 0}); err != nil {
+		t.Fatal(err)
+	}
+	username, pass, conPass, role := "customer", "", "customer.secret", "user"
+	userId, err := dao.SaveAccount(entity.Account{Username: &username, Pass: &pass, ConPass: &conPass, Role: &role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := service.SaveApiKey(1, "automation", []string{constant.ScopeAccountsWrite}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	Router(engine, nil)
+	post := func(body string) int {
+		request := httptest.NewRequest(http.MethodPost, "/hui/account/updateAccount", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", constant.ApiKeyTokenType+" "+apiKey.Key)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		var result struct {
+			Code int `json:"code"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+			t.Fatalf("unexpected response: %s", recorder.Body.String())
+		}
+		return result.Code
+	}
+
+	cases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"admin target", `{"id":1,"quota":1024}`, constant.CodeForbiddenError},
+		{"admin password", `{"id":1,"pass":"newpass123"}`, constant.CodeForbiddenError},
+		{"user password", `{"id":` + strconv.FormatInt(userId, 10) + `,"pass":"newpass123"}`, constant.CodeForbiddenError},
+		{"user quota", `{"id":` + strconv.FormatInt(userId, 10) + `,"quota":1024}`, constant.CodeSuccess},
+	}
+	for _, c := range cases {
+		if code := post(c.body); code != c.code {
+			t.Errorf("%s: expected code %d, got %d", c.name, c.code, code)
+		}
+	}
+	account, err := dao.GetAccount("id = ?", 1)
+	if err != nil || *account.Quota == 1024 {
+		t.Fatalf("admin account should be untouched: %v", err)
+	}
+}
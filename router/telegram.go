@@ -0,0 +1,21 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initTelegramWebhookRouter(telegramApi *gin.RouterGroup) {
+	telegramApi.POST("/telegram/webhook", controller.TelegramWebhook)
+}
+
+func initTelegramRouter(telegramApi *gin.RouterGroup) {
+	telegram := telegramApi.Group("/telegram")
+	{
+		telegram.GET("/getTelegramBotStatus", controller.GetTelegramBotStatus)
+		telegram.GET("/listTelegramChat", controller.ListTelegramChat)
+		telegram.POST("/saveTelegramChat", controller.SaveTelegramChat)
+		telegram.POST("/updateTelegramChat", controller.UpdateTelegramChat)
+		telegram.POST("/deleteTelegramChat", controller.DeleteTelegramChat)
+	}
+}
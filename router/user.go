@@ -0,0 +1,28 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+	"h-ui/middleware"
+)
+
+func initUserAuthRouter(userApi *gin.RouterGroup) {
+	user := userApi.Group("/user")
+	{
+		user.POST("/login", controller.UserLogin)
+		user.POST("/loginByToken", controller.UserLoginByToken)
+	}
+}
+
+func initUserRouter(userApi *gin.RouterGroup) {
+	user := userApi.Group("/user", middleware.UserHandler())
+	{
+		user.GET("/getUserInfo", controller.GetUserInfo)
+		user.GET("/listTrafficDaily", controller.ListUserTrafficDaily)
+		user.GET("/getSubscribe", controller.GetUserSubscribe)
+		user.POST("/rotateConPass", controller.RotateUserConPass)
+		user.POST("/kick", controller.KickUser)
+		user.POST("/redeemVoucher", controller.RedeemUserVoucher)
+		user.POST("/changePass", controller.ChangePass)
+	}
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initVoucherRouter(voucherApi *gin.RouterGroup) {
+	voucher := voucherApi.Group("/voucher")
+	{
+		voucher.POST("/generateVoucherBatch", controller.GenerateVoucherBatch)
+		voucher.GET("/listVoucherBatch", controller.ListVoucherBatch)
+		voucher.POST("/deleteVoucherBatch", controller.DeleteVoucherBatch)
+		voucher.GET("/listVoucher", controller.ListVoucher)
+		voucher.POST("/exportVoucherBatch", controller.ExportVoucherBatch)
+		voucher.POST("/redeemVoucher", controller.RedeemVoucher)
+		voucher.GET("/pageVoucherRedemption", controller.PageVoucherRedemption)
+	}
+}
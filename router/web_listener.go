@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initWebListenerRouter(webListenerApi *gin.RouterGroup) {
+	webListener := webListenerApi.Group("/webListener")
+	{
+		webListener.GET("/listWebListener", controller.ListWebListener)
+		webListener.POST("/saveWebListener", controller.SaveWebListener)
+		webListener.POST("/updateWebListener", controller.UpdateWebListener)
+		webListener.POST("/deleteWebListener", controller.DeleteWebListener)
+	}
+}
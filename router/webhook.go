@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"h-ui/controller"
+)
+
+func initWebhookRouter(webhookApi *gin.RouterGroup) {
+	webhook := webhookApi.Group("/webhook")
+	{
+		webhook.GET("/listWebhook", controller.ListWebhook)
+		webhook.POST("/saveWebhook", controller.SaveWebhook)
+		webhook.POST("/updateWebhook", controller.UpdateWebhook)
+		webhook.POST("/deleteWebhook", controller.DeleteWebhook)
+		webhook.POST("/testWebhook", controller.TestWebhook)
+		webhook.POST("/redeliverWebhook", controller.RedeliverWebhook)
+		webhook.GET("/pageWebhookDelivery", controller.PageWebhookDelivery)
+	}
+}
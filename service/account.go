@@ -13,10 +13,13 @@ import (
 )
 
 func Login(username string, pass string) (string, error) {
-	account, err := dao.GetAccount("username = ? and pass = ? and role = 'admin' and deleted = 0", username, pass)
+	account, err := dao.GetAccount("username = ? and role = 'admin' and deleted = 0", username)
 	if err != nil {
 		return "", err
 	}
+	if !verifyAccountPass(account, pass) {
+		return "", errors.New(constant.WrongPassword)
+	}
 	accountBo := bo.AccountBo{
 		Id:       *account.Id,
 		Username: *account.Username,
@@ -27,16 +30,32 @@ func Login(username string, pass string) (string, error) {
 }
 
 func PageAccount(accountPageDto dto.AccountPageDto) ([]entity.Account, int64, error) {
-	return dao.PageAccount(accountPageDto)
+	onlineUsernames, err := accountOnlineUsernames(accountPageDto.AccountQueryDto)
+	if err != nil {
+		return nil, 0, err
+	}
+	return dao.PageAccount(accountPageDto, onlineUsernames)
 }
 
 func SaveAccount(account entity.Account) error {
-	_, err := dao.SaveAccount(account)
-	return err
+	id, err := dao.SaveAccount(account)
+	if err != nil {
+		return err
+	}
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountCreated, []int64{id})
+	return nil
 }
 
 func DeleteAccount(ids []int64) error {
-	return dao.DeleteAccount(ids)
+	accounts, err := dao.ListAccount("id in ?", ids)
+	if err != nil {
+		return err
+	}
+	if err = dao.DeleteAccountCascade(ids); err != nil {
+		return err
+	}
+	EmitAccountWebhookEvent(constant.WebhookEventAccountDeleted, accounts)
+	return nil
 }
 
 func UpdateAccount(account entity.Account) error {
@@ -45,7 +64,15 @@ func UpdateAccount(account entity.Account) error {
 		updates["username"] = *account.Username
 	}
 	if account.Pass != nil && *account.Pass != "" {
+		oldAccount, err := dao.GetAccount("id = ?", *account.Id)
+		if err != nil {
+			return err
+		}
+		if err = recordAccountPass(oldAccount); err != nil {
+			return err
+		}
 		updates["pass"] = *account.Pass
+		updates["must_change_pass"] = 0
 	}
 	if account.ConPass != nil && *account.ConPass != "" {
 		updates["con_pass"] = fmt.Sprintf("%s.%s", *account.Username, *account.ConPass)
@@ -77,11 +104,26 @@ func UpdateAccount(account entity.Account) error {
 	if account.ConAt != nil && *account.ConAt > 0 {
 		updates["con_at"] = *account.ConAt
 	}
-	return dao.UpdateAccount([]int64{*account.Id}, updates)
+	if err := dao.UpdateAccount([]int64{*account.Id}, updates); err != nil {
+		return err
+	}
+	// 仅更新登录、连接时间时不推送
+	if len(updates) > 0 && account.LoginAt == nil && account.ConAt == nil {
+		event := constant.WebhookEventAccountUpdated
+		if account.Deleted != nil && *account.Deleted == 1 {
+			event = constant.WebhookEventAccountDisabled
+		}
+		emitAccountWebhookEventByIds(event, []int64{*account.Id})
+	}
+	return nil
 }
 
 func ResetTraffic(id int64) error {
-	return dao.UpdateAccount([]int64{id}, map[string]interface{}{"download": 0, "upload": 0})
+	if err := dao.UpdateAccount([]int64{id}, map[string]interface{}{"download": 0, "upload": 0}); err != nil {
+		return err
+	}
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountTrafficReset, []int64{id})
+	return nil
 }
 
 func ExistAccountUsername(username string, id int64) bool {
@@ -134,34 +176,53 @@ func ListExportAccount() ([]bo.AccountExport, error) {
 	return accountExports, nil
 }
 
-func ReleaseKickAccount(id int64) error {
-	return dao.UpdateAccount([]int64{id}, map[string]interface{}{"kick_util_time": 0})
+func toAccountVo(account entity.Account) vo.AccountVo {
+	return vo.AccountVo{
+		BaseVo: vo.BaseVo{
+			Id:         *account.Id,
+			CreateTime: *account.CreateTime,
+		},
+		Username:     *account.Username,
+		Quota:        *account.Quota,
+		Download:     *account.Download,
+		Upload:       *account.Upload,
+		ExpireTime:   *account.ExpireTime,
+		KickUtilTime: *account.KickUtilTime,
+		DeviceNo:     *account.DeviceNo,
+		Role:         *account.Role,
+		NodeAccess:   *account.NodeAccess,
+		Deleted:      *account.Deleted,
+		LoginAt:      *account.LoginAt,
+		ConAt:        *account.ConAt,
+		AclProfileId: *account.AclProfileId,
+	}
 }
 
-func UpsertAccount(accounts []entity.Account) error {
-	return dao.UpsertAccount(accounts)
+func ReleaseKickAccount(id int64) error {
+	return dao.UpdateAccount([]int64{id}, map[string]interface{}{"kick_util_time": 0})
 }
 
 func GetAccountInfo(c *gin.Context) (vo.AccountInfoVo, error) {
-	myClaims, err := ParseToken(GetToken(c))
+	accountBo, err := GetAccountBo(c)
 	if err != nil {
 		return vo.AccountInfoVo{}, err
 	}
-	if myClaims.AccountBo.Deleted != 0 {
+	if accountBo.Deleted != 0 {
 		return vo.AccountInfoVo{}, errors.New("this account has been disabled")
 	}
 	return vo.AccountInfoVo{
-		Id:       myClaims.AccountBo.Id,
-		Username: myClaims.AccountBo.Username,
-		Roles:    myClaims.AccountBo.Roles,
+		Id:       accountBo.Id,
+		Username: accountBo.Username,
+		Roles:    accountBo.Roles,
 	}, nil
 }
+
 // ValidateNodeAccess 验证用户节点权限
 func ValidateNodeAccess(nodeAccess int64) error {
 	if nodeAccess != 1 && nodeAccess != 2 {
 		return errors.New("invalid node access value, must be 1 or 2")
 	}
-	
+
 	// 如果用户要求双节点权限，检查第二节点是否启用
 	if nodeAccess == 2 {
 		enabled, err := IsNode2Enabled()
@@ -172,7 +233,7 @@ func ValidateNodeAccess(nodeAccess int64) error {
 			return errors.New("node2 is not enabled, cannot set dual node access")
 		}
 	}
-	
+
 	return nil
 }
 
@@ -182,11 +243,11 @@ func GetUserNodeAccess(accountId int64) (int64, error) {
 	if err != nil {
 		return 1, err
 	}
-	
+
 	if account.NodeAccess == nil {
 		return 1, nil // 默认单节点
 	}
-	
+
 	return *account.NodeAccess, nil
 }
 
@@ -197,7 +258,7 @@ func SetDefaultNodeAccess() error {
 	if err != nil {
 		return err
 	}
-	
+
 	for _, account := range accounts {
 		if account.NodeAccess == nil {
 			defaultAccess := int64(1)
@@ -207,10 +268,11 @@ func SetDefaultNodeAccess() error {
 			}
 		}
 	}
-	
+
 	return nil
-}// 
-DowngradeUsersToSingleNode 将所有双节点用户降级为单节点
+}
+
+// DowngradeUsersToSingleNode 将所有双节点用户降级为单节点
 func DowngradeUsersToSingleNode() error {
 	// 查找所有双节点权限的用户
 	accounts, err := dao.ListAccount("node_access = ?", []interface{}{2})
@@ -230,4 +292,4 @@ func DowngradeUsersToSingleNode() error {
 
 	updates := map[string]interface{}{"node_access": 1}
 	return dao.UpdateAccount(userIds, updates)
-}
\ No newline at end of file
+}
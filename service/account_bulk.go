@@ -0,0 +1,219 @@
+package service
+
+import (
+	"errors"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"sort"
+	"strings"
+	"time"
+)
+
+// accountOnlineUsernames 仅在按在线状态筛选时查询 hysteria2
+func accountOnlineUsernames(accountQueryDto dto.AccountQueryDto) ([]string, error) {
+	if accountQueryDto.Online == nil {
+		return nil, nil
+	}
+	onlineUsers, err := Hysteria2Online()
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, 0, len(onlineUsers))
+	for username := range onlineUsers {
+		usernames = append(usernames, username)
+	}
+	return usernames, nil
+}
+
+// bulkAccounts 批量操作的目标账号，ids 优先于筛选条件
+func bulkAccounts(accountBulkDto dto.AccountBulkDto) ([]entity.Account, error) {
+	ids := accountBulkDto.Ids
+	if len(ids) == 0 {
+		if accountBulkDto.Filter == nil {
+			return nil, errors.New("either ids or filter is required")
+		}
+		onlineUsernames, err := accountOnlineUsernames(*accountBulkDto.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if ids, err = dao.ListAccountId(*accountBulkDto.Filter, onlineUsernames); err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return dao.ListAccount("id in ?", ids)
+}
+
+func accountIds(accounts []entity.Account) []int64 {
+	ids := make([]int64, 0, len(accounts))
+	for _, item := range accounts {
+		ids = append(ids, *item.Id)
+	}
+	return ids
+}
+
+// withoutAdmin 管理员账号不能被批量禁用或删除
+func withoutAdmin(accounts []entity.Account) []entity.Account {
+	var users []entity.Account
+	for _, item := range accounts {
+		if *item.Role != "admin" {
+			users = append(users, item)
+		}
+	}
+	return users
+}
+
+// BulkAccount 对 id 列表或筛选结果执行批量操作，返回受影响的账号数
+func BulkAccount(accountBulkDto dto.AccountBulkDto) (vo.AccountBulkVo, error) {
+	accounts, err := bulkAccounts(accountBulkDto)
+	if err != nil {
+		return vo.AccountBulkVo{}, err
+	}
+	action := *accountBulkDto.Action
+	if action == "disable" || action == "delete" {
+		accounts = withoutAdmin(accounts)
+	}
+	if len(accounts) == 0 {
+		return vo.AccountBulkVo{}, nil
+	}
+	ids := accountIds(accounts)
+	event := constant.WebhookEventAccountUpdated
+
+	switch action {
+	case "update":
+		updates := map[string]interface{}{}
+		if accountBulkDto.Quota != nil {
+			updates["quota"] = *accountBulkDto.Quota
+		}
+		if accountBulkDto.ExpireTime != nil {
+			updates["expire_time"] = *accountBulkDto.ExpireTime
+		}
+		if accountBulkDto.DeviceNo != nil {
+			updates["device_no"] = *accountBulkDto.DeviceNo
+		}
+		if accountBulkDto.NodeAccess != nil {
+			if err = ValidateNodeAccess(*accountBulkDto.NodeAccess); err != nil {
+				return vo.AccountBulkVo{}, err
+			}
+			updates["node_access"] = *accountBulkDto.NodeAccess
+		}
+		if len(updates) == 0 {
+			return vo.AccountBulkVo{}, errors.New("nothing to update")
+		}
+		err = dao.UpdateAccount(ids, updates)
+	case "extend":
+		if accountBulkDto.Days == nil {
+			return vo.AccountBulkVo{}, errors.New("days is required")
+		}
+		err = dao.ExtendAccountExpireTime(ids, time.Now().UnixMilli(), *accountBulkDto.Days*planDayMillis)
+	case "addQuota":
+		if accountBulkDto.AddQuota == nil {
+			return vo.AccountBulkVo{}, errors.New("addQuota is required")
+		}
+		err = dao.AddAccountQuota(ids, *accountBulkDto.AddQuota)
+	case "resetTraffic":
+		event = constant.WebhookEventAccountTrafficReset
+		err = dao.UpdateAccount(ids, map[string]interface{}{"download": 0, "upload": 0})
+	case "kick":
+		kickUtilTime := time.Now().UnixMilli()
+		if accountBulkDto.KickUtilTime != nil {
+			kickUtilTime = *accountBulkDto.KickUtilTime
+		}
+		// Hysteria2Kick 会推送 account.kicked 事件
+		if err = Hysteria2Kick(ids, kickUtilTime); err != nil {
+			return vo.AccountBulkVo{}, err
+		}
+		return vo.AccountBulkVo{Affected: int64(len(ids))}, nil
+	case "disable":
+		event = constant.WebhookEventAccountDisabled
+		err = dao.UpdateAccount(ids, map[string]interface{}{"deleted": 1})
+	case "enable":
+		err = dao.UpdateAccount(ids, map[string]interface{}{"deleted": 0})
+	case "delete":
+		if err = dao.DeleteAccountCascade(ids); err != nil {
+			return vo.AccountBulkVo{}, err
+		}
+		EmitAccountWebhookEvent(constant.WebhookEventAccountDeleted, accounts)
+		return vo.AccountBulkVo{Affected: int64(len(ids))}, nil
+	case "addTag", "removeTag":
+		if accountBulkDto.Tag == nil || strings.TrimSpace(*accountBulkDto.Tag) == "" {
+			return vo.AccountBulkVo{}, errors.New("tag is required")
+		}
+		tag := strings.TrimSpace(*accountBulkDto.Tag)
+		if action == "addTag" {
+			err = dao.AddAccountTag(ids, tag)
+		} else {
+			err = dao.DeleteAccountTag("account_id in ? and tag = ?", ids, tag)
+		}
+		if err != nil {
+			return vo.AccountBulkVo{}, err
+		}
+		return vo.AccountBulkVo{Affected: int64(len(ids))}, nil
+	default:
+		return vo.AccountBulkVo{}, errors.New("unsupported action")
+	}
+	if err != nil {
+		return vo.AccountBulkVo{}, err
+	}
+	emitAccountWebhookEventByIds(event, ids)
+	return vo.AccountBulkVo{Affected: int64(len(ids))}, nil
+}
+
+// normalizeTags 去掉首尾空格、空标签和重复标签
+func normalizeTags(tags []string) []string {
+	tagSet := make(map[string]struct{}, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, item := range tags {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if _, exist := tagSet[item]; exist {
+			continue
+		}
+		tagSet[item] = struct{}{}
+		result = append(result, item)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func SetAccountTags(accountId int64, tags []string) error {
+	if _, err := dao.GetAccount("id = ?", accountId); err != nil {
+		return errors.New("account not found")
+	}
+	return dao.SetAccountTags(accountId, normalizeTags(tags))
+}
+
+// AccountTagsMap 账号 id 与标签的对应关系
+func AccountTagsMap(accountIds []int64) (map[int64][]string, error) {
+	tagsMap := make(map[int64][]string, len(accountIds))
+	if len(accountIds) == 0 {
+		return tagsMap, nil
+	}
+	accountTags, err := dao.ListAccountTag("account_id in ?", accountIds)
+	if err != nil {
+		return tagsMap, err
+	}
+	for _, item := range accountTags {
+		tagsMap[*item.AccountId] = append(tagsMap[*item.AccountId], *item.Tag)
+	}
+	return tagsMap, nil
+}
+
+func ListTag() ([]vo.AccountTagVo, error) {
+	tagCounts, err := dao.ListTagCount()
+	if err != nil {
+		return nil, err
+	}
+	tagVos := make([]vo.AccountTagVo, 0, len(tagCounts))
+	for _, item := range tagCounts {
+		tagVos = append(tagVos, vo.AccountTagVo{Tag: item.Tag, Count: item.Count})
+	}
+	return tagVos, nil
+}
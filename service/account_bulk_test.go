@@ -0,0 +1,142 @@
+package service
+
+import (
+	"h-ui/dao"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{" vip ", "trial", "", "vip", "  "})
+	if expect := []string{"trial", "vip"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %v, got: %v", expect, got)
+	}
+}
+
+func TestWithoutAdmin(t *testing.T) {
+	admin, user := "admin", "user"
+	id1, id2 := int64(1), int64(2)
+	accounts := withoutAdmin([]entity.Account{
+		{Role: &admin, BaseEntity: entity.BaseEntity{Id: &id1}},
+		{Role: &user, BaseEntity: entity.BaseEntity{Id: &id2}},
+	})
+	if len(accounts) != 1 || *accounts[0].Id != 2 {
+		t.Errorf("admin accounts should be excluded: %+v", accounts)
+	}
+}
+
+func saveTestBulkAccount(t *testing.T, username string, quota int64, download int64, expireTime int64, conAt int64) {
+	t.Helper()
+	conPass, role, upload, deleted := username+"."+username, "user", int64(0), int64(0)
+	if _, err := dao.SaveAccount(entity.Account{
+		Username:   &username,
+		ConPass:    &conPass,
+		Quota:      &quota,
+		Download:   &download,
+		Upload:     &upload,
+		ExpireTime: &expireTime,
+		ConAt:      &conAt,
+		Role:       &role,
+		Deleted:    &deleted,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listTestBulkUsernames(t *testing.T, accountQueryDto dto.AccountQueryDto, onlineUsernames []string) []string {
+	t.Helper()
+	ids, err := dao.ListAccountId(accountQueryDto, onlineUsernames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usernames := []string{}
+	if len(ids) == 0 {
+		return usernames
+	}
+	accounts, err := dao.ListAccount("id in ?", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range accounts {
+		usernames = append(usernames, *item.Username)
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+func TestAccountQueryFilters(t *testing.T) {
+	initTestSqlite(t)
+	now := time.Now().UnixMilli()
+	day := int64(24 * time.Hour / time.Millisecond)
+	// 初始化时自带的 sysadmin 不限流量、未过期、从未连接
+	saveTestBulkAccount(t, "expired", -1, 0, now-day, now-day/24)
+	saveTestBulkAccount(t, "overquota", 100, 100, now+day, now-40*day)
+	saveTestBulkAccount(t, "fresh", 100, 10, now+day, 0)
+	saveTestBulkAccount(t, "active", -1, 500, now+day, now-day)
+
+	one, zero, inactiveDays := int64(1), int64(0), int64(30)
+	tests := []struct {
+		name   string
+		query  dto.AccountQueryDto
+		online []string
+		expect []string
+	}{
+		{"expired", dto.AccountQueryDto{Expired: &one}, nil, []string{"expired"}},
+		{"not expired", dto.AccountQueryDto{Expired: &zero}, nil, []string{"active", "fresh", "overquota", "sysadmin"}},
+		{"over quota", dto.AccountQueryDto{OverQuota: &one}, nil, []string{"overquota"}},
+		{"within quota", dto.AccountQueryDto{OverQuota: &zero}, nil, []string{"active", "expired", "fresh", "sysadmin"}},
+		{"online", dto.AccountQueryDto{Online: &one}, []string{"active"}, []string{"active"}},
+		{"online with nobody online", dto.AccountQueryDto{Online: &one}, nil, []string{}},
+		{"offline", dto.AccountQueryDto{Online: &zero}, []string{"active"}, []string{"expired", "fresh", "overquota", "sysadmin"}},
+		{"offline with nobody online", dto.AccountQueryDto{Online: &zero}, nil, []string{"active", "expired", "fresh", "overquota", "sysadmin"}},
+		{"never connected", dto.AccountQueryDto{NeverConnected: &one}, nil, []string{"fresh", "sysadmin"}},
+		{"connected", dto.AccountQueryDto{NeverConnected: &zero}, nil, []string{"active", "expired", "overquota"}},
+		{"inactive days", dto.AccountQueryDto{InactiveDays: &inactiveDays}, nil, []string{"overquota"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listTestBulkUsernames(t, tt.query, tt.online); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected %v, got: %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestBulkAccountSkipsAdmin(t *testing.T) {
+	initTestSqlite(t)
+	now := time.Now().UnixMilli()
+	saveTestBulkAccount(t, "bulkuser", -1, 0, now+int64(time.Hour/time.Millisecond), 0)
+	user, err := dao.GetAccount("username = ?", "bulkuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zero, disable, remove := int64(0), "disable", "delete"
+	// 按筛选结果禁用，再按包含管理员的 id 列表删除
+	for _, accountBulkDto := range []dto.AccountBulkDto{
+		{Filter: &dto.AccountQueryDto{Deleted: &zero}, Action: &disable},
+		{Ids: []int64{1, *user.Id}, Action: &remove},
+	} {
+		result, err := BulkAccount(accountBulkDto)
+		if err != nil {
+			t.Fatalf("%s err: %v", *accountBulkDto.Action, err)
+		}
+		if result.Affected != 1 {
+			t.Errorf("%s should only affect the user account, affected: %d", *accountBulkDto.Action, result.Affected)
+		}
+		admin, err := dao.GetAccount("id = ?", 1)
+		if err != nil {
+			t.Fatalf("admin should not be deleted by %s: %v", *accountBulkDto.Action, err)
+		}
+		if *admin.Deleted != 0 || *admin.Role != "admin" {
+			t.Errorf("admin should not be disabled by %s", *accountBulkDto.Action)
+		}
+	}
+	if _, err = dao.GetAccount("username = ?", "bulkuser"); err == nil {
+		t.Error("user account should be deleted")
+	}
+}
@@ -0,0 +1,620 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountImportFields 可导入的字段，顺序即默认的表头顺序
+var accountImportFields = []string{"username", "pass", "conPass", "quota", "download", "upload",
+	"expireTime", "deviceNo", "nodeAccess", "deleted", "tags"}
+
+// accountImportColumns 导入字段对应的数据库列
+var accountImportColumns = map[string]string{
+	"pass":       "pass",
+	"conPass":    "con_pass",
+	"quota":      "quota",
+	"download":   "download",
+	"upload":     "upload",
+	"expireTime": "expire_time",
+	"deviceNo":   "device_no",
+	"nodeAccess": "node_access",
+	"deleted":    "deleted",
+}
+
+// accountImportDefaults 新建账号或 overwrite 时空单元格使用的默认值
+var accountImportDefaults = map[string]int64{
+	"download":   0,
+	"upload":     0,
+	"deviceNo":   3,
+	"nodeAccess": 1,
+	"deleted":    0,
+}
+
+// accountExportColumns 可导出的列
+var accountExportColumns = []string{"id", "username", "conPass", "quota", "download", "upload",
+	"expireTime", "deviceNo", "nodeAccess", "role", "deleted", "tags", "planId", "loginAt", "conAt", "createTime"}
+
+var accountImportTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02",
+	"2006/01/02 15:04:05", "2006/01/02", time.RFC3339}
+
+// accountImportRow 文件中的一行，values 只包含已映射的字段
+type accountImportRow struct {
+	row    int64
+	values map[string]string
+}
+
+// parseAccountImportRows 按表头和映射解析文件内容，mapping 为导入字段 -> 文件表头，未指定时按字段名匹配表头（忽略大小写）
+func parseAccountImportRows(rows [][]string, mapping map[string]string) ([]accountImportRow, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+	headerIndex := make(map[string]int, len(rows[0]))
+	for i, item := range rows[0] {
+		key := strings.ToLower(strings.TrimSpace(item))
+		if _, exist := headerIndex[key]; !exist && key != "" {
+			headerIndex[key] = i
+		}
+	}
+
+	fieldIndex := make(map[string]int)
+	if len(mapping) == 0 {
+		for _, field := range accountImportFields {
+			if i, exist := headerIndex[strings.ToLower(field)]; exist {
+				fieldIndex[field] = i
+			}
+		}
+	} else {
+		for field, header := range mapping {
+			if !util.ArrContain(accountImportFields, field) {
+				return nil, fmt.Errorf("unknown import field %s", field)
+			}
+			i, exist := headerIndex[strings.ToLower(strings.TrimSpace(header))]
+			if !exist {
+				return nil, fmt.Errorf("column %s not found in the file", header)
+			}
+			fieldIndex[field] = i
+		}
+	}
+	if _, exist := fieldIndex["username"]; !exist {
+		return nil, errors.New("the username column is required")
+	}
+
+	var importRows []accountImportRow
+	for i, row := range rows[1:] {
+		values := make(map[string]string, len(fieldIndex))
+		blank := true
+		for field, index := range fieldIndex {
+			var value string
+			if index < len(row) {
+				value = strings.TrimSpace(row[index])
+			}
+			if value != "" {
+				blank = false
+			}
+			values[field] = value
+		}
+		if blank {
+			continue
+		}
+		importRows = append(importRows, accountImportRow{row: int64(i + 2), values: values})
+	}
+	return importRows, nil
+}
+
+// parseAccountImportTime 毫秒时间戳或本地时间的日期字符串
+func parseAccountImportTime(value string) (int64, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return millis, nil
+	}
+	for _, layout := range accountImportTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.UnixMilli(), nil
+		}
+	}
+	return 0, errors.New("invalid time")
+}
+
+func splitAccountImportTags(value string) []string {
+	return normalizeTags(strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	}))
+}
+
+// parseAccountImportValues 校验非空单元格并转换为对应的类型
+func parseAccountImportValues(values map[string]string, nodeAccessErr map[int64]error) (map[string]interface{}, []string) {
+	parsed := make(map[string]interface{}, len(values))
+	var errs []string
+	for _, field := range accountImportFields {
+		value := values[field]
+		if value == "" {
+			continue
+		}
+		switch field {
+		case "username", "pass", "conPass":
+			if !util.ValidateStr(value) {
+				errs = append(errs, fmt.Sprintf("%s must be 6-32 letters, digits or !@#$%%^&*()_+-=", field))
+				continue
+			}
+			parsed[field] = value
+		case "expireTime":
+			expireTime, err := parseAccountImportTime(value)
+			if err != nil || expireTime < 0 {
+				errs = append(errs, fmt.Sprintf("invalid expireTime %s", value))
+				continue
+			}
+			parsed[field] = expireTime
+		case "tags":
+			tags := splitAccountImportTags(value)
+			if len(tags) > 20 {
+				errs = append(errs, "at most 20 tags")
+				continue
+			}
+			for _, tag := range tags {
+				if len(tag) > 32 {
+					errs = append(errs, fmt.Sprintf("tag %s is too long", tag))
+				}
+			}
+			parsed[field] = tags
+		default:
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be an integer", field))
+				continue
+			}
+			switch {
+			case field == "quota" && number < -1,
+				(field == "download" || field == "upload") && number < 0,
+				field == "deviceNo" && number < 1,
+				field == "deleted" && number != 0 && number != 1:
+				errs = append(errs, fmt.Sprintf("invalid %s %d", field, number))
+				continue
+			case field == "nodeAccess":
+				if _, exist := nodeAccessErr[number]; !exist {
+					nodeAccessErr[number] = ValidateNodeAccess(number)
+				}
+				if nodeAccessErr[number] != nil {
+					errs = append(errs, nodeAccessErr[number].Error())
+					continue
+				}
+			}
+			parsed[field] = number
+		}
+	}
+	return parsed, errs
+}
+
+// accountImportUpdates 已存在账号的更新内容。overwrite：已映射的列全部写入，空单元格恢复默认值；merge：只写入非空单元格，标签合并
+// 两种策略都不会清空密码，也不会修改 create_time
+func accountImportUpdates(account entity.Account, accountTags []string, values map[string]string,
+	parsed map[string]interface{}, conflict string) (bo.AccountImport, []string, []string) {
+	accountImport := bo.AccountImport{Id: account.Id, Updates: map[string]interface{}{}}
+	var fields, errs []string
+	for _, field := range accountImportFields {
+		if _, mapped := values[field]; !mapped || field == "username" {
+			continue
+		}
+		value, exist := parsed[field]
+		if !exist && conflict == constant.AccountImportConflictMerge {
+			continue
+		}
+		switch field {
+		case "pass":
+			if !exist {
+				continue
+			}
+			// 明文密码在写入前统一哈希，见 hashAccountImportPass
+			accountImport.Updates["pass"] = value.(string)
+		case "conPass":
+			if !exist {
+				continue
+			}
+			accountImport.Updates["con_pass"] = fmt.Sprintf("%s.%s", *account.Username, value.(string))
+		case "tags":
+			var tags []string
+			if exist {
+				tags = value.([]string)
+			}
+			if conflict == constant.AccountImportConflictMerge {
+				tags = normalizeTags(append(tags, accountTags...))
+			}
+			accountImport.SetTags = true
+			accountImport.Tags = tags
+		default:
+			if !exist {
+				defaultValue, hasDefault := accountImportDefaults[field]
+				if !hasDefault {
+					errs = append(errs, fmt.Sprintf("%s is required", field))
+					continue
+				}
+				value = defaultValue
+			}
+			accountImport.Updates[accountImportColumns[field]] = value
+		}
+		fields = append(fields, field)
+	}
+	return accountImport, fields, errs
+}
+
+// accountImportCreate 新建账号，pass、quota、expireTime 必填，conPass 为空时随机生成
+func accountImportCreate(username string, parsed map[string]interface{}) (bo.AccountImport, []string, []string) {
+	var fields, errs []string
+	for _, field := range []string{"pass", "quota", "expireTime"} {
+		if _, exist := parsed[field]; !exist {
+			errs = append(errs, fmt.Sprintf("%s is required", field))
+		}
+	}
+	if len(errs) > 0 {
+		return bo.AccountImport{}, nil, errs
+	}
+
+	numbers := make(map[string]int64, len(accountImportDefaults)+2)
+	for field, value := range accountImportDefaults {
+		numbers[field] = value
+	}
+	for field, value := range parsed {
+		if number, ok := value.(int64); ok {
+			numbers[field] = number
+		}
+	}
+	conPass, exist := parsed["conPass"].(string)
+	if !exist {
+		var err error
+		if conPass, err = util.RandomString(16); err != nil {
+			return bo.AccountImport{}, nil, []string{constant.SysError}
+		}
+	}
+	pass := parsed["pass"].(string)
+	conPass = fmt.Sprintf("%s.%s", username, conPass)
+	role := "user"
+	quota, download, upload := numbers["quota"], numbers["download"], numbers["upload"]
+	expireTime, deviceNo := numbers["expireTime"], numbers["deviceNo"]
+	nodeAccess, deleted := numbers["nodeAccess"], numbers["deleted"]
+	accountImport := bo.AccountImport{
+		Account: entity.Account{
+			Username:   &username,
+			Pass:       &pass,
+			ConPass:    &conPass,
+			Quota:      &quota,
+			Download:   &download,
+			Upload:     &upload,
+			ExpireTime: &expireTime,
+			DeviceNo:   &deviceNo,
+			Role:       &role,
+			NodeAccess: &nodeAccess,
+			Deleted:    &deleted,
+		},
+	}
+	if tags, exist := parsed["tags"].([]string); exist && len(tags) > 0 {
+		accountImport.SetTags = true
+		accountImport.Tags = tags
+	}
+	for _, field := range accountImportFields {
+		if _, exist := parsed[field]; exist {
+			fields = append(fields, field)
+		}
+	}
+	return accountImport, fields, nil
+}
+
+// listAccountByUsernames 分批查询，避免超出 sqlite 参数个数限制
+func listAccountByUsernames(usernames []string) ([]entity.Account, error) {
+	var accounts []entity.Account
+	if len(usernames) == 0 {
+		return accounts, nil
+	}
+	for _, item := range util.SplitArr(usernames, 500) {
+		items, err := dao.ListAccount("username in ?", item)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, items...)
+	}
+	return accounts, nil
+}
+
+// ImportAccountTable 导入 csv/xlsx 的内容。任意一行有错误时不写入，dryRun 时只返回每行的校验结果和将执行的操作
+func ImportAccountTable(rows [][]string, accountImportDto dto.AccountImportDto) (vo.AccountImportVo, error) {
+	var mapping map[string]string
+	if accountImportDto.Mapping != nil && *accountImportDto.Mapping != "" {
+		if err := json.Unmarshal([]byte(*accountImportDto.Mapping), &mapping); err != nil {
+			return vo.AccountImportVo{}, errors.New("invalid mapping")
+		}
+	}
+
+	importRows, err := parseAccountImportRows(rows, mapping)
+	if err != nil {
+		return vo.AccountImportVo{}, err
+	}
+	return importAccountRows(importRows, nil, accountImportDto)
+}
+
+// ImportAccountJson 导入 json 备份，与 csv/xlsx 使用相同的冲突策略和校验。
+// 备份中的密码哈希只用于新建账号，已存在账号的密码、角色和 create_time 不会被修改，管理员账号跳过
+func ImportAccountJson(accounts []entity.Account, accountImportDto dto.AccountImportDto) (vo.AccountImportVo, error) {
+	importRows := make([]accountImportRow, 0, len(accounts))
+	passHashes := make(map[string]string, len(accounts))
+	var adminRows []vo.AccountImportRowVo
+	for i, item := range accounts {
+		if item.Role != nil && *item.Role == "admin" {
+			rowVo := vo.AccountImportRowVo{Row: int64(i + 1), Action: constant.AccountImportActionSkip}
+			if item.Username != nil {
+				rowVo.Username = *item.Username
+			}
+			adminRows = append(adminRows, rowVo)
+			continue
+		}
+		values := make(map[string]string, len(accountImportFields))
+		setString := func(field string, value *string) {
+			if value != nil {
+				values[field] = strings.TrimSpace(*value)
+			}
+		}
+		setInt := func(field string, value *int64) {
+			if value != nil {
+				values[field] = strconv.FormatInt(*value, 10)
+			}
+		}
+		setString("username", item.Username)
+		if item.ConPass != nil && item.Username != nil {
+			// 备份中的连接密码带有用户名前缀
+			conPass := strings.TrimPrefix(*item.ConPass, *item.Username+".")
+			setString("conPass", &conPass)
+		}
+		setInt("quota", item.Quota)
+		setInt("download", item.Download)
+		setInt("upload", item.Upload)
+		setInt("expireTime", item.ExpireTime)
+		setInt("deviceNo", item.DeviceNo)
+		setInt("nodeAccess", item.NodeAccess)
+		setInt("deleted", item.Deleted)
+		if item.Username != nil && item.Pass != nil && util.IsPasswordHash(*item.Pass) {
+			passHashes[*item.Username] = *item.Pass
+		}
+		importRows = append(importRows, accountImportRow{row: int64(i + 1), values: values})
+	}
+	accountImportVo, err := importAccountRows(importRows, passHashes, accountImportDto)
+	if err != nil || len(adminRows) == 0 {
+		return accountImportVo, err
+	}
+	accountImportVo.Total += int64(len(adminRows))
+	accountImportVo.Skipped += int64(len(adminRows))
+	accountImportVo.Rows = append(accountImportVo.Rows, adminRows...)
+	sort.Slice(accountImportVo.Rows, func(i, j int) bool {
+		return accountImportVo.Rows[i].Row < accountImportVo.Rows[j].Row
+	})
+	return accountImportVo, nil
+}
+
+// importAccountRows passHashes 为新建账号时直接使用的密码哈希，username -> hash
+func importAccountRows(importRows []accountImportRow, passHashes map[string]string, accountImportDto dto.AccountImportDto) (vo.AccountImportVo, error) {
+	conflict := constant.AccountImportConflictSkip
+	if accountImportDto.Conflict != nil {
+		conflict = *accountImportDto.Conflict
+	}
+	dryRun := accountImportDto.DryRun != nil && *accountImportDto.DryRun == 1
+
+	var usernames []string
+	for _, item := range importRows {
+		if username := item.values["username"]; username != "" {
+			usernames = append(usernames, username)
+		}
+	}
+	accounts, err := listAccountByUsernames(usernames)
+	if err != nil {
+		return vo.AccountImportVo{}, err
+	}
+	accountMap := make(map[string]entity.Account, len(accounts))
+	for _, item := range accounts {
+		accountMap[*item.Username] = item
+	}
+	tagsMap, err := AccountTagsMap(accountIds(accounts))
+	if err != nil {
+		return vo.AccountImportVo{}, err
+	}
+
+	accountImportVo := vo.AccountImportVo{DryRun: dryRun, Total: int64(len(importRows)), Rows: []vo.AccountImportRowVo{}}
+	var accountImports []bo.AccountImport
+	rowNos := make(map[string]int64, len(importRows))
+	nodeAccessErr := make(map[int64]error)
+	passMinLength, _ := passPolicy()
+	for _, item := range importRows {
+		username := item.values["username"]
+		rowVo := vo.AccountImportRowVo{Row: item.row, Username: username}
+		parsed, errs := parseAccountImportValues(item.values, nodeAccessErr)
+		if pass, exist := parsed["pass"].(string); exist {
+			if err = checkPassPolicy(username, pass, passMinLength, nil); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if username == "" {
+			errs = append(errs, "username is required")
+		} else if rowNo, exist := rowNos[username]; exist {
+			errs = append(errs, fmt.Sprintf("duplicate username, first seen in row %d", rowNo))
+		} else {
+			rowNos[username] = item.row
+		}
+
+		var accountImport bo.AccountImport
+		var fields, planErrs []string
+		account, exist := accountMap[username]
+		if len(errs) == 0 {
+			switch {
+			case !exist:
+				rowVo.Action = constant.AccountImportActionCreate
+				hash, hashed := passHashes[username]
+				if hashed {
+					parsed["pass"] = hash
+				}
+				accountImport, fields, planErrs = accountImportCreate(username, parsed)
+				accountImport.PassHashed = hashed
+			case *account.Role == "admin":
+				planErrs = []string{"the admin account cannot be imported"}
+			case conflict == constant.AccountImportConflictSkip:
+				rowVo.Action = constant.AccountImportActionSkip
+			default:
+				rowVo.Action = constant.AccountImportActionUpdate
+				accountImport, fields, planErrs = accountImportUpdates(account, tagsMap[*account.Id], item.values, parsed, conflict)
+			}
+			errs = append(errs, planErrs...)
+		}
+
+		if len(errs) > 0 {
+			rowVo.Action = constant.AccountImportActionError
+			rowVo.Errors = errs
+			accountImportVo.Failed++
+		} else {
+			rowVo.Fields = fields
+			switch rowVo.Action {
+			case constant.AccountImportActionCreate:
+				accountImportVo.Created++
+				accountImports = append(accountImports, accountImport)
+			case constant.AccountImportActionUpdate:
+				accountImportVo.Updated++
+				accountImports = append(accountImports, accountImport)
+			default:
+				accountImportVo.Skipped++
+			}
+		}
+		accountImportVo.Rows = append(accountImportVo.Rows, rowVo)
+	}
+
+	if dryRun || accountImportVo.Failed > 0 || len(accountImports) == 0 {
+		return accountImportVo, nil
+	}
+	if err = hashAccountImportPass(accountImports); err != nil {
+		return vo.AccountImportVo{}, err
+	}
+	createdIds, updatedIds, err := dao.ImportAccount(accountImports)
+	if err != nil {
+		return vo.AccountImportVo{}, err
+	}
+	accountImportVo.Applied = true
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountCreated, createdIds)
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountUpdated, updatedIds)
+	return accountImportVo, nil
+}
+
+// hashAccountImportPass 只在真正写入时计算哈希，dryRun 不必等待
+func hashAccountImportPass(accountImports []bo.AccountImport) error {
+	for i := range accountImports {
+		if accountImports[i].Id == nil {
+			if accountImports[i].PassHashed {
+				continue
+			}
+			hash, err := hashPass(*accountImports[i].Account.Pass)
+			if err != nil {
+				return err
+			}
+			accountImports[i].Account.Pass = &hash
+		} else if pass, exist := accountImports[i].Updates["pass"].(string); exist {
+			hash, err := hashPass(pass)
+			if err != nil {
+				return err
+			}
+			accountImports[i].Updates["pass"] = hash
+			accountImports[i].Updates["must_change_pass"] = 0
+		}
+	}
+	return nil
+}
+
+// ListAccountTable 按筛选条件和列生成导出的表格，不包含密码
+func ListAccountTable(accountExportDto dto.AccountExportDto) ([]string, [][]string, error) {
+	columns := accountExportDto.Columns
+	if len(columns) == 0 {
+		columns = accountExportColumns
+	}
+	for _, item := range columns {
+		if !util.ArrContain(accountExportColumns, item) {
+			return nil, nil, fmt.Errorf("unknown export column %s", item)
+		}
+	}
+
+	var accounts []entity.Account
+	if accountExportDto.Filter == nil {
+		var err error
+		if accounts, err = dao.ListAccount(nil, nil); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		onlineUsernames, err := accountOnlineUsernames(*accountExportDto.Filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids, err := dao.ListAccountId(*accountExportDto.Filter, onlineUsernames)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(ids) > 0 {
+			if accounts, err = dao.ListAccount("id in ?", ids); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	tagsMap, err := AccountTagsMap(accountIds(accounts))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([][]string, 0, len(accounts))
+	for _, item := range accounts {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, accountTableCell(item, tagsMap[*item.Id], column))
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+func accountTableCell(account entity.Account, tags []string, column string) string {
+	switch column {
+	case "id":
+		return strconv.FormatInt(*account.Id, 10)
+	case "username":
+		return *account.Username
+	case "conPass":
+		// 与导入格式一致，去掉 username. 前缀
+		return strings.TrimPrefix(*account.ConPass, *account.Username+".")
+	case "quota":
+		return strconv.FormatInt(*account.Quota, 10)
+	case "download":
+		return strconv.FormatInt(*account.Download, 10)
+	case "upload":
+		return strconv.FormatInt(*account.Upload, 10)
+	case "expireTime":
+		return strconv.FormatInt(*account.ExpireTime, 10)
+	case "deviceNo":
+		return strconv.FormatInt(*account.DeviceNo, 10)
+	case "nodeAccess":
+		return strconv.FormatInt(*account.NodeAccess, 10)
+	case "role":
+		return *account.Role
+	case "deleted":
+		return strconv.FormatInt(*account.Deleted, 10)
+	case "tags":
+		return strings.Join(tags, ",")
+	case "planId":
+		return strconv.FormatInt(*account.PlanId, 10)
+	case "loginAt":
+		return strconv.FormatInt(*account.LoginAt, 10)
+	case "conAt":
+		return strconv.FormatInt(*account.ConAt, 10)
+	case "createTime":
+		return account.CreateTime.Format("2006-01-02 15:04:05")
+	}
+	return ""
+}
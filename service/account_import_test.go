@@ -0,0 +1,176 @@
+package service
+
+import (
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/util"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAccountImportRows(t *testing.T) {
+	rows := [][]string{
+		{"User", "Password", "Quota"},
+		{"alice01", "secret01", "1024"},
+		{"", "", ""},
+		{"bob0001"},
+	}
+	importRows, err := parseAccountImportRows(rows, map[string]string{"username": "user", "pass": "Password"})
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(importRows) != 2 {
+		t.Fatalf("blank rows should be ignored, got: %+v", importRows)
+	}
+	if importRows[1].row != 4 || !reflect.DeepEqual(importRows[1].values, map[string]string{"username": "bob0001", "pass": ""}) {
+		t.Errorf("unexpected row: %+v", importRows[1])
+	}
+
+	// 未指定映射时按字段名匹配表头
+	importRows, err = parseAccountImportRows([][]string{{"USERNAME", "quota"}, {"alice01", "1"}}, nil)
+	if err != nil || !reflect.DeepEqual(importRows[0].values, map[string]string{"username": "alice01", "quota": "1"}) {
+		t.Errorf("unexpected default mapping: %+v %v", importRows, err)
+	}
+
+	for _, mapping := range []map[string]string{{"pass": "Password"}, {"username": "missing"}, {"role": "User"}} {
+		if _, err = parseAccountImportRows(rows, mapping); err == nil {
+			t.Errorf("mapping %v should fail", mapping)
+		}
+	}
+}
+
+func TestParseAccountImportTime(t *testing.T) {
+	expect := time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local).UnixMilli()
+	for _, value := range []string{"2030-01-02", "2030/01/02", "2030-01-02 00:00:00"} {
+		if got, err := parseAccountImportTime(value); err != nil || got != expect {
+			t.Errorf("%s: expected %d, got: %d %v", value, expect, got, err)
+		}
+	}
+	if got, _ := parseAccountImportTime("1700000000000"); got != 1700000000000 {
+		t.Errorf("millis not parsed: %d", got)
+	}
+	if _, err := parseAccountImportTime("tomorrow"); err == nil {
+		t.Error("invalid time should fail")
+	}
+}
+
+func TestParseAccountImportValues(t *testing.T) {
+	parsed, errs := parseAccountImportValues(map[string]string{
+		"username": "alice01", "quota": "-1", "deviceNo": "2", "tags": "vip, trial;vip", "pass": "",
+	}, map[int64]error{})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errs: %v", errs)
+	}
+	expect := map[string]interface{}{"username": "alice01", "quota": int64(-1), "deviceNo": int64(2), "tags": []string{"trial", "vip"}}
+	if !reflect.DeepEqual(parsed, expect) {
+		t.Errorf("expected %v, got: %v", expect, parsed)
+	}
+
+	_, errs = parseAccountImportValues(map[string]string{
+		"username": "bad", "quota": "-2", "deleted": "2", "upload": "x", "expireTime": "soon",
+	}, map[int64]error{})
+	if len(errs) != 5 {
+		t.Errorf("expected 5 errs, got: %v", errs)
+	}
+}
+
+func TestAccountImportUpdates(t *testing.T) {
+	id, username := int64(1), "alice01"
+	account := entity.Account{Username: &username, BaseEntity: entity.BaseEntity{Id: &id}}
+	values := map[string]string{"username": username, "pass": "", "deviceNo": "", "quota": "10", "tags": "new"}
+	parsed := map[string]interface{}{"username": username, "quota": int64(10), "tags": []string{"new"}}
+
+	// overwrite：空单元格恢复默认值，空密码保持不变
+	accountImport, _, errs := accountImportUpdates(account, []string{"old"}, values, parsed, constant.AccountImportConflictOverwrite)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errs: %v", errs)
+	}
+	if expect := map[string]interface{}{"quota": int64(10), "device_no": int64(3)}; !reflect.DeepEqual(accountImport.Updates, expect) {
+		t.Errorf("expected %v, got: %v", expect, accountImport.Updates)
+	}
+	if !accountImport.SetTags || !reflect.DeepEqual(accountImport.Tags, []string{"new"}) {
+		t.Errorf("tags should be replaced: %v", accountImport.Tags)
+	}
+
+	// merge：只写入非空单元格，标签合并
+	accountImport, _, _ = accountImportUpdates(account, []string{"old"}, values, parsed, constant.AccountImportConflictMerge)
+	if expect := map[string]interface{}{"quota": int64(10)}; !reflect.DeepEqual(accountImport.Updates, expect) {
+		t.Errorf("expected %v, got: %v", expect, accountImport.Updates)
+	}
+	if !reflect.DeepEqual(accountImport.Tags, []string{"new", "old"}) {
+		t.Errorf("tags should be merged: %v", accountImport.Tags)
+	}
+
+	// overwrite 时必填列不能为空
+	values["expireTime"] = ""
+	if _, _, errs = accountImportUpdates(account, nil, values, parsed, constant.AccountImportConflictOverwrite); len(errs) != 1 {
+		t.Errorf("expected expireTime required, got: %v", errs)
+	}
+}
+
+func TestImportAccountJson(t *testing.T) {
+	initTestSqlite(t)
+	hash, err := util.HashPassword("Alice-Pass-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, role, conPass := "alice01", "user", "alice01.alicecon"
+	quota, expireTime := int64(10), int64(4102444800000)
+	id, err := dao.SaveAccount(entity.Account{Username: &username, Pass: &hash, ConPass: &conPass, Quota: &quota, ExpireTime: &expireTime, Role: &role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := dao.GetAccount("id = ?", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherHash, err := util.HashPassword("Other-Pass-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, newUser, newConPass, sysadmin := "admin", "bob00001", "bob00001.bobconpass", "sysadmin"
+	promoted := "carol001"
+	newQuota, backupTime := int64(-1), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	backupQuota := int64(20)
+	backup := []entity.Account{
+		{Username: &username, Pass: &otherHash, ConPass: &conPass, Quota: &backupQuota, ExpireTime: &expireTime, Role: &role,
+			BaseEntity: entity.BaseEntity{CreateTime: &backupTime}},
+		{Username: &newUser, Pass: &otherHash, ConPass: &newConPass, Quota: &newQuota, ExpireTime: &expireTime, Role: &role},
+		{Username: &sysadmin, Pass: &otherHash, Role: &admin},
+		{Username: &promoted, Pass: &otherHash, ConPass: &conPass, Quota: &quota, ExpireTime: &expireTime, Role: &admin},
+	}
+
+	overwrite := constant.AccountImportConflictOverwrite
+	importVo, err := ImportAccountJson(backup, dto.AccountImportDto{Conflict: &overwrite})
+	if err != nil || !importVo.Applied || importVo.Updated != 1 || importVo.Created != 1 || importVo.Skipped != 2 {
+		t.Fatalf("unexpected import result: %+v %v", importVo, err)
+	}
+	after, err := dao.GetAccount("id = ?", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *after.Pass != *before.Pass || *after.Role != "user" || !after.CreateTime.Equal(*before.CreateTime) {
+		t.Fatalf("import must not change pass, role or create_time: %s %v", *after.Role, *after.CreateTime)
+	}
+	if *after.Quota != backupQuota {
+		t.Fatalf("expected quota %d, got %d", backupQuota, *after.Quota)
+	}
+	created, err := dao.GetAccount("username = ?", newUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := util.VerifyPassword("Other-Pass-01", *created.Pass); !ok || *created.ConPass != newConPass || *created.Role != "user" {
+		t.Fatalf("unexpected created account: %s %s", *created.ConPass, *created.Role)
+	}
+	adminAccount, err := dao.GetAccount("id = 1")
+	if err != nil || *adminAccount.Pass == otherHash {
+		t.Fatalf("admin account must be skipped: %v", err)
+	}
+	if _, err = dao.GetAccount("username = ?", promoted); err == nil {
+		t.Fatal("admin accounts in the backup must not be created")
+	}
+}
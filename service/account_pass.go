@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/util"
+	"strconv"
+)
+
+const (
+	defaultPassMinLength = 8
+	defaultPassHistory   = 3
+)
+
+// passPolicy 密码最小长度和不能重复使用的历史密码个数
+func passPolicy() (int64, int64) {
+	minLength, history := int64(defaultPassMinLength), int64(defaultPassHistory)
+	configs, err := dao.ListConfig("key in ?", []string{constant.PasswordMinLength, constant.PasswordHistory})
+	if err != nil {
+		return minLength, history
+	}
+	for _, item := range configs {
+		value, err := strconv.ParseInt(*item.Value, 10, 64)
+		if err != nil || value < 0 {
+			continue
+		}
+		if *item.Key == constant.PasswordMinLength {
+			minLength = value
+		} else if *item.Key == constant.PasswordHistory {
+			history = value
+		}
+	}
+	return minLength, history
+}
+
+// checkPassPolicy usedHashes 为当前密码和最近使用过的密码的哈希
+func checkPassPolicy(username string, pass string, minLength int64, usedHashes []string) error {
+	if int64(len(pass)) < minLength {
+		return fmt.Errorf("the password must be at least %d characters", minLength)
+	}
+	if pass == username {
+		return errors.New("the password cannot be the same as the username")
+	}
+	for _, item := range usedHashes {
+		if ok, _ := util.VerifyPassword(pass, item); ok {
+			return errors.New("the password has been used recently")
+		}
+	}
+	return nil
+}
+
+// ValidateAccountPass 校验密码策略，accountId 为 0 时为新建账号，不检查历史密码
+func ValidateAccountPass(accountId int64, username string, pass string) error {
+	minLength, history := passPolicy()
+	var usedHashes []string
+	if accountId != 0 && history > 0 {
+		account, err := dao.GetAccount("id = ?", accountId)
+		if err != nil {
+			return errors.New("account not found")
+		}
+		usedHashes = append(usedHashes, *account.Pass)
+		histories, err := dao.ListAccountPassHistory(accountId, history)
+		if err != nil {
+			return err
+		}
+		for _, item := range histories {
+			usedHashes = append(usedHashes, *item.Pass)
+		}
+	}
+	return checkPassPolicy(username, pass, minLength, usedHashes)
+}
+
+func hashPass(pass string) (string, error) {
+	hash, err := util.HashPassword(pass)
+	if err != nil {
+		logrus.Errorf("hash password err: %v", err)
+		return "", errors.New(constant.SysError)
+	}
+	return hash, nil
+}
+
+// verifyAccountPass 校验成功后，旧格式或参数已变化的哈希自动升级
+func verifyAccountPass(account entity.Account, pass string) bool {
+	ok, rehash := util.VerifyPassword(pass, *account.Pass)
+	if ok && rehash {
+		if hash, err := hashPass(pass); err == nil {
+			_ = dao.UpdateAccount([]int64{*account.Id}, map[string]interface{}{"pass": hash})
+		}
+	}
+	return ok
+}
+
+// recordAccountPass 修改密码前记录旧密码，用于检查重复使用
+func recordAccountPass(account entity.Account) error {
+	if _, history := passPolicy(); history > 0 && *account.Pass != "" {
+		return dao.SaveAccountPassHistory(*account.Id, *account.Pass, history)
+	}
+	return nil
+}
+
+// ChangePass 修改自己的密码，同时解除默认密码的限制
+func ChangePass(accountId int64, oldPass string, newPass string) error {
+	account, err := dao.GetAccount("id = ? and deleted = 0", accountId)
+	if err != nil {
+		return errors.New("account not found")
+	}
+	if ok, _ := util.VerifyPassword(oldPass, *account.Pass); !ok {
+		return errors.New("the old password is wrong")
+	}
+	if err = ValidateAccountPass(accountId, *account.Username, newPass); err != nil {
+		return err
+	}
+	hash, err := hashPass(newPass)
+	if err != nil {
+		return err
+	}
+	if err = recordAccountPass(account); err != nil {
+		return err
+	}
+	return dao.UpdateAccount([]int64{accountId}, map[string]interface{}{"pass": hash, "must_change_pass": 0})
+}
+
+// AccountMustChangePass 仍在使用默认密码的账号只能修改密码
+func AccountMustChangePass(accountId int64) bool {
+	account, err := dao.GetAccount("id = ?", accountId)
+	return err == nil && *account.MustChangePass == 1
+}
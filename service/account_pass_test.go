@@ -0,0 +1,27 @@
+package service
+
+import (
+	"h-ui/util"
+	"testing"
+)
+
+func TestCheckPassPolicy(t *testing.T) {
+	used, _ := util.HashPassword("oldpass01")
+	legacy := util.SHA224String("oldpass02")
+	cases := []struct {
+		pass string
+		ok   bool
+	}{
+		{"short", false},
+		{"alice001", false},
+		{"oldpass01", false},
+		{"oldpass02", false},
+		{"newpass01", true},
+	}
+	for _, item := range cases {
+		err := checkPassPolicy("alice001", item.pass, 8, []string{used, legacy})
+		if (err == nil) != item.ok {
+			t.Errorf("%s: expected ok %v, got: %v", item.pass, item.ok, err)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// aclBandwidthRegex hysteria2 的带宽格式，如 100 mbps、1gbps
+var aclBandwidthRegex = regexp.MustCompile(`(?i)^[0-9]+ ?(b|bps|k|kb|kbps|m|mb|mbps|g|gb|gbps|t|tb|tbps)?$`)
+
+// ValidateAclBandwidth 空表示不限速
+func ValidateAclBandwidth(bandwidth string) error {
+	if bandwidth != "" && !aclBandwidthRegex.MatchString(bandwidth) {
+		return fmt.Errorf("bandwidth %s is invalid", bandwidth)
+	}
+	return nil
+}
+
+func existAclProfileName(name string, id int64) bool {
+	aclProfile, err := dao.GetAclProfile("name = ?", name)
+	return err == nil && *aclProfile.Id != id
+}
+
+// ParseAclTags 逗号分隔的账号标签，去掉空白和重复
+func ParseAclTags(value string) ([]string, error) {
+	var tags []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if len(item) > 32 {
+			return nil, fmt.Errorf("tag %s is too long", item)
+		}
+		if !util.ArrContain(tags, item) {
+			tags = append(tags, item)
+		}
+	}
+	return tags, nil
+}
+
+// checkAclProfile 检查规则语法和端口占用，端口不能与节点、面板和其他规则集重复
+func checkAclProfile(id int64, port int64, rules string, outbounds string) error {
+	result := ValidateAclRules(rules, outbounds)
+	if !result.Valid {
+		item := result.Errors[0]
+		if item.Line > 0 {
+			return fmt.Errorf("acl rules line %d: %s", item.Line, item.Message)
+		}
+		return fmt.Errorf("acl rules: %s", item.Message)
+	}
+	for _, node := range portHoppingNodes {
+		nodePort, err := nodeListenPort(node.name)
+		if err != nil {
+			return err
+		}
+		if int64(nodePort) == port {
+			return fmt.Errorf("port %d is used by %s", port, node.name)
+		}
+	}
+	webPort, err := dao.GetConfig("key = ?", constant.HUIWebPort)
+	if err != nil {
+		return err
+	}
+	if *webPort.Value == strconv.FormatInt(port, 10) {
+		return fmt.Errorf("port %d is used by h-ui", port)
+	}
+	aclProfile, err := dao.GetAclProfile("port = ? and id != ?", port, id)
+	if err == nil {
+		return fmt.Errorf("port %d is used by acl profile %s", port, *aclProfile.Name)
+	}
+	return nil
+}
+
+func SaveAclProfile(aclProfileSaveDto dto.AclProfileSaveDto) error {
+	if existAclProfileName(*aclProfileSaveDto.Name, 0) {
+		return fmt.Errorf("acl profile %s already exists", *aclProfileSaveDto.Name)
+	}
+	outbounds := ""
+	if aclProfileSaveDto.Outbounds != nil {
+		outbounds = *aclProfileSaveDto.Outbounds
+	}
+	if err := checkAclProfile(0, *aclProfileSaveDto.Port, *aclProfileSaveDto.Rules, outbounds); err != nil {
+		return err
+	}
+	var bandwidthUp, bandwidthDown string
+	if aclProfileSaveDto.BandwidthUp != nil {
+		bandwidthUp = *aclProfileSaveDto.BandwidthUp
+	}
+	if aclProfileSaveDto.BandwidthDown != nil {
+		bandwidthDown = *aclProfileSaveDto.BandwidthDown
+	}
+	if err := ValidateAclBandwidth(bandwidthUp); err != nil {
+		return err
+	}
+	if err := ValidateAclBandwidth(bandwidthDown); err != nil {
+		return err
+	}
+	var tags string
+	if aclProfileSaveDto.Tags != nil {
+		tagArr, err := ParseAclTags(*aclProfileSaveDto.Tags)
+		if err != nil {
+			return err
+		}
+		tags = strings.Join(tagArr, ",")
+	}
+	id, err := dao.SaveAclProfile(entity.AclProfile{
+		Name:          aclProfileSaveDto.Name,
+		Port:          aclProfileSaveDto.Port,
+		Rules:         aclProfileSaveDto.Rules,
+		Outbounds:     &outbounds,
+		Tags:          &tags,
+		BandwidthUp:   &bandwidthUp,
+		BandwidthDown: &bandwidthDown,
+		Enable:        aclProfileSaveDto.Enable,
+		Remark:        aclProfileSaveDto.Remark,
+	})
+	if err != nil {
+		return err
+	}
+	return applyAclProfileChange(id, tags)
+}
+
+func UpdateAclProfile(aclProfileUpdateDto dto.AclProfileUpdateDto) error {
+	aclProfile, err := dao.GetAclProfile("id = ?", *aclProfileUpdateDto.Id)
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{}
+	if aclProfileUpdateDto.Name != nil {
+		if existAclProfileName(*aclProfileUpdateDto.Name, *aclProfile.Id) {
+			return fmt.Errorf("acl profile %s already exists", *aclProfileUpdateDto.Name)
+		}
+		updates["name"] = *aclProfileUpdateDto.Name
+	}
+	port, rules, outbounds := *aclProfile.Port, *aclProfile.Rules, *aclProfile.Outbounds
+	if aclProfileUpdateDto.Port != nil {
+		port = *aclProfileUpdateDto.Port
+		updates["port"] = port
+	}
+	if aclProfileUpdateDto.Rules != nil {
+		rules = *aclProfileUpdateDto.Rules
+		updates["rules"] = rules
+	}
+	if aclProfileUpdateDto.Outbounds != nil {
+		outbounds = *aclProfileUpdateDto.Outbounds
+		updates["outbounds"] = outbounds
+	}
+	if err = checkAclProfile(*aclProfile.Id, port, rules, outbounds); err != nil {
+		return err
+	}
+	if aclProfileUpdateDto.Tags != nil {
+		tags, err := ParseAclTags(*aclProfileUpdateDto.Tags)
+		if err != nil {
+			return err
+		}
+		updates["tags"] = strings.Join(tags, ",")
+	}
+	if aclProfileUpdateDto.BandwidthUp != nil {
+		if err = ValidateAclBandwidth(*aclProfileUpdateDto.BandwidthUp); err != nil {
+			return err
+		}
+		updates["bandwidth_up"] = *aclProfileUpdateDto.BandwidthUp
+	}
+	if aclProfileUpdateDto.BandwidthDown != nil {
+		if err = ValidateAclBandwidth(*aclProfileUpdateDto.BandwidthDown); err != nil {
+			return err
+		}
+		updates["bandwidth_down"] = *aclProfileUpdateDto.BandwidthDown
+	}
+	if aclProfileUpdateDto.Enable != nil {
+		updates["enable"] = *aclProfileUpdateDto.Enable
+	}
+	if aclProfileUpdateDto.Remark != nil {
+		updates["remark"] = *aclProfileUpdateDto.Remark
+	}
+	if err = dao.UpdateAclProfile([]int64{*aclProfile.Id}, updates); err != nil {
+		return err
+	}
+	// 套餐的限速档位按名称引用规则集，改名时一起修改
+	if name, ok := updates["name"].(string); ok && name != *aclProfile.Name {
+		if err = updatePlanSpeedTier(*aclProfile.Name, name); err != nil {
+			return err
+		}
+	}
+	newTags, _ := updates["tags"].(string)
+	return applyAclProfileChange(*aclProfile.Id, *aclProfile.Tags, newTags)
+}
+
+// DeleteAclProfile 单独指定了该规则集的账号恢复为按标签匹配
+func DeleteAclProfile(id int64) error {
+	aclProfile, err := dao.GetAclProfile("id = ?", id)
+	if err != nil {
+		return err
+	}
+	plans, err := dao.ListPlan("speed_tier = ?", *aclProfile.Name)
+	if err != nil {
+		return err
+	}
+	if len(plans) > 0 {
+		return fmt.Errorf("the acl profile is used by %d plans as speed tier", len(plans))
+	}
+	accounts, err := dao.ListAccount("acl_profile_id = ?", id)
+	if err != nil {
+		return err
+	}
+	if err = dao.DeleteAclProfile([]int64{id}); err != nil {
+		return err
+	}
+	kickAclAccounts(accounts)
+	return applyAclProfileChange(id, *aclProfile.Tags)
+}
+
+func ListAclProfile() ([]vo.AclProfileVo, error) {
+	aclProfiles, err := dao.ListAclProfile(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	aclProfileVos := make([]vo.AclProfileVo, 0, len(aclProfiles))
+	for _, item := range aclProfiles {
+		accounts, err := dao.ListAccount("acl_profile_id = ?", *item.Id)
+		if err != nil {
+			return nil, err
+		}
+		aclProfileVos = append(aclProfileVos, vo.AclProfileVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Name:          *item.Name,
+			Port:          *item.Port,
+			Rules:         *item.Rules,
+			Outbounds:     *item.Outbounds,
+			Tags:          *item.Tags,
+			BandwidthUp:   *item.BandwidthUp,
+			BandwidthDown: *item.BandwidthDown,
+			Enable:        *item.Enable,
+			Remark:        *item.Remark,
+			Running:       aclInstanceRunning(*item.Id),
+			Accounts:      int64(len(accounts)),
+		})
+	}
+	return aclProfileVos, nil
+}
+
+func updatePlanSpeedTier(oldName string, newName string) error {
+	plans, err := dao.ListPlan("speed_tier = ?", oldName)
+	if err != nil || len(plans) == 0 {
+		return err
+	}
+	var ids []int64
+	for _, item := range plans {
+		ids = append(ids, *item.Id)
+	}
+	return dao.UpdatePlan(ids, map[string]interface{}{"speed_tier": newName})
+}
+
+// SetAccountAclProfile 单独为账号指定规则集，已在线的连接断开后按新的规则集重新认证
+func SetAccountAclProfile(ids []int64, aclProfileId int64) error {
+	if aclProfileId > 0 {
+		if _, err := dao.GetAclProfile("id = ?", aclProfileId); err != nil {
+			return err
+		}
+	}
+	if err := dao.UpdateAccount(ids, map[string]interface{}{"acl_profile_id": aclProfileId}); err != nil {
+		return err
+	}
+	accounts, err := dao.ListAccount("id in ?", ids)
+	if err != nil {
+		return err
+	}
+	kickAclAccounts(accounts)
+	EmitAccountWebhookEvent(constant.WebhookEventAccountUpdated, accounts)
+	return nil
+}
+
+// applyAclProfileChange 重新生成实例，单独指定或标签匹配该规则集的账号可能换到其他规则集，需要重新认证
+func applyAclProfileChange(id int64, tags ...string) error {
+	if err := SyncAclInstances(); err != nil {
+		return err
+	}
+	if err := InitFirewall(); err != nil {
+		return err
+	}
+	var tagArr []string
+	for _, item := range tags {
+		itemTags, _ := ParseAclTags(item)
+		tagArr = append(tagArr, itemTags...)
+	}
+	accounts, err := dao.ListAccount("acl_profile_id = ? or id in (select account_id from account_tag where tag in ?)", id, tagArr)
+	if err != nil {
+		return err
+	}
+	kickAclAccounts(accounts)
+	return nil
+}
+
+// resolveAclProfileId 优先使用账号单独指定的规则集，其次是第一个匹配账号标签的规则集，都没有时返回 0
+func resolveAclProfileId(aclProfileId int64, accountTags []string, aclProfiles []entity.AclProfile) int64 {
+	for _, item := range aclProfiles {
+		if aclProfileId > 0 && *item.Id == aclProfileId {
+			return aclProfileId
+		}
+	}
+	for _, item := range aclProfiles {
+		tags, _ := ParseAclTags(*item.Tags)
+		for _, tag := range tags {
+			if util.ArrContain(accountTags, tag) {
+				return *item.Id
+			}
+		}
+	}
+	return 0
+}
+
+// GetAccountAclProfile 账号生效的规则集，未启用的规则集不生效
+func GetAccountAclProfile(account entity.Account) (entity.AclProfile, bool, error) {
+	aclProfiles, err := dao.ListAclProfile("enable = 1")
+	if err != nil || len(aclProfiles) == 0 {
+		return entity.AclProfile{}, false, err
+	}
+	accountTags, err := dao.ListAccountTag("account_id = ?", *account.Id)
+	if err != nil {
+		return entity.AclProfile{}, false, err
+	}
+	var tags []string
+	for _, item := range accountTags {
+		tags = append(tags, *item.Tag)
+	}
+	var aclProfileId int64
+	if account.AclProfileId != nil {
+		aclProfileId = *account.AclProfileId
+	}
+	id := resolveAclProfileId(aclProfileId, tags, aclProfiles)
+	for _, item := range aclProfiles {
+		if *item.Id == id {
+			return item, true, nil
+		}
+	}
+	return entity.AclProfile{}, false, nil
+}
+
+// checkAccountAclProfile 账号只能通过其规则集对应的实例认证，没有规则集的账号只能使用节点
+func checkAccountAclProfile(account entity.Account, aclProfileId int64) error {
+	aclProfile, ok, err := GetAccountAclProfile(account)
+	if err != nil {
+		return err
+	}
+	if ok && *aclProfile.Id != aclProfileId || !ok && aclProfileId != 0 {
+		logrus.Warnf("account %s is not allowed on acl profile %d", *account.Username, aclProfileId)
+		return errors.New("acl profile not allowed")
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/proxy"
+	"h-ui/util"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	aclLock sync.Mutex
+	// aclApiPorts 运行中实例的流量统计端口，每次启动时重新分配
+	aclApiPorts = map[int64]int64{}
+	// aclConfigs 运行中实例的配置，配置不变时不重启
+	aclConfigs = map[int64]string{}
+)
+
+// SyncAclInstances 每个启用的规则集运行一个 hysteria2 实例，主节点未运行时全部停止
+func SyncAclInstances() error {
+	aclLock.Lock()
+	defer aclLock.Unlock()
+	if !Hysteria2IsRunning() {
+		stopAclInstances()
+		return nil
+	}
+	aclProfiles, err := dao.ListAclProfile("enable = 1")
+	if err != nil {
+		return err
+	}
+	baseConfig, err := GetHysteria2Config()
+	if err != nil {
+		return err
+	}
+	authHttpUrl, err := GetAuthHttpUrl()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	enabled := map[int64]bool{}
+	for _, item := range aclProfiles {
+		enabled[*item.Id] = true
+		if err = startAclInstance(baseConfig, item, authHttpUrl); err != nil {
+			logrus.Errorf("start acl profile %s err: %v", *item.Name, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("start acl profile %s err", *item.Name)
+			}
+		}
+	}
+	for _, id := range proxy.ListHysteria2AclInstanceIds() {
+		if !enabled[id] {
+			stopAclInstance(id)
+		}
+	}
+	return firstErr
+}
+
+func startAclInstance(baseConfig bo.Hysteria2ServerConfig, aclProfile entity.AclProfile, authHttpUrl string) error {
+	id := *aclProfile.Id
+	instance := proxy.NewHysteria2AclInstance(id)
+	instance.SetCrashHandler(func(err error) {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeCrashed, aclNodeName(id), err)
+	})
+
+	outbounds, err := ParseAclOutbounds(*aclProfile.Outbounds)
+	if err != nil {
+		return err
+	}
+	rules, errs := ParseAclRules(*aclProfile.Rules, append(aclOutboundNames(outbounds), aclSocks5Outbound))
+	if len(errs) > 0 {
+		return fmt.Errorf("line %d: %s", errs[0].Line, errs[0].Message)
+	}
+	if aclRulesUse(rules, aclSocks5Outbound) && !util.ArrContain(aclOutboundNames(outbounds), aclSocks5Outbound) {
+		socks5Config, err := GetSocks5Config()
+		if err != nil {
+			return err
+		}
+		if outbounds, err = aclSocks5Outbounds(outbounds, socks5Config); err != nil {
+			return err
+		}
+	}
+
+	apiPort, running := aclApiPorts[id]
+	running = running && instance.IsRunning()
+	if !running {
+		if apiPort, err = aclFreePort(); err != nil {
+			return err
+		}
+	}
+	config := aclInstanceConfig(baseConfig, aclProfile, rules, outbounds, apiPort, fmt.Sprintf("%s?aclProfileId=%d", authHttpUrl, id))
+	if err = prepareGeoAcl(config.ACL); err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(&config)
+	if err != nil {
+		return err
+	}
+	if running && aclConfigs[id] == string(content) {
+		return nil
+	}
+	if err = instance.StopHysteria2(); err != nil {
+		return err
+	}
+	if err = os.WriteFile(instance.ConfigPath(), content, 0644); err != nil {
+		return err
+	}
+	if err = instance.StartHysteria2(); err != nil {
+		return err
+	}
+	aclApiPorts[id] = apiPort
+	aclConfigs[id] = string(content)
+	if !running {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeStarted, aclNodeName(id), nil)
+	}
+	return nil
+}
+
+func stopAclInstance(id int64) {
+	instance := proxy.NewHysteria2AclInstance(id)
+	running := instance.IsRunning()
+	if err := instance.StopHysteria2(); err != nil {
+		logrus.Errorf("stop acl instance %d err: %v", id, err)
+		return
+	}
+	delete(aclApiPorts, id)
+	delete(aclConfigs, id)
+	if running {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeStopped, aclNodeName(id), nil)
+	}
+}
+
+func stopAclInstances() {
+	for _, id := range proxy.ListHysteria2AclInstanceIds() {
+		stopAclInstance(id)
+	}
+}
+
+// StopAclInstances 主节点停止时调用
+func StopAclInstances() {
+	aclLock.Lock()
+	defer aclLock.Unlock()
+	stopAclInstances()
+}
+
+// ReleaseAclInstances 面板退出时释放实例
+func ReleaseAclInstances() error {
+	var lastErr error
+	for _, id := range proxy.ListHysteria2AclInstanceIds() {
+		if err := proxy.NewHysteria2AclInstance(id).Release(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func aclInstanceRunning(id int64) bool {
+	aclLock.Lock()
+	defer aclLock.Unlock()
+	_, ok := aclApiPorts[id]
+	return ok && proxy.NewHysteria2AclInstance(id).IsRunning()
+}
+
+// aclInstanceApiPorts 运行中实例的流量统计端口
+func aclInstanceApiPorts() map[int64]int64 {
+	aclLock.Lock()
+	defer aclLock.Unlock()
+	apiPorts := make(map[int64]int64, len(aclApiPorts))
+	for id, apiPort := range aclApiPorts {
+		if proxy.NewHysteria2AclInstance(id).IsRunning() {
+			apiPorts[id] = apiPort
+		}
+	}
+	return apiPorts
+}
+
+// kickAclAccounts 在主节点和所有规则集实例上断开账号的连接，之后重新认证
+func kickAclAccounts(accounts []entity.Account) {
+	if len(accounts) == 0 || !Hysteria2IsRunning() {
+		return
+	}
+	jwtSecretConfig, err := dao.GetConfig("key = ?", constant.JwtSecret)
+	if err != nil {
+		return
+	}
+	var usernames []string
+	for _, item := range accounts {
+		usernames = append(usernames, *item.Username)
+	}
+	apiPorts := []int64{}
+	if apiPort, err := GetHysteria2ApiPort(); err == nil {
+		apiPorts = append(apiPorts, apiPort)
+	}
+	for _, apiPort := range aclInstanceApiPorts() {
+		apiPorts = append(apiPorts, apiPort)
+	}
+	for _, apiPort := range apiPorts {
+		if err = proxy.NewHysteria2Api(apiPort).KickUsers(usernames, *jwtSecretConfig.Value); err != nil {
+			logrus.Errorf("kick acl accounts err: %v", err)
+		}
+	}
+}
+
+// aclInstanceConfig 在主节点配置的基础上替换监听端口、流量统计端口、认证地址、ACL、出站和带宽
+func aclInstanceConfig(baseConfig bo.Hysteria2ServerConfig, aclProfile entity.AclProfile, rules []AclRule,
+	outbounds []bo.ServerConfigOutboundEntry, apiPort int64, authHttpUrl string) bo.Hysteria2ServerConfig {
+	config := baseConfig
+	host := ""
+	if baseConfig.Listen != nil {
+		if index := strings.LastIndex(*baseConfig.Listen, ":"); index >= 0 {
+			host = (*baseConfig.Listen)[:index]
+		}
+	}
+	listen := fmt.Sprintf("%s:%d", host, *aclProfile.Port)
+	config.Listen = &listen
+
+	var trafficStats bo.ServerConfigTrafficStats
+	if baseConfig.TrafficStats != nil {
+		trafficStats = *baseConfig.TrafficStats
+	}
+	trafficStatsListen := fmt.Sprintf("127.0.0.1:%d", apiPort)
+	trafficStats.Listen = &trafficStatsListen
+	config.TrafficStats = &trafficStats
+
+	var auth bo.ServerConfigAuth
+	if baseConfig.Auth != nil {
+		auth = *baseConfig.Auth
+	}
+	var authHttp bo.ServerConfigAuthHTTP
+	if auth.HTTP != nil {
+		authHttp = *auth.HTTP
+	}
+	authHttp.URL = &authHttpUrl
+	auth.HTTP = &authHttp
+	config.Auth = &auth
+
+	var acl bo.ServerConfigACL
+	if baseConfig.ACL != nil {
+		acl.GeoIP = baseConfig.ACL.GeoIP
+		acl.GeoSite = baseConfig.ACL.GeoSite
+		acl.GeoUpdateInterval = baseConfig.ACL.GeoUpdateInterval
+	}
+	for _, rule := range rules {
+		acl.Inline = append(acl.Inline, rule.String())
+	}
+	config.ACL = &acl
+	config.Outbounds = outbounds
+
+	// 作为套餐限速档位时限制每个客户端的带宽
+	up, down := "", ""
+	if aclProfile.BandwidthUp != nil {
+		up = *aclProfile.BandwidthUp
+	}
+	if aclProfile.BandwidthDown != nil {
+		down = *aclProfile.BandwidthDown
+	}
+	if up != "" || down != "" {
+		var bandwidth bo.ServerConfigBandwidth
+		if baseConfig.Bandwidth != nil {
+			bandwidth = *baseConfig.Bandwidth
+		}
+		if up != "" {
+			bandwidth.Up = &up
+		}
+		if down != "" {
+			bandwidth.Down = &down
+		}
+		config.Bandwidth = &bandwidth
+		ignoreClientBandwidth := false
+		config.IgnoreClientBandwidth = &ignoreClientBandwidth
+	}
+	return config
+}
+
+// aclSocks5Outbounds 追加 SOCKS5 上游；没有其他出站时先加一个直连出站，使未匹配的流量仍然直连
+func aclSocks5Outbounds(outbounds []bo.ServerConfigOutboundEntry, socks5Config bo.Socks5Config) ([]bo.ServerConfigOutboundEntry, error) {
+	if socks5Config.Addr == "" {
+		return nil, errors.New("socks5 upstream is not configured")
+	}
+	if len(outbounds) == 0 {
+		directName, directType := "hui_direct", "direct"
+		outbounds = append(outbounds, bo.ServerConfigOutboundEntry{Name: &directName, Type: &directType})
+	}
+	name, outboundType := aclSocks5Outbound, "socks5"
+	socks5 := bo.ServerConfigOutboundSOCKS5{Addr: &socks5Config.Addr}
+	if socks5Config.Username != "" {
+		socks5.Username = &socks5Config.Username
+	}
+	if socks5Config.Password != "" {
+		socks5.Password = &socks5Config.Password
+	}
+	return append(outbounds, bo.ServerConfigOutboundEntry{Name: &name, Type: &outboundType, SOCKS5: &socks5}), nil
+}
+
+func aclFreePort() (int64, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		logrus.Errorf("acl instance api port err: %v", err)
+		return 0, errors.New("acl instance api port err")
+	}
+	defer listener.Close()
+	return int64(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+func aclNodeName(id int64) string {
+	return fmt.Sprintf("acl-%d", id)
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"h-ui/model/bo"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// aclSocks5Outbound 规则中引用该出站但未定义时，使用第二节点的 SOCKS5 上游
+const aclSocks5Outbound = "socks5_proxy"
+
+var (
+	// aclBuiltinOutbounds hysteria2 内置的出站
+	aclBuiltinOutbounds = []string{"direct", "reject", "default"}
+	aclRuleRegexp       = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*\((.*)\)$`)
+	aclNameRegexp       = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	aclDomainRegexp     = regexp.MustCompile(`^(\*\.)?([A-Za-z0-9*]([A-Za-z0-9*-]{0,61}[A-Za-z0-9*])?\.)*[A-Za-z0-9*]([A-Za-z0-9*-]{0,61}[A-Za-z0-9*])?\.?$`)
+	aclGeoRegexp        = regexp.MustCompile(`^[A-Za-z0-9_!.-]+(@[A-Za-z0-9_!-]+)?$`)
+)
+
+// AclRule 一条 outbound(address[, proto/port][, hijackAddress]) 规则
+type AclRule struct {
+	Line      int64
+	Outbound  string
+	Address   string
+	ProtoPort string
+	Hijack    string
+}
+
+func (r AclRule) String() string {
+	args := []string{r.Address}
+	if r.ProtoPort != "" || r.Hijack != "" {
+		protoPort := r.ProtoPort
+		if protoPort == "" {
+			protoPort = "*"
+		}
+		args = append(args, protoPort)
+	}
+	if r.Hijack != "" {
+		args = append(args, r.Hijack)
+	}
+	return fmt.Sprintf("%s(%s)", r.Outbound, strings.Join(args, ", "))
+}
+
+// ParseAclOutbounds 解析 YAML 格式的出站列表，名称不能与内置出站重复
+func ParseAclOutbounds(value string) ([]bo.ServerConfigOutboundEntry, error) {
+	var outbounds []bo.ServerConfigOutboundEntry
+	if strings.TrimSpace(value) == "" {
+		return outbounds, nil
+	}
+	if err := yaml.Unmarshal([]byte(value), &outbounds); err != nil {
+		return nil, fmt.Errorf("outbounds: %v", err)
+	}
+	var names []string
+	for _, item := range outbounds {
+		if item.Name == nil || !aclNameRegexp.MatchString(*item.Name) {
+			return nil, fmt.Errorf("outbounds: name is invalid")
+		}
+		name := strings.ToLower(*item.Name)
+		if util.ArrContain(aclBuiltinOutbounds, name) || util.ArrContain(names, name) {
+			return nil, fmt.Errorf("outbounds: %s is duplicated", *item.Name)
+		}
+		names = append(names, name)
+		outboundType := ""
+		if item.Type != nil {
+			outboundType = *item.Type
+		}
+		switch outboundType {
+		case "direct":
+		case "socks5":
+			if item.SOCKS5 == nil || item.SOCKS5.Addr == nil || *item.SOCKS5.Addr == "" {
+				return nil, fmt.Errorf("outbounds: %s socks5 addr is required", *item.Name)
+			}
+		case "http":
+			if item.HTTP == nil || item.HTTP.URL == nil || *item.HTTP.URL == "" {
+				return nil, fmt.Errorf("outbounds: %s http url is required", *item.Name)
+			}
+		default:
+			return nil, fmt.Errorf("outbounds: %s type %s is not supported", *item.Name, outboundType)
+		}
+	}
+	return outbounds, nil
+}
+
+// ParseAclRules 解析 hysteria2 ACL 规则，# 之后为注释，outbounds 为可引用的自定义出站名称
+func ParseAclRules(value string, outbounds []string) ([]AclRule, []vo.AclRuleErrorVo) {
+	var rules []AclRule
+	var errs []vo.AclRuleErrorVo
+	for i, line := range strings.Split(value, "\n") {
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rule, err := parseAclRule(line, outbounds)
+		if err != nil {
+			errs = append(errs, vo.AclRuleErrorVo{Line: int64(i + 1), Message: err.Error()})
+			continue
+		}
+		rule.Line = int64(i + 1)
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 && len(errs) == 0 {
+		errs = append(errs, vo.AclRuleErrorVo{Message: "rules is empty"})
+	}
+	return rules, errs
+}
+
+func parseAclRule(line string, outbounds []string) (AclRule, error) {
+	var rule AclRule
+	match := aclRuleRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return rule, fmt.Errorf("%s is not in the form outbound(address[, proto/port][, hijack])", line)
+	}
+	rule.Outbound = match[1]
+	name := strings.ToLower(rule.Outbound)
+	if !util.ArrContain(aclBuiltinOutbounds, name) && !util.ArrContain(outbounds, name) {
+		return rule, fmt.Errorf("outbound %s is not defined", rule.Outbound)
+	}
+	args := strings.Split(match[2], ",")
+	if len(args) > 3 {
+		return rule, fmt.Errorf("too many arguments")
+	}
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+	rule.Address = args[0]
+	if err := checkAclAddress(rule.Address); err != nil {
+		return rule, err
+	}
+	if len(args) > 1 {
+		rule.ProtoPort = args[1]
+		if err := checkAclProtoPort(rule.ProtoPort); err != nil {
+			return rule, err
+		}
+	}
+	if len(args) > 2 {
+		rule.Hijack = args[2]
+		if net.ParseIP(rule.Hijack) == nil {
+			return rule, fmt.Errorf("hijack address %s is not an ip", rule.Hijack)
+		}
+	}
+	return rule, nil
+}
+
+// checkAclAddress all、IP、CIDR、域名、*.通配符、suffix:、geoip:、geosite:
+func checkAclAddress(address string) error {
+	lower := strings.ToLower(address)
+	switch {
+	case address == "":
+		return fmt.Errorf("address is empty")
+	case lower == "all" || lower == "*":
+		return nil
+	case strings.HasPrefix(lower, "geoip:"):
+		if !aclGeoRegexp.MatchString(address[len("geoip:"):]) || strings.Contains(address, "@") {
+			return fmt.Errorf("geoip %s is invalid", address)
+		}
+		return nil
+	case strings.HasPrefix(lower, "geosite:"):
+		if !aclGeoRegexp.MatchString(address[len("geosite:"):]) {
+			return fmt.Errorf("geosite %s is invalid", address)
+		}
+		return nil
+	case strings.HasPrefix(lower, "suffix:"):
+		if !aclDomainRegexp.MatchString(address[len("suffix:"):]) {
+			return fmt.Errorf("suffix %s is invalid", address)
+		}
+		return nil
+	case strings.Contains(address, "/"):
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return fmt.Errorf("cidr %s is invalid", address)
+		}
+		return nil
+	case net.ParseIP(address) != nil:
+		return nil
+	case aclDomainRegexp.MatchString(address):
+		return nil
+	}
+	return fmt.Errorf("address %s is invalid", address)
+}
+
+// checkAclProtoPort tcp、udp、*，可选 /端口、/端口范围、/*
+func checkAclProtoPort(protoPort string) error {
+	proto, port, hasPort := strings.Cut(strings.ToLower(protoPort), "/")
+	if proto != "tcp" && proto != "udp" && proto != "*" {
+		return fmt.Errorf("protocol %s is invalid", proto)
+	}
+	if !hasPort || port == "*" {
+		return nil
+	}
+	bounds := strings.Split(port, "-")
+	if len(bounds) > 2 {
+		return fmt.Errorf("port %s is invalid", port)
+	}
+	var values []uint64
+	for _, bound := range bounds {
+		value, err := strconv.ParseUint(bound, 10, 16)
+		if err != nil || value == 0 {
+			return fmt.Errorf("port %s is invalid", port)
+		}
+		values = append(values, value)
+	}
+	if len(values) == 2 && values[0] > values[1] {
+		return fmt.Errorf("port %s is invalid", port)
+	}
+	return nil
+}
+
+// ValidateAclRules 规则编辑器的语法检查，引用 socks5_proxy 时需要已配置 SOCKS5 上游，geo 分类需要在 geo 文件中存在
+func ValidateAclRules(rules string, outbounds string) vo.AclRulesValidateVo {
+	result := vo.AclRulesValidateVo{Errors: []vo.AclRuleErrorVo{}}
+	entries, err := ParseAclOutbounds(outbounds)
+	if err != nil {
+		result.Errors = append(result.Errors, vo.AclRuleErrorVo{Message: err.Error()})
+		return result
+	}
+	names := aclOutboundNames(entries)
+	socks5Defined := util.ArrContain(names, aclSocks5Outbound)
+	if !socks5Defined {
+		names = append(names, aclSocks5Outbound)
+	}
+	parsed, errs := ParseAclRules(rules, names)
+	result.Errors = append(result.Errors, errs...)
+	if hysteria2Config, err := GetHysteria2Config(); err == nil {
+		if categories, err := geoAclCategories(hysteria2Config.ACL, nil); err == nil {
+			for _, rule := range parsed {
+				if err = checkGeoAddress(rule.Address, categories); err != nil {
+					result.Errors = append(result.Errors, vo.AclRuleErrorVo{Line: rule.Line, Message: err.Error()})
+				}
+			}
+		}
+	}
+	if !socks5Defined && aclRulesUse(parsed, aclSocks5Outbound) {
+		if socks5Config, err := GetSocks5Config(); err != nil || socks5Config.Addr == "" {
+			result.Errors = append(result.Errors, vo.AclRuleErrorVo{Message: "socks5 upstream is not configured"})
+		}
+	}
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+func aclOutboundNames(outbounds []bo.ServerConfigOutboundEntry) []string {
+	var names []string
+	for _, item := range outbounds {
+		names = append(names, strings.ToLower(*item.Name))
+	}
+	return names
+}
+
+func aclRulesUse(rules []AclRule, outbound string) bool {
+	for _, rule := range rules {
+		if strings.ToLower(rule.Outbound) == outbound {
+			return true
+		}
+	}
+	return false
+}
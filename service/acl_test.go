@@ -0,0 +1,184 @@
+package service
+
+import (
+	"h-ui/model/bo"
+	"h-ui/model/entity"
+	"reflect"
+	"testing"
+)
+
+func TestParseAclRules(t *testing.T) {
+	value := `# 试用账号
+reject(all, udp/6881-6889)
+reject(geosite:category-porn)  # 注释
+socks5_proxy(geosite:netflix@!cn, tcp/443)
+Upstream(suffix:example.com)
+direct(192.168.0.0/16, *, 127.0.0.1)
+direct(*.google.com, tcp)
+direct(all)`
+	rules, errs := ParseAclRules(value, []string{"socks5_proxy", "upstream"})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	expect := []string{
+		"reject(all, udp/6881-6889)",
+		"reject(geosite:category-porn)",
+		"socks5_proxy(geosite:netflix@!cn, tcp/443)",
+		"Upstream(suffix:example.com)",
+		"direct(192.168.0.0/16, *, 127.0.0.1)",
+		"direct(*.google.com, tcp)",
+		"direct(all)",
+	}
+	for i, rule := range rules {
+		if rule.String() != expect[i] {
+			t.Errorf("expected %s, got: %s", expect[i], rule.String())
+		}
+	}
+	if rules[0].Line != 2 || rules[6].Line != 8 {
+		t.Errorf("unexpected line numbers: %d %d", rules[0].Line, rules[6].Line)
+	}
+
+	for _, line := range []string{
+		"direct",
+		"proxy(all)",
+		"direct(all, icmp)",
+		"direct(all, tcp/70000)",
+		"direct(all, tcp/2000-1000)",
+		"direct(10.0.0.0/33)",
+		"direct(all, *, example.com)",
+		"direct(all, *, 1.1.1.1, x)",
+		"direct(geoip:cn@x)",
+		"direct(exa mple.com)",
+	} {
+		if _, errs = ParseAclRules("direct(all)\n"+line, nil); len(errs) != 1 || errs[0].Line != 2 {
+			t.Errorf("%s should fail on line 2, got: %v", line, errs)
+		}
+	}
+	if _, errs = ParseAclRules("# only comment\n", nil); len(errs) != 1 {
+		t.Errorf("empty rules should fail")
+	}
+}
+
+func TestParseAclOutbounds(t *testing.T) {
+	outbounds, err := ParseAclOutbounds(`
+- name: upstream
+  type: socks5
+  socks5:
+    addr: 10.0.0.1:1080
+- name: local
+  type: direct
+`)
+	if err != nil || len(outbounds) != 2 || *outbounds[0].SOCKS5.Addr != "10.0.0.1:1080" {
+		t.Fatalf("unexpected outbounds: %v %v", outbounds, err)
+	}
+	if names := aclOutboundNames(outbounds); !reflect.DeepEqual(names, []string{"upstream", "local"}) {
+		t.Errorf("unexpected names: %v", names)
+	}
+	for _, value := range []string{
+		"- name: direct\n  type: direct",
+		"- name: a\n  type: direct\n- name: A\n  type: direct",
+		"- name: a\n  type: socks5",
+		"- name: a\n  type: http",
+		"- name: a\n  type: wireguard",
+		"- type: direct",
+		"name: a",
+	} {
+		if _, err = ParseAclOutbounds(value); err == nil {
+			t.Errorf("%q should fail", value)
+		}
+	}
+}
+
+func TestResolveAclProfileId(t *testing.T) {
+	id1, id2 := int64(1), int64(2)
+	tags1, tags2 := "trial", "kids, family"
+	aclProfiles := []entity.AclProfile{
+		{BaseEntity: entity.BaseEntity{Id: &id1}, Tags: &tags1},
+		{BaseEntity: entity.BaseEntity{Id: &id2}, Tags: &tags2},
+	}
+	cases := []struct {
+		aclProfileId int64
+		tags         []string
+		expect       int64
+	}{
+		{0, nil, 0},
+		{2, []string{"trial"}, 2},
+		{0, []string{"family", "trial"}, 1},
+		{0, []string{"family"}, 2},
+		{3, []string{"other"}, 0},
+		{3, []string{"kids"}, 2},
+	}
+	for _, item := range cases {
+		if got := resolveAclProfileId(item.aclProfileId, item.tags, aclProfiles); got != item.expect {
+			t.Errorf("%d %v expected %d, got: %d", item.aclProfileId, item.tags, item.expect, got)
+		}
+	}
+}
+
+func TestAclInstanceConfig(t *testing.T) {
+	listen, apiListen, geoIP, url, secret := "[::]:443", "127.0.0.1:7653", "/data/geoip.dat", "http://127.0.0.1:8081/hui/hysteria2/auth", "s"
+	baseConfig := bo.Hysteria2ServerConfig{
+		Listen:       &listen,
+		TrafficStats: &bo.ServerConfigTrafficStats{Listen: &apiListen, Secret: &secret},
+		Auth:         &bo.ServerConfigAuth{HTTP: &bo.ServerConfigAuthHTTP{URL: &url}},
+		ACL:          &bo.ServerConfigACL{GeoIP: &geoIP, Inline: []string{"reject(all)"}},
+	}
+	port := int64(8443)
+	rules, _ := ParseAclRules("socks5_proxy(geosite:netflix)\ndirect(all)", []string{aclSocks5Outbound})
+	outbounds, err := aclSocks5Outbounds(nil, bo.Socks5Config{Addr: "10.0.0.1:1080"})
+	if err != nil {
+		t.Fatalf("socks5 outbounds err: %v", err)
+	}
+	config := aclInstanceConfig(baseConfig, entity.AclProfile{Port: &port}, rules, outbounds, 40000, url+"?aclProfileId=1")
+
+	if *config.Listen != "[::]:8443" || *config.TrafficStats.Listen != "127.0.0.1:40000" || *config.TrafficStats.Secret != secret {
+		t.Errorf("unexpected listen: %s %s", *config.Listen, *config.TrafficStats.Listen)
+	}
+	if *config.Auth.HTTP.URL != url+"?aclProfileId=1" {
+		t.Errorf("unexpected auth url: %s", *config.Auth.HTTP.URL)
+	}
+	if !reflect.DeepEqual(config.ACL.Inline, []string{"socks5_proxy(geosite:netflix)", "direct(all)"}) || *config.ACL.GeoIP != geoIP {
+		t.Errorf("unexpected acl: %v", config.ACL.Inline)
+	}
+	if len(config.Outbounds) != 2 || *config.Outbounds[0].Type != "direct" || *config.Outbounds[1].Name != aclSocks5Outbound {
+		t.Errorf("unexpected outbounds: %v", config.Outbounds)
+	}
+	// 主节点配置不能被修改
+	if *baseConfig.Listen != listen || *baseConfig.TrafficStats.Listen != apiListen || *baseConfig.Auth.HTTP.URL != url || len(baseConfig.ACL.Inline) != 1 {
+		t.Errorf("base config should not be modified")
+	}
+	if _, err = aclSocks5Outbounds(nil, bo.Socks5Config{}); err == nil {
+		t.Errorf("socks5 without addr should fail")
+	}
+}
+
+func TestAclInstanceConfigBandwidth(t *testing.T) {
+	listen, up, down := "[::]:443", "1 gbps", "1 gbps"
+	baseConfig := bo.Hysteria2ServerConfig{
+		Listen:    &listen,
+		Bandwidth: &bo.ServerConfigBandwidth{Up: &up, Down: &down},
+	}
+	port, tierUp, tierDown := int64(8443), "20 mbps", ""
+	config := aclInstanceConfig(baseConfig, entity.AclProfile{Port: &port, BandwidthUp: &tierUp, BandwidthDown: &tierDown}, nil, nil, 40000, "")
+	if *config.Bandwidth.Up != "20 mbps" || *config.Bandwidth.Down != "1 gbps" {
+		t.Errorf("unexpected bandwidth: %s %s", *config.Bandwidth.Up, *config.Bandwidth.Down)
+	}
+	if *baseConfig.Bandwidth.Up != "1 gbps" {
+		t.Errorf("base config should not be modified")
+	}
+	config = aclInstanceConfig(baseConfig, entity.AclProfile{Port: &port}, nil, nil, 40000, "")
+	if config.Bandwidth != baseConfig.Bandwidth {
+		t.Errorf("acl profile without bandwidth should keep the node bandwidth")
+	}
+
+	for _, item := range []string{"", "100 mbps", "100mbps", "1 Gbps", "500000"} {
+		if err := ValidateAclBandwidth(item); err != nil {
+			t.Errorf("%q should be valid: %v", item, err)
+		}
+	}
+	for _, item := range []string{"fast", "100 mbit", "-1 mbps", "1.5 gbps"} {
+		if err := ValidateAclBandwidth(item); err == nil {
+			t.Errorf("%q should be invalid", item)
+		}
+	}
+}
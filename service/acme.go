@@ -0,0 +1,666 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	panelAcmeLock sync.Mutex
+	// panelCert 面板当前使用的证书，续期或修改证书后替换，不需要重启面板
+	panelCert atomic.Pointer[tls.Certificate]
+	// panelAcmeTokens http-01 验证的 token 与响应
+	panelAcmeTokens sync.Map
+	// panelAcmeAlpnCerts tls-alpn-01 验证的域名与证书
+	panelAcmeAlpnCerts sync.Map
+	panelAcmeRenewing  atomic.Bool
+	panelAcmeRenewAt   atomic.Int64
+	panelAcmeError     atomic.Value
+	// panelAcmeDnsWait 添加 TXT 记录后等待解析生效的时间
+	panelAcmeDnsWait = 10 * time.Second
+
+	panelAcmeDomainRegexp = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+)
+
+var panelAcmeConfigKeys = []string{
+	constant.PanelAcmeEnable,
+	constant.PanelAcmeDomains,
+	constant.PanelAcmeEmail,
+	constant.PanelAcmeCa,
+	constant.PanelAcmeCaRoot,
+	constant.PanelAcmeChallenge,
+	constant.PanelAcmeHttpPort,
+	constant.PanelAcmeTlsPort,
+	constant.PanelAcmeDnsProvider,
+	constant.PanelAcmeDnsConfig,
+}
+
+type panelAcmeSettings struct {
+	enable      bool
+	domains     []string
+	email       string
+	ca          string
+	caRoot      string
+	challenge   string
+	httpPort    int
+	tlsPort     int
+	dnsProvider string
+	dnsConfig   string
+}
+
+// IsPanelAcmeConfigKey 修改后需要重新申请面板证书的配置
+func IsPanelAcmeConfigKey(key string) bool {
+	return util.ArrContain(panelAcmeConfigKeys, key)
+}
+
+// ParsePanelAcmeDomains 逗号分隔的域名，小写并去重
+func ParsePanelAcmeDomains(value string) ([]string, error) {
+	var domains []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if !panelAcmeDomainRegexp.MatchString(item) {
+			return nil, fmt.Errorf("domain %s is invalid", item)
+		}
+		if !util.ArrContain(domains, item) {
+			domains = append(domains, item)
+		}
+	}
+	return domains, nil
+}
+
+// parsePanelAcmeSettings 解析配置，未启用时不检查
+func parsePanelAcmeSettings(values map[string]string) (panelAcmeSettings, error) {
+	settings := panelAcmeSettings{
+		enable:      values[constant.PanelAcmeEnable] == "1",
+		email:       values[constant.PanelAcmeEmail],
+		ca:          values[constant.PanelAcmeCa],
+		caRoot:      values[constant.PanelAcmeCaRoot],
+		challenge:   values[constant.PanelAcmeChallenge],
+		dnsProvider: values[constant.PanelAcmeDnsProvider],
+		dnsConfig:   values[constant.PanelAcmeDnsConfig],
+	}
+	if value := values[constant.PanelAcmeEnable]; value != "0" && value != "1" {
+		return settings, fmt.Errorf("panel acme enable: %s is invalid", value)
+	}
+	domains, err := ParsePanelAcmeDomains(values[constant.PanelAcmeDomains])
+	if err != nil {
+		return settings, err
+	}
+	settings.domains = domains
+	for _, key := range []string{constant.PanelAcmeHttpPort, constant.PanelAcmeTlsPort} {
+		port, err := strconv.Atoi(values[key])
+		if err != nil || port <= 0 || port > 65535 {
+			return settings, fmt.Errorf("%s: %s is invalid", key, values[key])
+		}
+		if key == constant.PanelAcmeHttpPort {
+			settings.httpPort = port
+		} else {
+			settings.tlsPort = port
+		}
+	}
+	if !settings.enable {
+		return settings, nil
+	}
+	if len(domains) == 0 {
+		return settings, errors.New("panel acme domains is empty")
+	}
+	caUrl, err := url.Parse(settings.ca)
+	if err != nil || (caUrl.Scheme != "http" && caUrl.Scheme != "https") || caUrl.Host == "" {
+		return settings, fmt.Errorf("panel acme ca: %s is invalid", settings.ca)
+	}
+	if settings.caRoot != "" && !util.Exists(settings.caRoot) {
+		return settings, fmt.Errorf("panel acme ca root: %s does not exist", settings.caRoot)
+	}
+	switch settings.challenge {
+	case constant.AcmeChallengeHttp01, constant.AcmeChallengeTlsAlpn01:
+		for _, domain := range domains {
+			if strings.HasPrefix(domain, "*.") {
+				return settings, fmt.Errorf("wildcard domain %s requires %s", domain, constant.AcmeChallengeDns01)
+			}
+		}
+	case constant.AcmeChallengeDns01:
+		if _, err = newAcmeDnsProvider(settings.dnsProvider, settings.dnsConfig); err != nil {
+			return settings, err
+		}
+	default:
+		return settings, fmt.Errorf("panel acme challenge: %s is invalid", settings.challenge)
+	}
+	return settings, nil
+}
+
+func listPanelAcmeConfigs() (map[string]string, error) {
+	configs, err := dao.ListConfig("key in ?", panelAcmeConfigKeys)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, item := range configs {
+		values[*item.Key] = *item.Value
+	}
+	return values, nil
+}
+
+func getPanelAcmeSettings() (panelAcmeSettings, error) {
+	values, err := listPanelAcmeConfigs()
+	if err != nil {
+		return panelAcmeSettings{}, err
+	}
+	return parsePanelAcmeSettings(values)
+}
+
+// CheckPanelAcme 保存配置前检查修改后的面板 ACME 配置，没有修改相关配置时不检查
+func CheckPanelAcme(updates map[string]string) error {
+	changed := false
+	for key := range updates {
+		changed = changed || IsPanelAcmeConfigKey(key)
+	}
+	if !changed {
+		return nil
+	}
+	values, err := listPanelAcmeConfigs()
+	if err != nil {
+		return err
+	}
+	for key, value := range updates {
+		if IsPanelAcmeConfigKey(key) {
+			values[key] = value
+		}
+	}
+	_, err = parsePanelAcmeSettings(values)
+	return err
+}
+
+func PanelAcmeEnabled() bool {
+	config, err := dao.GetConfig("key = ?", constant.PanelAcmeEnable)
+	return err == nil && *config.Value == "1"
+}
+
+// InitPanelAcme 面板启动前调用：没有证书时先生成自签名证书，然后在后台申请
+func InitPanelAcme() error {
+	settings, err := getPanelAcmeSettings()
+	if err != nil {
+		return err
+	}
+	if !settings.enable {
+		return nil
+	}
+	if !util.Exists(constant.PanelAcmeCrtPath) || !util.Exists(constant.PanelAcmeKeyPath) {
+		certPEM, keyPEM, err := selfSignedPanelCert(settings.domains)
+		if err != nil {
+			return err
+		}
+		if err = writePanelCert(certPEM, keyPEM); err != nil {
+			return err
+		}
+	}
+	if err = loadPanelCert(constant.PanelAcmeCrtPath, constant.PanelAcmeKeyPath); err != nil {
+		return err
+	}
+	go func() {
+		_ = RenewPanelAcme(false)
+	}()
+	return nil
+}
+
+// PanelTLSConfig 证书通过 GetCertificate 读取，续期后无需重启面板
+func PanelTLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: getPanelCertificate,
+		NextProtos:     []string{"h2", "http/1.1", acme.ALPNProto},
+	}
+}
+
+func getPanelCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto {
+		if cert, ok := panelAcmeAlpnCerts.Load(strings.ToLower(hello.ServerName)); ok {
+			return cert.(*tls.Certificate), nil
+		}
+		return nil, fmt.Errorf("no tls-alpn-01 challenge for %s", hello.ServerName)
+	}
+	if cert := panelCert.Load(); cert != nil {
+		return cert, nil
+	}
+	return nil, errors.New("panel cert is not loaded")
+}
+
+func loadPanelCert(crtPath string, keyPath string) error {
+	cert, err := parsePanelCert(crtPath, keyPath)
+	if err != nil {
+		return err
+	}
+	panelCert.Store(cert)
+	return nil
+}
+
+// parsePanelCert 只读取证书，不替换正在使用的证书
+func parsePanelCert(crtPath string, keyPath string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(crtPath, keyPath)
+	if err != nil {
+		logrus.Errorf("load panel cert err: %v", err)
+		return nil, errors.New("load panel cert err")
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		logrus.Errorf("parse panel cert err: %v", err)
+		return nil, errors.New("parse panel cert err")
+	}
+	return &cert, nil
+}
+
+// CheckPanelCert 保存证书配置前检查证书和私钥是否匹配
+func CheckPanelCert(crtPath string, keyPath string) error {
+	_, err := parsePanelCert(crtPath, keyPath)
+	return err
+}
+
+// writePanelCert 先写临时文件再替换，避免读到写了一半的证书
+func writePanelCert(certPEM []byte, keyPEM []byte) error {
+	if err := os.MkdirAll(constant.PanelAcmeDir, 0700); err != nil {
+		logrus.Errorf("create panel acme dir err: %v", err)
+		return errors.New("create panel acme dir err")
+	}
+	for path, content := range map[string][]byte{constant.PanelAcmeCrtPath: certPEM, constant.PanelAcmeKeyPath: keyPEM} {
+		if err := os.WriteFile(path+".tmp", content, 0600); err != nil {
+			logrus.Errorf("write %s err: %v", path, err)
+			return fmt.Errorf("write %s err", path)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			logrus.Errorf("rename %s err: %v", path, err)
+			return fmt.Errorf("write %s err", path)
+		}
+	}
+	return nil
+}
+
+func encodePanelKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// selfSignedPanelCert 申请成功之前使用的临时证书
+func selfSignedPanelCert(domains []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domains[0]},
+		DNSNames:     domains,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(constant.PanelAcmeRenewBefore),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := encodePanelKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// loadPanelAcmeAccountKey ACME 账号密钥，第一次使用时生成
+func loadPanelAcmeAccountKey() (crypto.Signer, error) {
+	if content, err := os.ReadFile(constant.PanelAcmeAccountKeyPath); err == nil {
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return nil, errors.New("panel acme account key is invalid")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodePanelKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(constant.PanelAcmeDir, 0700); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(constant.PanelAcmeAccountKeyPath, keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// panelCertNeedRenew 自签名、域名不一致或即将到期时需要重新申请
+func panelCertNeedRenew(leaf *x509.Certificate, domains []string, now time.Time) bool {
+	if leaf == nil || bytes.Equal(leaf.RawIssuer, leaf.RawSubject) {
+		return true
+	}
+	if now.Add(constant.PanelAcmeRenewBefore).After(leaf.NotAfter) {
+		return true
+	}
+	certDomains := append([]string{}, leaf.DNSNames...)
+	expectDomains := append([]string{}, domains...)
+	sort.Strings(certDomains)
+	sort.Strings(expectDomains)
+	return strings.Join(certDomains, ",") != strings.Join(expectDomains, ",")
+}
+
+// RenewPanelAcme 申请或续期面板证书，force 为 true 时不检查到期时间
+func RenewPanelAcme(force bool) error {
+	if !panelAcmeLock.TryLock() {
+		return errors.New("panel acme is renewing")
+	}
+	defer panelAcmeLock.Unlock()
+	panelAcmeRenewing.Store(true)
+	defer panelAcmeRenewing.Store(false)
+
+	settings, err := getPanelAcmeSettings()
+	if err != nil {
+		return err
+	}
+	if !settings.enable {
+		return errors.New("panel acme is disabled")
+	}
+	var leaf *x509.Certificate
+	if cert := panelCert.Load(); cert != nil {
+		leaf = cert.Leaf
+	}
+	if !force && !panelCertNeedRenew(leaf, settings.domains, time.Now()) {
+		return nil
+	}
+
+	accountKey, err := loadPanelAcmeAccountKey()
+	if err != nil {
+		logrus.Errorf("load panel acme account key err: %v", err)
+		return errors.New("load panel acme account key err")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), constant.PanelAcmeTimeout)
+	defer cancel()
+	certPEM, keyPEM, err := obtainPanelCert(ctx, settings, accountKey)
+	panelAcmeRenewAt.Store(time.Now().UnixMilli())
+	if err != nil {
+		logrus.Errorf("panel acme err: %v", err)
+		panelAcmeError.Store(err.Error())
+		return fmt.Errorf("panel acme err: %v", err)
+	}
+	panelAcmeError.Store("")
+	if err = writePanelCert(certPEM, keyPEM); err != nil {
+		return err
+	}
+	if err = loadPanelCert(constant.PanelAcmeCrtPath, constant.PanelAcmeKeyPath); err != nil {
+		return err
+	}
+	logrus.Infof("panel cert for %s renewed", strings.Join(settings.domains, ","))
+	return nil
+}
+
+func panelAcmeClient(settings panelAcmeSettings, accountKey crypto.Signer) (*acme.Client, error) {
+	client := &acme.Client{Key: accountKey, DirectoryURL: settings.ca, UserAgent: "h-ui/" + constant.Version}
+	if settings.caRoot != "" {
+		content, err := os.ReadFile(settings.caRoot)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, errors.New("panel acme ca root is invalid")
+		}
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+			Timeout:   30 * time.Second,
+		}
+	}
+	return client, nil
+}
+
+// obtainPanelCert 注册账号、完成验证并签发证书，返回 PEM 格式的证书链和私钥
+func obtainPanelCert(ctx context.Context, settings panelAcmeSettings, accountKey crypto.Signer) ([]byte, []byte, error) {
+	client, err := panelAcmeClient(settings, accountKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	account := &acme.Account{}
+	if settings.email != "" {
+		account.Contact = []string{"mailto:" + settings.email}
+	}
+	if _, err = client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, nil, fmt.Errorf("register account: %v", err)
+	}
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(settings.domains...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create order: %v", err)
+	}
+	if order.Status != acme.StatusReady {
+		stop, err := startPanelAcmeChallenge(settings)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer stop()
+		for _, authzUrl := range order.AuthzURLs {
+			if err = authorizePanelAcme(ctx, client, authzUrl, settings); err != nil {
+				return nil, nil, err
+			}
+		}
+		if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+			return nil, nil, fmt.Errorf("wait order: %v", err)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: settings.domains[0]},
+		DNSNames: settings.domains,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finalize order: %v", err)
+	}
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyPEM, err := encodePanelKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// authorizePanelAcme 完成单个域名的验证
+func authorizePanelAcme(ctx context.Context, client *acme.Client, authzUrl string, settings panelAcmeSettings) error {
+	authz, err := client.GetAuthorization(ctx, authzUrl)
+	if err != nil {
+		return fmt.Errorf("get authorization: %v", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	var challenge *acme.Challenge
+	for _, item := range authz.Challenges {
+		if item.Type == settings.challenge {
+			challenge = item
+		}
+	}
+	domain := authz.Identifier.Value
+	if challenge == nil {
+		return fmt.Errorf("%s challenge is not offered for %s", settings.challenge, domain)
+	}
+
+	switch settings.challenge {
+	case constant.AcmeChallengeHttp01:
+		response, err := client.HTTP01ChallengeResponse(challenge.Token)
+		if err != nil {
+			return err
+		}
+		panelAcmeTokens.Store(challenge.Token, response)
+		defer panelAcmeTokens.Delete(challenge.Token)
+	case constant.AcmeChallengeTlsAlpn01:
+		cert, err := client.TLSALPN01ChallengeCert(challenge.Token, domain)
+		if err != nil {
+			return err
+		}
+		panelAcmeAlpnCerts.Store(domain, &cert)
+		defer panelAcmeAlpnCerts.Delete(domain)
+	case constant.AcmeChallengeDns01:
+		provider, err := newAcmeDnsProvider(settings.dnsProvider, settings.dnsConfig)
+		if err != nil {
+			return err
+		}
+		value, err := client.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return err
+		}
+		fqdn := fmt.Sprintf("_acme-challenge.%s.", domain)
+		if err = provider.Present(ctx, fqdn, value); err != nil {
+			return fmt.Errorf("dns record %s: %v", fqdn, err)
+		}
+		defer func() {
+			if err := provider.CleanUp(context.Background(), fqdn, value); err != nil {
+				logrus.Warnf("clean up dns record %s err: %v", fqdn, err)
+			}
+		}()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(panelAcmeDnsWait):
+		}
+	}
+
+	if _, err = client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("accept %s challenge for %s: %v", settings.challenge, domain, err)
+	}
+	if _, err = client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("authorize %s: %v", domain, err)
+	}
+	return nil
+}
+
+// startPanelAcmeChallenge 在验证端口上临时监听；面板已经在验证端口上监听时由面板响应
+func startPanelAcmeChallenge(settings panelAcmeSettings) (func(), error) {
+	switch settings.challenge {
+	case constant.AcmeChallengeHttp01:
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", settings.httpPort))
+		if err != nil {
+			if panelServesPort(settings.httpPort, false) {
+				return func() {}, nil
+			}
+			return nil, fmt.Errorf("listen http-01 port %d: %v", settings.httpPort, err)
+		}
+		challengeServer := &http.Server{Handler: http.HandlerFunc(panelAcmeHttpHandler), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			_ = challengeServer.Serve(listener)
+		}()
+		return func() {
+			_ = challengeServer.Close()
+		}, nil
+	case constant.AcmeChallengeTlsAlpn01:
+		listener, err := tls.Listen("tcp", fmt.Sprintf(":%d", settings.tlsPort), PanelTLSConfig())
+		if err != nil {
+			if panelServesPort(settings.tlsPort, true) {
+				return func() {}, nil
+			}
+			return nil, fmt.Errorf("listen tls-alpn-01 port %d: %v", settings.tlsPort, err)
+		}
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+					_ = conn.(*tls.Conn).Handshake()
+					_ = conn.Close()
+				}()
+			}
+		}()
+		return func() {
+			_ = listener.Close()
+		}, nil
+	}
+	return func() {}, nil
+}
+
+func panelAcmeHttpHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")
+	response, ok := panelAcmeTokens.Load(token)
+	if !ok || token == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(response.(string)))
+}
+
+func GetPanelAcme() (vo.PanelAcmeVo, error) {
+	settings, err := getPanelAcmeSettings()
+	if err != nil {
+		return vo.PanelAcmeVo{}, err
+	}
+	panelAcmeVo := vo.PanelAcmeVo{
+		Enable:      settings.enable,
+		Domains:     settings.domains,
+		Challenge:   settings.challenge,
+		RenewAt:     panelAcmeRenewAt.Load(),
+		Renewing:    panelAcmeRenewing.Load(),
+		SelfSigned:  true,
+		CertDomains: []string{},
+	}
+	if lastError, ok := panelAcmeError.Load().(string); ok {
+		panelAcmeVo.LastError = lastError
+	}
+	if cert := panelCert.Load(); cert != nil && cert.Leaf != nil {
+		panelAcmeVo.CertDomains = cert.Leaf.DNSNames
+		panelAcmeVo.Issuer = cert.Leaf.Issuer.String()
+		panelAcmeVo.NotBefore = cert.Leaf.NotBefore.UnixMilli()
+		panelAcmeVo.NotAfter = cert.Leaf.NotAfter.UnixMilli()
+		panelAcmeVo.SelfSigned = bytes.Equal(cert.Leaf.RawIssuer, cert.Leaf.RawSubject)
+	}
+	return panelAcmeVo, nil
+}
+
+// CronPanelAcme 定期检查面板证书是否需要续期
+func CronPanelAcme() {
+	if !PanelAcmeEnabled() || panelCert.Load() == nil {
+		return
+	}
+	_ = RenewPanelAcme(false)
+}
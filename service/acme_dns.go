@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"h-ui/model/constant"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// acmeDnsProvider dns-01 验证时添加和删除 TXT 记录，fqdn 以点结尾
+type acmeDnsProvider interface {
+	Present(ctx context.Context, fqdn string, value string) error
+	CleanUp(ctx context.Context, fqdn string, value string) error
+}
+
+func newAcmeDnsProvider(name string, config string) (acmeDnsProvider, error) {
+	switch name {
+	case constant.AcmeDnsProviderCloudflare:
+		if config == "" {
+			return nil, errors.New("cloudflare api token is empty")
+		}
+		return &cloudflareDnsProvider{apiUrl: "https://api.cloudflare.com/client/v4", token: config}, nil
+	case constant.AcmeDnsProviderWebhook:
+		webhookUrl, err := url.Parse(config)
+		if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+			return nil, fmt.Errorf("dns webhook url: %s is invalid", config)
+		}
+		return &webhookDnsProvider{url: config}, nil
+	}
+	return nil, fmt.Errorf("dns provider: %s is invalid", name)
+}
+
+func acmeDnsRequest(ctx context.Context, method string, requestUrl string, header map[string]string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestUrl, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	content, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %d %s", method, requestUrl, response.StatusCode, strings.TrimSpace(string(content)))
+	}
+	if result != nil {
+		return json.Unmarshal(content, result)
+	}
+	return nil
+}
+
+// webhookDnsProvider 把记录交给外部脚本处理
+type webhookDnsProvider struct {
+	url string
+}
+
+func (p *webhookDnsProvider) request(ctx context.Context, action string, fqdn string, value string) error {
+	return acmeDnsRequest(ctx, http.MethodPost, p.url, nil, map[string]string{
+		"action": action,
+		"fqdn":   fqdn,
+		"value":  value,
+	}, nil)
+}
+
+func (p *webhookDnsProvider) Present(ctx context.Context, fqdn string, value string) error {
+	return p.request(ctx, "present", fqdn, value)
+}
+
+func (p *webhookDnsProvider) CleanUp(ctx context.Context, fqdn string, value string) error {
+	return p.request(ctx, "cleanup", fqdn, value)
+}
+
+// cloudflareDnsProvider 使用有 Zone.DNS 编辑权限的 API token
+type cloudflareDnsProvider struct {
+	apiUrl string
+	token  string
+}
+
+type cloudflareResult struct {
+	Result []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"result"`
+}
+
+func (p *cloudflareDnsProvider) request(ctx context.Context, method string, path string, body interface{}) (cloudflareResult, error) {
+	var result cloudflareResult
+	err := acmeDnsRequest(ctx, method, p.apiUrl+path, map[string]string{"Authorization": "Bearer " + p.token}, body, &result)
+	return result, err
+}
+
+// zoneId 从完整域名开始逐级向上查找所在的 zone
+func (p *cloudflareDnsProvider) zoneId(ctx context.Context, fqdn string) (string, error) {
+	labels := strings.Split(strings.TrimSuffix(fqdn, "."), ".")
+	for i := 0; i < len(labels)-1; i++ {
+		name := strings.Join(labels[i:], ".")
+		result, err := p.request(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(name), nil)
+		if err != nil {
+			return "", err
+		}
+		if len(result.Result) > 0 {
+			return result.Result[0].Id, nil
+		}
+	}
+	return "", fmt.Errorf("cloudflare zone for %s is not found", fqdn)
+}
+
+func (p *cloudflareDnsProvider) Present(ctx context.Context, fqdn string, value string) error {
+	zoneId, err := p.zoneId(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	_, err = p.request(ctx, http.MethodPost, fmt.Sprintf("/zones/%s/dns_records", zoneId), map[string]interface{}{
+		"type":    "TXT",
+		"name":    strings.TrimSuffix(fqdn, "."),
+		"content": value,
+		"ttl":     120,
+	})
+	return err
+}
+
+func (p *cloudflareDnsProvider) CleanUp(ctx context.Context, fqdn string, value string) error {
+	zoneId, err := p.zoneId(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"type": {"TXT"}, "name": {strings.TrimSuffix(fqdn, ".")}, "content": {value}}
+	result, err := p.request(ctx, http.MethodGet, fmt.Sprintf("/zones/%s/dns_records?%s", zoneId, query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	for _, item := range result.Result {
+		if _, err = p.request(ctx, http.MethodDelete, fmt.Sprintf("/zones/%s/dns_records/%s", zoneId, item.Id), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"golang.org/x/crypto/acme"
+	"h-ui/model/constant"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAcmeServer 最小的 RFC 8555 服务端，所有域名都解析到 127.0.0.1，不校验 JWS 签名
+type testAcmeServer struct {
+	*httptest.Server
+	accountKey crypto.Signer
+	httpPort   int
+	tlsPort    int
+	caKey      *ecdsa.PrivateKey
+	caCert     *x509.Certificate
+
+	lock        sync.Mutex
+	domains     []string
+	authzStatus map[int]string
+	records     map[string]string // dns-01 webhook 写入的 TXT 记录
+	cert        []byte
+}
+
+func newTestAcmeServer(t *testing.T, accountKey crypto.Signer) *testAcmeServer {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+	s := &testAcmeServer{
+		accountKey:  accountKey,
+		caKey:       caKey,
+		caCert:      caCert,
+		authzStatus: map[int]string{},
+		records:     map[string]string{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testAcmeServer) keyAuth(token string) string {
+	thumbprint, _ := acme.JWKThumbprint(s.accountKey.Public())
+	return token + "." + thumbprint
+}
+
+func (s *testAcmeServer) authz(index int) map[string]interface{} {
+	status := s.authzStatus[index]
+	if status == "" {
+		status = "pending"
+	}
+	var challenges []map[string]string
+	for _, item := range []string{constant.AcmeChallengeHttp01, constant.AcmeChallengeTlsAlpn01, constant.AcmeChallengeDns01} {
+		challenges = append(challenges, map[string]string{
+			"type":   item,
+			"url":    fmt.Sprintf("%s/chal/%d/%s", s.URL, index, item),
+			"token":  fmt.Sprintf("token%d", index),
+			"status": status,
+		})
+	}
+	return map[string]interface{}{
+		"identifier": map[string]string{"type": "dns", "value": strings.TrimPrefix(s.domains[index], "*.")},
+		"status":     status,
+		"challenges": challenges,
+	}
+}
+
+func (s *testAcmeServer) order() map[string]interface{} {
+	status := "ready"
+	var authorizations []string
+	for index := range s.domains {
+		authorizations = append(authorizations, fmt.Sprintf("%s/authz/%d", s.URL, index))
+		if s.authzStatus[index] != "valid" {
+			status = "pending"
+		}
+	}
+	order := map[string]interface{}{
+		"status":         status,
+		"authorizations": authorizations,
+		"finalize":       s.URL + "/finalize",
+	}
+	if s.cert != nil {
+		order["status"] = "valid"
+		order["certificate"] = s.URL + "/cert"
+	}
+	return order
+}
+
+// validate 按挑战类型检查客户端的响应
+func (s *testAcmeServer) validate(index int, challenge string) bool {
+	domain := strings.TrimPrefix(s.domains[index], "*.")
+	keyAuth := s.keyAuth(fmt.Sprintf("token%d", index))
+	switch challenge {
+	case constant.AcmeChallengeHttp01:
+		response, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/.well-known/acme-challenge/token%d", s.httpPort, index))
+		if err != nil {
+			return false
+		}
+		defer response.Body.Close()
+		content, _ := io.ReadAll(response.Body)
+		return string(content) == keyAuth
+	case constant.AcmeChallengeDns01:
+		sum := sha256.Sum256([]byte(keyAuth))
+		return s.records["_acme-challenge."+domain+"."] == base64.RawURLEncoding.EncodeToString(sum[:])
+	case constant.AcmeChallengeTlsAlpn01:
+		conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", s.tlsPort), &tls.Config{
+			ServerName:         domain,
+			NextProtos:         []string{acme.ALPNProto},
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		sum := sha256.Sum256([]byte(keyAuth))
+		expect, _ := asn1.Marshal(sum[:])
+		for _, extension := range conn.ConnectionState().PeerCertificates[0].Extensions {
+			if extension.Id.Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}) {
+				return string(extension.Value) == string(expect)
+			}
+		}
+	}
+	return false
+}
+
+func (s *testAcmeServer) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce%d", time.Now().UnixNano()))
+	var payload []byte
+	if r.Method == http.MethodPost && r.URL.Path != "/dns" {
+		var jws struct {
+			Payload string `json:"payload"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&jws)
+		payload, _ = base64.RawURLEncoding.DecodeString(jws.Payload)
+	}
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var index int
+	var challenge string
+	switch {
+	case r.URL.Path == "/dir":
+		reply(http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/nonce",
+			"newAccount": s.URL + "/account",
+			"newOrder":   s.URL + "/order",
+		})
+	case r.URL.Path == "/nonce":
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == "/account":
+		w.Header().Set("Location", s.URL+"/account/1")
+		reply(http.StatusCreated, map[string]string{"status": "valid"})
+	case r.URL.Path == "/order":
+		var request struct {
+			Identifiers []struct{ Value string }
+		}
+		_ = json.Unmarshal(payload, &request)
+		s.domains = nil
+		for _, item := range request.Identifiers {
+			s.domains = append(s.domains, item.Value)
+		}
+		w.Header().Set("Location", s.URL+"/order/1")
+		reply(http.StatusCreated, s.order())
+	case r.URL.Path == "/order/1":
+		w.Header().Set("Location", s.URL+"/order/1")
+		reply(http.StatusOK, s.order())
+	case r.URL.Path == "/dns":
+		var record struct{ Action, Fqdn, Value string }
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		if record.Action == "present" {
+			s.records[record.Fqdn] = record.Value
+		} else {
+			delete(s.records, record.Fqdn)
+		}
+	default:
+		if _, err := fmt.Sscanf(r.URL.Path, "/authz/%d", &index); err == nil {
+			reply(http.StatusOK, s.authz(index))
+			return
+		}
+		if _, err := fmt.Sscanf(strings.Replace(r.URL.Path, "/", " ", -1), " chal %d %s", &index, &challenge); err == nil {
+			s.authzStatus[index] = "invalid"
+			if s.validate(index, challenge) {
+				s.authzStatus[index] = "valid"
+			}
+			reply(http.StatusOK, s.authz(index)["challenges"].([]map[string]string)[0])
+			return
+		}
+		if r.URL.Path == "/finalize" {
+			var request struct{ Csr string }
+			_ = json.Unmarshal(payload, &request)
+			der, _ := base64.RawURLEncoding.DecodeString(request.Csr)
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil {
+				reply(http.StatusBadRequest, map[string]string{"type": "urn:ietf:params:acme:error:badCSR"})
+				return
+			}
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(2),
+				Subject:      csr.Subject,
+				DNSNames:     csr.DNSNames,
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}
+			s.cert, _ = x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+			w.Header().Set("Location", s.URL+"/order/1")
+			reply(http.StatusOK, s.order())
+			return
+		}
+		if r.URL.Path == "/cert" {
+			w.Header().Set("Content-Type", "application/pem-certificate-chain")
+			_ = pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: s.cert})
+			_ = pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})
+			return
+		}
+		http.NotFound(w, r)
+	}
+}
+
+func testFreePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestObtainPanelCert(t *testing.T) {
+	panelAcmeDnsWait = 0
+	accountKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for _, challenge := range []string{constant.AcmeChallengeHttp01, constant.AcmeChallengeTlsAlpn01, constant.AcmeChallengeDns01} {
+		t.Run(challenge, func(t *testing.T) {
+			server := newTestAcmeServer(t, accountKey)
+			server.httpPort = testFreePort(t)
+			server.tlsPort = testFreePort(t)
+			domains := []string{"panel.example.com", "www.example.com"}
+			if challenge == constant.AcmeChallengeDns01 {
+				domains = append(domains, "*.example.com")
+			}
+			settings := panelAcmeSettings{
+				enable:      true,
+				domains:     domains,
+				ca:          server.URL + "/dir",
+				challenge:   challenge,
+				httpPort:    server.httpPort,
+				tlsPort:     server.tlsPort,
+				dnsProvider: constant.AcmeDnsProviderWebhook,
+				dnsConfig:   server.URL + "/dns",
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			certPEM, keyPEM, err := obtainPanelCert(ctx, settings, accountKey)
+			if err != nil {
+				t.Fatalf("obtain cert err: %v", err)
+			}
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatalf("key pair err: %v", err)
+			}
+			if len(cert.Certificate) != 2 {
+				t.Fatalf("chain length = %d", len(cert.Certificate))
+			}
+			leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+			if panelCertNeedRenew(leaf, domains, time.Now()) {
+				t.Fatalf("issued cert %v should not need renew", leaf.DNSNames)
+			}
+			if len(server.records) != 0 {
+				t.Fatalf("dns records should be cleaned up: %v", server.records)
+			}
+		})
+	}
+}
+
+func TestPanelCertNeedRenew(t *testing.T) {
+	now := time.Now()
+	issuer := &x509.Certificate{RawSubject: []byte("ca"), RawIssuer: []byte("ca")}
+	leaf := func(notAfter time.Time, domains ...string) *x509.Certificate {
+		return &x509.Certificate{RawSubject: []byte("leaf"), RawIssuer: issuer.RawSubject, NotAfter: notAfter, DNSNames: domains}
+	}
+	tests := []struct {
+		cert    *x509.Certificate
+		domains []string
+		want    bool
+	}{
+		{nil, []string{"a.com"}, true},
+		{issuer, nil, true},
+		{leaf(now.Add(60*24*time.Hour), "b.com", "a.com"), []string{"a.com", "b.com"}, false},
+		{leaf(now.Add(10*24*time.Hour), "a.com"), []string{"a.com"}, true},
+		{leaf(now.Add(60*24*time.Hour), "a.com"), []string{"a.com", "b.com"}, true},
+	}
+	for i, item := range tests {
+		if got := panelCertNeedRenew(item.cert, item.domains, now); got != item.want {
+			t.Fatalf("case %d: got %v, want %v", i, got, item.want)
+		}
+	}
+}
+
+func TestParsePanelAcmeSettings(t *testing.T) {
+	base := map[string]string{
+		constant.PanelAcmeEnable:    "1",
+		constant.PanelAcmeDomains:   "Panel.Example.com, panel.example.com",
+		constant.PanelAcmeCa:        "https://acme.example.com/dir",
+		constant.PanelAcmeChallenge: constant.AcmeChallengeHttp01,
+		constant.PanelAcmeHttpPort:  "80",
+		constant.PanelAcmeTlsPort:   "443",
+	}
+	settings, err := parsePanelAcmeSettings(base)
+	if err != nil || len(settings.domains) != 1 || settings.domains[0] != "panel.example.com" {
+		t.Fatalf("unexpected settings: %v %v", settings.domains, err)
+	}
+	invalid := []map[string]string{
+		{constant.PanelAcmeDomains: ""},
+		{constant.PanelAcmeDomains: "bad_domain"},
+		{constant.PanelAcmeDomains: "*.example.com"},
+		{constant.PanelAcmeChallenge: "tls-sni-01"},
+		{constant.PanelAcmeChallenge: constant.AcmeChallengeDns01},
+		{constant.PanelAcmeHttpPort: "0"},
+		{constant.PanelAcmeCa: "ftp://acme"},
+	}
+	for _, item := range invalid {
+		values := map[string]string{}
+		for key, value := range base {
+			values[key] = value
+		}
+		for key, value := range item {
+			values[key] = value
+		}
+		if _, err = parsePanelAcmeSettings(values); err == nil {
+			t.Fatalf("%v should be invalid", item)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var alertMutex sync.Mutex
+
+// alertDefaultTemplates 规则未设置模板时使用
+var alertDefaultTemplates = map[string]string{
+	constant.AlertTypeAccountQuota:  "[time], [username] has used [value]% of the traffic quota, threshold is [threshold]%",
+	constant.AlertTypeAccountExpire: "[time], [username] will expire at [expire]",
+	constant.AlertTypeCpu:           "[time], CPU usage is [value]%, threshold is [threshold]%",
+	constant.AlertTypeMem:           "[time], memory usage is [value]%, threshold is [threshold]%",
+	constant.AlertTypeDisk:          "[time], disk usage is [value]%, threshold is [threshold]%",
+	constant.AlertTypeHysteria2Down: "[time], hysteria2 [subject] is not running",
+	constant.AlertTypeCertExpire:    "[time], certificate [subject] will expire at [expire]",
+}
+
+// alertHit 一条规则命中的对象，subject 用于去重
+type alertHit struct {
+	subject string
+	values  map[string]string
+}
+
+// alertChannel 告警发送渠道
+type alertChannel interface {
+	send(rule entity.AlertRule, hit alertHit, text string) error
+}
+
+var alertChannels = map[string]alertChannel{
+	constant.AlertChannelTelegram: telegramAlertChannel{},
+	constant.AlertChannelWebhook:  webhookAlertChannel{},
+	constant.AlertChannelEmail:    emailAlertChannel{},
+}
+
+// renderAlertTemplate 替换 [time] [rule] [subject] [username] [value] [threshold] [expire] 等占位符
+func renderAlertTemplate(template string, values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for key, value := range values {
+		pairs = append(pairs, fmt.Sprintf("[%s]", key), value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// alertShouldNotify 首次命中立即提醒；持续命中时按 cooldown（秒）重复提醒，cooldown 为 0 不再重复
+func alertShouldNotify(state *entity.AlertState, cooldown int64, now int64) bool {
+	if state == nil {
+		return true
+	}
+	if cooldown <= 0 {
+		return false
+	}
+	return now-*state.LastSentAt >= cooldown*1000
+}
+
+func formatAlertFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// evaluateAlertRule 计算规则当前命中的对象
+func evaluateAlertRule(rule entity.AlertRule, system func() (vo.SystemMonitorVo, error)) ([]alertHit, error) {
+	threshold := *rule.Threshold
+	now := time.Now()
+	switch *rule.Type {
+	case constant.AlertTypeAccountQuota:
+		accounts, err := dao.ListAccount("deleted = 0 and quota > 0 and (download + upload) * 100 >= quota * ?", threshold)
+		if err != nil {
+			return nil, err
+		}
+		var hits []alertHit
+		for _, item := range accounts {
+			percent := float64(*item.Download+*item.Upload) * 100 / float64(*item.Quota)
+			hits = append(hits, alertHit{
+				subject: strconv.FormatInt(*item.Id, 10),
+				values: map[string]string{
+					"username": *item.Username,
+					"value":    fmt.Sprintf("%.1f", percent),
+				},
+			})
+		}
+		return hits, nil
+	case constant.AlertTypeAccountExpire:
+		deadline := now.Add(time.Duration(threshold * float64(24*time.Hour))).UnixMilli()
+		accounts, err := dao.ListAccount("deleted = 0 and expire_time > ? and expire_time <= ?", now.UnixMilli(), deadline)
+		if err != nil {
+			return nil, err
+		}
+		var hits []alertHit
+		for _, item := range accounts {
+			hits = append(hits, alertHit{
+				subject: strconv.FormatInt(*item.Id, 10),
+				values: map[string]string{
+					"username": *item.Username,
+					"expire":   time.UnixMilli(*item.ExpireTime).Format("2006-01-02 15:04:05"),
+				},
+			})
+		}
+		return hits, nil
+	case constant.AlertTypeCpu, constant.AlertTypeMem, constant.AlertTypeDisk:
+		systemMonitorVo, err := system()
+		if err != nil {
+			return nil, err
+		}
+		value := systemMonitorVo.CpuPercent
+		if *rule.Type == constant.AlertTypeMem {
+			value = systemMonitorVo.MemPercent
+		} else if *rule.Type == constant.AlertTypeDisk {
+			value = systemMonitorVo.DiskPercent
+		}
+		if value < threshold {
+			return nil, nil
+		}
+		return []alertHit{{subject: *rule.Type, values: map[string]string{"value": formatAlertFloat(value)}}}, nil
+	case constant.AlertTypeHysteria2Down:
+		var hits []alertHit
+		hysteria2Enable, err := dao.GetConfig("key = ?", constant.Hysteria2Enable)
+		if err != nil {
+			return nil, err
+		}
+		if *hysteria2Enable.Value == "1" && !Hysteria2IsRunning() {
+			hits = append(hits, alertHit{subject: "node1"})
+		}
+		if enabled, err := IsNode2Enabled(); err == nil && enabled && !Hysteria2Node2IsRunning() {
+			hits = append(hits, alertHit{subject: "node2"})
+		}
+		return hits, nil
+	case constant.AlertTypeCertExpire:
+		var hits []alertHit
+		deadline := now.Add(time.Duration(threshold * float64(24*time.Hour)))
+		for _, certPath := range alertCertPaths() {
+			notAfter, err := util.GetCertNotAfter(certPath)
+			if err != nil {
+				logrus.Warnf("alert read cert %s err: %v", certPath, err)
+				continue
+			}
+			if notAfter.Before(deadline) {
+				hits = append(hits, alertHit{
+					subject: certPath,
+					values:  map[string]string{"expire": notAfter.Local().Format("2006-01-02 15:04:05")},
+				})
+			}
+		}
+		return hits, nil
+	}
+	return nil, fmt.Errorf("unknown alert type: %s", *rule.Type)
+}
+
+// alertCertPaths 证书清单中的所有证书
+func alertCertPaths() []string {
+	var certPaths []string
+	certVos, err := ListCert()
+	if err != nil {
+		logrus.Warnf("alert list cert err: %v", err)
+		return nil
+	}
+	for _, item := range certVos {
+		if !util.ArrContain(certPaths, item.CertPath) {
+			certPaths = append(certPaths, item.CertPath)
+		}
+	}
+	return certPaths
+}
+
+// CronAlert 评估所有启用的告警规则
+func CronAlert() {
+	if !alertMutex.TryLock() {
+		return
+	}
+	defer alertMutex.Unlock()
+
+	rules, err := dao.ListAlertRule("enable = 1")
+	if err != nil || len(rules) == 0 {
+		return
+	}
+
+	// 系统指标只采集一次
+	var systemMonitorVo *vo.SystemMonitorVo
+	system := func() (vo.SystemMonitorVo, error) {
+		if systemMonitorVo == nil {
+			monitorVo, err := MonitorSystem()
+			if err != nil {
+				return monitorVo, err
+			}
+			systemMonitorVo = &monitorVo
+		}
+		return *systemMonitorVo, nil
+	}
+
+	for _, rule := range rules {
+		hits, err := evaluateAlertRule(rule, system)
+		if err != nil {
+			logrus.Errorf("evaluate alert rule %d err: %v", *rule.Id, err)
+			continue
+		}
+		handleAlertHits(rule, hits)
+	}
+}
+
+func handleAlertHits(rule entity.AlertRule, hits []alertHit) {
+	states, err := dao.ListAlertState("rule_id = ?", *rule.Id)
+	if err != nil {
+		return
+	}
+	stateMap := make(map[string]*entity.AlertState, len(states))
+	for i := range states {
+		stateMap[*states[i].Subject] = &states[i]
+	}
+
+	now := time.Now().UnixMilli()
+	for _, hit := range hits {
+		state := stateMap[hit.subject]
+		delete(stateMap, hit.subject)
+		if !alertShouldNotify(state, *rule.Cooldown, now) {
+			continue
+		}
+		// 所有渠道都发送失败时不记录，下次检查时重试
+		text, sent, _ := sendAlert(rule, hit)
+		if sent == 0 {
+			continue
+		}
+		if state == nil {
+			_, _ = dao.SaveAlertState(entity.AlertState{
+				RuleId:     rule.Id,
+				Subject:    &hit.subject,
+				Message:    &text,
+				LastSentAt: &now,
+			})
+		} else {
+			_ = dao.UpdateAlertState([]int64{*state.Id}, map[string]interface{}{"message": text, "last_sent_at": now})
+		}
+	}
+
+	// 不再命中的对象视为恢复，下次命中时重新提醒
+	var resolved []int64
+	for _, state := range stateMap {
+		resolved = append(resolved, *state.Id)
+	}
+	if len(resolved) > 0 {
+		_ = dao.DeleteAlertState(resolved)
+	}
+}
+
+// sendAlert 渲染模板并通过规则配置的渠道发送，返回发送的内容和发送成功的渠道数
+func sendAlert(rule entity.AlertRule, hit alertHit) (string, int, error) {
+	values := map[string]string{
+		"time":      time.Now().Format("2006-01-02 15:04:05"),
+		"rule":      *rule.Name,
+		"subject":   hit.subject,
+		"threshold": formatAlertFloat(*rule.Threshold),
+	}
+	for key, value := range hit.values {
+		values[key] = value
+	}
+	template := *rule.Template
+	if template == "" {
+		template = alertDefaultTemplates[*rule.Type]
+	}
+	text := renderAlertTemplate(template, values)
+	var errs []error
+	sent := 0
+	for _, name := range strings.Split(*rule.Channels, ",") {
+		channel, exist := alertChannels[name]
+		if !exist {
+			continue
+		}
+		if err := channel.send(rule, hit, text); err != nil {
+			logrus.Errorf("send alert rule %d via %s err: %v", *rule.Id, name, err)
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			continue
+		}
+		sent++
+	}
+	return text, sent, errors.Join(errs...)
+}
+
+type telegramAlertChannel struct{}
+
+func (telegramAlertChannel) send(rule entity.AlertRule, hit alertHit, text string) error {
+	return sendTelegramNotify(constant.TelegramNotifyAlert, fmt.Sprintf("【H UI】\n%s", text))
+}
+
+type webhookAlertChannel struct{}
+
+func (webhookAlertChannel) send(rule entity.AlertRule, hit alertHit, text string) error {
+	EmitWebhookEvent(constant.WebhookEventAlert, map[string]interface{}{
+		"ruleId":  *rule.Id,
+		"rule":    *rule.Name,
+		"type":    *rule.Type,
+		"subject": hit.subject,
+		"message": text,
+	})
+	return nil
+}
+
+type emailAlertChannel struct{}
+
+func (emailAlertChannel) send(rule entity.AlertRule, hit alertHit, text string) error {
+	configs, err := dao.ListConfig("key in ?", []string{
+		constant.SmtpHost,
+		constant.SmtpPort,
+		constant.SmtpUsername,
+		constant.SmtpPassword,
+		constant.SmtpFrom,
+		constant.SmtpTo})
+	if err != nil {
+		return err
+	}
+	values := make(map[string]string, len(configs))
+	for _, item := range configs {
+		values[*item.Key] = *item.Value
+	}
+	if values[constant.SmtpHost] == "" || values[constant.SmtpFrom] == "" || values[constant.SmtpTo] == "" {
+		return errors.New("smtp not configured")
+	}
+	port, err := strconv.ParseInt(values[constant.SmtpPort], 10, 64)
+	if err != nil {
+		return fmt.Errorf("smtp port: %s is invalid", values[constant.SmtpPort])
+	}
+	var to []string
+	for _, item := range strings.Split(values[constant.SmtpTo], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			to = append(to, item)
+		}
+	}
+	return util.SendMail(values[constant.SmtpHost], port, values[constant.SmtpUsername], values[constant.SmtpPassword],
+		values[constant.SmtpFrom], to, fmt.Sprintf("[H UI] %s", *rule.Name), text)
+}
+
+func SaveAlertRule(alertRuleSaveDto dto.AlertRuleSaveDto) error {
+	channels := strings.Join(alertRuleSaveDto.Channels, ",")
+	template := ""
+	if alertRuleSaveDto.Template != nil {
+		template = *alertRuleSaveDto.Template
+	}
+	_, err := dao.SaveAlertRule(entity.AlertRule{
+		Name:      alertRuleSaveDto.Name,
+		Type:      alertRuleSaveDto.Type,
+		Threshold: alertRuleSaveDto.Threshold,
+		Channels:  &channels,
+		Template:  &template,
+		Cooldown:  alertRuleSaveDto.Cooldown,
+		Enable:    alertRuleSaveDto.Enable,
+	})
+	return err
+}
+
+func UpdateAlertRule(alertRuleUpdateDto dto.AlertRuleUpdateDto) error {
+	if _, err := dao.GetAlertRule("id = ?", *alertRuleUpdateDto.Id); err != nil {
+		return err
+	}
+	updates := map[string]interface{}{}
+	if alertRuleUpdateDto.Name != nil {
+		updates["name"] = *alertRuleUpdateDto.Name
+	}
+	if alertRuleUpdateDto.Threshold != nil {
+		updates["threshold"] = *alertRuleUpdateDto.Threshold
+	}
+	if len(alertRuleUpdateDto.Channels) > 0 {
+		updates["channels"] = strings.Join(alertRuleUpdateDto.Channels, ",")
+	}
+	if alertRuleUpdateDto.Template != nil {
+		updates["template"] = *alertRuleUpdateDto.Template
+	}
+	if alertRuleUpdateDto.Cooldown != nil {
+		updates["cooldown"] = *alertRuleUpdateDto.Cooldown
+	}
+	if alertRuleUpdateDto.Enable != nil {
+		updates["enable"] = *alertRuleUpdateDto.Enable
+	}
+	return dao.UpdateAlertRule([]int64{*alertRuleUpdateDto.Id}, updates)
+}
+
+func DeleteAlertRule(id int64) error {
+	return dao.DeleteAlertRule([]int64{id})
+}
+
+func ListAlertRule() ([]vo.AlertRuleVo, error) {
+	rules, err := dao.ListAlertRule(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	alertRuleVos := make([]vo.AlertRuleVo, 0, len(rules))
+	for _, item := range rules {
+		alertRuleVos = append(alertRuleVos, vo.AlertRuleVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Name:      *item.Name,
+			Type:      *item.Type,
+			Threshold: *item.Threshold,
+			Channels:  strings.Split(*item.Channels, ","),
+			Template:  *item.Template,
+			Cooldown:  *item.Cooldown,
+			Enable:    *item.Enable,
+		})
+	}
+	return alertRuleVos, nil
+}
+
+// ListAlertState 当前处于告警状态的对象
+func ListAlertState() ([]vo.AlertStateVo, error) {
+	rules, err := dao.ListAlertRule(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	ruleNames := make(map[int64]string, len(rules))
+	for _, item := range rules {
+		ruleNames[*item.Id] = *item.Name
+	}
+	states, err := dao.ListAlertState(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	alertStateVos := make([]vo.AlertStateVo, 0, len(states))
+	for _, item := range states {
+		alertStateVos = append(alertStateVos, vo.AlertStateVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			RuleId:     *item.RuleId,
+			RuleName:   ruleNames[*item.RuleId],
+			Subject:    *item.Subject,
+			Message:    *item.Message,
+			LastSentAt: *item.LastSentAt,
+		})
+	}
+	return alertStateVos, nil
+}
+
+// TestAlertRule 不经过去重直接发送一条测试告警
+func TestAlertRule(id int64) error {
+	rule, err := dao.GetAlertRule("id = ?", id)
+	if err != nil {
+		return err
+	}
+	_, _, err = sendAlert(rule, alertHit{subject: "test", values: map[string]string{"username": "test", "value": "0", "expire": "-"}})
+	return err
+}
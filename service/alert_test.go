@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"testing"
+)
+
+func TestRenderAlertTemplate(t *testing.T) {
+	values := map[string]string{
+		"username":  "alice",
+		"value":     "81.5",
+		"threshold": "80",
+	}
+	tests := []struct {
+		template string
+		expect   string
+	}{
+		{"[username] used [value]% ([threshold]%)", "alice used 81.5% (80%)"},
+		{"[username] [username]", "alice alice"},
+		{"[unknown] stays", "[unknown] stays"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if text := renderAlertTemplate(tt.template, values); text != tt.expect {
+			t.Errorf("renderAlertTemplate(%s) expected %s, got: %s", tt.template, tt.expect, text)
+		}
+	}
+}
+
+func TestAlertShouldNotify(t *testing.T) {
+	var lastSentAt int64 = 1_000_000
+	state := &entity.AlertState{LastSentAt: &lastSentAt}
+	tests := []struct {
+		state    *entity.AlertState
+		cooldown int64
+		now      int64
+		expect   bool
+	}{
+		{nil, 0, lastSentAt, true},
+		{state, 0, lastSentAt + 3600_000, false},
+		{state, 60, lastSentAt + 59_000, false},
+		{state, 60, lastSentAt + 60_000, true},
+	}
+	for _, tt := range tests {
+		if notify := alertShouldNotify(tt.state, tt.cooldown, tt.now); notify != tt.expect {
+			t.Errorf("alertShouldNotify(%v, %d, %d) expected %v, got: %v", tt.state != nil, tt.cooldown, tt.now, tt.expect, notify)
+		}
+	}
+}
+
+type testAlertChannel struct {
+	err  error
+	sent int
+}
+
+func (c *testAlertChannel) send(rule entity.AlertRule, hit alertHit, text string) error {
+	c.sent++
+	return c.err
+}
+
+func TestHandleAlertHitsRetriesFailedDelivery(t *testing.T) {
+	initTestSqlite(t)
+	channel := &testAlertChannel{err: errors.New("smtp unavailable")}
+	previous := alertChannels
+	alertChannels = map[string]alertChannel{"test": channel}
+	t.Cleanup(func() {
+		alertChannels = previous
+	})
+
+	name, ruleType, channels, template := "quota", constant.AlertTypeAccountQuota, "test", "[subject]"
+	var threshold float64 = 80
+	var cooldown int64 = 3600
+	id, err := dao.SaveAlertRule(entity.AlertRule{Name: &name, Type: &ruleType, Threshold: &threshold, Channels: &channels, Template: &template, Cooldown: &cooldown})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule, err := dao.GetAlertRule("id = ?", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := []alertHit{{subject: "alice"}}
+
+	handleAlertHits(rule, hits)
+	if states, _ := dao.ListAlertState("rule_id = ?", id); len(states) != 0 {
+		t.Fatalf("failed delivery should not start the cooldown, got %d states", len(states))
+	}
+	channel.err = nil
+	handleAlertHits(rule, hits)
+	if states, _ := dao.ListAlertState("rule_id = ?", id); len(states) != 1 {
+		t.Fatalf("delivered alert should be recorded, got %d states", len(states))
+	}
+	handleAlertHits(rule, hits)
+	if channel.sent != 2 {
+		t.Fatalf("alert in cooldown should not be resent, sent %d times", channel.sent)
+	}
+}
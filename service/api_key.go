@@ -0,0 +1,189 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyScopesKey     = "apiKeyScopes"
+	apiKeyPrefixLength  = 8
+	apiKeySecretLength  = 32
+	apiKeyTouchInterval = time.Minute // last_used_at 的最小更新间隔
+)
+
+// apiKeyAdminActions 可以修改面板密码的接口，API Key 需要 admin 范围
+var apiKeyAdminActions = []string{"account.importAccount", "account.changePass"}
+
+// SaveApiKey 创建 API Key，明文只在创建时返回
+func SaveApiKey(accountId int64, name string, scopes []string, expireTime int64) (vo.ApiKeySaveVo, error) {
+	prefix, err := util.RandomString(apiKeyPrefixLength)
+	if err != nil {
+		return vo.ApiKeySaveVo{}, errors.New(constant.SysError)
+	}
+	secret, err := util.RandomString(apiKeySecretLength)
+	if err != nil {
+		return vo.ApiKeySaveVo{}, errors.New(constant.SysError)
+	}
+	key := constant.ApiKeyPrefix + prefix + secret
+	keyHash := util.SHA256String(key)
+	scopesStr := strings.Join(scopes, ",")
+	id, err := dao.SaveApiKey(entity.ApiKey{
+		AccountId:  &accountId,
+		Name:       &name,
+		Prefix:     &prefix,
+		KeyHash:    &keyHash,
+		Scopes:     &scopesStr,
+		ExpireTime: &expireTime,
+	})
+	if err != nil {
+		return vo.ApiKeySaveVo{}, err
+	}
+	apiKey, err := dao.GetApiKey("id = ?", id)
+	if err != nil {
+		return vo.ApiKeySaveVo{}, err
+	}
+	return vo.ApiKeySaveVo{
+		ApiKeyVo: toApiKeyVo(apiKey),
+		Key:      key,
+	}, nil
+}
+
+func ListApiKey(accountId int64) ([]vo.ApiKeyVo, error) {
+	apiKeys, err := dao.ListApiKey("account_id = ?", accountId)
+	if err != nil {
+		return nil, err
+	}
+	apiKeyVos := make([]vo.ApiKeyVo, 0, len(apiKeys))
+	for _, item := range apiKeys {
+		apiKeyVos = append(apiKeyVos, toApiKeyVo(item))
+	}
+	return apiKeyVos, nil
+}
+
+func DeleteApiKey(accountId int64, id int64) error {
+	return dao.DeleteApiKey("id = ? and account_id = ?", id, accountId)
+}
+
+func toApiKeyVo(apiKey entity.ApiKey) vo.ApiKeyVo {
+	return vo.ApiKeyVo{
+		BaseVo: vo.BaseVo{
+			Id:         *apiKey.Id,
+			CreateTime: *apiKey.CreateTime,
+		},
+		Name:       *apiKey.Name,
+		Prefix:     constant.ApiKeyPrefix + *apiKey.Prefix,
+		Scopes:     strings.Split(*apiKey.Scopes, ","),
+		ExpireTime: *apiKey.ExpireTime,
+		LastUsedAt: *apiKey.LastUsedAt,
+	}
+}
+
+// ParseApiKey 校验 API Key，返回所属账号和授权范围
+func ParseApiKey(key string) (bo.AccountBo, []string, error) {
+	if !strings.HasPrefix(key, constant.ApiKeyPrefix) ||
+		len(key) != len(constant.ApiKeyPrefix)+apiKeyPrefixLength+apiKeySecretLength {
+		return bo.AccountBo{}, nil, errors.New(constant.IllegalTokenError)
+	}
+	prefix := key[len(constant.ApiKeyPrefix) : len(constant.ApiKeyPrefix)+apiKeyPrefixLength]
+	apiKey, err := dao.GetApiKey("prefix = ?", prefix)
+	if err != nil {
+		return bo.AccountBo{}, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(util.SHA256String(key)), []byte(*apiKey.KeyHash)) != 1 {
+		return bo.AccountBo{}, nil, errors.New(constant.IllegalTokenError)
+	}
+	now := time.Now().UnixMilli()
+	if *apiKey.ExpireTime > 0 && now > *apiKey.ExpireTime {
+		return bo.AccountBo{}, nil, errors.New(constant.TokenExpiredError)
+	}
+	account, err := dao.GetAccount("id = ?", *apiKey.AccountId)
+	if err != nil {
+		return bo.AccountBo{}, nil, errors.New(constant.IllegalTokenError)
+	}
+	if now-*apiKey.LastUsedAt > apiKeyTouchInterval.Milliseconds() {
+		_ = dao.UpdateApiKey([]int64{*apiKey.Id}, map[string]interface{}{"last_used_at": now})
+	}
+	accountBo := bo.AccountBo{
+		Id:       *account.Id,
+		Username: *account.Username,
+		Roles:    []string{*account.Role},
+		Deleted:  *account.Deleted,
+	}
+	return accountBo, strings.Split(*apiKey.Scopes, ","), nil
+}
+
+// SetApiKeyScopes 通过 API Key 认证时保存授权范围
+func SetApiKeyScopes(c *gin.Context, scopes []string) {
+	c.Set(apiKeyScopesKey, scopes)
+}
+
+// GetApiKeyScopes 获取 API Key 授权范围，ok 为 false 表示不是通过 API Key 认证
+func GetApiKeyScopes(c *gin.Context) ([]string, bool) {
+	value, exists := c.Get(apiKeyScopesKey)
+	if !exists {
+		return nil, false
+	}
+	scopes, ok := value.([]string)
+	return scopes, ok
+}
+
+// apiKeyRequiredScope 接口所需的授权范围，空字符串表示不允许通过 API Key 访问
+func apiKeyRequiredScope(group string, method string) string {
+	read := method == http.MethodGet
+	switch group {
+	case "account", "hysteria2", "plan", "voucher":
+		if read {
+			return constant.ScopeAccountsRead
+		}
+		return constant.ScopeAccountsWrite
+	case "monitor":
+		return constant.ScopeTrafficRead
+	case "config", "webhook", "alert", "telegram", "acl", "outbound", "geo", "acme", "cert", "webListener":
+		if read {
+			return constant.ScopeConfigRead
+		}
+		return constant.ScopeConfigWrite
+	case "log":
+		return constant.ScopeLogsRead
+	case "audit":
+		return constant.ScopeAuditRead
+	}
+	return ""
+}
+
+// ApiKeyAllowed 判断授权范围是否允许访问接口，action 形如 account.pageAccount
+func ApiKeyAllowed(scopes []string, action string, method string) bool {
+	group := strings.SplitN(action, ".", 2)[0]
+	required := apiKeyRequiredScope(group, method)
+	if required == "" {
+		return false
+	}
+	if util.ArrContain(apiKeyAdminActions, action) {
+		required = constant.ScopeAdmin
+	}
+	return util.ArrContain(scopes, constant.ScopeAdmin) || util.ArrContain(scopes, required)
+}
+
+// ApiKeyAccountAllowed 没有 admin 范围的 API Key 不能修改管理员账号，也不能修改面板密码
+func ApiKeyAccountAllowed(c *gin.Context, accountId int64, changePass bool) bool {
+	scopes, ok := GetApiKeyScopes(c)
+	if !ok || util.ArrContain(scopes, constant.ScopeAdmin) {
+		return true
+	}
+	if changePass {
+		return false
+	}
+	account, err := dao.GetAccount("id = ?", accountId)
+	return err == nil && *account.Role != "admin"
+}
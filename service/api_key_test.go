@@ -0,0 +1,35 @@
+package service
+
+import (
+	"h-ui/model/constant"
+	"net/http"
+	"testing"
+)
+
+func TestApiKeyAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		action string
+		method string
+		expect bool
+	}{
+		{"read accounts", []string{constant.ScopeAccountsRead}, "account.pageAccount", http.MethodGet, true},
+		{"write without scope", []string{constant.ScopeAccountsRead}, "account.updateAccount", http.MethodPost, false},
+		{"write accounts", []string{constant.ScopeAccountsWrite}, "account.updateAccount", http.MethodPost, true},
+		{"kick needs write", []string{constant.ScopeAccountsRead}, "hysteria2.hysteria2Kick", http.MethodPost, false},
+		{"traffic", []string{constant.ScopeTrafficRead}, "monitor.monitorHysteria2", http.MethodGet, true},
+		{"admin scope", []string{constant.ScopeAdmin}, "config.updateConfigs", http.MethodPost, true},
+		{"api key management is never allowed", []string{constant.ScopeAdmin}, "apiKey.saveApiKey", http.MethodPost, false},
+		{"import needs admin", []string{constant.ScopeAccountsWrite}, "account.importAccount", http.MethodPost, false},
+		{"change pass needs admin", []string{constant.ScopeAccountsWrite}, "account.changePass", http.MethodPost, false},
+		{"admin scope imports", []string{constant.ScopeAdmin}, "account.importAccount", http.MethodPost, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := ApiKeyAllowed(tt.scopes, tt.action, tt.method); allowed != tt.expect {
+				t.Errorf("expected %v, got: %v", tt.expect, allowed)
+			}
+		})
+	}
+}
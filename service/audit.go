@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const auditRetention = 180 * 24 * time.Hour // 审计日志保留时间
+
+// auditConfigKeys 不同操作涉及的配置项
+var auditConfigKeys = map[string][]string{
+	"config.updateHysteria2Config":      {constant.Hysteria2Config},
+	"config.importHysteria2Config":      {constant.Hysteria2Config},
+	"config.updateHysteria2Node2Config": {constant.Hysteria2Node2Config},
+	"config.importNode2Config":          {constant.Hysteria2Node2Config, constant.Hysteria2Socks5Addr, constant.Hysteria2Socks5User, constant.Hysteria2Socks5Pass},
+	"config.updateSocks5Config":         {constant.Hysteria2Socks5Addr, constant.Hysteria2Socks5User, constant.Hysteria2Socks5Pass},
+	"config.toggleNode2":                {constant.Hysteria2Node2Enable, constant.Hysteria2Node2Remark},
+}
+
+func SaveAudit(audit entity.Audit) {
+	if _, err := dao.SaveAudit(audit); err != nil {
+		logrus.Errorf("save audit err: %v", err)
+	}
+}
+
+// CronCleanAudit 清理过期的审计日志
+func CronCleanAudit() {
+	_ = dao.DeleteAuditBefore(time.Now().Add(-auditRetention))
+}
+
+// AuditLogin 记录登录相关的审计日志
+func AuditLogin(username string, ip string, result string, message string) {
+	var actorId int64 = 0
+	action := constant.AuditActionLogin
+	targetIds := ""
+	if account, err := dao.GetAccount("username = ?", username); err == nil {
+		actorId = *account.Id
+		targetIds = strconv.FormatInt(*account.Id, 10)
+	}
+	SaveAudit(entity.Audit{
+		ActorId:   &actorId,
+		Actor:     &username,
+		Action:    &action,
+		TargetIds: &targetIds,
+		Ip:        &ip,
+		Result:    &result,
+		Message:   &message,
+	})
+}
+
+// AuditSnapshot 生成操作对象的快照，返回快照内容和实际涉及的账号 id
+func AuditSnapshot(action string, target bo.AuditTarget) (string, []int64) {
+	if strings.HasPrefix(action, "account.") || strings.HasPrefix(action, "hysteria2.") {
+		return auditAccountSnapshot(target)
+	}
+	keys := target.ConfigKeys
+	if action != "config.updateConfigs" {
+		keys = auditConfigKeys[action]
+	}
+	if len(keys) == 0 {
+		return "", target.Ids
+	}
+	return auditConfigSnapshot(keys), target.Ids
+}
+
+func auditAccountSnapshot(target bo.AuditTarget) (string, []int64) {
+	var accounts []entity.Account
+	var err error
+	if len(target.Ids) > 0 {
+		accounts, err = dao.ListAccount("id in ?", target.Ids)
+	} else if target.Username != "" {
+		accounts, err = dao.ListAccount("username = ?", target.Username)
+	} else {
+		return "", nil
+	}
+	if err != nil || len(accounts) == 0 {
+		return "", target.Ids
+	}
+	var ids []int64
+	var accountVos []vo.AccountVo
+	for _, item := range accounts {
+		ids = append(ids, *item.Id)
+		accountVos = append(accountVos, toAccountVo(item))
+	}
+	return auditMarshal(accountVos), ids
+}
+
+func auditConfigSnapshot(keys []string) string {
+	configs, err := dao.ListConfig("key in ?", keys)
+	if err != nil {
+		return ""
+	}
+	values := make(map[string]interface{}, len(configs))
+	for _, item := range configs {
+		values[*item.Key] = redactConfigValue(*item.Key, *item.Value)
+	}
+	return auditMarshal(values)
+}
+
+func auditMarshal(value interface{}) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		logrus.Errorf("audit marshal err: %v", err)
+		return ""
+	}
+	return string(content)
+}
+
+func PageAudit(auditPageDto dto.AuditPageDto) ([]entity.Audit, int64, error) {
+	return dao.PageAudit(auditPageDto)
+}
+
+func ListAudit(auditQueryDto dto.AuditQueryDto) ([]entity.Audit, error) {
+	return dao.ListAudit(auditQueryDto)
+}
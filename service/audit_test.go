@@ -0,0 +1,97 @@
+package service
+
+import (
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditConfigSnapshotRedactsSecrets(t *testing.T) {
+	initTestSqlite(t)
+	if err := dao.UpdateConfig([]string{constant.Hysteria2Socks5Pass}, map[string]interface{}{"value": "socks5-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dao.UpdateConfig([]string{constant.Hysteria2Socks5User}, map[string]interface{}{"value": "socks5-user"}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, _ := AuditSnapshot("config.updateSocks5Config", bo.AuditTarget{})
+	if strings.Contains(snapshot, "socks5-secret") || !strings.Contains(snapshot, ConfigRedacted) {
+		t.Fatalf("secret not redacted: %s", snapshot)
+	}
+	if !strings.Contains(snapshot, "socks5-user") {
+		t.Fatalf("non secret config missing: %s", snapshot)
+	}
+}
+
+func TestAuditAccountSnapshot(t *testing.T) {
+	initTestSqlite(t)
+
+	before, ids := AuditSnapshot("account.updateAccount", bo.AuditTarget{Username: "sysadmin"})
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("unexpected ids: %v", ids)
+	}
+	if err := dao.UpdateAccount([]int64{1}, map[string]interface{}{"quota": 1024}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := AuditSnapshot("account.updateAccount", bo.AuditTarget{Ids: []int64{1}})
+	if before == after || !strings.Contains(after, `"quota":1024`) {
+		t.Fatalf("unexpected snapshots, before: %s after: %s", before, after)
+	}
+	if strings.Contains(after, `"pass"`) || strings.Contains(after, `"conPass"`) {
+		t.Fatalf("snapshot leaks password: %s", after)
+	}
+}
+
+func TestLoginLockoutAuditedOnce(t *testing.T) {
+	initTestSqlite(t)
+	previous := defaultLoginGuard
+	defaultLoginGuard = newLoginGuard(time.Now)
+	t.Cleanup(func() {
+		defaultLoginGuard = previous
+	})
+
+	username, ip := "nobody", "192.0.2.1"
+	for i := 0; i < loginUserLockFailures; i++ {
+		LoginFailed(username, ip)
+	}
+	for i := 0; i < 10; i++ {
+		if err := LoginAllowed(username, ip); err == nil {
+			t.Fatal("expected login to be locked")
+		}
+	}
+	audits, err := dao.ListAudit(dto.AuditQueryDto{Actor: &username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audits) != 1 {
+		t.Fatalf("expected one audit row per lockout, got %d", len(audits))
+	}
+}
+
+func TestCronCleanAudit(t *testing.T) {
+	initTestSqlite(t)
+	old := time.Now().Add(-auditRetention - 24*time.Hour)
+	for _, actor := range []string{"old", "new"} {
+		audit := entity.Audit{Actor: &actor}
+		if actor == "old" {
+			audit.CreateTime = &old
+		}
+		if _, err := dao.SaveAudit(audit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	CronCleanAudit()
+	audits, err := dao.ListAudit(dto.AuditQueryDto{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audits) != 1 || *audits[0].Actor != "new" {
+		t.Fatalf("expected only the recent audit row to be kept, got %d", len(audits))
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"fmt"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// certSource 一处使用证书的位置，names 为客户端校验的域名
+type certSource struct {
+	source   string
+	certPath string
+	keyPath  string
+	names    []string
+}
+
+// publicHost H_UI_PUBLIC_URL 中的域名，订阅链接和面板通过它访问
+func publicHost() string {
+	config, err := dao.GetConfig("key = ?", constant.HUIPublicUrl)
+	if err != nil || *config.Value == "" {
+		return ""
+	}
+	publicUrl, err := url.Parse(*config.Value)
+	if err != nil {
+		return ""
+	}
+	return publicUrl.Hostname()
+}
+
+// findAcmeCerts ACME 目录下同名的 .crt 与 .key
+func findAcmeCerts(dir string) [][2]string {
+	var pairs [][2]string
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".crt" {
+			return nil
+		}
+		keyPath := strings.TrimSuffix(path, ".crt") + ".key"
+		if util.Exists(keyPath) {
+			pairs = append(pairs, [2]string{path, keyPath})
+		}
+		return nil
+	})
+	return pairs
+}
+
+func nodeCertSources(name string, config bo.Hysteria2ServerConfig, host string) []certSource {
+	var names []string
+	if sni := nodeSni(config); sni != "" {
+		names = append(names, sni)
+	} else if host != "" {
+		names = append(names, host)
+	}
+	var sources []certSource
+	if config.TLS != nil && config.TLS.Cert != nil && *config.TLS.Cert != "" {
+		keyPath := ""
+		if config.TLS.Key != nil {
+			keyPath = *config.TLS.Key
+		}
+		sources = append(sources, certSource{source: name, certPath: *config.TLS.Cert, keyPath: keyPath, names: names})
+	}
+	if config.ACME != nil && config.ACME.Dir != nil && *config.ACME.Dir != "" {
+		for _, pair := range findAcmeCerts(*config.ACME.Dir) {
+			sources = append(sources, certSource{source: name + " acme", certPath: pair[0], keyPath: pair[1], names: names})
+		}
+	}
+	return sources
+}
+
+// listCertSources 面板证书、节点证书、节点 ACME 目录和上传到 bin 目录的证书
+func listCertSources() ([]certSource, error) {
+	host := publicHost()
+	var hostNames []string
+	if host != "" {
+		hostNames = append(hostNames, host)
+	}
+	var sources []certSource
+	if PanelAcmeEnabled() {
+		sources = append(sources, certSource{source: "panel acme", certPath: constant.PanelAcmeCrtPath, keyPath: constant.PanelAcmeKeyPath, names: hostNames})
+	} else if _, crtPath, keyPath, err := GetPortAndCert(); err == nil && crtPath != "" {
+		sources = append(sources, certSource{source: "panel", certPath: crtPath, keyPath: keyPath, names: hostNames})
+	}
+
+	hysteria2Config, err := GetHysteria2Config()
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, nodeCertSources("node1", hysteria2Config, host)...)
+	node2Config, err := GetHysteria2Node2Config()
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, nodeCertSources("node2", node2Config, host)...)
+
+	// 上传时 bin 目录下只保留一个 .crt 和一个 .key
+	certPaths, _ := filepath.Glob(filepath.Join(constant.BinDir, "*.crt"))
+	keyPaths, _ := filepath.Glob(filepath.Join(constant.BinDir, "*.key"))
+	for _, certPath := range certPaths {
+		keyPath := ""
+		if len(keyPaths) > 0 {
+			keyPath = keyPaths[0]
+		}
+		sources = append(sources, certSource{source: "upload", certPath: certPath, keyPath: keyPath})
+	}
+	return sources, nil
+}
+
+// inspectCert 解析证书并检查私钥和域名
+func inspectCert(certPath string, keyPath string, names []string, now time.Time) vo.CertVo {
+	certVo := vo.CertVo{
+		CertPath:  certPath,
+		KeyPath:   keyPath,
+		Sans:      []string{},
+		Names:     append([]string{}, names...),
+		Uncovered: []string{},
+		Warnings:  []string{},
+	}
+	certs, err := util.ParseCertFile(certPath)
+	if err != nil {
+		certVo.Error = err.Error()
+		return certVo
+	}
+	leaf := certs[0]
+	certVo.Subject = leaf.Subject.String()
+	certVo.Issuer = leaf.Issuer.String()
+	certVo.NotBefore = leaf.NotBefore.UnixMilli()
+	certVo.NotAfter = leaf.NotAfter.UnixMilli()
+	certVo.DaysLeft = int64(leaf.NotAfter.Sub(now) / (24 * time.Hour))
+	certVo.Sans = append(certVo.Sans, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		certVo.Sans = append(certVo.Sans, ip.String())
+	}
+
+	if now.After(leaf.NotAfter) {
+		certVo.Warnings = append(certVo.Warnings, "certificate has expired")
+	} else if now.Before(leaf.NotBefore) {
+		certVo.Warnings = append(certVo.Warnings, "certificate is not yet valid")
+	}
+	if keyPath == "" {
+		certVo.Warnings = append(certVo.Warnings, "private key is not configured")
+	} else if err = util.CheckCertKeyPair(certPath, keyPath); err != nil {
+		certVo.Warnings = append(certVo.Warnings, fmt.Sprintf("private key does not match: %v", err))
+	} else {
+		certVo.KeyMatch = true
+	}
+	for _, name := range names {
+		if err = leaf.VerifyHostname(name); err != nil {
+			certVo.Uncovered = append(certVo.Uncovered, name)
+			if net.ParseIP(name) == nil {
+				certVo.Warnings = append(certVo.Warnings, fmt.Sprintf("sni %s is not covered by the certificate", name))
+			} else {
+				certVo.Warnings = append(certVo.Warnings, fmt.Sprintf("ip %s is not covered by the certificate", name))
+			}
+		}
+	}
+	return certVo
+}
+
+// certSourceKey 证书与私钥都相同时视为同一项
+func certSourceKey(source certSource) string {
+	key := source.certPath
+	if absPath, err := filepath.Abs(source.certPath); err == nil {
+		key = absPath
+	}
+	if source.keyPath == "" {
+		return key
+	}
+	if absPath, err := filepath.Abs(source.keyPath); err == nil {
+		return key + "|" + absPath
+	}
+	return key + "|" + source.keyPath
+}
+
+// ListCert 证书清单，同一对证书和私钥被多处使用时合并
+func ListCert() ([]vo.CertVo, error) {
+	sources, err := listCertSources()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	merged := map[string]*certSource{}
+	sourceNames := map[string][]string{}
+	for _, item := range sources {
+		path := certSourceKey(item)
+		if source, ok := merged[path]; ok {
+			for _, name := range item.names {
+				if !util.ArrContain(source.names, name) {
+					source.names = append(source.names, name)
+				}
+			}
+		} else {
+			source := item
+			source.names = append([]string{}, item.names...)
+			merged[path] = &source
+			paths = append(paths, path)
+		}
+		if !util.ArrContain(sourceNames[path], item.source) {
+			sourceNames[path] = append(sourceNames[path], item.source)
+		}
+	}
+	now := time.Now()
+	certVos := make([]vo.CertVo, 0, len(paths))
+	for _, path := range paths {
+		source := merged[path]
+		names := source.names
+		sort.Strings(names)
+		certVo := inspectCert(source.certPath, source.keyPath, names, now)
+		certVo.Sources = sourceNames[path]
+		certVos = append(certVos, certVo)
+	}
+	return certVos, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestCert(t *testing.T, dir string, name string, notAfter time.Time, dnsNames ...string) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("192.0.2.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	_ = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certPath, keyPath
+}
+
+func TestInspectCert(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	certPath, keyPath := writeTestCert(t, dir, "a", now.Add(10*24*time.Hour+12*time.Hour), "a.example.com", "*.b.example.com")
+	_, otherKeyPath := writeTestCert(t, dir, "other", now.Add(time.Hour), "other.example.com")
+
+	certVo := inspectCert(certPath, keyPath, []string{"a.example.com", "x.b.example.com", "192.0.2.1"}, now)
+	if certVo.Error != "" || !certVo.KeyMatch || len(certVo.Warnings) != 0 {
+		t.Fatalf("unexpected cert: %+v", certVo)
+	}
+	if certVo.DaysLeft != 10 || !reflect.DeepEqual(certVo.Sans, []string{"a.example.com", "*.b.example.com", "192.0.2.1"}) {
+		t.Fatalf("unexpected days left or sans: %d %v", certVo.DaysLeft, certVo.Sans)
+	}
+
+	certVo = inspectCert(certPath, otherKeyPath, []string{"c.example.com"}, now.Add(11*24*time.Hour))
+	if certVo.KeyMatch || !reflect.DeepEqual(certVo.Uncovered, []string{"c.example.com"}) || len(certVo.Warnings) != 3 {
+		t.Fatalf("unexpected cert: %+v", certVo)
+	}
+
+	certVo = inspectCert(filepath.Join(dir, "missing.crt"), "", nil, now)
+	if certVo.Error == "" {
+		t.Fatal("missing cert should report an error")
+	}
+}
+
+func TestFindAcmeCerts(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "certificates", "acme-v02", "a.example.com")
+	_ = os.MkdirAll(sub, 0700)
+	certPath, keyPath := writeTestCert(t, sub, "a.example.com", time.Now().Add(time.Hour), "a.example.com")
+	_ = os.WriteFile(filepath.Join(sub, "lonely.crt"), nil, 0600)
+	if pairs := findAcmeCerts(dir); !reflect.DeepEqual(pairs, [][2]string{{certPath, keyPath}}) {
+		t.Fatalf("unexpected pairs: %v", pairs)
+	}
+}
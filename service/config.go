@@ -9,6 +9,8 @@ import (
 	"h-ui/model/bo"
 	"h-ui/model/constant"
 	"h-ui/model/entity"
+	"h-ui/util"
+	"net"
 	"strconv"
 	"strings"
 )
@@ -165,24 +167,55 @@ func GetPortAndCert() (int64, string, string, error) {
 	return portInt, crtPath, keyPath, nil
 }
 
+// TrustedProxies 信任的反向代理，只有来自这些地址的请求才从 X-Forwarded-For 读取客户端 IP
+func TrustedProxies() ([]string, error) {
+	config, err := dao.GetConfig("key = ?", constant.HUITrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTrustedProxies(*config.Value)
+}
+
+func ParseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		proxy := normalizeFirewallSource(item)
+		if proxy == "" {
+			return nil, fmt.Errorf("trusted proxy: %s is invalid", item)
+		}
+		if !util.ArrContain(proxies, proxy) {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies, nil
+}
+
 func GetAuthHttpUrl() (string, error) {
-	port, crtPath, keyPath, err := GetPortAndCert()
+	host, port, useTls, err := authListenAddr()
 	if err != nil {
 		return "", err
 	}
 	protocol := "http"
-	if crtPath != "" && keyPath != "" {
+	if useTls {
 		protocol = "https"
 	}
 	config, err := dao.GetConfig("key = ?", constant.HUIWebContext)
 	if err != nil {
 		return "", err
 	}
-	webContext := ""
-	if config.Value != nil && *config.Value != "/" && strings.HasPrefix(*config.Value, "/") {
-		webContext = *config.Value
+	return fmt.Sprintf("%s://%s%s%s", protocol, net.JoinHostPort(host, strconv.FormatInt(port, 10)), webContextPath(config.Value), hysteria2AuthPath), nil
+}
+
+// webContextPath 面板访问路径，根路径时为空
+func webContextPath(value *string) string {
+	if value != nil && *value != "/" && strings.HasPrefix(*value, "/") {
+		return *value
 	}
-	return fmt.Sprintf("%s://127.0.0.1:%d%s/hui/hysteria2/auth", protocol, port, webContext), nil
+	return ""
 }
 // GetHysteria2Node2Config 获取第二节点配置
 func GetHysteria2Node2Config() (bo.Hysteria2ServerConfig, error) {
@@ -274,19 +307,17 @@ func GetSocks5Config() (bo.Socks5Config, error) {
 
 // UpdateSocks5Config 更新SOCKS5配置
 func UpdateSocks5Config(socks5Config bo.Socks5Config) error {
-	updates := map[string]interface{}{
+	values := map[string]string{
 		constant.Hysteria2Socks5Addr: socks5Config.Addr,
 		constant.Hysteria2Socks5User: socks5Config.Username,
 		constant.Hysteria2Socks5Pass: socks5Config.Password,
 	}
-	
-	keys := []string{
-		constant.Hysteria2Socks5Addr,
-		constant.Hysteria2Socks5User,
-		constant.Hysteria2Socks5Pass,
+	for key, value := range values {
+		if err := dao.UpdateConfig([]string{key}, map[string]interface{}{"value": value}); err != nil {
+			return err
+		}
 	}
-	
-	return dao.UpdateConfig(keys, updates)
+	return nil
 }
 
 // GenerateNode2ConfigWithSocks5Outbound 生成带SOCKS5出站的第二节点配置
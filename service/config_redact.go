@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/util"
+	"strings"
+)
+
+// ConfigRedacted 审计和导出时替代敏感配置的值，导入时忽略
+const ConfigRedacted = "******"
+
+// redactedConfigKeys 审计快照和导出文件中隐藏的配置
+var redactedConfigKeys = []string{
+	constant.JwtSecret,
+	constant.TelegramToken,
+	constant.TelegramWebhookSecret,
+	constant.Hysteria2Socks5Pass,
+	constant.SmtpPassword,
+	constant.PanelAcmeDnsConfig,
+}
+
+// hysteria2ConfigKeys 以 YAML 保存的节点配置，只隐藏其中的密码
+var hysteria2ConfigKeys = []string{
+	constant.Hysteria2Config,
+	constant.Hysteria2Node2Config,
+}
+
+func redactConfigValue(key string, value string) interface{} {
+	if value != "" && util.ArrContain(redactedConfigKeys, key) {
+		return ConfigRedacted
+	}
+	if util.ArrContain(hysteria2ConfigKeys, key) {
+		var serverConfig bo.Hysteria2ServerConfig
+		if err := yaml.Unmarshal([]byte(value), &serverConfig); err != nil {
+			return value
+		}
+		// Auth 与 TrafficStats.Secret 不参与 json 序列化
+		return RedactHysteria2Config(serverConfig)
+	}
+	return value
+}
+
+// RedactConfigs 隐藏敏感配置的值，节点配置保留 YAML 格式只隐藏其中的密码；无法解析的节点配置整体隐藏
+func RedactConfigs(configs []entity.Config) []entity.Config {
+	redacted := make([]entity.Config, 0, len(configs))
+	for _, item := range configs {
+		if item.Key != nil && item.Value != nil && *item.Value != "" {
+			if util.ArrContain(redactedConfigKeys, *item.Key) {
+				value := ConfigRedacted
+				item.Value = &value
+			} else if util.ArrContain(hysteria2ConfigKeys, *item.Key) {
+				value := ConfigRedacted
+				var serverConfig bo.Hysteria2ServerConfig
+				if err := yaml.Unmarshal([]byte(*item.Value), &serverConfig); err == nil {
+					serverConfig = RedactHysteria2Config(serverConfig)
+					if content, err := yaml.Marshal(&serverConfig); err == nil {
+						value = string(content)
+					}
+				}
+				item.Value = &value
+			}
+		}
+		redacted = append(redacted, item)
+	}
+	return redacted
+}
+
+// RestoreRedactedConfigs 导入时保留被隐藏的敏感配置：整体隐藏的配置跳过，节点配置中隐藏的密码换回当前保存的值
+func RestoreRedactedConfigs(configs []entity.Config) ([]entity.Config, error) {
+	restored := make([]entity.Config, 0, len(configs))
+	for _, item := range configs {
+		if item.Key == nil || item.Value == nil {
+			continue
+		}
+		if *item.Value == ConfigRedacted {
+			continue
+		}
+		if util.ArrContain(hysteria2ConfigKeys, *item.Key) && strings.Contains(*item.Value, ConfigRedacted) {
+			var serverConfig bo.Hysteria2ServerConfig
+			if err := yaml.Unmarshal([]byte(*item.Value), &serverConfig); err != nil {
+				return nil, fmt.Errorf("%s is invalid", *item.Key)
+			}
+			var stored bo.Hysteria2ServerConfig
+			if config, err := dao.GetConfig("key = ?", *item.Key); err == nil && *config.Value != "" {
+				if err = yaml.Unmarshal([]byte(*config.Value), &stored); err != nil {
+					return nil, err
+				}
+			}
+			serverConfig = restoreHysteria2Config(serverConfig, stored)
+			content, err := yaml.Marshal(&serverConfig)
+			if err != nil {
+				return nil, err
+			}
+			value := string(content)
+			item.Value = &value
+		}
+		restored = append(restored, item)
+	}
+	return restored, nil
+}
+
+// RedactHysteria2Config 隐藏 obfs、认证、流量统计接口与 SOCKS5 出站的密码
+func RedactHysteria2Config(serverConfig bo.Hysteria2ServerConfig) bo.Hysteria2ServerConfig {
+	redacted := ConfigRedacted
+	if serverConfig.Auth != nil {
+		auth := *serverConfig.Auth
+		if auth.Password != nil && *auth.Password != "" {
+			auth.Password = &redacted
+		}
+		if len(auth.UserPass) > 0 {
+			auth.UserPass = make(map[string]string, len(serverConfig.Auth.UserPass))
+			for username := range serverConfig.Auth.UserPass {
+				auth.UserPass[username] = ConfigRedacted
+			}
+		}
+		serverConfig.Auth = &auth
+	}
+	if serverConfig.TrafficStats != nil && serverConfig.TrafficStats.Secret != nil && *serverConfig.TrafficStats.Secret != "" {
+		trafficStats := *serverConfig.TrafficStats
+		trafficStats.Secret = &redacted
+		serverConfig.TrafficStats = &trafficStats
+	}
+	if serverConfig.Obfs != nil && serverConfig.Obfs.Salamander != nil && serverConfig.Obfs.Salamander.Password != nil {
+		obfs := *serverConfig.Obfs
+		obfs.Salamander = &bo.ServerConfigObfsSalamander{Password: &redacted}
+		serverConfig.Obfs = &obfs
+	}
+	outbounds := make([]bo.ServerConfigOutboundEntry, 0, len(serverConfig.Outbounds))
+	for _, item := range serverConfig.Outbounds {
+		if item.SOCKS5 != nil && item.SOCKS5.Password != nil && *item.SOCKS5.Password != "" {
+			socks5 := *item.SOCKS5
+			socks5.Password = &redacted
+			item.SOCKS5 = &socks5
+		}
+		outbounds = append(outbounds, item)
+	}
+	if serverConfig.Outbounds != nil {
+		serverConfig.Outbounds = outbounds
+	}
+	return serverConfig
+}
+
+// restoreHysteria2Config 把隐藏的密码换回 stored 中的值，出站按名称对应
+func restoreHysteria2Config(serverConfig bo.Hysteria2ServerConfig, stored bo.Hysteria2ServerConfig) bo.Hysteria2ServerConfig {
+	isRedacted := func(value *string) bool {
+		return value != nil && *value == ConfigRedacted
+	}
+	if serverConfig.Obfs != nil && serverConfig.Obfs.Salamander != nil && isRedacted(serverConfig.Obfs.Salamander.Password) {
+		serverConfig.Obfs.Salamander.Password = nil
+		if stored.Obfs != nil && stored.Obfs.Salamander != nil {
+			serverConfig.Obfs.Salamander.Password = stored.Obfs.Salamander.Password
+		}
+	}
+	if serverConfig.Auth != nil {
+		if isRedacted(serverConfig.Auth.Password) {
+			serverConfig.Auth.Password = nil
+			if stored.Auth != nil {
+				serverConfig.Auth.Password = stored.Auth.Password
+			}
+		}
+		for username, pass := range serverConfig.Auth.UserPass {
+			if pass != ConfigRedacted {
+				continue
+			}
+			if stored.Auth != nil && stored.Auth.UserPass[username] != "" {
+				serverConfig.Auth.UserPass[username] = stored.Auth.UserPass[username]
+			} else {
+				delete(serverConfig.Auth.UserPass, username)
+			}
+		}
+	}
+	if serverConfig.TrafficStats != nil && isRedacted(serverConfig.TrafficStats.Secret) {
+		serverConfig.TrafficStats.Secret = nil
+		if stored.TrafficStats != nil {
+			serverConfig.TrafficStats.Secret = stored.TrafficStats.Secret
+		}
+	}
+	for _, item := range serverConfig.Outbounds {
+		if item.SOCKS5 == nil || !isRedacted(item.SOCKS5.Password) {
+			continue
+		}
+		item.SOCKS5.Password = nil
+		for _, storedItem := range stored.Outbounds {
+			if storedItem.SOCKS5 != nil && storedItem.Name != nil && item.Name != nil && *storedItem.Name == *item.Name {
+				item.SOCKS5.Password = storedItem.SOCKS5.Password
+				break
+			}
+		}
+	}
+	return serverConfig
+}
+
+func RedactSocks5Config(socks5Config bo.Socks5Config) bo.Socks5Config {
+	if socks5Config.Password != "" {
+		socks5Config.Password = ConfigRedacted
+	}
+	return socks5Config
+}
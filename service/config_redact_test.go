@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/util"
+	"strings"
+	"testing"
+)
+
+func TestRedactHysteria2Config(t *testing.T) {
+	obfsPass, socks5Pass, obfsType := "obfs-pass", "socks5-pass", "salamander"
+	serverConfig := bo.Hysteria2ServerConfig{
+		Obfs: &bo.ServerConfigObfs{Type: &obfsType, Salamander: &bo.ServerConfigObfsSalamander{Password: &obfsPass}},
+		Outbounds: []bo.ServerConfigOutboundEntry{
+			{SOCKS5: &bo.ServerConfigOutboundSOCKS5{Password: &socks5Pass}},
+		},
+	}
+	redacted := RedactHysteria2Config(serverConfig)
+	if *redacted.Obfs.Salamander.Password != ConfigRedacted || *redacted.Outbounds[0].SOCKS5.Password != ConfigRedacted {
+		t.Errorf("passwords should be redacted: %+v", redacted)
+	}
+	if *serverConfig.Obfs.Salamander.Password != obfsPass || *serverConfig.Outbounds[0].SOCKS5.Password != socks5Pass {
+		t.Error("the original config should not be modified")
+	}
+}
+
+func TestRedactConfigs(t *testing.T) {
+	token, empty, port := constant.TelegramToken, constant.SmtpPassword, constant.HUIWebPort
+	tokenValue, emptyValue, portValue := "123:abc", "", "8081"
+	configs := RedactConfigs([]entity.Config{
+		{Key: &token, Value: &tokenValue},
+		{Key: &empty, Value: &emptyValue},
+		{Key: &port, Value: &portValue},
+	})
+	if *configs[0].Value != ConfigRedacted || *configs[1].Value != "" || *configs[2].Value != "8081" {
+		t.Errorf("unexpected redaction: %s %s %s", *configs[0].Value, *configs[1].Value, *configs[2].Value)
+	}
+	if tokenValue != "123:abc" {
+		t.Error("the original value should not be modified")
+	}
+}
+
+func TestExportConfigRedactsSecrets(t *testing.T) {
+	initTestSqlite(t)
+	secrets := map[string]string{}
+	configs := make([]entity.Config, 0, len(constant.SecretConfigKeys))
+	for _, key := range constant.SecretConfigKeys {
+		key, value := key, "secret-"+strings.ToLower(key)
+		if util.ArrContain(hysteria2ConfigKeys, key) {
+			value = fmt.Sprintf(`listen: :443
+obfs:
+  type: salamander
+  salamander:
+    password: %[1]s-obfs
+auth:
+  type: password
+  password: %[1]s-auth
+trafficStats:
+  listen: 127.0.0.1:7653
+  secret: %[1]s-stats
+outbounds:
+  - name: upstream
+    type: socks5
+    socks5:
+      addr: 10.0.0.1:1080
+      password: %[1]s-socks5
+`, value)
+			for _, suffix := range []string{"-obfs", "-auth", "-stats", "-socks5"} {
+				secrets[key+suffix] = "secret-" + strings.ToLower(key) + suffix
+			}
+		} else {
+			secrets[key] = value
+		}
+		configs = append(configs, entity.Config{Key: &key, Value: &value})
+	}
+	if err := UpsertConfig(configs); err != nil {
+		t.Fatal(err)
+	}
+
+	// 与导出接口相同，包含全部敏感配置
+	stored, err := dao.ListConfig("key in ?", constant.SecretConfigKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := json.Marshal(RedactConfigs(stored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, secret := range secrets {
+		if strings.Contains(string(exported), secret) {
+			t.Errorf("%s leaked in the export", name)
+		}
+	}
+
+	// 原样导入导出文件后敏感配置保持不变
+	var importConfigs []entity.Config
+	if err = json.Unmarshal(exported, &importConfigs); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreRedactedConfigs(importConfigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = UpsertConfig(restored); err != nil {
+		t.Fatal(err)
+	}
+	node2Config, err := GetHysteria2Node2Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *node2Config.Obfs.Salamander.Password != secrets[constant.Hysteria2Node2Config+"-obfs"] ||
+		*node2Config.Outbounds[0].SOCKS5.Password != secrets[constant.Hysteria2Node2Config+"-socks5"] ||
+		*node2Config.TrafficStats.Secret != secrets[constant.Hysteria2Node2Config+"-stats"] ||
+		*node2Config.Auth.Password != secrets[constant.Hysteria2Node2Config+"-auth"] {
+		t.Errorf("node2 secrets should be kept on import: %+v", node2Config)
+	}
+	jwtSecret, err := dao.GetConfig("key = ?", constant.JwtSecret)
+	if err != nil || *jwtSecret.Value != secrets[constant.JwtSecret] {
+		t.Errorf("redacted config should be kept on import")
+	}
+}
@@ -5,17 +5,23 @@ import (
 	"h-ui/dao"
 	"h-ui/model/bo"
 	"h-ui/model/constant"
+	"h-ui/model/entity"
 	"h-ui/proxy"
 	"h-ui/util"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var trafficMutex sync.Mutex
 var kickMutex sync.Mutex
+var expireCheckAt int64
 
 func CronHandleAccount() {
+	go checkAccountExpired()
+	go CronAlert()
+
 	go func() {
 		hysteriaEnable, err := dao.GetConfig("key = ?", constant.Hysteria2Enable)
 		if err != nil {
@@ -32,11 +38,24 @@ func CronHandleAccount() {
 				return
 			}
 
-			// 保存流量数据
-			go saveAccountTraffic(apiPort, *jwtSecretConfig.Value)
+			apiPorts := []int64{apiPort}
+			for _, aclApiPort := range aclInstanceApiPorts() {
+				apiPorts = append(apiPorts, aclApiPort)
+			}
+
+			// 保存流量数据，依次统计主节点和规则集实例
+			go func() {
+				for _, item := range apiPorts {
+					saveAccountTraffic(item, *jwtSecretConfig.Value)
+				}
+			}()
 
 			// 踢下线
-			go kickAccount(apiPort, *jwtSecretConfig.Value)
+			go func() {
+				for _, item := range apiPorts {
+					kickAccount(item, *jwtSecretConfig.Value)
+				}
+			}()
 		}
 	}()
 }
@@ -55,7 +74,22 @@ func CronResetTraffic() {
 		if err := dao.UpdateAccount(item, map[string]interface{}{"download": 0, "upload": 0}); err != nil {
 			continue
 		}
+		emitAccountWebhookEventByIds(constant.WebhookEventAccountTrafficReset, item)
+	}
+}
+
+// checkAccountExpired 推送上次检查以来到期的账号
+func checkAccountExpired() {
+	now := time.Now().UnixMilli()
+	last := atomic.SwapInt64(&expireCheckAt, now)
+	if last == 0 {
+		return
+	}
+	accounts, err := dao.ListAccount("deleted = 0 and expire_time > ? and expire_time <= ?", last, now)
+	if err != nil {
+		return
 	}
+	EmitAccountWebhookEvent(constant.WebhookEventAccountExpired, accounts)
 }
 
 func saveAccountTraffic(apiPort int64, jwtSecret string) {
@@ -79,20 +113,51 @@ func saveAccountTraffic(apiPort int64, jwtSecret string) {
 		return
 	}
 	if len(users) > 0 {
+		deltas := make(map[string]int64, len(users))
+		usernames := make([]string, 0, len(users))
+		for username, traffic := range users {
+			deltas[username] = int64(float64(traffic.Rx)*hysteria2TrafficTimeFloat) + int64(float64(traffic.Tx)*hysteria2TrafficTimeFloat)
+			usernames = append(usernames, username)
+		}
 		userLists := util.SplitMap(users, 10)
 		var wg sync.WaitGroup
 		for _, userList := range userLists {
 			wg.Add(1)
 			go func(userList map[string]bo.Hysteria2UserTraffic) {
 				defer wg.Done()
+				day := time.Now().Format("2006-01-02")
 				for username, traffic := range userList {
-					if err = dao.UpdateAccountTraffic(username, int64(float64(traffic.Rx)*hysteria2TrafficTimeFloat), int64(float64(traffic.Tx)*hysteria2TrafficTimeFloat)); err != nil {
+					download := int64(float64(traffic.Rx) * hysteria2TrafficTimeFloat)
+					upload := int64(float64(traffic.Tx) * hysteria2TrafficTimeFloat)
+					if err := dao.UpdateAccountTraffic(username, download, upload); err != nil {
 						continue
 					}
+					_ = dao.AddAccountTrafficDaily(username, day, download, upload)
 				}
 			}(userList)
 		}
 		wg.Wait()
+		emitQuotaExhausted(usernames, deltas)
+	}
+}
+
+// accountQuotaExhausted 流量已用完的账号，和认证接口的 quota < 0 or quota > download + upload 正好相反
+const accountQuotaExhausted = "quota >= 0 and quota <= download + upload"
+
+// emitQuotaExhausted 推送本次统计中刚刚用尽流量的账号
+func emitQuotaExhausted(usernames []string, deltas map[string]int64) {
+	for _, usernameList := range util.SplitArr(usernames, 100) {
+		accounts, err := dao.ListAccount("username in ? and "+accountQuotaExhausted, usernameList)
+		if err != nil {
+			continue
+		}
+		var exhausted []entity.Account
+		for _, item := range accounts {
+			if *item.Download+*item.Upload-deltas[*item.Username] < *item.Quota {
+				exhausted = append(exhausted, item)
+			}
+		}
+		EmitAccountWebhookEvent(constant.WebhookEventAccountQuotaExhausted, exhausted)
 	}
 }
 
@@ -120,7 +185,7 @@ func kickAccount(apiPort int64, jwtSecret string) {
 			go func(usernameList []string) {
 				defer wg.Done()
 				now := time.Now().UnixMilli()
-				accounts, err := dao.ListAccount("username in ? and (deleted = 1 or (quota > 0 and quota < download + upload)) or ? > expire_time or ? < kick_util_time", usernameList, now, now)
+				accounts, err := dao.ListAccount("username in ? and (deleted = 1 or ("+accountQuotaExhausted+") or ? > expire_time or ? < kick_util_time)", usernameList, now, now)
 				if err != nil {
 					return
 				}
@@ -133,6 +198,7 @@ func kickAccount(apiPort int64, jwtSecret string) {
 				if err = proxy.NewHysteria2Api(apiPort).KickUsers(kickUsernames, jwtSecret); err != nil {
 					return
 				}
+				EmitAccountWebhookEvent(constant.WebhookEventAccountKicked, accounts)
 			}(usernameList)
 		}
 		wg.Wait()
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"h-ui/dao"
+	"h-ui/model/entity"
+	"testing"
+	"time"
+)
+
+func TestAccountQuotaExhaustedMatchesAuth(t *testing.T) {
+	initTestSqlite(t)
+	expire := time.Now().Add(24 * time.Hour).UnixMilli()
+	var zero int64
+	cases := []struct {
+		username  string
+		quota     int64
+		used      int64
+		exhausted bool
+	}{
+		{"under", 100, 50, false},
+		{"equal", 100, 100, true},
+		{"over", 100, 150, true},
+		{"unlimited", -1, 500, false},
+		{"zero", 0, 0, true},
+	}
+	for _, c := range cases {
+		username, conPass, role := c.username, c.username+".secret", "user"
+		quota, used := c.quota, c.used
+		if _, err := dao.SaveAccount(entity.Account{Username: &username, ConPass: &conPass, Role: &role,
+			Quota: &quota, Download: &used, Upload: &zero, ExpireTime: &expire}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range cases {
+		accounts, err := dao.ListAccount("username = ? and "+accountQuotaExhausted, c.username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, authErr := hysteria2AuthAccount(c.username + ".secret")
+		if (len(accounts) == 1) != c.exhausted || (authErr != nil) != c.exhausted {
+			t.Errorf("%s: exhausted %v, auth err %v, want exhausted %v", c.username, len(accounts) == 1, authErr, c.exhausted)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	firewallAccept  = "accept"
+	firewallDrop    = "drop"
+	firewallComment = "hui_firewall"
+)
+
+var (
+	firewallBackends []firewallBackend
+	firewallDetected bool
+	firewallLock     sync.Mutex
+	// firewallLoopback 白名单模式下本机始终可以访问面板
+	firewallLoopback = []string{"127.0.0.0/8", "::1/128"}
+)
+
+// FirewallRule Source 为空时不限制来源，Action 为 accept 或 drop
+type FirewallRule struct {
+	Protocol string
+	Start    uint16
+	End      uint16
+	Source   string
+	Action   string
+}
+
+func (r FirewallRule) ports(sep string) string {
+	ports := strconv.Itoa(int(r.Start))
+	if r.End != r.Start {
+		ports += sep + strconv.Itoa(int(r.End))
+	}
+	return ports
+}
+
+func (r FirewallRule) ipv6() bool {
+	return strings.Contains(r.Source, ":")
+}
+
+// firewallBackend 防火墙的实现，只管理带有 h-ui 标记或在 h-ui 自己的链/服务中的规则
+type firewallBackend interface {
+	Name() string
+	// Handles 后端是否负责该规则，iptables 和 ip6tables 只处理对应地址族的来源
+	Handles(rule FirewallRule) bool
+	List() ([]FirewallRule, error)
+	// Apply 用 rules 替换 h-ui 的所有规则，accept 规则在 drop 规则之前
+	Apply(rules []FirewallRule) error
+	Clean() error
+}
+
+// detectFirewall 优先使用 firewalld 和 ufw，否则直接操作 iptables 或 nftables
+func detectFirewall() []firewallBackend {
+	if output, err := util.Exec("command -v firewall-cmd >/dev/null && firewall-cmd --state 2>/dev/null || true"); err == nil &&
+		strings.TrimSpace(output) == "running" {
+		return []firewallBackend{&firewalldBackend{}}
+	}
+	if output, err := util.Exec("command -v ufw >/dev/null && ufw status 2>/dev/null || true"); err == nil &&
+		strings.Contains(output, "Status: active") {
+		return []firewallBackend{&ufwBackend{}}
+	}
+	var iptablesBackends []firewallBackend
+	for _, command := range []string{"iptables", "ip6tables"} {
+		if backend := newIptablesFirewallBackend(command); backend != nil {
+			iptablesBackends = append(iptablesBackends, backend)
+		}
+	}
+	// 已有 iptables 规则时继续使用 iptables，避免与 iptables-nft 管理的表冲突
+	if len(iptablesBackends) > 0 && iptablesInputManaged() {
+		return iptablesBackends
+	}
+	if backend, err := newNftFirewallBackend(); err == nil {
+		return []firewallBackend{backend}
+	}
+	return iptablesBackends
+}
+
+func firewallEnabled() (bool, error) {
+	config, err := dao.GetConfig("key = ?", constant.FirewallEnable)
+	if err != nil {
+		return false, err
+	}
+	return *config.Value == "1", nil
+}
+
+// InitFirewall 开启时按节点监听端口、端口跳跃范围和面板端口放行，关闭时清除 h-ui 添加的规则
+func InitFirewall() error {
+	enabled, err := firewallEnabled()
+	if err != nil {
+		return err
+	}
+	firewallLock.Lock()
+	defer firewallLock.Unlock()
+	if !enabled {
+		if !firewallDetected {
+			return nil
+		}
+		return cleanFirewall(firewallBackends)
+	}
+	if !firewallDetected {
+		firewallBackends, firewallDetected = detectFirewall(), true
+	}
+	desired, err := desiredFirewallRules()
+	if err != nil {
+		return err
+	}
+	return applyFirewall(firewallBackends, desired)
+}
+
+// CleanFirewall 删除 h-ui 添加的所有防火墙规则
+func CleanFirewall() error {
+	firewallLock.Lock()
+	defer firewallLock.Unlock()
+	if !firewallDetected {
+		firewallBackends, firewallDetected = detectFirewall(), true
+	}
+	return cleanFirewall(firewallBackends)
+}
+
+// CronFirewall 规则被其他程序清空后重新应用
+func CronFirewall() {
+	if enabled, err := firewallEnabled(); err != nil || !enabled {
+		return
+	}
+	if err := InitFirewall(); err != nil {
+		logrus.Errorf("firewall reconcile err: %v", err)
+	}
+}
+
+func applyFirewall(backends []firewallBackend, desired []FirewallRule) error {
+	if len(backends) == 0 {
+		return errors.New("no supported firewall detected")
+	}
+	for _, backend := range backends {
+		rules := backendFirewallRules(backend, desired)
+		current, err := backend.List()
+		if err != nil {
+			logrus.Errorf("%s list firewall rules err: %v", backend.Name(), err)
+			return fmt.Errorf("%s list firewall rules err", backend.Name())
+		}
+		if add, del := util.DiffArr(current, rules); len(add) == 0 && len(del) == 0 {
+			continue
+		}
+		if err = backend.Apply(rules); err != nil {
+			logrus.Errorf("%s apply firewall rules err: %v", backend.Name(), err)
+			return fmt.Errorf("%s apply firewall rules err", backend.Name())
+		}
+		logrus.Infof("%s firewall rules applied", backend.Name())
+	}
+	return nil
+}
+
+func cleanFirewall(backends []firewallBackend) error {
+	for _, backend := range backends {
+		if err := backend.Clean(); err != nil {
+			logrus.Errorf("%s clean firewall rules err: %v", backend.Name(), err)
+			return fmt.Errorf("%s clean firewall rules err", backend.Name())
+		}
+	}
+	return nil
+}
+
+func backendFirewallRules(backend firewallBackend, rules []FirewallRule) []FirewallRule {
+	var handled []FirewallRule
+	for _, rule := range rules {
+		if backend.Handles(rule) {
+			handled = append(handled, rule)
+		}
+	}
+	return handled
+}
+
+// FirewallStatus 当前和期望的防火墙规则
+func FirewallStatus() (vo.FirewallStatusVo, error) {
+	status := vo.FirewallStatusVo{Backends: []vo.FirewallBackendVo{}}
+	enabled, err := firewallEnabled()
+	if err != nil {
+		return status, err
+	}
+	status.Enable = enabled
+	desired, err := desiredFirewallRules()
+	if err != nil {
+		return status, err
+	}
+	status.Desired = firewallRuleVos(desired)
+
+	firewallLock.Lock()
+	defer firewallLock.Unlock()
+	if !firewallDetected {
+		firewallBackends, firewallDetected = detectFirewall(), true
+	}
+	status.InSync = true
+	for _, backend := range firewallBackends {
+		current, err := backend.List()
+		if err != nil {
+			logrus.Errorf("%s list firewall rules err: %v", backend.Name(), err)
+			return status, fmt.Errorf("%s list firewall rules err", backend.Name())
+		}
+		if !enabled {
+			desired = nil
+		}
+		add, del := util.DiffArr(current, backendFirewallRules(backend, desired))
+		backendVo := vo.FirewallBackendVo{
+			Name:    backend.Name(),
+			Current: firewallRuleVos(current),
+			Missing: firewallRuleVos(add),
+			Extra:   firewallRuleVos(del),
+			InSync:  len(add) == 0 && len(del) == 0,
+		}
+		status.InSync = status.InSync && backendVo.InSync
+		status.Backends = append(status.Backends, backendVo)
+	}
+	if enabled && len(firewallBackends) == 0 {
+		status.InSync = false
+	}
+	return status, nil
+}
+
+// desiredFirewallRules 读取节点和规则集实例的监听端口、端口跳跃范围、面板的监听端口和白名单
+func desiredFirewallRules() ([]FirewallRule, error) {
+	var udpPorts [][2]uint16
+	for _, node := range portHoppingNodes {
+		port, err := nodeListenPort(node.name)
+		if err != nil {
+			return nil, err
+		}
+		if port != 0 {
+			udpPorts = append(udpPorts, [2]uint16{port, port})
+		}
+	}
+	portHoppingRules, _, err := desiredPortHoppingRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range portHoppingRules {
+		udpPorts = append(udpPorts, [2]uint16{rule.Start, rule.End})
+	}
+	aclProfiles, err := dao.ListAclProfile("enable = 1")
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range aclProfiles {
+		udpPorts = append(udpPorts, [2]uint16{uint16(*item.Port), uint16(*item.Port)})
+	}
+
+	webPorts, err := firewallWebPorts()
+	if err != nil {
+		return nil, err
+	}
+	config, err := dao.GetConfig("key = ?", constant.FirewallPanelAllowlist)
+	if err != nil {
+		return nil, err
+	}
+	allowlist, err := ParseFirewallAllowlist(*config.Value)
+	if err != nil {
+		return nil, err
+	}
+	return buildFirewallRules(udpPorts, webPorts, allowlist), nil
+}
+
+// firewallWebPort 面板的 tcp 监听端口，panel 为 true 时开放了面板路由，需要使用白名单
+type firewallWebPort struct {
+	port  uint16
+	panel bool
+}
+
+// firewallWebPorts 面板端口和启用的 tcp 监听的端口，只监听本机地址的不需要放行
+func firewallWebPorts() ([]firewallWebPort, error) {
+	mainRoutes, err := webRoutes()
+	if err != nil {
+		return nil, err
+	}
+	listeners, err := dao.ListWebListener("enable = 1")
+	if err != nil {
+		return nil, err
+	}
+	specs, err := listenerSpecs(mainRoutes, listeners)
+	if err != nil {
+		return nil, err
+	}
+	var webPorts []firewallWebPort
+	for _, spec := range specs {
+		if spec.network != constant.ListenerNetworkTcp || spec.port <= 0 || spec.port > 65535 {
+			continue
+		}
+		if host, _, err := net.SplitHostPort(spec.address); err == nil {
+			if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+				continue
+			}
+		}
+		webPorts = append(webPorts, firewallWebPort{
+			port:  uint16(spec.port),
+			panel: spec.redirectPort == 0 && util.ArrContain(spec.routes, constant.RouteGroupPanel),
+		})
+	}
+	return webPorts, nil
+}
+
+// buildFirewallRules 白名单为空时面板端口对所有来源开放，否则开放面板路由的端口只放行白名单和本机，其余丢弃
+func buildFirewallRules(udpPorts [][2]uint16, webPorts []firewallWebPort, allowlist []string) []FirewallRule {
+	var rules []FirewallRule
+	sort.Slice(udpPorts, func(i, j int) bool {
+		return udpPorts[i][0] < udpPorts[j][0]
+	})
+	for _, ports := range udpPorts {
+		rule := FirewallRule{Protocol: "udp", Start: ports[0], End: ports[1], Action: firewallAccept}
+		if !util.ArrContain(rules, rule) {
+			rules = append(rules, rule)
+		}
+	}
+	sources := append(append([]string{}, firewallLoopback...), allowlist...)
+	for _, webPort := range webPorts {
+		web := FirewallRule{Protocol: "tcp", Start: webPort.port, End: webPort.port, Action: firewallAccept}
+		if !webPort.panel || len(allowlist) == 0 {
+			if !util.ArrContain(rules, web) {
+				rules = append(rules, web)
+			}
+			continue
+		}
+		for _, source := range sources {
+			web.Source = source
+			if !util.ArrContain(rules, web) {
+				rules = append(rules, web)
+			}
+		}
+		rules = append(rules, FirewallRule{Protocol: "tcp", Start: webPort.port, End: webPort.port, Action: firewallDrop})
+	}
+	return rules
+}
+
+// ParseFirewallAllowlist 逗号分隔的 IP 或 CIDR，统一转换为 CIDR
+func ParseFirewallAllowlist(value string) ([]string, error) {
+	var allowlist []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		source := normalizeFirewallSource(item)
+		if source == "" {
+			return nil, fmt.Errorf("panel allowlist: %s is invalid", item)
+		}
+		if !util.ArrContain(allowlist, source) {
+			allowlist = append(allowlist, source)
+		}
+	}
+	return allowlist, nil
+}
+
+// normalizeFirewallSource 1.2.3.4 转换为 1.2.3.4/32，1.2.3.4/24 转换为 1.2.3.0/24，无效时返回空
+func normalizeFirewallSource(source string) string {
+	if !strings.Contains(source, "/") {
+		ip := net.ParseIP(source)
+		if ip == nil {
+			return ""
+		}
+		if ip.To4() != nil {
+			source += "/32"
+		} else {
+			source += "/128"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(source)
+	if err != nil {
+		return ""
+	}
+	return ipNet.String()
+}
+
+// FirewallAllowlistContains 防止修改白名单后把自己挡在面板外
+func FirewallAllowlistContains(allowlist []string, ip string) bool {
+	clientIP := net.ParseIP(ip)
+	if len(allowlist) == 0 || clientIP == nil || clientIP.IsLoopback() {
+		return true
+	}
+	for _, item := range allowlist {
+		if _, ipNet, err := net.ParseCIDR(item); err == nil && ipNet.Contains(clientIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortFirewallRules accept 规则在 drop 规则之前，其余保持原有顺序
+func sortFirewallRules(rules []FirewallRule) []FirewallRule {
+	sorted := append([]FirewallRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Action == firewallAccept && sorted[j].Action != firewallAccept
+	})
+	return sorted
+}
+
+func firewallRuleVos(rules []FirewallRule) []vo.FirewallRuleVo {
+	ruleVos := make([]vo.FirewallRuleVo, 0, len(rules))
+	for _, rule := range rules {
+		ruleVos = append(ruleVos, vo.FirewallRuleVo{
+			Protocol: rule.Protocol,
+			Ports:    rule.ports("-"),
+			Source:   rule.Source,
+			Action:   rule.Action,
+		})
+	}
+	return ruleVos
+}
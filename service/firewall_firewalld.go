@@ -0,0 +1,278 @@
+package service
+
+import (
+	"fmt"
+	"h-ui/util"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	firewalldService      = "h-ui"
+	firewalldPanelService = "h-ui-panel"
+)
+
+var firewalldSourceRegexp = regexp.MustCompile(`source address="([^"]+)"`)
+
+// firewalldBackend 不限来源的端口放在 h-ui 服务中并加入默认区域，白名单通过引用 h-ui-panel 服务的富规则实现。
+// 富规则使用负优先级，先于区域中已有的端口和服务匹配：白名单来源 accept，其余来源 drop
+type firewalldBackend struct {
+}
+
+func (b *firewalldBackend) Name() string {
+	return "firewalld"
+}
+
+func (b *firewalldBackend) Handles(rule FirewallRule) bool {
+	return true
+}
+
+func (b *firewalldBackend) List() ([]FirewallRule, error) {
+	var rules []FirewallRule
+	enabled, err := util.Exec(fmt.Sprintf("firewall-cmd --query-service=%s || true", firewalldService))
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(enabled) == "yes" {
+		ports, err := firewalldServicePorts(firewalldService, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range ports {
+			rule.Action = firewallAccept
+			rules = append(rules, rule)
+		}
+	}
+
+	panelPorts, err := firewalldServicePorts(firewalldPanelService, false)
+	if err != nil || len(panelPorts) == 0 {
+		return rules, err
+	}
+	sources, err := firewalldSources(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range panelPorts {
+		for _, source := range sources {
+			rule := port
+			rule.Source, rule.Action = source, firewallAccept
+			rules = append(rules, rule)
+		}
+		port.Action = firewallDrop
+		rules = append(rules, port)
+	}
+	return rules, nil
+}
+
+func (b *firewalldBackend) Apply(rules []FirewallRule) error {
+	var openPorts, panelPorts []FirewallRule
+	var sources []string
+	for _, rule := range rules {
+		if rule.Action != firewallAccept {
+			continue
+		}
+		port := FirewallRule{Protocol: rule.Protocol, Start: rule.Start, End: rule.End}
+		if rule.Source == "" {
+			openPorts = append(openPorts, port)
+			continue
+		}
+		if !util.ArrContain(panelPorts, port) {
+			panelPorts = append(panelPorts, port)
+		}
+		if !util.ArrContain(sources, rule.Source) {
+			sources = append(sources, rule.Source)
+		}
+	}
+
+	if err := firewalldSyncService(firewalldService, openPorts); err != nil {
+		return err
+	}
+	if err := firewalldSyncService(firewalldPanelService, panelPorts); err != nil {
+		return err
+	}
+	zoneCommand := "--remove-service"
+	if len(openPorts) > 0 {
+		zoneCommand = "--add-service"
+	}
+	if _, err := util.Exec(fmt.Sprintf("firewall-cmd --permanent %s=%s", zoneCommand, firewalldService)); err != nil {
+		return err
+	}
+	if err := firewalldRemoveRichRules(); err != nil {
+		return err
+	}
+	for _, rule := range firewalldRichRules(sources) {
+		if _, err := util.Exec(fmt.Sprintf("firewall-cmd --permanent --add-rich-rule='%s'", rule)); err != nil {
+			return err
+		}
+	}
+	_, err := util.Exec("firewall-cmd --reload")
+	return err
+}
+
+func (b *firewalldBackend) Clean() error {
+	if err := firewalldRemoveRichRules(); err != nil {
+		return err
+	}
+	_, err := util.Exec(fmt.Sprintf("firewall-cmd --permanent --remove-service=%s >/dev/null 2>&1; "+
+		"firewall-cmd --permanent --delete-service=%s >/dev/null 2>&1; "+
+		"firewall-cmd --permanent --delete-service=%s >/dev/null 2>&1; firewall-cmd --reload",
+		firewalldService, firewalldService, firewalldPanelService))
+	return err
+}
+
+// firewalldServicePorts 服务不存在时返回空
+func firewalldServicePorts(service string, permanent bool) ([]FirewallRule, error) {
+	command := fmt.Sprintf("firewall-cmd --info-service=%s 2>/dev/null || true", service)
+	if permanent {
+		command = fmt.Sprintf("firewall-cmd --permanent --service=%s --get-ports 2>/dev/null || true", service)
+	}
+	output, err := util.Exec(command)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !permanent {
+			if !strings.HasPrefix(line, "ports:") {
+				continue
+			}
+			line = strings.TrimPrefix(line, "ports:")
+		}
+		return parseFirewalldPorts(line), nil
+	}
+	return nil, nil
+}
+
+// parseFirewalldPorts 443/udp 30000-40000/udp
+func parseFirewalldPorts(value string) []FirewallRule {
+	var rules []FirewallRule
+	for _, item := range strings.Fields(value) {
+		parts := strings.Split(item, "/")
+		if len(parts) != 2 {
+			continue
+		}
+		bounds := strings.Split(parts[0], "-")
+		start, err := strconv.ParseUint(bounds[0], 10, 16)
+		if err != nil {
+			continue
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.ParseUint(bounds[1], 10, 16); err != nil {
+				continue
+			}
+		}
+		rules = append(rules, FirewallRule{Protocol: parts[1], Start: uint16(start), End: uint16(end)})
+	}
+	return rules
+}
+
+func firewalldSyncService(service string, ports []FirewallRule) error {
+	services, err := util.Exec("firewall-cmd --permanent --get-services")
+	if err != nil {
+		return err
+	}
+	if !util.ArrContain(strings.Fields(services), service) {
+		if _, err = util.Exec(fmt.Sprintf("firewall-cmd --permanent --new-service=%s", service)); err != nil {
+			return err
+		}
+	}
+	current, err := firewalldServicePorts(service, true)
+	if err != nil {
+		return err
+	}
+	add, del := util.DiffArr(current, ports)
+	for _, port := range del {
+		if _, err = util.Exec(fmt.Sprintf("firewall-cmd --permanent --service=%s --remove-port=%s/%s", service, port.ports("-"), port.Protocol)); err != nil {
+			return err
+		}
+	}
+	for _, port := range add {
+		if _, err = util.Exec(fmt.Sprintf("firewall-cmd --permanent --service=%s --add-port=%s/%s", service, port.ports("-"), port.Protocol)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firewalldPanelRichRules 引用 h-ui-panel 服务的富规则
+func firewalldPanelRichRules(permanent bool) ([]string, error) {
+	command := "firewall-cmd --list-rich-rules"
+	if permanent {
+		command = "firewall-cmd --permanent --list-rich-rules"
+	}
+	output, err := util.Exec(command)
+	if err != nil {
+		return nil, err
+	}
+	var rules []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, fmt.Sprintf(`service name="%s"`, firewalldPanelService)) {
+			rules = append(rules, line)
+		}
+	}
+	return rules, nil
+}
+
+// firewalldSources 引用 h-ui-panel 服务的 accept 富规则中的来源
+func firewalldSources(permanent bool) ([]string, error) {
+	rules, err := firewalldPanelRichRules(permanent)
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, line := range rules {
+		if source, ok := parseFirewalldRichRule(line); ok {
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+func firewalldRemoveRichRules() error {
+	rules, err := firewalldPanelRichRules(true)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if _, err = util.Exec(fmt.Sprintf("firewall-cmd --permanent --remove-rich-rule='%s'", rule)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firewalldRichRules 白名单来源的 accept 优先级高于 drop，两者都先于区域中的其他规则
+func firewalldRichRules(sources []string) []string {
+	if len(sources) == 0 {
+		return nil
+	}
+	rules := make([]string, 0, len(sources)+1)
+	for _, source := range sources {
+		rules = append(rules, firewalldRichRule(source))
+	}
+	return append(rules, fmt.Sprintf(`rule priority="-1" service name="%s" drop`, firewalldPanelService))
+}
+
+// firewalldRichRule rule priority="-2" family="ipv4" source address="1.2.3.0/24" service name="h-ui-panel" accept
+func firewalldRichRule(source string) string {
+	family := "ipv4"
+	if strings.Contains(source, ":") {
+		family = "ipv6"
+	}
+	return fmt.Sprintf(`rule priority="-2" family="%s" source address="%s" service name="%s" accept`, family, source, firewalldPanelService)
+}
+
+func parseFirewalldRichRule(line string) (string, bool) {
+	if !strings.Contains(line, fmt.Sprintf(`service name="%s"`, firewalldPanelService)) || !strings.HasSuffix(strings.TrimSpace(line), "accept") {
+		return "", false
+	}
+	match := firewalldSourceRegexp.FindStringSubmatch(line)
+	if len(match) != 2 {
+		return "", false
+	}
+	source := normalizeFirewallSource(match[1])
+	return source, source != ""
+}
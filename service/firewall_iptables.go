@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"h-ui/util"
+	"strconv"
+	"strings"
+)
+
+const iptablesFirewallChain = "HUI_FIREWALL"
+
+// iptablesFirewallBackend 规则放在 HUI_FIREWALL 链中，INPUT 链第一条规则跳转到该链
+type iptablesFirewallBackend struct {
+	command string
+}
+
+func newIptablesFirewallBackend(command string) firewallBackend {
+	if path, err := util.Exec(fmt.Sprintf("command -v %s || true", command)); err != nil || strings.TrimSpace(path) == "" {
+		return nil
+	}
+	return &iptablesFirewallBackend{command: command}
+}
+
+// iptablesInputManaged INPUT 链已有规则或默认策略不是 ACCEPT
+func iptablesInputManaged() bool {
+	output, err := util.Exec("iptables -w -S INPUT 2>/dev/null || true")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "-P INPUT ACCEPT" || strings.Contains(line, "-j "+iptablesFirewallChain) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (b *iptablesFirewallBackend) Name() string {
+	return b.command
+}
+
+func (b *iptablesFirewallBackend) Handles(rule FirewallRule) bool {
+	return rule.Source == "" || rule.ipv6() == (b.command == "ip6tables")
+}
+
+func (b *iptablesFirewallBackend) List() ([]FirewallRule, error) {
+	// 链或跳转规则不存在时视为没有规则
+	output, err := util.Exec(fmt.Sprintf("%s -w -C INPUT -j %s 2>/dev/null && %s -w -S %s 2>/dev/null || true",
+		b.command, iptablesFirewallChain, b.command, iptablesFirewallChain))
+	if err != nil {
+		return nil, err
+	}
+	var rules []FirewallRule
+	for _, line := range strings.Split(output, "\n") {
+		if rule, ok := parseIptablesFirewallRule(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (b *iptablesFirewallBackend) Apply(rules []FirewallRule) error {
+	commands := []string{
+		fmt.Sprintf("%s -w -N %s 2>/dev/null || true", b.command, iptablesFirewallChain),
+		fmt.Sprintf("%s -w -C INPUT -j %s 2>/dev/null || %s -w -I INPUT 1 -j %s", b.command, iptablesFirewallChain, b.command, iptablesFirewallChain),
+		fmt.Sprintf("%s -w -F %s", b.command, iptablesFirewallChain),
+	}
+	for _, rule := range sortFirewallRules(rules) {
+		commands = append(commands, fmt.Sprintf("%s -w -A %s", b.command, iptablesFirewallRuleSpec(rule)))
+	}
+	for _, command := range commands {
+		if _, err := util.Exec(command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *iptablesFirewallBackend) Clean() error {
+	_, err := util.Exec(fmt.Sprintf("while %s -w -D INPUT -j %s 2>/dev/null; do :; done; %s -w -F %s 2>/dev/null; %s -w -X %s 2>/dev/null || true",
+		b.command, iptablesFirewallChain, b.command, iptablesFirewallChain, b.command, iptablesFirewallChain))
+	return err
+}
+
+// iptablesFirewallRuleSpec HUI_FIREWALL -s 1.2.3.0/24 -p tcp --dport 8081 -j ACCEPT
+func iptablesFirewallRuleSpec(rule FirewallRule) string {
+	spec := iptablesFirewallChain
+	if rule.Source != "" {
+		spec += " -s " + rule.Source
+	}
+	target := "ACCEPT"
+	if rule.Action == firewallDrop {
+		target = "DROP"
+	}
+	return fmt.Sprintf("%s -p %s --dport %s -j %s", spec, rule.Protocol, rule.ports(":"), target)
+}
+
+func parseIptablesFirewallRule(line string) (FirewallRule, bool) {
+	var rule FirewallRule
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "-A" || fields[1] != iptablesFirewallChain {
+		return rule, false
+	}
+	for i := 2; i+1 < len(fields); i++ {
+		value := fields[i+1]
+		switch fields[i] {
+		case "-s":
+			rule.Source = normalizeFirewallSource(value)
+		case "-p":
+			rule.Protocol = value
+		case "--dport":
+			bounds := strings.Split(value, ":")
+			start, err := strconv.ParseUint(bounds[0], 10, 16)
+			if err != nil {
+				return rule, false
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseUint(bounds[1], 10, 16); err != nil {
+					return rule, false
+				}
+			}
+			rule.Start, rule.End = uint16(start), uint16(end)
+		case "-j":
+			switch value {
+			case "ACCEPT":
+				rule.Action = firewallAccept
+			case "DROP":
+				rule.Action = firewallDrop
+			}
+		}
+	}
+	return rule, rule.Protocol != "" && rule.Start != 0 && rule.Action != ""
+}
@@ -0,0 +1,353 @@
+//go:build linux
+
+package service
+
+import (
+	"bytes"
+	"errors"
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+	"math/bits"
+	"net"
+	"strconv"
+)
+
+const firewallTable = "hui_firewall"
+
+// nftFirewallBackend 所有规则放在 inet hui_firewall 表中，drop 规则在任何表中都是最终的；
+// 其他表的 input 链可能默认丢弃，所以 accept 规则同时插入到这些链的最前面并带有 hui_firewall 注释
+type nftFirewallBackend struct {
+	table *nftables.Table
+	chain *nftables.Chain
+}
+
+func newNftFirewallBackend() (firewallBackend, error) {
+	conn := &nftables.Conn{}
+	if _, err := conn.ListTablesOfFamily(nftables.TableFamilyINet); err != nil {
+		return nil, err
+	}
+	table := &nftables.Table{Name: firewallTable, Family: nftables.TableFamilyINet}
+	policy := nftables.ChainPolicyAccept
+	return &nftFirewallBackend{
+		table: table,
+		chain: &nftables.Chain{
+			Name:     "input",
+			Table:    table,
+			Type:     nftables.ChainTypeFilter,
+			Hooknum:  nftables.ChainHookInput,
+			Priority: nftables.ChainPriorityRef(-10),
+			Policy:   &policy,
+		},
+	}, nil
+}
+
+func (b *nftFirewallBackend) Name() string {
+	return "nftables"
+}
+
+func (b *nftFirewallBackend) Handles(rule FirewallRule) bool {
+	return true
+}
+
+// List 其他表的 input 链中缺少 accept 规则时，这些 accept 规则不生效，视为不存在
+func (b *nftFirewallBackend) List() ([]FirewallRule, error) {
+	conn := &nftables.Conn{}
+	nftRules, err := conn.GetRules(b.table, b.chain)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var rules []FirewallRule
+	for _, item := range nftRules {
+		if rule, ok := parseNftFirewallExprs(item.Exprs); ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	chains, err := b.foreignChains(conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range chains {
+		foreignRules, err := conn.GetRules(chain.Table, chain)
+		if err != nil {
+			return nil, err
+		}
+		var copies []FirewallRule
+		for _, item := range foreignRules {
+			if !bytes.Equal(item.UserData, nftComment(firewallComment)) {
+				continue
+			}
+			if rule, ok := parseNftFirewallExprs(item.Exprs); ok {
+				copies = append(copies, rule)
+			}
+		}
+		for _, rule := range rules {
+			if rule.Action == firewallAccept && nftFamilyHandles(chain.Table.Family, rule) {
+				if len(copies) == 0 || copies[0] != rule {
+					return nftDropRules(rules), nil
+				}
+				copies = copies[1:]
+			}
+		}
+	}
+	return rules, nil
+}
+
+func (b *nftFirewallBackend) Apply(rules []FirewallRule) error {
+	conn := &nftables.Conn{}
+	chains, err := b.foreignChains(conn)
+	if err != nil {
+		return err
+	}
+	if err = b.deleteForeignRules(conn, chains); err != nil {
+		return err
+	}
+	conn.AddTable(b.table)
+	conn.AddChain(b.chain)
+	conn.FlushChain(b.chain)
+	rules = sortFirewallRules(rules)
+	for _, rule := range rules {
+		conn.AddRule(&nftables.Rule{
+			Table:    b.table,
+			Chain:    b.chain,
+			Exprs:    nftFirewallExprs(nftables.TableFamilyINet, rule),
+			UserData: nftComment(firewallComment),
+		})
+	}
+	for _, chain := range chains {
+		// 逆序插入到链首，保持与 rules 相同的顺序
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].Action == firewallAccept && nftFamilyHandles(chain.Table.Family, rules[i]) {
+				conn.InsertRule(&nftables.Rule{
+					Table:    chain.Table,
+					Chain:    chain,
+					Exprs:    nftFirewallExprs(chain.Table.Family, rules[i]),
+					UserData: nftComment(firewallComment),
+				})
+			}
+		}
+	}
+	return conn.Flush()
+}
+
+func (b *nftFirewallBackend) Clean() error {
+	conn := &nftables.Conn{}
+	chains, err := b.foreignChains(conn)
+	if err != nil {
+		return err
+	}
+	if err = b.deleteForeignRules(conn, chains); err != nil {
+		return err
+	}
+	tables, err := conn.ListTablesOfFamily(nftables.TableFamilyINet)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if table.Name == firewallTable {
+			conn.DelTable(b.table)
+		}
+	}
+	return conn.Flush()
+}
+
+// foreignChains 其他表中的 input 过滤链，跳过 iptables-nft 管理的 ip/ip6 filter 表
+func (b *nftFirewallBackend) foreignChains(conn *nftables.Conn) ([]*nftables.Chain, error) {
+	chains, err := conn.ListChains()
+	if err != nil {
+		return nil, err
+	}
+	var foreign []*nftables.Chain
+	for _, chain := range chains {
+		if chain.Hooknum == nil || *chain.Hooknum != *nftables.ChainHookInput || chain.Type != nftables.ChainTypeFilter {
+			continue
+		}
+		family := chain.Table.Family
+		if family != nftables.TableFamilyINet && family != nftables.TableFamilyIPv4 && family != nftables.TableFamilyIPv6 {
+			continue
+		}
+		if family == nftables.TableFamilyINet && chain.Table.Name == firewallTable ||
+			family != nftables.TableFamilyINet && chain.Table.Name == "filter" {
+			continue
+		}
+		foreign = append(foreign, chain)
+	}
+	return foreign, nil
+}
+
+func (b *nftFirewallBackend) deleteForeignRules(conn *nftables.Conn, chains []*nftables.Chain) error {
+	for _, chain := range chains {
+		rules, err := conn.GetRules(chain.Table, chain)
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if bytes.Equal(rule.UserData, nftComment(firewallComment)) {
+				if err = conn.DelRule(&nftables.Rule{Table: chain.Table, Chain: chain, Handle: rule.Handle}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func nftFamilyHandles(family nftables.TableFamily, rule FirewallRule) bool {
+	switch family {
+	case nftables.TableFamilyIPv4:
+		return rule.Source == "" || !rule.ipv6()
+	case nftables.TableFamilyIPv6:
+		return rule.Source == "" || rule.ipv6()
+	}
+	return true
+}
+
+func nftDropRules(rules []FirewallRule) []FirewallRule {
+	var drops []FirewallRule
+	for _, rule := range rules {
+		if rule.Action == firewallDrop {
+			drops = append(drops, rule)
+		}
+	}
+	return drops
+}
+
+// nftFirewallExprs [ip saddr 1.2.3.0/24] tcp dport 8081 counter accept
+func nftFirewallExprs(family nftables.TableFamily, rule FirewallRule) []expr.Any {
+	var exprs []expr.Any
+	if rule.Source != "" {
+		_, ipNet, _ := net.ParseCIDR(rule.Source)
+		nfproto, offset, addr := byte(unix.NFPROTO_IPV4), uint32(12), ipNet.IP.To4()
+		if rule.ipv6() {
+			nfproto, offset, addr = unix.NFPROTO_IPV6, 8, ipNet.IP.To16()
+		}
+		if family == nftables.TableFamilyINet {
+			exprs = append(exprs,
+				&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{nfproto}},
+			)
+		}
+		exprs = append(exprs,
+			&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: offset, Len: uint32(len(addr))},
+			&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: uint32(len(addr)), Mask: ipNet.Mask, Xor: make([]byte, len(addr))},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: addr},
+		)
+	}
+	protocol := byte(unix.IPPROTO_TCP)
+	if rule.Protocol == "udp" {
+		protocol = unix.IPPROTO_UDP
+	}
+	exprs = append(exprs,
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{protocol}},
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2},
+	)
+	if rule.Start == rule.End {
+		exprs = append(exprs, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(rule.Start)})
+	} else {
+		exprs = append(exprs, &expr.Range{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			FromData: binaryutil.BigEndian.PutUint16(rule.Start),
+			ToData:   binaryutil.BigEndian.PutUint16(rule.End),
+		})
+	}
+	verdict := expr.VerdictAccept
+	if rule.Action == firewallDrop {
+		verdict = expr.VerdictDrop
+	}
+	return append(exprs, &expr.Counter{}, &expr.Verdict{Kind: verdict})
+}
+
+// parseNftFirewallExprs nftFirewallExprs 的逆操作
+func parseNftFirewallExprs(exprs []expr.Any) (FirewallRule, bool) {
+	var rule FirewallRule
+	var field string
+	var addr, mask []byte
+	for _, item := range exprs {
+		switch e := item.(type) {
+		case *expr.Meta:
+			switch e.Key {
+			case expr.MetaKeyNFPROTO:
+				field = "nfproto"
+			case expr.MetaKeyL4PROTO:
+				field = "l4proto"
+			default:
+				return rule, false
+			}
+		case *expr.Payload:
+			switch {
+			case e.Base == expr.PayloadBaseNetworkHeader && (e.Offset == 12 && e.Len == 4 || e.Offset == 8 && e.Len == 16):
+				field = "saddr"
+			case e.Base == expr.PayloadBaseTransportHeader && e.Offset == 2 && e.Len == 2:
+				field = "dport"
+			default:
+				return rule, false
+			}
+		case *expr.Bitwise:
+			if field != "saddr" {
+				return rule, false
+			}
+			mask = e.Mask
+		case *expr.Cmp:
+			if e.Op != expr.CmpOpEq {
+				return rule, false
+			}
+			switch field {
+			case "nfproto":
+			case "l4proto":
+				if len(e.Data) != 1 || e.Data[0] != unix.IPPROTO_TCP && e.Data[0] != unix.IPPROTO_UDP {
+					return rule, false
+				}
+				rule.Protocol = "tcp"
+				if e.Data[0] == unix.IPPROTO_UDP {
+					rule.Protocol = "udp"
+				}
+			case "saddr":
+				addr = e.Data
+			case "dport":
+				if len(e.Data) != 2 {
+					return rule, false
+				}
+				rule.Start = binaryutil.BigEndian.Uint16(e.Data)
+				rule.End = rule.Start
+			default:
+				return rule, false
+			}
+		case *expr.Range:
+			if field != "dport" || e.Op != expr.CmpOpEq || len(e.FromData) != 2 || len(e.ToData) != 2 {
+				return rule, false
+			}
+			rule.Start = binaryutil.BigEndian.Uint16(e.FromData)
+			rule.End = binaryutil.BigEndian.Uint16(e.ToData)
+		case *expr.Verdict:
+			switch e.Kind {
+			case expr.VerdictAccept:
+				rule.Action = firewallAccept
+			case expr.VerdictDrop:
+				rule.Action = firewallDrop
+			default:
+				return rule, false
+			}
+		case *expr.Counter:
+		default:
+			return rule, false
+		}
+	}
+	if addr != nil {
+		ones := 0
+		for _, item := range mask {
+			ones += bits.OnesCount8(item)
+		}
+		if mask == nil {
+			ones = len(addr) * 8
+		}
+		rule.Source = net.IP(addr).String() + "/" + strconv.Itoa(ones)
+	}
+	return rule, rule.Protocol != "" && rule.Start != 0 && rule.Action != ""
+}
@@ -0,0 +1,24 @@
+//go:build linux
+
+package service
+
+import (
+	"github.com/google/nftables"
+	"testing"
+)
+
+func TestNftFirewallExprs(t *testing.T) {
+	rules := []FirewallRule{
+		{Protocol: "udp", Start: 30000, End: 40000, Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Source: "1.2.3.0/24", Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Source: "2001:db8::/64", Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Action: firewallDrop},
+	}
+	for _, family := range []nftables.TableFamily{nftables.TableFamilyINet, nftables.TableFamilyIPv4, nftables.TableFamilyIPv6} {
+		for _, rule := range rules {
+			if parsed, ok := parseNftFirewallExprs(nftFirewallExprs(family, rule)); !ok || parsed != rule {
+				t.Errorf("expected %v, got: %v", rule, parsed)
+			}
+		}
+	}
+}
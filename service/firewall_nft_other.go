@@ -0,0 +1,9 @@
+//go:build !linux
+
+package service
+
+import "errors"
+
+func newNftFirewallBackend() (firewallBackend, error) {
+	return nil, errors.New("nftables is only supported on linux")
+}
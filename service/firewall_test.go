@@ -0,0 +1,262 @@
+package service
+
+import (
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeFirewallBackend struct {
+	rules []FirewallRule
+	calls int
+}
+
+func (b *fakeFirewallBackend) Name() string {
+	return "fake"
+}
+
+func (b *fakeFirewallBackend) Handles(rule FirewallRule) bool {
+	return !rule.ipv6()
+}
+
+func (b *fakeFirewallBackend) List() ([]FirewallRule, error) {
+	return append([]FirewallRule(nil), b.rules...), nil
+}
+
+func (b *fakeFirewallBackend) Apply(rules []FirewallRule) error {
+	b.calls++
+	b.rules = sortFirewallRules(rules)
+	return nil
+}
+
+func (b *fakeFirewallBackend) Clean() error {
+	b.rules = nil
+	return nil
+}
+
+func TestBuildFirewallRules(t *testing.T) {
+	rules := buildFirewallRules([][2]uint16{{30000, 40000}, {443, 443}, {443, 443}}, []firewallWebPort{{port: 8081, panel: true}}, nil)
+	expect := []FirewallRule{
+		{Protocol: "udp", Start: 443, End: 443, Action: firewallAccept},
+		{Protocol: "udp", Start: 30000, End: 40000, Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Action: firewallAccept},
+	}
+	if !reflect.DeepEqual(rules, expect) {
+		t.Errorf("expected %v, got: %v", expect, rules)
+	}
+
+	rules = buildFirewallRules(nil, []firewallWebPort{{port: 8081, panel: true}}, []string{"1.2.3.0/24"})
+	expect = []FirewallRule{
+		{Protocol: "tcp", Start: 8081, End: 8081, Source: "127.0.0.0/8", Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Source: "::1/128", Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Source: "1.2.3.0/24", Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Action: firewallDrop},
+	}
+	if !reflect.DeepEqual(rules, expect) {
+		t.Errorf("expected %v, got: %v", expect, rules)
+	}
+}
+
+func TestDesiredFirewallRulesWebListeners(t *testing.T) {
+	initTestSqlite(t)
+	for key, value := range map[string]string{
+		constant.HUIWebPort:             "8081",
+		constant.HUIWebRoutes:           "subscribe,auth",
+		constant.FirewallPanelAllowlist: "1.2.3.0/24",
+	} {
+		if err := UpdateConfig(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tcp, unix, zero, one := constant.ListenerNetworkTcp, constant.ListenerNetworkUnix, int64(0), int64(1)
+	for _, item := range []struct {
+		name    string
+		network string
+		address string
+		routes  string
+		enable  int64
+	}{
+		{"panel", tcp, ":9443", "panel", one},
+		{"local", tcp, "127.0.0.1:9444", "panel", one},
+		{"socket", unix, "/run/h-ui.sock", "panel", one},
+		{"disabled", tcp, ":9445", "panel", zero},
+	} {
+		item := item
+		if _, err := dao.SaveWebListener(entity.WebListener{Name: &item.name, Network: &item.network, Address: &item.address,
+			Tls: &zero, Routes: &item.routes, RedirectPort: &zero, Enable: &item.enable}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules, err := desiredFirewallRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tcpRules []FirewallRule
+	for _, rule := range rules {
+		if rule.Protocol == "tcp" {
+			tcpRules = append(tcpRules, rule)
+		}
+	}
+	// 面板端口没有开放面板路由，不使用白名单
+	expect := []FirewallRule{
+		{Protocol: "tcp", Start: 8081, End: 8081, Action: firewallAccept},
+		{Protocol: "tcp", Start: 9443, End: 9443, Source: "127.0.0.0/8", Action: firewallAccept},
+		{Protocol: "tcp", Start: 9443, End: 9443, Source: "::1/128", Action: firewallAccept},
+		{Protocol: "tcp", Start: 9443, End: 9443, Source: "1.2.3.0/24", Action: firewallAccept},
+		{Protocol: "tcp", Start: 9443, End: 9443, Action: firewallDrop},
+	}
+	if !reflect.DeepEqual(tcpRules, expect) {
+		t.Errorf("expected %v, got: %v", expect, tcpRules)
+	}
+}
+
+func TestParseFirewallAllowlist(t *testing.T) {
+	allowlist, err := ParseFirewallAllowlist(" 1.2.3.4, 10.0.0.1/8,2001:db8::1/64,1.2.3.4 ")
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	expect := []string{"1.2.3.4/32", "10.0.0.0/8", "2001:db8::/64"}
+	if !reflect.DeepEqual(allowlist, expect) {
+		t.Errorf("expected %v, got: %v", expect, allowlist)
+	}
+	if allowlist, err = ParseFirewallAllowlist(""); err != nil || len(allowlist) != 0 {
+		t.Errorf("empty allowlist should be empty, got: %v %v", allowlist, err)
+	}
+	for _, value := range []string{"1.2.3", "1.2.3.4/33", "example.com", "1.2.3.4;reboot"} {
+		if _, err = ParseFirewallAllowlist(value); err == nil {
+			t.Errorf("%s should fail", value)
+		}
+	}
+}
+
+func TestFirewallAllowlistContains(t *testing.T) {
+	allowlist := []string{"1.2.3.0/24", "2001:db8::/64"}
+	cases := map[string]bool{
+		"1.2.3.4":       true,
+		"1.2.4.4":       false,
+		"2001:db8::5":   true,
+		"2001:db9::5":   false,
+		"127.0.0.1":     true,
+		"::1":           true,
+		"not an ip":     true,
+		"192.168.1.100": false,
+	}
+	for ip, expect := range cases {
+		if got := FirewallAllowlistContains(allowlist, ip); got != expect {
+			t.Errorf("%s expected %v, got: %v", ip, expect, got)
+		}
+	}
+	if !FirewallAllowlistContains(nil, "192.168.1.100") {
+		t.Errorf("empty allowlist should contain all ips")
+	}
+}
+
+func TestSortFirewallRules(t *testing.T) {
+	rules := []FirewallRule{
+		{Protocol: "tcp", Start: 8081, End: 8081, Action: firewallDrop},
+		{Protocol: "udp", Start: 443, End: 443, Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Source: "1.2.3.0/24", Action: firewallAccept},
+	}
+	expect := []FirewallRule{rules[1], rules[2], rules[0]}
+	if sorted := sortFirewallRules(rules); !reflect.DeepEqual(sorted, expect) {
+		t.Errorf("expected %v, got: %v", expect, sorted)
+	}
+}
+
+func TestApplyFirewall(t *testing.T) {
+	backend := &fakeFirewallBackend{}
+	desired := buildFirewallRules([][2]uint16{{443, 443}}, []firewallWebPort{{port: 8081, panel: true}}, []string{"1.2.3.0/24", "2001:db8::/64"})
+	if err := applyFirewall([]firewallBackend{backend}, desired); err != nil {
+		t.Fatalf("apply err: %v", err)
+	}
+	for _, rule := range backend.rules {
+		if rule.ipv6() {
+			t.Errorf("ipv6 rule %v should not be applied", rule)
+		}
+	}
+	if err := applyFirewall([]firewallBackend{backend}, desired); err != nil || backend.calls != 1 {
+		t.Errorf("in sync rules should not be applied again, calls: %d %v", backend.calls, err)
+	}
+	backend.rules = backend.rules[1:]
+	if err := applyFirewall([]firewallBackend{backend}, desired); err != nil || backend.calls != 2 {
+		t.Errorf("drifted rules should be applied, calls: %d %v", backend.calls, err)
+	}
+	if err := applyFirewall(nil, desired); err == nil {
+		t.Errorf("no backend should fail")
+	}
+}
+
+func TestParseUfwRule(t *testing.T) {
+	rules := []FirewallRule{
+		{Protocol: "udp", Start: 30000, End: 40000, Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Source: "1.2.3.0/24", Action: firewallAccept},
+		{Protocol: "tcp", Start: 8081, End: 8081, Action: firewallDrop},
+	}
+	for _, rule := range rules {
+		line := "ufw " + ufwRuleSpec(rule) + " comment '" + firewallComment + "'"
+		if parsed, ok := parseUfwRule(line); !ok || parsed != rule {
+			t.Errorf("%s expected %v, got: %v", line, rule, parsed)
+		}
+	}
+	if _, ok := parseUfwRule("ufw allow 22/tcp"); ok {
+		t.Errorf("rules without comment should be ignored")
+	}
+}
+
+func TestParseIptablesFirewallRule(t *testing.T) {
+	rule, ok := parseIptablesFirewallRule("-A HUI_FIREWALL -s 1.2.3.4/32 -p tcp -m tcp --dport 8081 -j ACCEPT")
+	expect := FirewallRule{Protocol: "tcp", Start: 8081, End: 8081, Source: "1.2.3.4/32", Action: firewallAccept}
+	if !ok || rule != expect {
+		t.Errorf("expected %v, got: %v", expect, rule)
+	}
+	rule, ok = parseIptablesFirewallRule("-A HUI_FIREWALL -p udp -m udp --dport 30000:40000 -j ACCEPT")
+	expect = FirewallRule{Protocol: "udp", Start: 30000, End: 40000, Action: firewallAccept}
+	if !ok || rule != expect {
+		t.Errorf("expected %v, got: %v", expect, rule)
+	}
+	for _, line := range []string{"-N HUI_FIREWALL", "-A INPUT -p tcp --dport 22 -j ACCEPT", "-A HUI_FIREWALL -p tcp --dport 22 -j LOG"} {
+		if _, ok = parseIptablesFirewallRule(line); ok {
+			t.Errorf("%s should be ignored", line)
+		}
+	}
+}
+
+func TestParseFirewalld(t *testing.T) {
+	rules := parseFirewalldPorts("443/udp 30000-40000/udp bad 8081/tcp")
+	expect := []FirewallRule{
+		{Protocol: "udp", Start: 443, End: 443},
+		{Protocol: "udp", Start: 30000, End: 40000},
+		{Protocol: "tcp", Start: 8081, End: 8081},
+	}
+	if !reflect.DeepEqual(rules, expect) {
+		t.Errorf("expected %v, got: %v", expect, rules)
+	}
+	for _, source := range []string{"1.2.3.0/24", "2001:db8::/64"} {
+		if parsed, ok := parseFirewalldRichRule(firewalldRichRule(source)); !ok || parsed != source {
+			t.Errorf("expected %s, got: %s", source, parsed)
+		}
+	}
+	if _, ok := parseFirewalldRichRule(`rule family="ipv4" source address="1.2.3.4" service name="ssh" accept`); ok {
+		t.Errorf("other rich rules should be ignored")
+	}
+
+	richRules := firewalldRichRules([]string{"1.2.3.0/24", "2001:db8::/64"})
+	if len(richRules) != 3 || !strings.HasSuffix(richRules[2], `service name="h-ui-panel" drop`) {
+		t.Fatalf("expected two accepts followed by a drop, got: %v", richRules)
+	}
+	for _, rule := range richRules[:2] {
+		if !strings.HasPrefix(rule, `rule priority="-2"`) {
+			t.Errorf("accept rules must be matched before the drop rule: %s", rule)
+		}
+	}
+	if _, ok := parseFirewalldRichRule(richRules[2]); ok {
+		t.Errorf("the drop rule has no source")
+	}
+	if len(firewalldRichRules(nil)) != 0 {
+		t.Errorf("no rich rules without an allowlist")
+	}
+}
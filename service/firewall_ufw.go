@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+	"h-ui/util"
+	"strconv"
+	"strings"
+)
+
+// ufwBackend 规则带有 hui_firewall 注释，ufw 自动同时处理 IPv4 和 IPv6
+type ufwBackend struct {
+}
+
+func (b *ufwBackend) Name() string {
+	return "ufw"
+}
+
+func (b *ufwBackend) Handles(rule FirewallRule) bool {
+	return true
+}
+
+func (b *ufwBackend) List() ([]FirewallRule, error) {
+	output, err := util.Exec("ufw show added")
+	if err != nil {
+		return nil, err
+	}
+	var rules []FirewallRule
+	for _, line := range strings.Split(output, "\n") {
+		if rule, ok := parseUfwRule(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// Apply ufw 按顺序匹配第一条规则，先删除全部再逆序 prepend，使 accept、drop 排在已有规则之前。
+// 不使用 insert 1：规则列表为空或 IPv6 规则插入到 IPv4 规则的位置时 ufw 会报错
+func (b *ufwBackend) Apply(rules []FirewallRule) error {
+	if err := b.Clean(); err != nil {
+		return err
+	}
+	sorted := sortFirewallRules(rules)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if _, err := util.Exec(fmt.Sprintf("ufw prepend %s comment '%s'", ufwRuleSpec(sorted[i]), firewallComment)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *ufwBackend) Clean() error {
+	rules, err := b.List()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if _, err = util.Exec(fmt.Sprintf("ufw delete %s", ufwRuleSpec(rule))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ufwRuleSpec allow proto tcp from 1.2.3.0/24 to any port 8081
+func ufwRuleSpec(rule FirewallRule) string {
+	action := "allow"
+	if rule.Action == firewallDrop {
+		action = "deny"
+	}
+	spec := fmt.Sprintf("%s proto %s", action, rule.Protocol)
+	if rule.Source != "" {
+		spec += " from " + rule.Source
+	}
+	return fmt.Sprintf("%s to any port %s", spec, rule.ports(":"))
+}
+
+// parseUfwRule 解析 ufw show added 中带有 hui_firewall 注释的规则
+func parseUfwRule(line string) (FirewallRule, bool) {
+	var rule FirewallRule
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "ufw" || !strings.Contains(line, "comment '"+firewallComment+"'") {
+		return rule, false
+	}
+	switch fields[1] {
+	case "allow":
+		rule.Action = firewallAccept
+	case "deny":
+		rule.Action = firewallDrop
+	default:
+		return rule, false
+	}
+	for i := 2; i+1 < len(fields) && fields[i] != "comment"; i++ {
+		value := fields[i+1]
+		switch fields[i] {
+		case "proto":
+			rule.Protocol = value
+		case "from":
+			if value != "any" {
+				rule.Source = normalizeFirewallSource(value)
+			}
+		case "port":
+			bounds := strings.Split(value, ":")
+			start, err := strconv.ParseUint(bounds[0], 10, 16)
+			if err != nil {
+				return rule, false
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseUint(bounds[1], 10, 16); err != nil {
+					return rule, false
+				}
+			}
+			rule.Start, rule.End = uint16(start), uint16(end)
+		}
+	}
+	return rule, rule.Protocol != "" && rule.Start != 0
+}
@@ -3,232 +3,370 @@ package service
 import (
 	"errors"
 	"fmt"
+	"github.com/sirupsen/logrus"
 	"h-ui/dao"
 	"h-ui/model/constant"
+	"h-ui/model/vo"
 	"h-ui/util"
+	"net"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 var (
-	netManager       string
-	ingressInterface string
-	Add              = "add"
-	Delete           = "delete"
-	Table            = "hui_porthopping"
-	Comment          = "hui_hysteria_porthopping"
+	portHoppingBackends []portHoppingBackend
+	portHoppingLock     sync.Mutex
+	ifaceRegexp         = regexp.MustCompile(`^[a-zA-Z0-9_.:@-]{1,15}$`)
+	Table               = "hui_porthopping"
+	Comment             = "hui_hysteria_porthopping"
 )
 
-func InitForward() {
-	if nft, err := util.Exec("command -v nft"); err == nil && strings.TrimSpace(nft) != "" {
-		netManager = "nft"
-	} else if iptables, err := util.Exec("command -v iptables"); err == nil && strings.TrimSpace(iptables) != "" {
-		netManager = "iptables"
-	}
+const PortHoppingAllInterfaces = "all"
+
+// portHoppingNode 每个节点的端口跳跃范围和入口网卡配置
+type portHoppingNode struct {
+	name      string
+	rangeKey  string
+	ifacesKey string
+}
+
+var portHoppingNodes = []portHoppingNode{
+	{name: "node1", rangeKey: constant.Hysteria2ConfigPortHopping, ifacesKey: constant.Hysteria2ConfigPortHoppingIfaces},
+	{name: "node2", rangeKey: constant.Hysteria2Node2PortHopping, ifacesKey: constant.Hysteria2Node2PortHoppingIfaces},
+}
 
-	if ii, err := util.Exec("ls /sys/class/net | grep -E '^en|^eth'"); err == nil && strings.TrimSpace(ii) != "" {
-		iiList := strings.Split(ii, "\n")
-		ingressInterface = strings.TrimSpace(iiList[0])
+// PortHoppingRule 把入口网卡上的 UDP 端口范围重定向到 hysteria2 监听端口，Interface 为空时匹配所有网卡
+type PortHoppingRule struct {
+	Interface string `json:"interface"`
+	Start     uint16 `json:"start"`
+	End       uint16 `json:"end"`
+	Target    uint16 `json:"target"`
+}
+
+func (r PortHoppingRule) String() string {
+	iface := r.Interface
+	if iface == "" {
+		iface = PortHoppingAllInterfaces
+	}
+	ports := strconv.Itoa(int(r.Start))
+	if r.End != r.Start {
+		ports += "-" + strconv.Itoa(int(r.End))
 	}
+	return fmt.Sprintf("%s udp %s -> %d", iface, ports, r.Target)
 }
 
-func InitTableAndChain() error {
-	if netManager == "nft" {
-		_, err := util.Exec(fmt.Sprintf("nft add table inet %s", Table))
-		if err != nil {
-			return err
-		}
-		_, err = util.Exec(fmt.Sprintf("nft add chain inet %s prerouting { type nat hook prerouting priority dstnat\\; policy accept\\; }", Table))
-		if err != nil {
-			return err
+// portHoppingBackend 端口跳跃规则的实现，所有规则都放在 h-ui 自己的表/链中或带有 Comment 标记
+type portHoppingBackend interface {
+	Name() string
+	// Init 幂等地创建表和链
+	Init() error
+	List() ([]PortHoppingRule, error)
+	Add(rules []PortHoppingRule) error
+	Delete(rules []PortHoppingRule) error
+	// Clean 删除 h-ui 创建的所有规则
+	Clean() error
+}
+
+// InitForward 优先通过 netlink 使用 nftables，不可用时回退到 iptables/ip6tables
+func InitForward() {
+	if backend, err := newNftBackend(); err == nil {
+		portHoppingBackends = []portHoppingBackend{backend}
+	} else {
+		logrus.Infof("nftables unavailable, fallback to iptables: %v", err)
+		for _, command := range []string{"iptables", "ip6tables"} {
+			if backend := newIptablesBackend(command); backend != nil {
+				portHoppingBackends = append(portHoppingBackends, backend)
+			}
 		}
 	}
-	return nil
 }
 
 func InitPortHopping() error {
-	if err := RemoveByComment(); err != nil {
+	desired, _, err := desiredPortHoppingRules()
+	if err != nil {
 		return err
 	}
+	return applyPortHopping(portHoppingBackends, desired)
+}
 
-	hysteria2Config, err := GetHysteria2Config()
+// CronPortHopping 规则被 firewalld、docker 等清空或修改后重新应用
+func CronPortHopping() {
+	status, err := PortHoppingStatus()
 	if err != nil {
-		return err
+		logrus.Errorf("port hopping status err: %v", err)
+		return
+	}
+	if status.InSync {
+		return
+	}
+	for _, backend := range status.Backends {
+		if !backend.InSync {
+			logrus.Warnf("port hopping drift detected on %s, missing: %d extra: %d", backend.Name, len(backend.Missing), len(backend.Extra))
+		}
+	}
+	if err = InitPortHopping(); err != nil {
+		logrus.Errorf("port hopping reconcile err: %v", err)
 	}
+}
 
-	// set port forward
-	hysteria2ConfigPortHopping, err := dao.GetConfig("key = ?", constant.Hysteria2ConfigPortHopping)
+// PortHoppingStatus 对比每个后端当前的规则和期望的规则
+func PortHoppingStatus() (vo.PortHoppingStatusVo, error) {
+	status := vo.PortHoppingStatusVo{InSync: true, Interfaces: []string{}}
+	desired, nodes, err := desiredPortHoppingRules()
 	if err != nil {
-		return err
+		return status, err
 	}
-	if *hysteria2ConfigPortHopping.Value != "" {
-		listen := strings.Split(*hysteria2Config.Listen, ":")
-		if len(listen) == 2 {
-			portHoppings := strings.Split(*hysteria2ConfigPortHopping.Value, ",")
-			for _, item := range portHoppings {
-				if err := portForward(item, listen[1], Add); err != nil {
-					return err
-				}
+	status.Desired = portHoppingRuleVos(desired, nodes)
+	if interfaces, err := net.Interfaces(); err == nil {
+		for _, item := range interfaces {
+			if item.Flags&net.FlagLoopback == 0 {
+				status.Interfaces = append(status.Interfaces, item.Name)
 			}
 		}
 	}
-	return nil
-}
 
-func portForward(rules string, target string, option string) error {
-	switch netManager {
-	case "nft":
-		switch option {
-		case Add, Delete:
-			return nftForward(rules, target, option)
-		default:
-			return errors.New("unsupported command option")
-		}
-	case "iptables":
-		switch option {
-		case Add:
-			return iptablesForward(rules, target, "-A")
-		case Delete:
-			return iptablesForward(rules, target, "-D")
-		default:
-			return errors.New("unsupported command option")
-		}
-	default:
-		return errors.New("port hopping not supported on this system")
+	portHoppingLock.Lock()
+	defer portHoppingLock.Unlock()
+	status.Backends = []vo.PortHoppingBackendVo{}
+	for _, backend := range portHoppingBackends {
+		current, err := backend.List()
+		if err != nil {
+			logrus.Errorf("%s list port hopping err: %v", backend.Name(), err)
+			return status, fmt.Errorf("%s list port hopping err", backend.Name())
+		}
+		add, del := util.DiffArr(current, desired)
+		backendVo := vo.PortHoppingBackendVo{
+			Name:    backend.Name(),
+			Current: portHoppingRuleVos(current, nodes),
+			Missing: portHoppingRuleVos(add, nodes),
+			Extra:   portHoppingRuleVos(del, nodes),
+			InSync:  len(add) == 0 && len(del) == 0,
+		}
+		status.InSync = status.InSync && backendVo.InSync
+		status.Backends = append(status.Backends, backendVo)
 	}
-}
-
-func RemoveByComment() error {
-	switch netManager {
-	case "nft":
-		return ntfRemoveByComment(Comment)
-	case "iptables":
-		return iptablesRemoveByComment(Comment)
-	default:
-		return errors.New("port hopping not supported on this system")
+	if len(portHoppingBackends) == 0 && len(desired) > 0 {
+		status.InSync = false
 	}
+	return status, nil
 }
 
-func nftForward(rules string, target string, option string) error {
-	if ingressInterface == "" {
-		return fmt.Errorf("no network interface detected")
+// desiredPortHoppingRules 返回所有启用节点的期望规则，以及监听端口对应的节点
+func desiredPortHoppingRules() ([]PortHoppingRule, map[uint16]string, error) {
+	var keys []string
+	for _, node := range portHoppingNodes {
+		keys = append(keys, node.rangeKey, node.ifacesKey)
 	}
-	// nft list ruleset
-	// 创建表：nft add table inet hui_hysteria_porthopping
-	// 创建链：nft add chain inet hui_hysteria_porthopping prerouting { type nat hook prerouting priority dstnat\; policy accept\; }
-	// 添加规则：nft add rule inet hui_hysteria_porthopping prerouting iifname enp1s0 udp dport {30000-40000} counter redirect to :444 comment hui_hysteria_porthopping
-	_, err := util.Exec(fmt.Sprintf("nft %s rule inet %s prerouting iifname %s udp dport {%s} counter redirect to :%s comment %s", option, Table, ingressInterface, rules, target, Comment))
+	configs, err := dao.ListConfig("key in ?", keys)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-
-	return nil
-}
-
-func ntfRemoveByComment(comment string) error {
-	rules, err := nftRules()
-	if err != nil {
-		return err
+	values := make(map[string]string, len(configs))
+	for _, item := range configs {
+		values[*item.Key] = *item.Value
 	}
-	for _, rule := range rules {
-		if strings.Contains(rule, comment) {
-			parts := strings.Fields(rule)
-			handle := parts[len(parts)-1]
-			_, err := util.Exec(fmt.Sprintf("nft delete rule inet %s prerouting handle %s", Table, strings.TrimSpace(handle)))
-			if err != nil {
-				return err
-			}
+
+	var desired []PortHoppingRule
+	nodes := make(map[uint16]string)
+	for _, node := range portHoppingNodes {
+		if values[node.rangeKey] == "" {
+			continue
+		}
+		target, err := nodeListenPort(node.name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if target == 0 {
+			continue
+		}
+		ifaces, err := ParsePortHoppingInterfaces(values[node.ifacesKey])
+		if err != nil {
+			return nil, nil, err
 		}
+		rules, err := portHoppingRules(ifaces, values[node.rangeKey], target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", node.name, err)
+		}
+		desired = append(desired, rules...)
+		nodes[target] = node.name
 	}
-	return nil
+	if err = checkPortHoppingOverlap(desired); err != nil {
+		return nil, nil, err
+	}
+	return desired, nodes, nil
 }
 
-func nftRules() ([]string, error) {
-	listOutput, err := util.Exec(fmt.Sprintf("nft list ruleset | grep -q %s && echo 'found' || echo 'not found'", Comment))
+// nodeListenPort 节点的监听端口，节点未配置或未启用时返回 0
+func nodeListenPort(node string) (uint16, error) {
+	if node == "node1" {
+		hysteria2Config, err := GetHysteria2Config()
+		if err != nil || hysteria2Config.Listen == nil || *hysteria2Config.Listen == "" {
+			return 0, err
+		}
+		return listenPort(*hysteria2Config.Listen)
+	}
+	enabled, err := IsNode2Enabled()
+	if err != nil || !enabled {
+		return 0, err
+	}
+	node2Config, err := GetHysteria2Node2Config()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	if strings.TrimSpace(listOutput) == "not found" {
-		return []string{}, nil
+	if node2Config.Listen != nil && *node2Config.Listen != "" {
+		return listenPort(*node2Config.Listen)
 	}
-	output, err := util.Exec(fmt.Sprintf("nft --handle list chain inet %s prerouting", Table))
+	port, err := GetNode2Port()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-
-	rules := strings.Split(output, "\n")
-	return rules, nil
+	return uint16(port), nil
 }
 
-func iptablesForward(rules string, target string, option string) error {
-	if ingressInterface == "" {
-		return fmt.Errorf("no network interface detected")
+// ParsePortHoppingInterfaces 空或 all 表示所有网卡
+func ParsePortHoppingInterfaces(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == PortHoppingAllInterfaces {
+		return []string{""}, nil
 	}
-
-	rulePairs := strings.Split(rules, ",")
-	for _, pair := range rulePairs {
-		ports := ""
-		portRange := strings.Split(pair, "-")
-		if len(portRange) == 1 {
-			ports = strings.TrimSpace(portRange[0])
-		} else if len(portRange) == 2 {
-			startPort := strings.TrimSpace(portRange[0])
-			endPort := strings.TrimSpace(portRange[1])
-			ports = startPort + ":" + endPort
-		} else {
-			return fmt.Errorf("invalid port range format: %s", pair)
+	var ifaces []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if !ifaceRegexp.MatchString(item) || item == PortHoppingAllInterfaces {
+			return nil, fmt.Errorf("port hopping interface: %s is invalid", item)
 		}
-
-		if len(ports) != 0 {
-			if err := iptablesAddRule(option, ports, target); err != nil {
-				return err
-			}
+		if !util.ArrContain(ifaces, item) {
+			ifaces = append(ifaces, item)
 		}
 	}
+	return ifaces, nil
+}
 
+// RemovePortHopping 退出时删除所有端口跳跃规则
+func RemovePortHopping() error {
+	portHoppingLock.Lock()
+	defer portHoppingLock.Unlock()
+	for _, backend := range portHoppingBackends {
+		if err := backend.Clean(); err != nil {
+			logrus.Errorf("%s clean port hopping err: %v", backend.Name(), err)
+			return fmt.Errorf("%s clean port hopping err", backend.Name())
+		}
+	}
 	return nil
 }
 
-func iptablesAddRule(option, ports, target string) error {
-	protocols := [2]string{"iptables", "ip6tables"}
-	for _, protocol := range protocols {
-		// iptables -t nat -A PREROUTING -i enp1s0 -p udp --dport 30000:40000 -j REDIRECT --to-port 444 -m comment --comment hui_hysteria_porthopping
-		_, err := util.Exec(fmt.Sprintf("%s -t nat %s PREROUTING -i %s -p udp --dport %s -j REDIRECT --to-port %s -m comment --comment %s", protocol, option, ingressInterface, ports, target, Comment))
+// applyPortHopping 对比每个后端当前的规则和期望的规则，只增删有差异的部分
+func applyPortHopping(backends []portHoppingBackend, desired []PortHoppingRule) error {
+	portHoppingLock.Lock()
+	defer portHoppingLock.Unlock()
+	if len(backends) == 0 {
+		if len(desired) == 0 {
+			return nil
+		}
+		return errors.New("port hopping not supported on this system")
+	}
+	for _, backend := range backends {
+		if err := backend.Init(); err != nil {
+			logrus.Errorf("%s init port hopping err: %v", backend.Name(), err)
+			return fmt.Errorf("%s init port hopping err", backend.Name())
+		}
+		current, err := backend.List()
 		if err != nil {
-			return err
+			logrus.Errorf("%s list port hopping err: %v", backend.Name(), err)
+			return fmt.Errorf("%s list port hopping err", backend.Name())
+		}
+		add, del := util.DiffArr(current, desired)
+		if len(del) > 0 {
+			if err = backend.Delete(del); err != nil {
+				logrus.Errorf("%s delete port hopping err: %v", backend.Name(), err)
+				return fmt.Errorf("%s delete port hopping err", backend.Name())
+			}
+		}
+		if len(add) > 0 {
+			if err = backend.Add(add); err != nil {
+				logrus.Errorf("%s add port hopping err: %v", backend.Name(), err)
+				return fmt.Errorf("%s add port hopping err", backend.Name())
+			}
 		}
 	}
 	return nil
 }
 
-func iptablesRemoveByComment(comment string) error {
-	protocols := [2]string{"iptables", "ip6tables"}
-	for _, protocol := range protocols {
-		rules, err := iptablesRules(protocol)
-		if err != nil {
-			return err
-		}
-		for _, rule := range rules {
-			if strings.Contains(rule, comment) {
-				parts := strings.Fields(rule)
-				handle := parts[0]
-				_, err := util.Exec(fmt.Sprintf("%s -t nat -D PREROUTING %s", protocol, strings.TrimSpace(handle)))
-				if err != nil {
-					return err
-				}
+// portHoppingRules 解析 30000-40000,50000 格式的端口范围，每个网卡一条规则
+func portHoppingRules(ifaces []string, value string, target uint16) ([]PortHoppingRule, error) {
+	var rules []PortHoppingRule
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return nil, fmt.Errorf("invalid port range format: %s", item)
+		}
+		var ports []uint16
+		for _, bound := range bounds {
+			port, err := strconv.ParseUint(strings.TrimSpace(bound), 10, 16)
+			if err != nil || port == 0 {
+				return nil, fmt.Errorf("invalid port range format: %s", item)
 			}
+			ports = append(ports, uint16(port))
+		}
+		if ports[0] > ports[len(ports)-1] {
+			return nil, fmt.Errorf("invalid port range format: %s", item)
+		}
+		for _, iface := range ifaces {
+			rules = append(rules, PortHoppingRule{Interface: iface, Start: ports[0], End: ports[len(ports)-1], Target: target})
 		}
 	}
+	return rules, checkPortHoppingOverlap(rules)
+}
 
+// checkPortHoppingOverlap 同一网卡上的端口范围不能重叠，所有网卡的规则与任何网卡都可能重叠
+func checkPortHoppingOverlap(rules []PortHoppingRule) error {
+	for i := 0; i < len(rules); i++ {
+		for j := i + 1; j < len(rules); j++ {
+			a, b := rules[i], rules[j]
+			if a.Interface != "" && b.Interface != "" && a.Interface != b.Interface {
+				continue
+			}
+			if a.Start <= b.End && b.Start <= a.End {
+				return fmt.Errorf("port range %s overlaps %s", a, b)
+			}
+		}
+	}
 	return nil
 }
 
-func iptablesRules(protocol string) ([]string, error) {
-	// iptables -t nat -L PREROUTING -v --line-numbers
-	output, err := util.Exec(fmt.Sprintf("%s -t nat -L PREROUTING -v --line-numbers", protocol))
-	if err != nil {
-		return nil, err
+func portHoppingRuleVos(rules []PortHoppingRule, nodes map[uint16]string) []vo.PortHoppingRuleVo {
+	ruleVos := make([]vo.PortHoppingRuleVo, 0, len(rules))
+	for _, rule := range rules {
+		iface := rule.Interface
+		if iface == "" {
+			iface = PortHoppingAllInterfaces
+		}
+		ports := strconv.Itoa(int(rule.Start))
+		if rule.End != rule.Start {
+			ports += "-" + strconv.Itoa(int(rule.End))
+		}
+		ruleVos = append(ruleVos, vo.PortHoppingRuleVo{
+			Node:      nodes[rule.Target],
+			Interface: iface,
+			Ports:     ports,
+			Target:    rule.Target,
+		})
 	}
+	return ruleVos
+}
 
-	rules := strings.Split(output, "\n")
-	return rules, nil
+// listenPort 支持 :443、0.0.0.0:443 和 [::]:443
+func listenPort(listen string) (uint16, error) {
+	index := strings.LastIndex(listen, ":")
+	port, err := strconv.ParseUint(listen[index+1:], 10, 16)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("invalid listen: %s", listen)
+	}
+	return uint16(port), nil
 }
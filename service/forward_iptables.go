@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"h-ui/util"
+	"strconv"
+	"strings"
+)
+
+// iptablesBackend nftables 不可用时的回退实现，iptables 和 ip6tables 各一个实例
+type iptablesBackend struct {
+	command string
+}
+
+func newIptablesBackend(command string) portHoppingBackend {
+	if path, err := util.Exec(fmt.Sprintf("command -v %s", command)); err != nil || strings.TrimSpace(path) == "" {
+		return nil
+	}
+	return &iptablesBackend{command: command}
+}
+
+func (b *iptablesBackend) Name() string {
+	return b.command
+}
+
+func (b *iptablesBackend) Init() error {
+	return nil
+}
+
+func (b *iptablesBackend) List() ([]PortHoppingRule, error) {
+	// iptables -t nat -S PREROUTING
+	output, err := util.Exec(fmt.Sprintf("%s -w -t nat -S PREROUTING", b.command))
+	if err != nil {
+		return nil, err
+	}
+	var rules []PortHoppingRule
+	for _, line := range strings.Split(output, "\n") {
+		if rule, ok := parseIptablesRule(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (b *iptablesBackend) Add(rules []PortHoppingRule) error {
+	for _, rule := range rules {
+		if _, err := util.Exec(fmt.Sprintf("%s -w -t nat -A %s", b.command, iptablesRuleSpec(rule))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *iptablesBackend) Delete(rules []PortHoppingRule) error {
+	for _, rule := range rules {
+		if _, err := util.Exec(fmt.Sprintf("%s -w -t nat -D %s", b.command, iptablesRuleSpec(rule))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *iptablesBackend) Clean() error {
+	rules, err := b.List()
+	if err != nil {
+		return err
+	}
+	return b.Delete(rules)
+}
+
+// iptablesRuleSpec PREROUTING -i enp1s0 -p udp --dport 30000:40000 -m comment --comment hui_hysteria_porthopping -j REDIRECT --to-ports 444
+func iptablesRuleSpec(rule PortHoppingRule) string {
+	spec := "PREROUTING"
+	if rule.Interface != "" {
+		spec += " -i " + rule.Interface
+	}
+	ports := strconv.Itoa(int(rule.Start))
+	if rule.End != rule.Start {
+		ports += ":" + strconv.Itoa(int(rule.End))
+	}
+	return fmt.Sprintf("%s -p udp --dport %s -m comment --comment %s -j REDIRECT --to-ports %d", spec, ports, Comment, rule.Target)
+}
+
+// parseIptablesRule 解析 iptables -S 输出中带有 Comment 标记的规则
+func parseIptablesRule(line string) (PortHoppingRule, bool) {
+	var rule PortHoppingRule
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "-A" || fields[1] != "PREROUTING" {
+		return rule, false
+	}
+	marked := false
+	for i := 2; i+1 < len(fields); i++ {
+		value := fields[i+1]
+		switch fields[i] {
+		case "-i":
+			rule.Interface = value
+		case "--comment":
+			marked = strings.Trim(value, "\"") == Comment
+		case "--dport":
+			bounds := strings.Split(value, ":")
+			start, err := strconv.ParseUint(bounds[0], 10, 16)
+			if err != nil {
+				return rule, false
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseUint(bounds[1], 10, 16); err != nil {
+					return rule, false
+				}
+			}
+			rule.Start, rule.End = uint16(start), uint16(end)
+		case "--to-ports":
+			target, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return rule, false
+			}
+			rule.Target = uint16(target)
+		}
+	}
+	return rule, marked && rule.Start != 0 && rule.Target != 0
+}
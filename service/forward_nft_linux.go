@@ -0,0 +1,228 @@
+//go:build linux
+
+package service
+
+import (
+	"bytes"
+	"errors"
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+// nftBackend 通过 netlink 操作 inet 表，同时作用于 IPv4 和 IPv6
+type nftBackend struct {
+	table *nftables.Table
+	chain *nftables.Chain
+}
+
+func newNftBackend() (portHoppingBackend, error) {
+	conn := &nftables.Conn{}
+	if _, err := conn.ListTablesOfFamily(nftables.TableFamilyINet); err != nil {
+		return nil, err
+	}
+	table := &nftables.Table{Name: Table, Family: nftables.TableFamilyINet}
+	policy := nftables.ChainPolicyAccept
+	return &nftBackend{
+		table: table,
+		chain: &nftables.Chain{
+			Name:     "prerouting",
+			Table:    table,
+			Type:     nftables.ChainTypeNAT,
+			Hooknum:  nftables.ChainHookPrerouting,
+			Priority: nftables.ChainPriorityNATDest,
+			Policy:   &policy,
+		},
+	}, nil
+}
+
+func (b *nftBackend) Name() string {
+	return "nftables"
+}
+
+func (b *nftBackend) Init() error {
+	conn := &nftables.Conn{}
+	conn.AddTable(b.table)
+	conn.AddChain(b.chain)
+	return conn.Flush()
+}
+
+func (b *nftBackend) List() ([]PortHoppingRule, error) {
+	nftRules, err := b.listNftRules()
+	if err != nil {
+		return nil, err
+	}
+	var rules []PortHoppingRule
+	for _, item := range nftRules {
+		rules = append(rules, item.rule)
+	}
+	return rules, nil
+}
+
+func (b *nftBackend) Add(rules []PortHoppingRule) error {
+	conn := &nftables.Conn{}
+	for _, rule := range rules {
+		conn.AddRule(&nftables.Rule{
+			Table:    b.table,
+			Chain:    b.chain,
+			Exprs:    nftRuleExprs(rule),
+			UserData: nftComment(Comment),
+		})
+	}
+	return conn.Flush()
+}
+
+func (b *nftBackend) Delete(rules []PortHoppingRule) error {
+	nftRules, err := b.listNftRules()
+	if err != nil {
+		return err
+	}
+	conn := &nftables.Conn{}
+	used := make(map[uint64]bool)
+	for _, rule := range rules {
+		for _, item := range nftRules {
+			if item.rule == rule && !used[item.handle] {
+				used[item.handle] = true
+				if err = conn.DelRule(&nftables.Rule{Table: b.table, Chain: b.chain, Handle: item.handle}); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return conn.Flush()
+}
+
+// Clean 表是 h-ui 独占的，直接删除整张表
+func (b *nftBackend) Clean() error {
+	conn := &nftables.Conn{}
+	tables, err := conn.ListTablesOfFamily(nftables.TableFamilyINet)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if table.Name == Table {
+			conn.DelTable(b.table)
+			return conn.Flush()
+		}
+	}
+	return nil
+}
+
+type nftRule struct {
+	rule   PortHoppingRule
+	handle uint64
+}
+
+// listNftRules 链不存在时返回空，无法识别的规则不是 h-ui 创建的，忽略
+func (b *nftBackend) listNftRules() ([]nftRule, error) {
+	conn := &nftables.Conn{}
+	rules, err := conn.GetRules(b.table, b.chain)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var nftRules []nftRule
+	for _, item := range rules {
+		if rule, ok := parseNftRuleExprs(item.Exprs); ok {
+			nftRules = append(nftRules, nftRule{rule: rule, handle: item.Handle})
+		}
+	}
+	return nftRules, nil
+}
+
+// nftRuleExprs iifname enp1s0 udp dport 30000-40000 counter redirect to :444
+func nftRuleExprs(rule PortHoppingRule) []expr.Any {
+	var exprs []expr.Any
+	if rule.Interface != "" {
+		exprs = append(exprs,
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: nftIfname(rule.Interface)},
+		)
+	}
+	exprs = append(exprs,
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{unix.IPPROTO_UDP}},
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2},
+	)
+	if rule.Start == rule.End {
+		exprs = append(exprs, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(rule.Start)})
+	} else {
+		exprs = append(exprs, &expr.Range{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			FromData: binaryutil.BigEndian.PutUint16(rule.Start),
+			ToData:   binaryutil.BigEndian.PutUint16(rule.End),
+		})
+	}
+	return append(exprs,
+		&expr.Counter{},
+		&expr.Immediate{Register: 1, Data: binaryutil.BigEndian.PutUint16(rule.Target)},
+		&expr.Redir{RegisterProtoMin: 1},
+	)
+}
+
+// parseNftRuleExprs nftRuleExprs 的逆操作
+func parseNftRuleExprs(exprs []expr.Any) (PortHoppingRule, bool) {
+	var rule PortHoppingRule
+	var meta expr.MetaKey
+	udp, dport, redirect := false, false, false
+	for _, item := range exprs {
+		switch e := item.(type) {
+		case *expr.Meta:
+			meta = e.Key
+		case *expr.Payload:
+			if e.Base != expr.PayloadBaseTransportHeader || e.Offset != 2 || e.Len != 2 {
+				return rule, false
+			}
+			meta, dport = 0, true
+		case *expr.Cmp:
+			if e.Op != expr.CmpOpEq {
+				return rule, false
+			}
+			switch {
+			case meta == expr.MetaKeyIIFNAME:
+				rule.Interface = string(bytes.TrimRight(e.Data, "\x00"))
+			case meta == expr.MetaKeyL4PROTO:
+				udp = len(e.Data) == 1 && e.Data[0] == unix.IPPROTO_UDP
+			case dport && len(e.Data) == 2:
+				rule.Start = binaryutil.BigEndian.Uint16(e.Data)
+				rule.End = rule.Start
+			default:
+				return rule, false
+			}
+		case *expr.Range:
+			if !dport || e.Op != expr.CmpOpEq || len(e.FromData) != 2 || len(e.ToData) != 2 {
+				return rule, false
+			}
+			rule.Start = binaryutil.BigEndian.Uint16(e.FromData)
+			rule.End = binaryutil.BigEndian.Uint16(e.ToData)
+		case *expr.Immediate:
+			if len(e.Data) != 2 {
+				return rule, false
+			}
+			rule.Target = binaryutil.BigEndian.Uint16(e.Data)
+		case *expr.Redir:
+			redirect = true
+		case *expr.Counter:
+		default:
+			return rule, false
+		}
+	}
+	return rule, udp && redirect && rule.Start != 0 && rule.Target != 0
+}
+
+func nftIfname(name string) []byte {
+	data := make([]byte, unix.IFNAMSIZ)
+	copy(data, name+"\x00")
+	return data
+}
+
+// nftComment 与 nft 命令行的 comment 格式一致，nft list ruleset 时可以看到
+func nftComment(comment string) []byte {
+	data := append([]byte(comment), 0)
+	return append([]byte{0, byte(len(data))}, data...)
+}
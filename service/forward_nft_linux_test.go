@@ -0,0 +1,23 @@
+//go:build linux
+
+package service
+
+import (
+	"github.com/google/nftables/expr"
+	"testing"
+)
+
+func TestNftRuleExprs(t *testing.T) {
+	for _, rule := range []PortHoppingRule{
+		{Interface: "enp1s0", Start: 30000, End: 40000, Target: 443},
+		{Start: 50000, End: 50000, Target: 8443},
+	} {
+		if got, ok := parseNftRuleExprs(nftRuleExprs(rule)); !ok || got != rule {
+			t.Errorf("expected %v, got: %v %v", rule, got, ok)
+		}
+	}
+	masquerade := []expr.Any{&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1}, &expr.Masq{}}
+	if _, ok := parseNftRuleExprs(masquerade); ok {
+		t.Error("foreign rule should be ignored")
+	}
+}
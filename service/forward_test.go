@@ -0,0 +1,157 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fakePortHoppingBackend struct {
+	rules []PortHoppingRule
+	calls int
+}
+
+func (b *fakePortHoppingBackend) Name() string {
+	return "fake"
+}
+
+func (b *fakePortHoppingBackend) Init() error {
+	return nil
+}
+
+func (b *fakePortHoppingBackend) List() ([]PortHoppingRule, error) {
+	return append([]PortHoppingRule(nil), b.rules...), nil
+}
+
+func (b *fakePortHoppingBackend) Add(rules []PortHoppingRule) error {
+	b.calls++
+	b.rules = append(b.rules, rules...)
+	return nil
+}
+
+func (b *fakePortHoppingBackend) Delete(rules []PortHoppingRule) error {
+	b.calls++
+	for _, rule := range rules {
+		for i, item := range b.rules {
+			if item == rule {
+				b.rules = append(b.rules[:i], b.rules[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (b *fakePortHoppingBackend) Clean() error {
+	b.rules = nil
+	return nil
+}
+
+func TestPortHoppingRules(t *testing.T) {
+	rules, err := portHoppingRules([]string{"eth0", "bond0"}, "50000, 30000-40000", 443)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	expect := []PortHoppingRule{
+		{Interface: "eth0", Start: 50000, End: 50000, Target: 443},
+		{Interface: "bond0", Start: 50000, End: 50000, Target: 443},
+		{Interface: "eth0", Start: 30000, End: 40000, Target: 443},
+		{Interface: "bond0", Start: 30000, End: 40000, Target: 443},
+	}
+	if !reflect.DeepEqual(rules, expect) {
+		t.Errorf("expected %v, got: %v", expect, rules)
+	}
+	for _, value := range []string{"0", "40000-30000", "1-2-3", "a", "70000", "30000-40000,35000"} {
+		if _, err = portHoppingRules([]string{""}, value, 443); err == nil {
+			t.Errorf("%s should fail", value)
+		}
+	}
+}
+
+func TestParsePortHoppingInterfaces(t *testing.T) {
+	for _, value := range []string{"", "all", " all "} {
+		if ifaces, err := ParsePortHoppingInterfaces(value); err != nil || !reflect.DeepEqual(ifaces, []string{""}) {
+			t.Errorf("%q should match all interfaces, got: %v %v", value, ifaces, err)
+		}
+	}
+	if ifaces, err := ParsePortHoppingInterfaces("ens3, bond0,ens3"); err != nil || !reflect.DeepEqual(ifaces, []string{"ens3", "bond0"}) {
+		t.Errorf("unexpected interfaces: %v %v", ifaces, err)
+	}
+	for _, value := range []string{"eth0,all", "eth0;reboot", "averyveryverylongname", "eth0,"} {
+		if _, err := ParsePortHoppingInterfaces(value); err == nil {
+			t.Errorf("%q should fail", value)
+		}
+	}
+}
+
+func TestCheckPortHoppingOverlap(t *testing.T) {
+	node1 := PortHoppingRule{Interface: "eth0", Start: 30000, End: 40000, Target: 443}
+	// 不同网卡上的相同端口范围可以转发到不同节点
+	if err := checkPortHoppingOverlap([]PortHoppingRule{node1, {Interface: "eth1", Start: 30000, End: 40000, Target: 444}}); err != nil {
+		t.Errorf("different interfaces should not overlap: %v", err)
+	}
+	for _, rule := range []PortHoppingRule{
+		{Interface: "eth0", Start: 40000, End: 40000, Target: 444},
+		{Start: 35000, End: 45000, Target: 444},
+	} {
+		if err := checkPortHoppingOverlap([]PortHoppingRule{node1, rule}); err == nil {
+			t.Errorf("%s should overlap %s", rule, node1)
+		}
+	}
+}
+
+func TestListenPort(t *testing.T) {
+	for listen, expect := range map[string]uint16{":443": 443, "0.0.0.0:8443": 8443, "[::]:444": 444} {
+		if port, err := listenPort(listen); err != nil || port != expect {
+			t.Errorf("%s: expected %d, got: %d %v", listen, expect, port, err)
+		}
+	}
+	if _, err := listenPort("443a"); err == nil {
+		t.Error("invalid listen should fail")
+	}
+}
+
+func TestApplyPortHopping(t *testing.T) {
+	keep := PortHoppingRule{Interface: "eth0", Start: 30000, End: 40000, Target: 443}
+	stale := PortHoppingRule{Interface: "eth0", Start: 20000, End: 20000, Target: 443}
+	backend := &fakePortHoppingBackend{rules: []PortHoppingRule{keep, stale, keep}}
+	desired := []PortHoppingRule{keep, {Interface: "eth0", Start: 50000, End: 50000, Target: 443}}
+
+	if err := applyPortHopping([]portHoppingBackend{backend}, desired); err != nil {
+		t.Fatalf("apply err: %v", err)
+	}
+	if !reflect.DeepEqual(backend.rules, desired) {
+		t.Errorf("expected %v, got: %v", desired, backend.rules)
+	}
+
+	// 规则一致时不做任何修改
+	backend.calls = 0
+	if err := applyPortHopping([]portHoppingBackend{backend}, desired); err != nil || backend.calls != 0 {
+		t.Errorf("apply should be idempotent, calls: %d err: %v", backend.calls, err)
+	}
+
+	if err := applyPortHopping(nil, desired); err == nil {
+		t.Error("apply without backend should fail")
+	}
+	if err := applyPortHopping(nil, nil); err != nil {
+		t.Errorf("nothing to apply: %v", err)
+	}
+}
+
+func TestParseIptablesRule(t *testing.T) {
+	rule, ok := parseIptablesRule("-A PREROUTING -i enp1s0 -p udp -m udp --dport 30000:40000 -m comment --comment hui_hysteria_porthopping -j REDIRECT --to-ports 444")
+	if expect := (PortHoppingRule{Interface: "enp1s0", Start: 30000, End: 40000, Target: 444}); !ok || rule != expect {
+		t.Errorf("expected %v, got: %v %v", expect, rule, ok)
+	}
+	if got, _ := parseIptablesRule("-A PREROUTING " + iptablesRuleSpec(rule)[len("PREROUTING "):]); got != rule {
+		t.Errorf("rule spec round trip failed: %v", got)
+	}
+	for _, line := range []string{
+		"-P PREROUTING ACCEPT",
+		"-A PREROUTING -p udp -m udp --dport 53 -j REDIRECT --to-ports 5353",
+		"-A DOCKER -i docker0 -j RETURN",
+	} {
+		if _, ok = parseIptablesRule(line); ok {
+			t.Errorf("%s should be ignored", line)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// geoMaxSize geo 文件的大小上限
+	geoMaxSize = 64 * 1024 * 1024
+	// geoManagedUpdateInterval 使用 h-ui 管理的文件时 hysteria2 的更新间隔
+	geoManagedUpdateInterval = "876000h"
+)
+
+// geoFile 由 h-ui 管理的 geo 文件，ACL 未指定 geoip/geosite 时使用
+type geoFile struct {
+	name   string
+	path   string
+	urlKey string
+	parse  func([]byte) (map[string][]string, error)
+}
+
+var geoFiles = []geoFile{
+	{name: "geoip", path: constant.GeoIPPath, urlKey: constant.GeoIPUrl, parse: util.ParseGeoIP},
+	{name: "geosite", path: constant.GeoSitePath, urlKey: constant.GeoSiteUrl, parse: util.ParseGeoSite},
+}
+
+type geoCacheEntry struct {
+	modTime    time.Time
+	size       int64
+	categories map[string][]string
+}
+
+var (
+	// geoLock 更新和回滚 geo 文件时互斥
+	geoLock sync.Mutex
+	// geoCache 按路径缓存 geo 文件的分类，文件修改后重新解析
+	geoCache     = map[string]geoCacheEntry{}
+	geoCacheLock sync.Mutex
+)
+
+func getGeoFile(name string) (geoFile, error) {
+	for _, item := range geoFiles {
+		if item.name == name {
+			return item, nil
+		}
+	}
+	return geoFile{}, fmt.Errorf("geo type %s is not supported", name)
+}
+
+// loadGeoCategories 读取 geo 文件中的分类，文件不存在时返回 false
+func loadGeoCategories(path string, parse func([]byte) (map[string][]string, error)) (map[string][]string, bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	geoCacheLock.Lock()
+	entry, ok := geoCache[path]
+	geoCacheLock.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.categories, true, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	categories, err := parse(content)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	geoCacheLock.Lock()
+	geoCache[path] = geoCacheEntry{modTime: info.ModTime(), size: info.Size(), categories: categories}
+	geoCacheLock.Unlock()
+	return categories, true, nil
+}
+
+// geoAclPath ACL 中指定的文件，未指定时使用 h-ui 管理的文件
+func geoAclPath(acl *bo.ServerConfigACL, file geoFile) string {
+	var path *string
+	if acl != nil {
+		path = acl.GeoIP
+		if file.name == "geosite" {
+			path = acl.GeoSite
+		}
+	}
+	if path != nil && *path != "" {
+		return *path
+	}
+	return file.path
+}
+
+func sameGeoPath(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// geoAclCategories ACL 使用的 geo 文件中的分类，文件不存在时为 nil，由 hysteria2 自行下载且不做检查；
+// override 用于在替换文件之前检查新文件
+func geoAclCategories(acl *bo.ServerConfigACL, override map[string]map[string][]string) (map[string]map[string][]string, error) {
+	result := map[string]map[string][]string{}
+	for _, file := range geoFiles {
+		path := geoAclPath(acl, file)
+		if categories, ok := override[file.name]; ok && sameGeoPath(path, file.path) {
+			result[file.name] = categories
+			continue
+		}
+		categories, _, err := loadGeoCategories(path, file.parse)
+		if err != nil {
+			return nil, err
+		}
+		result[file.name] = categories
+	}
+	return result, nil
+}
+
+// checkGeoAddress 检查 geoip:xx、geosite:xx@attr 引用的分类和属性是否存在
+func checkGeoAddress(address string, categories map[string]map[string][]string) error {
+	name, value, ok := strings.Cut(strings.ToLower(address), ":")
+	if !ok || (name != "geoip" && name != "geosite") || categories[name] == nil {
+		return nil
+	}
+	code, attribute, _ := strings.Cut(value, "@")
+	attributes, ok := categories[name][code]
+	if !ok {
+		return fmt.Errorf("%s category %s is not found", name, code)
+	}
+	if attribute = strings.TrimPrefix(attribute, "!"); attribute != "" && !util.ArrContain(attributes, attribute) {
+		return fmt.Errorf("%s category %s has no attribute %s", name, code, attribute)
+	}
+	return nil
+}
+
+// aclLineAddress 规则的第一个参数，不是规则格式时返回空
+func aclLineAddress(line string) string {
+	if index := strings.Index(line, "#"); index >= 0 {
+		line = line[:index]
+	}
+	match := aclRuleRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return ""
+	}
+	address, _, _ := strings.Cut(match[2], ",")
+	return strings.TrimSpace(address)
+}
+
+func checkGeoLines(lines []string, categories map[string]map[string][]string) error {
+	for _, line := range lines {
+		if err := checkGeoAddress(aclLineAddress(line), categories); err != nil {
+			return fmt.Errorf("acl rule %s: %v", strings.TrimSpace(line), err)
+		}
+	}
+	return nil
+}
+
+// prepareGeoAcl 写入 hysteria2 配置前调用：未指定 geo 文件时使用 h-ui 管理的文件，并检查 ACL 规则引用的分类
+func prepareGeoAcl(acl *bo.ServerConfigACL) error {
+	if acl == nil {
+		return nil
+	}
+	for _, file := range geoFiles {
+		if !sameGeoPath(geoAclPath(acl, file), file.path) || !util.Exists(file.path) {
+			continue
+		}
+		path, err := filepath.Abs(file.path)
+		if err != nil {
+			return err
+		}
+		if file.name == "geoip" {
+			acl.GeoIP = &path
+		} else {
+			acl.GeoSite = &path
+		}
+		// 文件由 h-ui 校验和更新，避免 hysteria2 按默认的更新间隔自行下载覆盖
+		if acl.GeoUpdateInterval == nil || *acl.GeoUpdateInterval == "" {
+			interval := geoManagedUpdateInterval
+			acl.GeoUpdateInterval = &interval
+		}
+	}
+	categories, err := geoAclCategories(acl, nil)
+	if err != nil {
+		return err
+	}
+	return checkGeoLines(acl.Inline, categories)
+}
+
+// geoAclSource 使用 geo 分类的 ACL：主节点（第二节点与主节点相同）和启用的规则集
+type geoAclSource struct {
+	name  string
+	acl   *bo.ServerConfigACL
+	lines []string
+}
+
+func listGeoAclSources() ([]geoAclSource, error) {
+	hysteria2Config, err := GetHysteria2Config()
+	if err != nil {
+		return nil, err
+	}
+	var sources []geoAclSource
+	if hysteria2Config.ACL != nil {
+		sources = append(sources, geoAclSource{name: "node1", acl: hysteria2Config.ACL, lines: hysteria2Config.ACL.Inline})
+	}
+	aclProfiles, err := dao.ListAclProfile("enable = 1")
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range aclProfiles {
+		sources = append(sources, geoAclSource{
+			name:  fmt.Sprintf("acl profile %s", *item.Name),
+			acl:   hysteria2Config.ACL,
+			lines: strings.Split(*item.Rules, "\n"),
+		})
+	}
+	return sources, nil
+}
+
+// checkGeoSources 替换 geo 文件之前确认所有 ACL 规则引用的分类在新文件中都存在
+func checkGeoSources(override map[string]map[string][]string) error {
+	sources, err := listGeoAclSources()
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		categories, err := geoAclCategories(source.acl, override)
+		if err != nil {
+			return err
+		}
+		if err = checkGeoLines(source.lines, categories); err != nil {
+			return fmt.Errorf("%s: %v", source.name, err)
+		}
+	}
+	return nil
+}
+
+func geoReferenced(lines []string, name string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(strings.ToLower(aclLineAddress(line)), name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// applyGeoChange hysteria2 只在启动时加载 geo 文件，重启引用了该类型分类的节点
+func applyGeoChange(name string) error {
+	sources, err := listGeoAclSources()
+	if err != nil {
+		return err
+	}
+	referenced := false
+	for _, source := range sources {
+		referenced = referenced || geoReferenced(source.lines, name)
+	}
+	if !referenced {
+		return nil
+	}
+	if Hysteria2IsRunning() {
+		if err = RestartHysteria2(); err != nil {
+			return err
+		}
+	}
+	if Hysteria2Node2IsRunning() {
+		return RestartHysteria2Node2()
+	}
+	return nil
+}
+
+func geoSha256(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// installGeo 校验后替换 geo 文件，当前文件保留为 .bak 用于回滚
+func installGeo(file geoFile, content []byte, expectSha256 string) error {
+	if expectSha256 != "" && !strings.EqualFold(geoSha256(content), expectSha256) {
+		return fmt.Errorf("%s checksum mismatch", filepath.Base(file.path))
+	}
+	categories, err := file.parse(content)
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %v", filepath.Base(file.path), err)
+	}
+
+	geoLock.Lock()
+	defer geoLock.Unlock()
+	if err = checkGeoSources(map[string]map[string][]string{file.name: categories}); err != nil {
+		return err
+	}
+	tmpPath := file.path + ".tmp"
+	if err = os.WriteFile(tmpPath, content, 0644); err != nil {
+		logrus.Errorf("write geo file err: %v", err)
+		return errors.New("write geo file err")
+	}
+	if util.Exists(file.path) {
+		if err = os.Rename(file.path, file.path+".bak"); err != nil {
+			logrus.Errorf("backup geo file err: %v", err)
+			return errors.New("backup geo file err")
+		}
+	}
+	if err = os.Rename(tmpPath, file.path); err != nil {
+		logrus.Errorf("replace geo file err: %v", err)
+		return errors.New("replace geo file err")
+	}
+	logrus.Infof("%s updated, %d categories", filepath.Base(file.path), len(categories))
+	return applyGeoChange(file.name)
+}
+
+func UploadGeo(name string, content []byte, expectSha256 string) error {
+	file, err := getGeoFile(name)
+	if err != nil {
+		return err
+	}
+	return installGeo(file, content, expectSha256)
+}
+
+func downloadGeo(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		logrus.Errorf("download %s err: %v", url, err)
+		return nil, fmt.Errorf("download %s err", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s err, status code: %d", url, resp.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, geoMaxSize+1))
+	if err != nil {
+		logrus.Errorf("download %s err: %v", url, err)
+		return nil, fmt.Errorf("download %s err", url)
+	}
+	if len(content) > geoMaxSize {
+		return nil, fmt.Errorf("%s is too big", url)
+	}
+	return content, nil
+}
+
+// RefreshGeo 从配置的地址重新下载，未提供 sha256 时使用 <url>.sha256sum 校验
+func RefreshGeo(name string, expectSha256 string) error {
+	file, err := getGeoFile(name)
+	if err != nil {
+		return err
+	}
+	config, err := dao.GetConfig("key = ?", file.urlKey)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSpace(*config.Value)
+	if url == "" {
+		return fmt.Errorf("%s is empty", file.urlKey)
+	}
+	if expectSha256 == "" {
+		checksum, err := downloadGeo(url + ".sha256sum")
+		if err != nil {
+			return fmt.Errorf("checksum is unavailable, please provide sha256: %v", err)
+		}
+		fields := strings.Fields(string(checksum))
+		if len(fields) == 0 || len(fields[0]) != 64 {
+			return errors.New("checksum is invalid, please provide sha256")
+		}
+		expectSha256 = fields[0]
+	}
+	content, err := downloadGeo(url)
+	if err != nil {
+		return err
+	}
+	return installGeo(file, content, expectSha256)
+}
+
+// RollbackGeo 与上一个版本交换
+func RollbackGeo(name string) error {
+	file, err := getGeoFile(name)
+	if err != nil {
+		return err
+	}
+	geoLock.Lock()
+	defer geoLock.Unlock()
+	backupPath := file.path + ".bak"
+	content, err := os.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("%s has no previous version", filepath.Base(file.path))
+	}
+	categories, err := file.parse(content)
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %v", filepath.Base(backupPath), err)
+	}
+	if err = checkGeoSources(map[string]map[string][]string{file.name: categories}); err != nil {
+		return err
+	}
+	tmpPath := file.path + ".tmp"
+	for _, item := range [][2]string{{file.path, tmpPath}, {backupPath, file.path}, {tmpPath, backupPath}} {
+		if err = os.Rename(item[0], item[1]); err != nil {
+			logrus.Errorf("rollback geo file err: %v", err)
+			return errors.New("rollback geo file err")
+		}
+	}
+	logrus.Infof("%s rolled back", filepath.Base(file.path))
+	return applyGeoChange(file.name)
+}
+
+func geoFileInfo(path string) (int64, string, int64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, "", 0, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", 0, false
+	}
+	return info.Size(), geoSha256(content), info.ModTime().UnixMilli(), true
+}
+
+func ListGeo() []vo.GeoVo {
+	geoVos := make([]vo.GeoVo, 0, len(geoFiles))
+	for _, file := range geoFiles {
+		geoVo := vo.GeoVo{Type: file.name, Path: file.path}
+		geoVo.Size, geoVo.Sha256, geoVo.UpdateTime, geoVo.Exists = geoFileInfo(file.path)
+		_, geoVo.BackupSha256, geoVo.BackupUpdateTime, geoVo.Backup = geoFileInfo(file.path + ".bak")
+		if categories, _, err := loadGeoCategories(file.path, file.parse); err == nil {
+			geoVo.Categories = int64(len(categories))
+		}
+		geoVos = append(geoVos, geoVo)
+	}
+	return geoVos
+}
+
+// ListGeoCategory 按名称排序的分类
+func ListGeoCategory(name string) ([]vo.GeoCategoryVo, error) {
+	file, err := getGeoFile(name)
+	if err != nil {
+		return nil, err
+	}
+	categories, ok, err := loadGeoCategories(file.path, file.parse)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", filepath.Base(file.path))
+	}
+	geoCategoryVos := make([]vo.GeoCategoryVo, 0, len(categories))
+	for code, attributes := range categories {
+		sortedAttributes := append([]string{}, attributes...)
+		sort.Strings(sortedAttributes)
+		geoCategoryVos = append(geoCategoryVos, vo.GeoCategoryVo{Name: code, Attributes: sortedAttributes})
+	}
+	sort.Slice(geoCategoryVos, func(i, j int) bool {
+		return geoCategoryVos[i].Name < geoCategoryVos[j].Name
+	})
+	return geoCategoryVos, nil
+}
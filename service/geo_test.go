@@ -0,0 +1,46 @@
+package service
+
+import "testing"
+
+func TestCheckGeoLines(t *testing.T) {
+	categories := map[string]map[string][]string{
+		"geoip":   {"cn": nil, "private": nil},
+		"geosite": {"google": {"ads"}, "cn": nil},
+	}
+	valid := []string{
+		"direct(geoip:cn)",
+		"reject(geosite:google@ads) # ads",
+		"reject(geosite:google@!ads)",
+		"direct(geosite:CN, tcp/443)",
+		"proxy(all)",
+		"# geosite:unknown",
+		"",
+	}
+	if err := checkGeoLines(valid, categories); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	invalid := []string{
+		"direct(geoip:us)",
+		"reject(geosite:unknown)",
+		"reject(geosite:google@cn)",
+	}
+	for _, line := range invalid {
+		if err := checkGeoLines([]string{line}, categories); err == nil {
+			t.Fatalf("%s should be invalid", line)
+		}
+	}
+	// 没有加载到对应的文件时不检查
+	if err := checkGeoLines([]string{"direct(geoip:us)"}, map[string]map[string][]string{}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func TestGeoReferenced(t *testing.T) {
+	lines := []string{"direct(geoip:cn)", "# geosite:google"}
+	if !geoReferenced(lines, "geoip") {
+		t.Fatal("geoip should be referenced")
+	}
+	if geoReferenced(lines, "geosite") {
+		t.Fatal("geosite should not be referenced")
+	}
+}
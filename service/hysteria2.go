@@ -6,6 +6,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 	"h-ui/dao"
+	"h-ui/model/bo"
 	"h-ui/model/constant"
 	"h-ui/model/vo"
 	"h-ui/proxy"
@@ -14,6 +15,13 @@ import (
 )
 
 func InitHysteria2() error {
+	proxy.NewHysteria2Instance().SetCrashHandler(func(err error) {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeCrashed, "node1", err)
+	})
+	proxy.NewHysteria2Node2Instance().SetCrashHandler(func(err error) {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeCrashed, "node2", err)
+	})
+
 	if !util.Exists(util.GetHysteria2BinPath()) {
 		if err := util.DownloadHysteria2(""); err != nil {
 			logrus.Errorf("download hysteria2 bin err: %v", err)
@@ -63,6 +71,18 @@ func setHysteria2ConfigYAML() error {
 		}
 	}
 
+	// 引用了出站池时替换配置中的出站，可用的出站排在前面作为默认出站
+	outbounds, ok, err := renderNodeOutbounds("node1")
+	if err != nil {
+		return err
+	}
+	if ok {
+		serverConfig.Outbounds = outbounds
+	}
+	if err = prepareGeoAcl(serverConfig.ACL); err != nil {
+		return err
+	}
+
 	hysteria2Config, err := yaml.Marshal(&serverConfig)
 	if err != nil {
 		logrus.Errorf("marshal hysteria2 config err: %v", err)
@@ -89,14 +109,30 @@ func StartHysteria2() error {
 	if err := setHysteria2ConfigYAML(); err != nil {
 		return err
 	}
+	running := Hysteria2IsRunning()
 	if err := proxy.NewHysteria2Instance().StartHysteria2(); err != nil {
 		return err
 	}
+	if !running {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeStarted, "node1", nil)
+	}
+	// 规则集实例依赖主节点的认证，随主节点启动
+	if err := SyncAclInstances(); err != nil {
+		logrus.Errorf("sync acl instances err: %v", err)
+	}
 	return nil
 }
 
 func StopHysteria2() error {
-	return proxy.NewHysteria2Instance().StopHysteria2()
+	StopAclInstances()
+	running := Hysteria2IsRunning()
+	if err := proxy.NewHysteria2Instance().StopHysteria2(); err != nil {
+		return err
+	}
+	if running {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeStopped, "node1", nil)
+	}
+	return nil
 }
 
 func RestartHysteria2() error {
@@ -193,7 +229,14 @@ func setHysteria2Node2ConfigYAML() error {
 	if err != nil {
 		return err
 	}
-	if socks5Config.Addr == "" {
+	outbounds, ok, err := renderNodeOutbounds("node2")
+	if err != nil {
+		return err
+	}
+	if ok {
+		// 引用了出站池时不再使用单独的 SOCKS5 上游
+		socks5Config = bo.Socks5Config{}
+	} else if socks5Config.Addr == "" {
 		return errors.New("socks5 config is empty")
 	}
 
@@ -202,6 +245,12 @@ func setHysteria2Node2ConfigYAML() error {
 	if err != nil {
 		return err
 	}
+	if ok {
+		node2Config.Outbounds = outbounds
+	}
+	if err = prepareGeoAcl(node2Config.ACL); err != nil {
+		return err
+	}
 
 	// 更新认证URL
 	authHttpUrl, err := GetAuthHttpUrl()
@@ -253,10 +302,14 @@ func StartHysteria2Node2() error {
 		return err
 	}
 	
+	running := Hysteria2Node2IsRunning()
 	if err := proxy.NewHysteria2Node2Instance().StartHysteria2(); err != nil {
 		logrus.Errorf("failed to start node2 process: %v", err)
 		return err
 	}
+	if !running {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeStarted, "node2", nil)
+	}
 	
 	logrus.Info("Hysteria2 Node2 started successfully")
 	return nil
@@ -266,10 +319,14 @@ func StartHysteria2Node2() error {
 func StopHysteria2Node2() error {
 	logrus.Info("Stopping Hysteria2 Node2...")
 	
+	running := Hysteria2Node2IsRunning()
 	if err := proxy.NewHysteria2Node2Instance().StopHysteria2(); err != nil {
 		logrus.Errorf("failed to stop node2: %v", err)
 		return err
 	}
+	if running {
+		EmitNodeWebhookEvent(constant.WebhookEventNodeStopped, "node2", nil)
+	}
 	
 	logrus.Info("Hysteria2 Node2 stopped successfully")
 	return nil
@@ -355,6 +412,11 @@ func ReleaseAllNodes() error {
 		lastErr = err
 	}
 
+	if err := ReleaseAclInstances(); err != nil {
+		logrus.Errorf("release acl instances err: %v", err)
+		lastErr = err
+	}
+
 	if err := ReleaseHysteria2(); err != nil {
 		logrus.Errorf("release main node err: %v", err)
 		lastErr = err
@@ -16,16 +16,19 @@ import (
 	"time"
 )
 
-func Hysteria2Auth(conPass string) (int64, string, error) {
+// Hysteria2Auth aclProfileId 为发起认证的规则集实例，节点发起时为 0
+func Hysteria2Auth(conPass string, aclProfileId int64) (int64, string, error) {
 	if !Hysteria2IsRunning() {
 		return 0, "", errors.New("hysteria2 is not running")
 	}
 
-	now := time.Now().UnixMilli()
-	account, err := dao.GetAccount("con_pass = ? and deleted = 0 and (quota < 0 or quota > download + upload) and ? < expire_time and ? > kick_util_time", conPass, now, now)
+	account, err := hysteria2AuthAccount(conPass)
 	if err != nil {
 		return 0, "", err
 	}
+	if err = checkAccountAclProfile(account, aclProfileId); err != nil {
+		return 0, "", err
+	}
 
 	// 限制设备数
 	onlineUsers, err := Hysteria2Online()
@@ -40,6 +43,12 @@ func Hysteria2Auth(conPass string) (int64, string, error) {
 	return *account.Id, *account.Username, nil
 }
 
+// hysteria2AuthAccount 查询连接密码对应的可用账号
+func hysteria2AuthAccount(conPass string) (entity.Account, error) {
+	now := time.Now().UnixMilli()
+	return dao.GetAccount("con_pass = ? and deleted = 0 and (quota < 0 or quota > download + upload) and ? < expire_time and ? > kick_util_time", conPass, now, now)
+}
+
 func Hysteria2Online() (map[string]int64, error) {
 	if !Hysteria2IsRunning() {
 		return map[string]int64{}, nil
@@ -56,6 +65,19 @@ func Hysteria2Online() (map[string]int64, error) {
 	if err != nil {
 		return nil, err
 	}
+	if onlineUsers == nil {
+		onlineUsers = map[string]int64{}
+	}
+	// 合并规则集实例的在线设备
+	for _, aclApiPort := range aclInstanceApiPorts() {
+		aclOnlineUsers, err := proxy.NewHysteria2Api(aclApiPort).OnlineUsers(*jwtSecretConfig.Value)
+		if err != nil {
+			continue
+		}
+		for username, device := range aclOnlineUsers {
+			onlineUsers[username] += device
+		}
+	}
 	return onlineUsers, nil
 }
 
@@ -86,6 +108,12 @@ func Hysteria2Kick(ids []int64, kickUtilTime int64) error {
 	if err = proxy.NewHysteria2Api(apiPort).KickUsers(keys, *jwtSecretConfig.Value); err != nil {
 		return err
 	}
+	for _, aclApiPort := range aclInstanceApiPorts() {
+		if err = proxy.NewHysteria2Api(aclApiPort).KickUsers(keys, *jwtSecretConfig.Value); err != nil {
+			return err
+		}
+	}
+	EmitAccountWebhookEvent(constant.WebhookEventAccountKicked, accounts)
 	return nil
 }
 
@@ -123,14 +151,14 @@ func Hysteria2SubscribeWithNodeAccess(conPass string, clientType string, host st
 	}
 
 	// 生成多节点配置
-	nodeConfigs, err := generateMultiNodeConfig(*account, clientType, host, nodeAccess)
+	nodeConfigs, err := generateMultiNodeConfig(account, clientType, host, nodeAccess)
 	if err != nil {
 		return "", "", err
 	}
 
 	userInfo := ""
 	configStr := ""
-	
+
 	if clientType == constant.Shadowrocket || clientType == constant.Clash {
 		userInfo = fmt.Sprintf("upload=%d; download=%d; total=%d; expire=%d",
 			*account.Upload,
@@ -141,21 +169,21 @@ func Hysteria2SubscribeWithNodeAccess(conPass string, clientType string, host st
 		// 转换为bo.Hysteria2格式
 		var proxies []interface{}
 		var proxyNames []string
-		
+
 		for _, nodeConfig := range nodeConfigs {
 			hysteria2 := bo.Hysteria2{
-				Name:     nodeConfig.Name,
-				Type:     nodeConfig.Type,
-				Server:   nodeConfig.Server,
-				Port:     nodeConfig.Port,
-				Ports:    nodeConfig.Ports,
-				Password: nodeConfig.Password,
-				Up:       nodeConfig.Up,
-				Down:     nodeConfig.Down,
-				Sni:      nodeConfig.Sni,
+				Name:           nodeConfig.Name,
+				Type:           nodeConfig.Type,
+				Server:         nodeConfig.Server,
+				Port:           nodeConfig.Port,
+				Ports:          nodeConfig.Ports,
+				Password:       nodeConfig.Password,
+				Up:             nodeConfig.Up,
+				Down:           nodeConfig.Down,
+				Sni:            nodeConfig.Sni,
 				SkipCertVerify: nodeConfig.SkipCertVerify,
 			}
-			
+
 			if nodeConfig.Obfs != "" {
 				if clientType == constant.Shadowrocket {
 					hysteria2.Obfs = nodeConfig.Obfs
@@ -164,7 +192,7 @@ func Hysteria2SubscribeWithNodeAccess(conPass string, clientType string, host st
 					hysteria2.ObfsPassword = nodeConfig.Obfs
 				}
 			}
-			
+
 			proxies = append(proxies, hysteria2)
 			proxyNames = append(proxyNames, nodeConfig.Name)
 		}
@@ -224,6 +252,14 @@ func Hysteria2Url(accountId int64, hostname string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	aclProfile, aclEnable, err := GetAccountAclProfile(account)
+	if err != nil {
+		return "", err
+	}
+	if aclEnable {
+		aclListen := fmt.Sprintf(":%d", *aclProfile.Port)
+		hysteria2Config.Listen = &aclListen
+	}
 
 	urlConfig := ""
 	if hysteria2Config.Obfs != nil &&
@@ -256,7 +292,7 @@ func Hysteria2Url(accountId int64, hostname string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if *hysteria2ConfigPortHopping.Value != "" {
+	if *hysteria2ConfigPortHopping.Value != "" && !aclEnable {
 		// shadowrocket
 		urlConfig += fmt.Sprintf("&mport=%s", *hysteria2ConfigPortHopping.Value)
 	}
@@ -273,6 +309,7 @@ func Hysteria2Url(accountId int64, hostname string) (string, error) {
 	}
 	return fmt.Sprintf("hysteria2://%s@%s%s", *account.ConPass, hostname, *hysteria2Config.Listen) + urlConfig, nil
 }
+
 // generateMultiNodeConfig 生成多节点配置
 func generateMultiNodeConfig(account entity.Account, clientType string, host string, nodeAccess int64) ([]NodeConfig, error) {
 	var nodeConfigs []NodeConfig
@@ -302,6 +339,18 @@ func generateMultiNodeConfig(account entity.Account, clientType string, host str
 		return nil, err
 	}
 
+	// 使用规则集的账号只能连接规则集实例，不支持端口跳跃和第二节点
+	aclProfile, ok, err := GetAccountAclProfile(account)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		aclListen := fmt.Sprintf(":%d", *aclProfile.Port)
+		hysteria2Config.Listen = &aclListen
+		aclNode := generateNodeConfig(hysteria2Config, fmt.Sprintf("%s-%s", mainNodeName, *aclProfile.Name), *account.ConPass, host, "")
+		return append(nodeConfigs, aclNode), nil
+	}
+
 	// 生成主节点配置
 	mainNode := generateNodeConfig(hysteria2Config, mainNodeName, *account.ConPass, host, *hysteria2ConfigPortHopping.Value)
 	nodeConfigs = append(nodeConfigs, mainNode)
@@ -329,8 +378,13 @@ func generateMultiNodeConfig(account entity.Account, clientType string, host str
 					node2Name = *node2Remark.Value
 				}
 
+				node2PortHopping, err := dao.GetConfig("key = ?", constant.Hysteria2Node2PortHopping)
+				if err != nil {
+					return nil, err
+				}
+
 				// 生成第二节点配置
-				node2 := generateNodeConfig(node2Config, node2Name, *account.ConPass, host, *hysteria2ConfigPortHopping.Value)
+				node2 := generateNodeConfig(node2Config, node2Name, *account.ConPass, host, *node2PortHopping.Value)
 				nodeConfigs = append(nodeConfigs, node2)
 			}
 		}
@@ -371,17 +425,23 @@ func generateNodeConfig(config bo.Hysteria2ServerConfig, nodeName string, conPas
 	}
 
 	// 设置SNI
-	if config.ACME != nil &&
-		config.ACME.Domains != nil &&
-		len(config.ACME.Domains) > 0 {
-		nodeConfig.Sni = config.ACME.Domains[0]
-	}
+	nodeConfig.Sni = nodeSni(config)
 
 	nodeConfig.SkipCertVerify = false
 
 	return nodeConfig
 }
 
+// nodeSni 使用 ACME 时客户端以第一个域名作为 SNI，否则使用服务器地址
+func nodeSni(config bo.Hysteria2ServerConfig) string {
+	if config.ACME != nil &&
+		config.ACME.Domains != nil &&
+		len(config.ACME.Domains) > 0 {
+		return config.ACME.Domains[0]
+	}
+	return ""
+}
+
 // NodeConfig 节点配置结构
 type NodeConfig struct {
 	Name           string `json:"name" yaml:"name"`
@@ -396,11 +456,12 @@ type NodeConfig struct {
 	ObfsPassword   string `json:"obfs-password,omitempty" yaml:"obfs-password,omitempty"`
 	Sni            string `json:"sni,omitempty" yaml:"sni,omitempty"`
 	SkipCertVerify bool   `json:"skip-cert-verify" yaml:"skip-cert-verify"`
-}//
- generateHysteria2Url 生成Hysteria2 URL
+}
+
+// generateHysteria2Url 生成Hysteria2 URL
 func generateHysteria2Url(nodeConfig NodeConfig, hostname string) (string, error) {
 	urlConfig := ""
-	
+
 	if nodeConfig.Obfs != "" {
 		urlConfig += fmt.Sprintf("&obfs=salamander&obfs-password=%s", nodeConfig.Obfs)
 	}
@@ -429,10 +490,11 @@ func generateHysteria2Url(nodeConfig NodeConfig, hostname string) (string, error
 	if urlConfig != "" {
 		urlConfig = "/?" + strings.TrimPrefix(urlConfig, "&")
 	}
-	
+
 	return fmt.Sprintf("hysteria2://%s@%s:%s%s", nodeConfig.Password, hostname, nodeConfig.Port, urlConfig), nil
-}/
-/ Hysteria2MultiNodeUrl 生成多节点URL
+}
+
+// Hysteria2MultiNodeUrl 生成多节点URL
 func Hysteria2MultiNodeUrl(accountId int64, hostname string) ([]vo.Hysteria2NodeUrlVo, error) {
 	account, err := dao.GetAccount("id = ?", accountId)
 	if err != nil {
@@ -440,7 +502,7 @@ func Hysteria2MultiNodeUrl(accountId int64, hostname string) ([]vo.Hysteria2Node
 	}
 
 	// 生成多节点配置
-	nodeConfigs, err := generateMultiNodeConfig(*account, constant.V2rayN, hostname, *account.NodeAccess)
+	nodeConfigs, err := generateMultiNodeConfig(account, constant.V2rayN, hostname, *account.NodeAccess)
 	if err != nil {
 		return nil, err
 	}
@@ -467,4 +529,4 @@ func Hysteria2MultiNodeUrl(accountId int64, hostname string) ([]vo.Hysteria2Node
 	}
 
 	return nodeUrls, nil
-}
\ No newline at end of file
+}
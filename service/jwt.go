@@ -13,6 +13,8 @@ import (
 
 const TokenExpireDuration = time.Hour * 24
 
+const accountBoKey = "accountBo"
+
 type MyClaims struct {
 	AccountBo bo.AccountBo `json:"account"`
 	jwt.StandardClaims
@@ -58,3 +60,22 @@ func GetToken(c *gin.Context) string {
 	}
 	return strings.SplitN(tokenStr, " ", 2)[1]
 }
+
+// SetAccountBo 认证通过后将当前账号保存到请求上下文
+func SetAccountBo(c *gin.Context, accountBo bo.AccountBo) {
+	c.Set(accountBoKey, accountBo)
+}
+
+// GetAccountBo 获取当前请求的账号，优先读取上下文，否则解析 token
+func GetAccountBo(c *gin.Context) (bo.AccountBo, error) {
+	if value, exists := c.Get(accountBoKey); exists {
+		if accountBo, ok := value.(bo.AccountBo); ok {
+			return accountBo, nil
+		}
+	}
+	myClaims, err := ParseToken(GetToken(c))
+	if err != nil {
+		return bo.AccountBo{}, err
+	}
+	return myClaims.AccountBo, nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/model/constant"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	loginFreeFailures     = 3                // failures allowed before delays kick in
+	loginMaxDelay         = 30 * time.Second // upper bound of the progressive delay
+	loginUserLockFailures = 5                // failures per username before lockout
+	loginIpLockFailures   = 10               // failures per ip before lockout
+	loginLockDuration     = 15 * time.Minute
+	loginAttemptTTL       = 30 * time.Minute // idle records are forgotten after this
+	loginAttemptPrune     = 1024             // prune expired records once the map reaches this size
+	loginAttemptMax       = 8192             // hard cap on tracked records
+)
+
+type loginAttempt struct {
+	failures    int
+	pending     int // attempts that passed check and have no result yet
+	lastFailure time.Time
+	lastAttempt time.Time
+	lockedUntil time.Time
+}
+
+// last 最近一次尝试或失败的时间
+func (a *loginAttempt) last() time.Time {
+	if a.lastAttempt.After(a.lastFailure) {
+		return a.lastAttempt
+	}
+	return a.lastFailure
+}
+
+// release 结束一次预留的尝试
+func (a *loginAttempt) release() {
+	if a.pending > 0 {
+		a.pending--
+	}
+}
+
+type loginGuard struct {
+	mutex    sync.Mutex
+	attempts map[string]*loginAttempt
+	now      func() time.Time
+}
+
+var defaultLoginGuard = newLoginGuard(time.Now)
+
+func newLoginGuard(now func() time.Time) *loginGuard {
+	return &loginGuard{
+		attempts: make(map[string]*loginAttempt),
+		now:      now,
+	}
+}
+
+func loginUserKey(username string) string {
+	return "user:" + username
+}
+
+func loginIpKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginDelay 连续失败后下一次允许尝试前需要等待的时间
+func loginDelay(failures int) time.Duration {
+	if failures < loginFreeFailures {
+		return 0
+	}
+	shift := failures - loginFreeFailures
+	if shift > 5 {
+		return loginMaxDelay
+	}
+	delay := time.Second << shift
+	if delay > loginMaxDelay {
+		return loginMaxDelay
+	}
+	return delay
+}
+
+func (g *loginGuard) get(key string, now time.Time) *loginAttempt {
+	attempt, exist := g.attempts[key]
+	if !exist {
+		return nil
+	}
+	if now.After(attempt.lockedUntil) && now.Sub(attempt.last()) > loginAttemptTTL {
+		delete(g.attempts, key)
+		return nil
+	}
+	return attempt
+}
+
+// check 返回当前是否允许尝试登录，locked 表示处于锁定期
+// 允许时在同一把锁内预留这次尝试，并发的请求按失败计算退避，之后必须调用 fail 或 succeed
+func (g *loginGuard) check(username string, ip string) (wait time.Duration, locked bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := g.now()
+	keys := []string{loginUserKey(username), loginIpKey(ip)}
+	for _, key := range keys {
+		attempt := g.get(key, now)
+		if attempt == nil {
+			continue
+		}
+		if now.Before(attempt.lockedUntil) {
+			if remain := attempt.lockedUntil.Sub(now); remain > wait {
+				wait = remain
+			}
+			locked = true
+			continue
+		}
+		if remain := attempt.last().Add(loginDelay(attempt.failures + attempt.pending)).Sub(now); remain > wait {
+			wait = remain
+		}
+	}
+	if wait > 0 {
+		return wait, locked
+	}
+	for _, key := range keys {
+		attempt := g.get(key, now)
+		if attempt == nil {
+			attempt = &loginAttempt{}
+			g.attempts[key] = attempt
+		}
+		attempt.pending++
+		attempt.lastAttempt = now
+	}
+	g.prune(now)
+	return 0, false
+}
+
+// fail 记录一次失败，返回本次失败是否触发了锁定
+func (g *loginGuard) fail(username string, ip string) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	now := g.now()
+	lockTriggered := false
+	keys := map[string]int{
+		loginUserKey(username): loginUserLockFailures,
+		loginIpKey(ip):         loginIpLockFailures,
+	}
+	for key, limit := range keys {
+		attempt := g.get(key, now)
+		if attempt == nil {
+			attempt = &loginAttempt{}
+			g.attempts[key] = attempt
+		}
+		attempt.release()
+		attempt.failures++
+		attempt.lastFailure = now
+		if attempt.failures >= limit && !now.Before(attempt.lockedUntil) {
+			attempt.lockedUntil = now.Add(loginLockDuration)
+			attempt.failures = 0
+			lockTriggered = true
+		}
+	}
+	g.prune(now)
+	return lockTriggered
+}
+
+// succeed 只清除用户名的失败记录，IP 的失败记录不能通过登录自己的账号清除
+func (g *loginGuard) succeed(username string, ip string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.attempts, loginUserKey(username))
+	if attempt := g.get(loginIpKey(ip), g.now()); attempt != nil {
+		attempt.release()
+	}
+}
+
+func (g *loginGuard) prune(now time.Time) {
+	if len(g.attempts) < loginAttemptPrune {
+		return
+	}
+	for key := range g.attempts {
+		g.get(key, now)
+	}
+	if len(g.attempts) <= loginAttemptMax {
+		return
+	}
+	// 超过上限时优先淘汰未锁定且最久未失败的记录，一次淘汰到上限的 3/4 以免频繁排序
+	target := loginAttemptMax * 3 / 4
+	keys := make([]string, 0, len(g.attempts))
+	for key, attempt := range g.attempts {
+		if !now.Before(attempt.lockedUntil) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return g.attempts[keys[i]].last().Before(g.attempts[keys[j]].last())
+	})
+	for _, key := range keys {
+		if len(g.attempts) <= target {
+			return
+		}
+		delete(g.attempts, key)
+	}
+	// 全部处于锁定期时淘汰最早到期的记录
+	keys = keys[:0]
+	for key := range g.attempts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return g.attempts[keys[i]].lockedUntil.Before(g.attempts[keys[j]].lockedUntil)
+	})
+	for _, key := range keys[:len(keys)-target] {
+		delete(g.attempts, key)
+	}
+}
+
+// LoginAllowed 登录前校验用户名和 IP 是否处于退避或锁定状态，允许时随后必须调用 LoginFailed 或 LoginSucceeded
+func LoginAllowed(username string, ip string) error {
+	wait, locked := defaultLoginGuard.check(username, ip)
+	if wait <= 0 {
+		return nil
+	}
+	seconds := int64(wait.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	// 锁定时只在 LoginFailed 触发锁定的那一次写审计日志，避免被拒绝的请求不断写入
+	if locked {
+		return fmt.Errorf("too many failed attempts, locked for %d seconds", seconds)
+	}
+	return fmt.Errorf("too many failed attempts, retry after %d seconds", seconds)
+}
+
+// LoginFailed 记录一次登录失败，触发锁定时写入审计记录并发送 Telegram 提醒
+func LoginFailed(username string, ip string) {
+	if !defaultLoginGuard.fail(username, ip) {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"username": username,
+		"clientIP": ip,
+		"duration": loginLockDuration.String(),
+	}).Warn("login locked out after repeated failures")
+	AuditLogin(username, ip, constant.AuditResultFail, fmt.Sprintf("locked for %s after repeated failures", loginLockDuration.String()))
+	go TelegramLoginLockRemind(username, ip)
+}
+
+// LoginSucceeded 登录成功后清除用户名的失败记录
+func LoginSucceeded(username string, ip string) {
+	defaultLoginGuard.succeed(username, ip)
+}
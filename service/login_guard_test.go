@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		expect   time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{8, loginMaxDelay},
+		{100, loginMaxDelay},
+	}
+	for _, tt := range tests {
+		if delay := loginDelay(tt.failures); delay != tt.expect {
+			t.Errorf("loginDelay(%d) expected %v, got: %v", tt.failures, tt.expect, delay)
+		}
+	}
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newLoginGuard(func() time.Time { return now })
+
+	for i := 0; i < loginUserLockFailures-1; i++ {
+		if wait, _ := guard.check("sysadmin", "1.1.1.1"); wait > 0 {
+			now = now.Add(wait)
+		}
+		if guard.fail("sysadmin", "1.1.1.1") {
+			t.Fatalf("unexpected lockout after %d failures", i+1)
+		}
+	}
+
+	// 进入退避期
+	wait, locked := guard.check("sysadmin", "1.1.1.1")
+	if wait <= 0 || locked {
+		t.Fatalf("expected progressive delay, got wait: %v locked: %v", wait, locked)
+	}
+
+	now = now.Add(wait)
+	if !guard.fail("sysadmin", "1.1.1.1") {
+		t.Fatal("expected lockout to be triggered")
+	}
+	wait, locked = guard.check("sysadmin", "2.2.2.2")
+	if !locked || wait != loginLockDuration {
+		t.Fatalf("expected username to be locked for %v, got wait: %v locked: %v", loginLockDuration, wait, locked)
+	}
+
+	// 其他用户名不受影响（IP 尚未达到阈值）
+	if wait, _ = guard.check("other", "3.3.3.3"); wait != 0 {
+		t.Errorf("expected other username to be allowed, got wait: %v", wait)
+	}
+
+	now = now.Add(loginLockDuration + time.Second)
+	if wait, locked = guard.check("sysadmin", "1.1.1.1"); locked || wait != 0 {
+		t.Errorf("expected lock to expire, got wait: %v locked: %v", wait, locked)
+	}
+}
+
+func TestLoginGuardSucceedResets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newLoginGuard(func() time.Time { return now })
+
+	for i := 0; i < loginFreeFailures; i++ {
+		guard.fail("sysadmin", "1.1.1.1")
+	}
+	if wait, _ := guard.check("sysadmin", "1.1.1.1"); wait == 0 {
+		t.Fatal("expected delay after repeated failures")
+	}
+	guard.succeed("sysadmin", "1.1.1.1")
+	if wait, _ := guard.check("sysadmin", "2.2.2.2"); wait != 0 {
+		t.Errorf("expected no delay for the username after success, got: %v", wait)
+	}
+	// 登录成功不清除 IP 的失败记录
+	if wait, _ := guard.check("other", "1.1.1.1"); wait == 0 {
+		t.Error("expected ip delay to survive a successful login")
+	}
+}
+
+func TestLoginGuardSucceedKeepsIpLock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newLoginGuard(func() time.Time { return now })
+
+	for i := 0; i < loginIpLockFailures; i++ {
+		guard.fail(fmt.Sprintf("user%d", i), "1.1.1.1")
+	}
+	guard.succeed("owned", "1.1.1.1")
+	if wait, locked := guard.check("owned", "1.1.1.1"); !locked || wait <= 0 {
+		t.Fatalf("expected ip to stay locked, got wait: %v locked: %v", wait, locked)
+	}
+}
+
+func TestLoginGuardReservesConcurrentAttempts(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newLoginGuard(func() time.Time { return now })
+
+	// 并发的请求还没有结果时也计入退避
+	for i := 0; i < loginFreeFailures; i++ {
+		if wait, _ := guard.check("sysadmin", "1.1.1.1"); wait != 0 {
+			t.Fatalf("expected attempt %d to be allowed, got wait: %v", i+1, wait)
+		}
+	}
+	if wait, _ := guard.check("sysadmin", "1.1.1.1"); wait == 0 {
+		t.Fatal("expected parallel attempts beyond the free failures to wait")
+	}
+
+	// 成功结束的尝试释放预留
+	for i := 0; i < loginFreeFailures; i++ {
+		guard.succeed("sysadmin", "1.1.1.1")
+	}
+	if wait, _ := guard.check("sysadmin", "1.1.1.1"); wait != 0 {
+		t.Errorf("expected reservations to be released, got wait: %v", wait)
+	}
+}
+
+func TestLoginGuardCapsAttempts(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard := newLoginGuard(func() time.Time { return now })
+
+	for i := 0; i < loginAttemptMax; i++ {
+		now = now.Add(time.Millisecond)
+		guard.fail(fmt.Sprintf("user%d", i), fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	if len(guard.attempts) > loginAttemptMax {
+		t.Fatalf("expected at most %d records, got %d", loginAttemptMax, len(guard.attempts))
+	}
+	if _, exist := guard.attempts[loginUserKey("user0")]; exist {
+		t.Error("expected the oldest record to be evicted")
+	}
+	if _, exist := guard.attempts[loginUserKey(fmt.Sprintf("user%d", loginAttemptMax-1))]; !exist {
+		t.Error("expected the newest record to be kept")
+	}
+}
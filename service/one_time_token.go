@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"h-ui/model/constant"
+	"h-ui/util"
+	"sync"
+	"time"
+)
+
+type oneTimeToken struct {
+	accountId int64
+	expireAt  time.Time
+}
+
+// oneTimeTokens 短期有效且只能使用一次的凭证，如 Telegram 绑定码、用户登录链接
+type oneTimeTokens struct {
+	mutex  sync.Mutex
+	length int
+	ttl    time.Duration
+	tokens map[string]oneTimeToken
+}
+
+func newOneTimeTokens(length int, ttl time.Duration) *oneTimeTokens {
+	return &oneTimeTokens{
+		length: length,
+		ttl:    ttl,
+		tokens: make(map[string]oneTimeToken),
+	}
+}
+
+// issue 生成新凭证，同一账号之前的凭证作废
+func (t *oneTimeTokens) issue(accountId int64, now time.Time) (string, time.Time, error) {
+	token, err := util.RandomString(t.length)
+	if err != nil {
+		return "", time.Time{}, errors.New(constant.SysError)
+	}
+	expireAt := now.Add(t.ttl)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for key, item := range t.tokens {
+		if item.accountId == accountId || now.After(item.expireAt) {
+			delete(t.tokens, key)
+		}
+	}
+	t.tokens[token] = oneTimeToken{accountId: accountId, expireAt: expireAt}
+	return token, expireAt, nil
+}
+
+// take 取出凭证对应的账号，无论是否过期凭证都会被删除
+func (t *oneTimeTokens) take(token string, now time.Time) (int64, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	item, exist := t.tokens[token]
+	if !exist {
+		return 0, false
+	}
+	delete(t.tokens, token)
+	return item.accountId, now.Before(item.expireAt)
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// outboundNode 节点引用的出站，配置为逗号分隔的出站 id，顺序即故障转移顺序
+type outboundNode struct {
+	name string
+	key  string
+}
+
+var outboundNodes = []outboundNode{
+	{name: "node1", key: constant.Hysteria2Outbounds},
+	{name: "node2", key: constant.Hysteria2Node2Outbounds},
+}
+
+var (
+	outboundLock sync.Mutex
+	// outboundPrimary 每个节点配置文件中当前的默认出站，即排在第一位的出站
+	outboundPrimary = map[string]string{}
+)
+
+// ParseOutboundIds 解析节点引用的出站 id 列表，去掉空白和重复
+func ParseOutboundIds(value string) ([]int64, error) {
+	var ids []int64
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("outbound id %s is invalid", item)
+		}
+		if !util.ArrContain(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// CheckNodeOutbounds 节点引用的出站必须存在
+func CheckNodeOutbounds(value string) error {
+	ids, err := ParseOutboundIds(value)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err = dao.GetOutbound("id = ?", id); err != nil {
+			return fmt.Errorf("outbound %d not found", id)
+		}
+	}
+	return nil
+}
+
+func nodeOutboundIds(node outboundNode) ([]int64, error) {
+	config, err := dao.GetConfig("key = ?", node.key)
+	if err != nil {
+		return nil, err
+	}
+	return ParseOutboundIds(*config.Value)
+}
+
+// outboundAvailable 未启用或连续失败的出站不作为默认出站，未检查过的按可用处理
+func outboundAvailable(outbound entity.Outbound) bool {
+	return *outbound.Enable == 1 && *outbound.Status != constant.OutboundStatusUnhealthy
+}
+
+// orderOutbounds 按节点配置的顺序排列，可用的出站在前，其余的保留在后面以便 ACL 规则仍能按名称引用
+func orderOutbounds(ids []int64, outbounds []entity.Outbound) []entity.Outbound {
+	var available, unavailable []entity.Outbound
+	for _, id := range ids {
+		for _, item := range outbounds {
+			if *item.Id != id {
+				continue
+			}
+			if outboundAvailable(item) {
+				available = append(available, item)
+			} else {
+				unavailable = append(unavailable, item)
+			}
+		}
+	}
+	return append(available, unavailable...)
+}
+
+func outboundEntry(outbound entity.Outbound) bo.ServerConfigOutboundEntry {
+	entry := bo.ServerConfigOutboundEntry{Name: outbound.Name, Type: outbound.Type}
+	switch *outbound.Type {
+	case constant.OutboundTypeDirect:
+		direct := bo.ServerConfigOutboundDirect{}
+		if *outbound.BindIPv4 != "" {
+			direct.BindIPv4 = outbound.BindIPv4
+		}
+		if *outbound.BindIPv6 != "" {
+			direct.BindIPv6 = outbound.BindIPv6
+		}
+		if *outbound.BindDevice != "" {
+			direct.BindDevice = outbound.BindDevice
+		}
+		if direct.BindIPv4 != nil || direct.BindIPv6 != nil || direct.BindDevice != nil {
+			entry.Direct = &direct
+		}
+	case constant.OutboundTypeSocks5:
+		socks5 := bo.ServerConfigOutboundSOCKS5{Addr: outbound.Addr}
+		if *outbound.Username != "" {
+			socks5.Username = outbound.Username
+		}
+		if *outbound.Password != "" {
+			socks5.Password = outbound.Password
+		}
+		entry.SOCKS5 = &socks5
+	case constant.OutboundTypeHttp:
+		insecure := *outbound.Insecure == 1
+		entry.HTTP = &bo.ServerConfigOutboundHTTP{URL: outbound.Addr, Insecure: &insecure}
+	}
+	return entry
+}
+
+func listNodeOutbounds(node outboundNode) ([]entity.Outbound, error) {
+	ids, err := nodeOutboundIds(node)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	outbounds, err := dao.ListOutbound("id in ?", ids)
+	if err != nil {
+		return nil, err
+	}
+	return orderOutbounds(ids, outbounds), nil
+}
+
+// renderNodeOutbounds 生成节点配置中的出站，节点未引用出站时返回 false，使用节点自身的配置
+func renderNodeOutbounds(name string) ([]bo.ServerConfigOutboundEntry, bool, error) {
+	for _, node := range outboundNodes {
+		if node.name != name {
+			continue
+		}
+		outbounds, err := listNodeOutbounds(node)
+		if err != nil || len(outbounds) == 0 {
+			return nil, false, err
+		}
+		var entries []bo.ServerConfigOutboundEntry
+		for _, item := range outbounds {
+			entries = append(entries, outboundEntry(item))
+		}
+		outboundLock.Lock()
+		outboundPrimary[name] = *outbounds[0].Name
+		outboundLock.Unlock()
+		return entries, true, nil
+	}
+	return nil, false, nil
+}
+
+func nodeRunning(name string) bool {
+	if name == "node1" {
+		return Hysteria2IsRunning()
+	}
+	return Hysteria2Node2IsRunning()
+}
+
+// restartOutboundNode 重新生成运行中节点的配置并重启
+func restartOutboundNode(name string) error {
+	if !nodeRunning(name) {
+		return nil
+	}
+	if name == "node1" {
+		return RestartHysteria2()
+	}
+	return RestartHysteria2Node2()
+}
+
+// ApplyNodeOutbounds 节点引用的出站修改后重启节点
+func ApplyNodeOutbounds(key string) error {
+	for _, node := range outboundNodes {
+		if node.key == key {
+			return restartOutboundNode(node.name)
+		}
+	}
+	return nil
+}
+
+// outboundUsedBy 引用该出站的节点
+func outboundUsedBy(id int64) ([]string, error) {
+	var nodes []string
+	for _, node := range outboundNodes {
+		ids, err := nodeOutboundIds(node)
+		if err != nil {
+			return nil, err
+		}
+		if util.ArrContain(ids, id) {
+			nodes = append(nodes, node.name)
+		}
+	}
+	return nodes, nil
+}
+
+func existOutboundName(name string, id int64) bool {
+	outbound, err := dao.GetOutbound("name = ?", name)
+	return err == nil && *outbound.Id != id
+}
+
+// checkOutbound 名称会写入 hysteria2 配置被 ACL 规则引用，不能与内置出站重复
+func checkOutbound(outbound entity.Outbound) error {
+	if !aclNameRegexp.MatchString(*outbound.Name) || util.ArrContain(aclBuiltinOutbounds, strings.ToLower(*outbound.Name)) {
+		return fmt.Errorf("outbound name %s is invalid", *outbound.Name)
+	}
+	switch *outbound.Type {
+	case constant.OutboundTypeDirect:
+		if *outbound.BindDevice != "" && (*outbound.BindIPv4 != "" || *outbound.BindIPv6 != "") {
+			return fmt.Errorf("bind device and bind ip cannot be used together")
+		}
+		if *outbound.BindDevice != "" && !ifaceRegexp.MatchString(*outbound.BindDevice) {
+			return fmt.Errorf("bind device %s is invalid", *outbound.BindDevice)
+		}
+	case constant.OutboundTypeSocks5:
+		host, port, err := net.SplitHostPort(*outbound.Addr)
+		if err != nil || host == "" {
+			return fmt.Errorf("socks5 addr %s is invalid", *outbound.Addr)
+		}
+		if value, err := strconv.ParseUint(port, 10, 16); err != nil || value == 0 {
+			return fmt.Errorf("socks5 addr %s is invalid", *outbound.Addr)
+		}
+	case constant.OutboundTypeHttp:
+		proxyUrl, err := url.Parse(*outbound.Addr)
+		if err != nil || (proxyUrl.Scheme != "http" && proxyUrl.Scheme != "https") || proxyUrl.Host == "" {
+			return fmt.Errorf("http proxy url %s is invalid", *outbound.Addr)
+		}
+	default:
+		return fmt.Errorf("outbound type %s is not supported", *outbound.Type)
+	}
+	return nil
+}
+
+func SaveOutbound(outboundSaveDto dto.OutboundSaveDto) error {
+	if existOutboundName(*outboundSaveDto.Name, 0) {
+		return fmt.Errorf("outbound %s already exists", *outboundSaveDto.Name)
+	}
+	orEmpty := func(value *string) *string {
+		if value == nil {
+			empty := ""
+			return &empty
+		}
+		return value
+	}
+	outbound := entity.Outbound{
+		Name:       outboundSaveDto.Name,
+		Type:       outboundSaveDto.Type,
+		Addr:       orEmpty(outboundSaveDto.Addr),
+		Username:   orEmpty(outboundSaveDto.Username),
+		Password:   orEmpty(outboundSaveDto.Password),
+		BindIPv4:   orEmpty(outboundSaveDto.BindIPv4),
+		BindIPv6:   orEmpty(outboundSaveDto.BindIPv6),
+		BindDevice: orEmpty(outboundSaveDto.BindDevice),
+		Insecure:   outboundSaveDto.Insecure,
+		Enable:     outboundSaveDto.Enable,
+		Remark:     outboundSaveDto.Remark,
+	}
+	if err := checkOutbound(outbound); err != nil {
+		return err
+	}
+	_, err := dao.SaveOutbound(outbound)
+	return err
+}
+
+// UpdateOutbound 连接参数改变后重新计算健康状态，并重启引用它的节点
+func UpdateOutbound(outboundUpdateDto dto.OutboundUpdateDto) error {
+	outbound, err := dao.GetOutbound("id = ?", *outboundUpdateDto.Id)
+	if err != nil {
+		return err
+	}
+	if outboundUpdateDto.Name != nil && existOutboundName(*outboundUpdateDto.Name, *outbound.Id) {
+		return fmt.Errorf("outbound %s already exists", *outboundUpdateDto.Name)
+	}
+	updates := map[string]interface{}{}
+	setString := func(column string, dst **string, src *string) {
+		if src != nil && *src != **dst {
+			updates[column] = *src
+			*dst = src
+		}
+	}
+	setString("name", &outbound.Name, outboundUpdateDto.Name)
+	setString("type", &outbound.Type, outboundUpdateDto.Type)
+	setString("addr", &outbound.Addr, outboundUpdateDto.Addr)
+	setString("username", &outbound.Username, outboundUpdateDto.Username)
+	setString("password", &outbound.Password, outboundUpdateDto.Password)
+	setString("bind_ipv4", &outbound.BindIPv4, outboundUpdateDto.BindIPv4)
+	setString("bind_ipv6", &outbound.BindIPv6, outboundUpdateDto.BindIPv6)
+	setString("bind_device", &outbound.BindDevice, outboundUpdateDto.BindDevice)
+	if outboundUpdateDto.Insecure != nil && *outboundUpdateDto.Insecure != *outbound.Insecure {
+		updates["insecure"] = *outboundUpdateDto.Insecure
+	}
+	if outboundUpdateDto.Enable != nil && *outboundUpdateDto.Enable != *outbound.Enable {
+		updates["enable"] = *outboundUpdateDto.Enable
+	}
+	if err = checkOutbound(outbound); err != nil {
+		return err
+	}
+	changed := len(updates) > 0
+	if changed {
+		updates["status"] = constant.OutboundStatusUnknown
+		updates["fail_count"] = 0
+	}
+	if outboundUpdateDto.Remark != nil {
+		updates["remark"] = *outboundUpdateDto.Remark
+	}
+	if err = dao.UpdateOutbound([]int64{*outbound.Id}, updates); err != nil || !changed {
+		return err
+	}
+	nodes, err := outboundUsedBy(*outbound.Id)
+	if err != nil {
+		return err
+	}
+	for _, name := range nodes {
+		if err = restartOutboundNode(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteOutbound 被节点引用的出站不能删除
+func DeleteOutbound(id int64) error {
+	nodes, err := outboundUsedBy(id)
+	if err != nil {
+		return err
+	}
+	if len(nodes) > 0 {
+		return fmt.Errorf("outbound is used by %s", strings.Join(nodes, ","))
+	}
+	return dao.DeleteOutbound([]int64{id})
+}
+
+func ListOutbound() ([]vo.OutboundVo, error) {
+	outbounds, err := dao.ListOutbound(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	nodeIds := map[string][]int64{}
+	for _, node := range outboundNodes {
+		if nodeIds[node.name], err = nodeOutboundIds(node); err != nil {
+			return nil, err
+		}
+	}
+	outboundLock.Lock()
+	primary := make(map[string]string, len(outboundPrimary))
+	for name, outboundName := range outboundPrimary {
+		primary[name] = outboundName
+	}
+	outboundLock.Unlock()
+
+	outboundVos := make([]vo.OutboundVo, 0, len(outbounds))
+	for _, item := range outbounds {
+		outboundVo := vo.OutboundVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Name:       *item.Name,
+			Type:       *item.Type,
+			Addr:       *item.Addr,
+			Username:   *item.Username,
+			BindIPv4:   *item.BindIPv4,
+			BindIPv6:   *item.BindIPv6,
+			BindDevice: *item.BindDevice,
+			Insecure:   *item.Insecure,
+			Enable:     *item.Enable,
+			Remark:     *item.Remark,
+			Status:     *item.Status,
+			Latency:    *item.Latency,
+			FailCount:  *item.FailCount,
+			CheckAt:    *item.CheckAt,
+			CheckError: *item.CheckError,
+			Nodes:      []string{},
+			Primary:    []string{},
+		}
+		for _, node := range outboundNodes {
+			if !util.ArrContain(nodeIds[node.name], *item.Id) {
+				continue
+			}
+			outboundVo.Nodes = append(outboundVo.Nodes, node.name)
+			if nodeRunning(node.name) && primary[node.name] == *item.Name {
+				outboundVo.Primary = append(outboundVo.Primary, node.name)
+			}
+		}
+		outboundVos = append(outboundVos, outboundVo)
+	}
+	return outboundVos, nil
+}
+
+// failoverOutbounds 默认出站变为不可用或恢复时重新生成节点配置
+func failoverOutbounds() {
+	for _, node := range outboundNodes {
+		if !nodeRunning(node.name) {
+			continue
+		}
+		outbounds, err := listNodeOutbounds(node)
+		if err != nil || len(outbounds) == 0 {
+			continue
+		}
+		outboundLock.Lock()
+		current := outboundPrimary[node.name]
+		outboundLock.Unlock()
+		next := *outbounds[0].Name
+		if current == next {
+			continue
+		}
+		logrus.Warnf("%s outbound failover from %s to %s", node.name, current, next)
+		if err = restartOutboundNode(node.name); err != nil {
+			logrus.Errorf("%s outbound failover err: %v", node.name, err)
+			continue
+		}
+		EmitWebhookEvent(constant.WebhookEventOutboundFailover, map[string]interface{}{
+			"node": node.name,
+			"from": current,
+			"to":   next,
+		})
+	}
+}
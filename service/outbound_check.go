@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/proxy"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// outboundDialContext 按出站类型建立到目标地址的连接，http 出站由 Transport.Proxy 处理
+func outboundDialContext(outbound entity.Outbound) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	dialer := &net.Dialer{Timeout: constant.OutboundCheckTimeout}
+	switch *outbound.Type {
+	case constant.OutboundTypeDirect:
+		if *outbound.BindDevice != "" {
+			dialer.Control = bindDeviceControl(*outbound.BindDevice)
+		}
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if *outbound.BindIPv4 != "" {
+				dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(*outbound.BindIPv4)}
+				network = "tcp4"
+			} else if *outbound.BindIPv6 != "" {
+				dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(*outbound.BindIPv6)}
+				network = "tcp6"
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}, nil
+	case constant.OutboundTypeSocks5:
+		var auth *proxy.Auth
+		if *outbound.Username != "" {
+			auth = &proxy.Auth{User: *outbound.Username, Password: *outbound.Password}
+		}
+		socks5, err := proxy.SOCKS5("tcp", *outbound.Addr, auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+		return socks5.(proxy.ContextDialer).DialContext, nil
+	}
+	return dialer.DialContext, nil
+}
+
+// probeOutbound 通过出站请求检查地址，返回毫秒延迟，收到任意 HTTP 响应即认为连通
+func probeOutbound(outbound entity.Outbound, checkUrl string) (int64, error) {
+	dialContext, err := outboundDialContext(outbound)
+	if err != nil {
+		return 0, err
+	}
+	transport := &http.Transport{DialContext: dialContext, DisableKeepAlives: true}
+	if *outbound.Type == constant.OutboundTypeHttp {
+		proxyUrl, err := url.Parse(*outbound.Addr)
+		if err != nil {
+			return 0, err
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+		if *outbound.Insecure == 1 {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   constant.OutboundCheckTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	start := time.Now()
+	resp, err := client.Get(checkUrl)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return 0, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return time.Since(start).Milliseconds(), nil
+}
+
+// nextOutboundStatus 成功一次即恢复，连续失败达到阈值后标记为不健康
+func nextOutboundStatus(status string, failCount int64, err error) (string, int64) {
+	if err == nil {
+		return constant.OutboundStatusHealthy, 0
+	}
+	failCount++
+	if failCount >= constant.OutboundFailThreshold {
+		return constant.OutboundStatusUnhealthy, failCount
+	}
+	return status, failCount
+}
+
+func checkOutboundHealth(outbound entity.Outbound, checkUrl string) (entity.Outbound, error) {
+	latency, err := probeOutbound(outbound, checkUrl)
+	status, failCount := nextOutboundStatus(*outbound.Status, *outbound.FailCount, err)
+	updates := map[string]interface{}{
+		"status":     status,
+		"fail_count": failCount,
+		"check_at":   time.Now().UnixMilli(),
+	}
+	if err == nil {
+		updates["latency"] = latency
+		updates["check_error"] = ""
+	} else {
+		updates["check_error"] = err.Error()
+	}
+	if status == constant.OutboundStatusUnhealthy && *outbound.Status != status {
+		logrus.Warnf("outbound %s is unhealthy: %v", *outbound.Name, err)
+	} else if status == constant.OutboundStatusHealthy && *outbound.Status == constant.OutboundStatusUnhealthy {
+		logrus.Infof("outbound %s is healthy again", *outbound.Name)
+	}
+	if updateErr := dao.UpdateOutbound([]int64{*outbound.Id}, updates); updateErr != nil {
+		return outbound, updateErr
+	}
+	return dao.GetOutbound("id = ?", *outbound.Id)
+}
+
+func getOutboundCheckUrl() (string, error) {
+	config, err := dao.GetConfig("key = ?", constant.OutboundCheckUrl)
+	if err != nil {
+		return "", err
+	}
+	if *config.Value == "" {
+		return "", errors.New("outbound check url is empty")
+	}
+	return *config.Value, nil
+}
+
+// CheckOutbound 立即检查一个出站，状态改变时触发故障转移
+func CheckOutbound(id int64) (entity.Outbound, error) {
+	outbound, err := dao.GetOutbound("id = ?", id)
+	if err != nil {
+		return outbound, err
+	}
+	checkUrl, err := getOutboundCheckUrl()
+	if err != nil {
+		return outbound, err
+	}
+	if outbound, err = checkOutboundHealth(outbound, checkUrl); err != nil {
+		return outbound, err
+	}
+	failoverOutbounds()
+	return outbound, nil
+}
+
+// CronOutboundCheck 并发检查所有启用的出站，然后按健康状态切换节点的默认出站
+func CronOutboundCheck() {
+	outbounds, err := dao.ListOutbound("enable = 1")
+	if err != nil || len(outbounds) == 0 {
+		return
+	}
+	checkUrl, err := getOutboundCheckUrl()
+	if err != nil {
+		logrus.Errorf("outbound check err: %v", err)
+		return
+	}
+	var wg sync.WaitGroup
+	for _, item := range outbounds {
+		wg.Add(1)
+		go func(outbound entity.Outbound) {
+			defer wg.Done()
+			if _, err := checkOutboundHealth(outbound, checkUrl); err != nil {
+				logrus.Errorf("check outbound %s err: %v", *outbound.Name, err)
+			}
+		}(item)
+	}
+	wg.Wait()
+	failoverOutbounds()
+}
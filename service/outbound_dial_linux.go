@@ -0,0 +1,21 @@
+//go:build linux
+
+package service
+
+import (
+	"golang.org/x/sys/unix"
+	"syscall"
+)
+
+// bindDeviceControl 检查 direct 出站时绑定网卡，与 hysteria2 的 bindDevice 相同使用 SO_BINDTODEVICE
+func bindDeviceControl(device string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var bindErr error
+		if err := c.Control(func(fd uintptr) {
+			bindErr = unix.BindToDevice(int(fd), device)
+		}); err != nil {
+			return err
+		}
+		return bindErr
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package service
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindDeviceControl 只有 Linux 支持绑定网卡
+func bindDeviceControl(device string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return errors.New("bind device is only supported on linux")
+	}
+}
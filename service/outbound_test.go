@@ -0,0 +1,188 @@
+package service
+
+import (
+	"errors"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func testOutbound(id int64, name string, outboundType string, enable int64, status string) entity.Outbound {
+	empty := ""
+	var zero int64
+	return entity.Outbound{
+		BaseEntity: entity.BaseEntity{Id: &id},
+		Name:       &name,
+		Type:       &outboundType,
+		Addr:       &empty,
+		Username:   &empty,
+		Password:   &empty,
+		BindIPv4:   &empty,
+		BindIPv6:   &empty,
+		BindDevice: &empty,
+		Insecure:   &zero,
+		Enable:     &enable,
+		Status:     &status,
+		FailCount:  &zero,
+	}
+}
+
+func TestParseOutboundIds(t *testing.T) {
+	ids, err := ParseOutboundIds(" 3, 1,3,,2 ")
+	if err != nil || !reflect.DeepEqual(ids, []int64{3, 1, 2}) {
+		t.Errorf("unexpected ids: %v %v", ids, err)
+	}
+	if ids, err = ParseOutboundIds(""); err != nil || len(ids) != 0 {
+		t.Errorf("empty value should have no ids: %v %v", ids, err)
+	}
+	for _, value := range []string{"a", "0", "1,-2"} {
+		if _, err = ParseOutboundIds(value); err == nil {
+			t.Errorf("%s should be invalid", value)
+		}
+	}
+}
+
+func TestOrderOutbounds(t *testing.T) {
+	outbounds := []entity.Outbound{
+		testOutbound(1, "hk", constant.OutboundTypeSocks5, 1, constant.OutboundStatusUnhealthy),
+		testOutbound(2, "jp", constant.OutboundTypeSocks5, 1, constant.OutboundStatusHealthy),
+		testOutbound(3, "sg", constant.OutboundTypeHttp, 0, constant.OutboundStatusHealthy),
+		testOutbound(4, "local", constant.OutboundTypeDirect, 1, constant.OutboundStatusUnknown),
+	}
+	var names []string
+	for _, item := range orderOutbounds([]int64{1, 3, 2, 4, 5}, outbounds) {
+		names = append(names, *item.Name)
+	}
+	// 不可用的出站保持配置顺序排在最后，不存在的 id 忽略
+	if expect := []string{"jp", "local", "hk", "sg"}; !reflect.DeepEqual(names, expect) {
+		t.Errorf("expected %v, got: %v", expect, names)
+	}
+}
+
+func TestNextOutboundStatus(t *testing.T) {
+	failErr := errors.New("timeout")
+	status, failCount := constant.OutboundStatusHealthy, int64(0)
+	for i := 1; i < constant.OutboundFailThreshold; i++ {
+		if status, failCount = nextOutboundStatus(status, failCount, failErr); status != constant.OutboundStatusHealthy {
+			t.Fatalf("should stay healthy after %d failures", i)
+		}
+	}
+	if status, failCount = nextOutboundStatus(status, failCount, failErr); status != constant.OutboundStatusUnhealthy ||
+		failCount != constant.OutboundFailThreshold {
+		t.Errorf("should be unhealthy, got: %s %d", status, failCount)
+	}
+	if status, failCount = nextOutboundStatus(status, failCount, nil); status != constant.OutboundStatusHealthy || failCount != 0 {
+		t.Errorf("should recover after one success, got: %s %d", status, failCount)
+	}
+}
+
+func TestOutboundEntry(t *testing.T) {
+	direct := testOutbound(1, "local", constant.OutboundTypeDirect, 1, constant.OutboundStatusUnknown)
+	if entry := outboundEntry(direct); entry.Direct != nil {
+		t.Errorf("direct without bind should have no direct options")
+	}
+	device := "eth1"
+	direct.BindDevice = &device
+	if entry := outboundEntry(direct); entry.Direct == nil || *entry.Direct.BindDevice != "eth1" || entry.Direct.BindIPv4 != nil {
+		t.Errorf("unexpected direct entry: %+v", entry.Direct)
+	}
+
+	socks5 := testOutbound(2, "hk", constant.OutboundTypeSocks5, 1, constant.OutboundStatusUnknown)
+	addr, user := "127.0.0.1:1080", "user"
+	socks5.Addr, socks5.Username = &addr, &user
+	entry := outboundEntry(socks5)
+	if entry.SOCKS5 == nil || *entry.SOCKS5.Addr != addr || *entry.SOCKS5.Username != user || entry.SOCKS5.Password != nil {
+		t.Errorf("unexpected socks5 entry: %+v", entry.SOCKS5)
+	}
+}
+
+func TestProbeOutbound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	direct := testOutbound(1, "local", constant.OutboundTypeDirect, 1, constant.OutboundStatusUnknown)
+	bindIPv4 := "127.0.0.1"
+	direct.BindIPv4 = &bindIPv4
+	if _, err := probeOutbound(direct, server.URL); err != nil {
+		t.Errorf("direct probe failed: %v", err)
+	}
+
+	// 测试服务器同时作为 http 代理，收到的是绝对地址的请求
+	httpProxy := testOutbound(2, "proxy", constant.OutboundTypeHttp, 1, constant.OutboundStatusUnknown)
+	httpProxy.Addr = &server.URL
+	if _, err := probeOutbound(httpProxy, "http://example.invalid/generate_204"); err != nil {
+		t.Errorf("http proxy probe failed: %v", err)
+	}
+
+	socks5 := testOutbound(3, "hk", constant.OutboundTypeSocks5, 1, constant.OutboundStatusUnknown)
+	closedAddr := server.Listener.Addr().String()
+	server.Close()
+	socks5.Addr = &closedAddr
+	if _, err := probeOutbound(socks5, "http://example.invalid/"); err == nil {
+		t.Errorf("socks5 probe to a closed port should fail")
+	}
+}
+
+func TestOutboundPasswordEncrypted(t *testing.T) {
+	initTestSqlite(t)
+	raw, err := gorm.Open(sqlite.Open(os.Getenv("HUI_DATA")+constant.SqliteDBPath), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawPassword := func(name string) string {
+		var password string
+		if err := raw.Raw("select password from outbound where name = ?", name).Scan(&password).Error; err != nil {
+			t.Fatal(err)
+		}
+		return password
+	}
+
+	name, outboundType, addr, password := "proxy", constant.OutboundTypeSocks5, "127.0.0.1:1080", "socks-secret"
+	if err = SaveOutbound(dto.OutboundSaveDto{Name: &name, Type: &outboundType, Addr: &addr, Password: &password}); err != nil {
+		t.Fatal(err)
+	}
+	if stored := rawPassword(name); !util.IsEnvelope(stored) {
+		t.Fatalf("password should be stored encrypted: %s", stored)
+	}
+	outbound, err := dao.GetOutbound("name = ?", name)
+	if err != nil || *outbound.Password != password {
+		t.Fatalf("password should be decrypted on read: %v %v", outbound.Password, err)
+	}
+
+	password = "socks-secret-2"
+	if err = UpdateOutbound(dto.OutboundUpdateDto{IdDto: dto.IdDto{Id: outbound.Id}, Password: &password}); err != nil {
+		t.Fatal(err)
+	}
+	if stored := rawPassword(name); !util.IsEnvelope(stored) {
+		t.Fatalf("updated password should be stored encrypted: %s", stored)
+	}
+	outbounds, err := dao.ListOutbound("name = ?", name)
+	if err != nil || len(outbounds) != 1 || *outbounds[0].Password != password {
+		t.Fatalf("password should be decrypted on list: %v", err)
+	}
+
+	// 旧版本保存的明文密码在启动时加密
+	if err = raw.Exec("update outbound set password = ? where name = ?", "legacy", name).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = dao.InitSql(""); err != nil {
+		t.Fatal(err)
+	}
+	if stored := rawPassword(name); !util.IsEnvelope(stored) {
+		t.Fatalf("legacy password should be encrypted on startup: %s", stored)
+	}
+	if outbound, err = dao.GetOutbound("name = ?", name); err != nil || *outbound.Password != "legacy" {
+		t.Fatalf("legacy password should be readable: %v %v", outbound.Password, err)
+	}
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"time"
+)
+
+const planDayMillis = int64(24 * time.Hour / time.Millisecond)
+
+func planDurationMillis(plan entity.Plan) int64 {
+	return *plan.Duration * planDayMillis
+}
+
+// renewExpireTime 续期时长叠加在当前到期时间上，已过期的账号从现在开始计算
+func renewExpireTime(expireTime int64, duration int64, now int64) int64 {
+	if expireTime < now {
+		expireTime = now
+	}
+	return expireTime + duration
+}
+
+// prorateExpireTime 变更套餐时新周期从现在开始，旧套餐剩余时长按价格折算后叠加；
+// 任一套餐未设置价格时剩余时长原样保留
+func prorateExpireTime(oldPlan *entity.Plan, newPlan entity.Plan, expireTime int64, now int64) int64 {
+	remaining := expireTime - now
+	if remaining < 0 {
+		remaining = 0
+	}
+	if oldPlan != nil && *oldPlan.Price > 0 && *newPlan.Price > 0 {
+		value := float64(remaining) * float64(*oldPlan.Price) / float64(planDurationMillis(*oldPlan))
+		remaining = int64(value * float64(planDurationMillis(newPlan)) / float64(*newPlan.Price))
+	}
+	return now + planDurationMillis(newPlan) + remaining
+}
+
+func existPlanName(name string, id int64) bool {
+	plan, err := dao.GetPlan("name = ?", name)
+	return err == nil && *plan.Id != id
+}
+
+// checkSpeedTier 限速档位对应同名的规则集，由规则集实例的 bandwidth 限制每个客户端的速度
+func checkSpeedTier(speedTier string) error {
+	if speedTier == "" {
+		return nil
+	}
+	if _, err := dao.GetAclProfile("name = ?", speedTier); err != nil {
+		return fmt.Errorf("speed tier %s not found in acl profiles", speedTier)
+	}
+	return nil
+}
+
+func SavePlan(planSaveDto dto.PlanSaveDto) error {
+	if existPlanName(*planSaveDto.Name, 0) {
+		return fmt.Errorf("plan %s already exists", *planSaveDto.Name)
+	}
+	if err := ValidateNodeAccess(*planSaveDto.NodeAccess); err != nil {
+		return err
+	}
+	speedTier := ""
+	if planSaveDto.SpeedTier != nil {
+		speedTier = *planSaveDto.SpeedTier
+	}
+	if err := checkSpeedTier(speedTier); err != nil {
+		return err
+	}
+	_, err := dao.SavePlan(entity.Plan{
+		Name:       planSaveDto.Name,
+		Quota:      planSaveDto.Quota,
+		Duration:   planSaveDto.Duration,
+		DeviceNo:   planSaveDto.DeviceNo,
+		NodeAccess: planSaveDto.NodeAccess,
+		SpeedTier:  &speedTier,
+		Price:      planSaveDto.Price,
+		Remark:     planSaveDto.Remark,
+	})
+	return err
+}
+
+// UpdatePlan 修改套餐只影响之后的开通和续期，不修改已有账号
+func UpdatePlan(planUpdateDto dto.PlanUpdateDto) error {
+	plan, err := dao.GetPlan("id = ?", *planUpdateDto.Id)
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{}
+	if planUpdateDto.Name != nil {
+		if existPlanName(*planUpdateDto.Name, *plan.Id) {
+			return fmt.Errorf("plan %s already exists", *planUpdateDto.Name)
+		}
+		updates["name"] = *planUpdateDto.Name
+	}
+	if planUpdateDto.Quota != nil {
+		updates["quota"] = *planUpdateDto.Quota
+	}
+	if planUpdateDto.Duration != nil {
+		updates["duration"] = *planUpdateDto.Duration
+	}
+	if planUpdateDto.DeviceNo != nil {
+		updates["device_no"] = *planUpdateDto.DeviceNo
+	}
+	if planUpdateDto.NodeAccess != nil {
+		if err = ValidateNodeAccess(*planUpdateDto.NodeAccess); err != nil {
+			return err
+		}
+		updates["node_access"] = *planUpdateDto.NodeAccess
+	}
+	if planUpdateDto.SpeedTier != nil {
+		if err = checkSpeedTier(*planUpdateDto.SpeedTier); err != nil {
+			return err
+		}
+		updates["speed_tier"] = *planUpdateDto.SpeedTier
+	}
+	if planUpdateDto.Price != nil {
+		updates["price"] = *planUpdateDto.Price
+	}
+	if planUpdateDto.Remark != nil {
+		updates["remark"] = *planUpdateDto.Remark
+	}
+	return dao.UpdatePlan([]int64{*plan.Id}, updates)
+}
+
+func DeletePlan(id int64) error {
+	accounts, err := dao.ListAccount("plan_id = ?", id)
+	if err != nil {
+		return err
+	}
+	if len(accounts) > 0 {
+		return fmt.Errorf("the plan is used by %d accounts", len(accounts))
+	}
+	return dao.DeletePlan([]int64{id})
+}
+
+func ListPlan() ([]vo.PlanVo, error) {
+	plans, err := dao.ListPlan(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	planVos := make([]vo.PlanVo, 0, len(plans))
+	for _, item := range plans {
+		planVos = append(planVos, vo.PlanVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Name:       *item.Name,
+			Quota:      *item.Quota,
+			Duration:   *item.Duration,
+			DeviceNo:   *item.DeviceNo,
+			NodeAccess: *item.NodeAccess,
+			SpeedTier:  *item.SpeedTier,
+			Price:      *item.Price,
+			Remark:     *item.Remark,
+		})
+	}
+	return planVos, nil
+}
+
+func newAccountPlanHistory(accountId int64, plan entity.Plan, action string, expireTimeBefore int64, expireTimeAfter int64) entity.AccountPlanHistory {
+	return entity.AccountPlanHistory{
+		AccountId:        &accountId,
+		PlanId:           plan.Id,
+		PlanName:         plan.Name,
+		Action:           &action,
+		Quota:            plan.Quota,
+		DeviceNo:         plan.DeviceNo,
+		NodeAccess:       plan.NodeAccess,
+		ExpireTimeBefore: &expireTimeBefore,
+		ExpireTimeAfter:  &expireTimeAfter,
+	}
+}
+
+// SaveAccountFromPlan 按套餐开通账号，到期时间从现在开始计算
+func SaveAccountFromPlan(accountSaveFromPlanDto dto.AccountSaveFromPlanDto) error {
+	if ExistAccountUsername(*accountSaveFromPlanDto.Username, 0) {
+		return fmt.Errorf("username %s already exists", *accountSaveFromPlanDto.Username)
+	}
+	plan, err := dao.GetPlan("id = ?", *accountSaveFromPlanDto.PlanId)
+	if err != nil {
+		return err
+	}
+	if err = ValidateAccountPass(0, *accountSaveFromPlanDto.Username, *accountSaveFromPlanDto.Pass); err != nil {
+		return err
+	}
+	passEncrypt, err := hashPass(*accountSaveFromPlanDto.Pass)
+	if err != nil {
+		return err
+	}
+	conPass := fmt.Sprintf("%s.%s", *accountSaveFromPlanDto.Username, *accountSaveFromPlanDto.ConPass)
+	expireTime := time.Now().UnixMilli() + planDurationMillis(plan)
+	aclProfileId, _ := planAclProfileId(plan)
+	id, err := dao.SaveAccount(entity.Account{
+		Username:     accountSaveFromPlanDto.Username,
+		Pass:         &passEncrypt,
+		ConPass:      &conPass,
+		Quota:        plan.Quota,
+		ExpireTime:   &expireTime,
+		DeviceNo:     plan.DeviceNo,
+		NodeAccess:   plan.NodeAccess,
+		PlanId:       plan.Id,
+		AclProfileId: &aclProfileId,
+	})
+	if err != nil {
+		return err
+	}
+	_ = dao.SaveAccountPlanHistory(newAccountPlanHistory(id, plan, constant.PlanActionCreate, 0, expireTime))
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountCreated, []int64{id})
+	return nil
+}
+
+// planAclProfileId 套餐限速档位对应的规则集，套餐没有限速档位时返回 false
+func planAclProfileId(plan entity.Plan) (int64, bool) {
+	if plan.SpeedTier == nil || *plan.SpeedTier == "" {
+		return 0, false
+	}
+	aclProfile, err := dao.GetAclProfile("name = ?", *plan.SpeedTier)
+	if err != nil {
+		return 0, false
+	}
+	return *aclProfile.Id, true
+}
+
+// kickSpeedTierChange 限速档位变化后断开账号的连接，使其通过新的规则集实例重新认证
+func kickSpeedTierChange(account entity.Account, updates map[string]interface{}) {
+	aclProfileId, ok := updates["acl_profile_id"].(int64)
+	if ok && account.AclProfileId != nil && *account.AclProfileId != aclProfileId {
+		kickAclAccounts([]entity.Account{account})
+	}
+}
+
+// accountPlanUpdates 账号已是该套餐时按周期续期，否则变更套餐并折算剩余时长；两种情况都开始新的流量周期。
+// 套餐有限速档位时账号改用对应的规则集
+func accountPlanUpdates(account entity.Account, plan entity.Plan, periods int64, now int64) (map[string]interface{}, entity.AccountPlanHistory) {
+	action := constant.PlanActionRenew
+	var expireTime int64
+	if *account.PlanId == *plan.Id {
+		expireTime = renewExpireTime(*account.ExpireTime, planDurationMillis(plan)*periods, now)
+	} else {
+		action = constant.PlanActionChange
+		var oldPlan *entity.Plan
+		if *account.PlanId != 0 {
+			if item, err := dao.GetPlan("id = ?", *account.PlanId); err == nil {
+				oldPlan = &item
+			}
+		}
+		expireTime = prorateExpireTime(oldPlan, plan, *account.ExpireTime, now)
+	}
+	updates := map[string]interface{}{
+		"plan_id":     *plan.Id,
+		"quota":       *plan.Quota,
+		"device_no":   *plan.DeviceNo,
+		"node_access": *plan.NodeAccess,
+		"expire_time": expireTime,
+		"download":    0,
+		"upload":      0,
+	}
+	if aclProfileId, ok := planAclProfileId(plan); ok {
+		updates["acl_profile_id"] = aclProfileId
+	}
+	return updates, newAccountPlanHistory(*account.Id, plan, action, *account.ExpireTime, expireTime)
+}
+
+func applyAccountPlan(account entity.Account, plan entity.Plan, periods int64, now int64) error {
+	updates, history := accountPlanUpdates(account, plan, periods, now)
+	if err := dao.ApplyAccountPlan(*account.Id, updates, history); err != nil {
+		return err
+	}
+	kickSpeedTierChange(account, updates)
+	return nil
+}
+
+// RenewAccount 按账号当前套餐续期
+func RenewAccount(id int64, periods int64) error {
+	account, err := dao.GetAccount("id = ?", id)
+	if err != nil {
+		return errors.New("account not found")
+	}
+	if *account.PlanId == 0 {
+		return errors.New("the account is not on any plan")
+	}
+	plan, err := dao.GetPlan("id = ?", *account.PlanId)
+	if err != nil {
+		return err
+	}
+	if periods <= 0 {
+		periods = 1
+	}
+	if err = applyAccountPlan(account, plan, periods, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountUpdated, []int64{id})
+	return nil
+}
+
+func ChangeAccountPlan(id int64, planId int64) error {
+	account, err := dao.GetAccount("id = ?", id)
+	if err != nil {
+		return errors.New("account not found")
+	}
+	if *account.PlanId == planId {
+		return errors.New("the account is already on this plan")
+	}
+	plan, err := dao.GetPlan("id = ?", planId)
+	if err != nil {
+		return err
+	}
+	if err = applyAccountPlan(account, plan, 1, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountUpdated, []int64{id})
+	return nil
+}
+
+// ApplyPlan 批量应用套餐，已是该套餐的账号续期一个周期，其余账号变更套餐
+func ApplyPlan(ids []int64, planId int64) error {
+	plan, err := dao.GetPlan("id = ?", planId)
+	if err != nil {
+		return err
+	}
+	accounts, err := dao.ListAccount("id in ?", ids)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	var appliedIds []int64
+	for _, item := range accounts {
+		if err = applyAccountPlan(item, plan, 1, now); err != nil {
+			continue
+		}
+		appliedIds = append(appliedIds, *item.Id)
+	}
+	if len(appliedIds) > 0 {
+		emitAccountWebhookEventByIds(constant.WebhookEventAccountUpdated, appliedIds)
+	}
+	if len(appliedIds) < len(ids) {
+		return fmt.Errorf("applied to %d of %d accounts", len(appliedIds), len(ids))
+	}
+	return nil
+}
+
+func ListAccountPlanHistory(accountId int64) ([]vo.AccountPlanHistoryVo, error) {
+	histories, err := dao.ListAccountPlanHistory("account_id = ?", accountId)
+	if err != nil {
+		return nil, err
+	}
+	historyVos := make([]vo.AccountPlanHistoryVo, 0, len(histories))
+	for _, item := range histories {
+		historyVos = append(historyVos, vo.AccountPlanHistoryVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			PlanId:           *item.PlanId,
+			PlanName:         *item.PlanName,
+			Action:           *item.Action,
+			Quota:            *item.Quota,
+			DeviceNo:         *item.DeviceNo,
+			NodeAccess:       *item.NodeAccess,
+			ExpireTimeBefore: *item.ExpireTimeBefore,
+			ExpireTimeAfter:  *item.ExpireTimeAfter,
+		})
+	}
+	return historyVos, nil
+}
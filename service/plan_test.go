@@ -0,0 +1,129 @@
+package service
+
+import (
+	"h-ui/dao"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"testing"
+)
+
+func newTestPlan(duration int64, price int64) entity.Plan {
+	return entity.Plan{Duration: &duration, Price: &price}
+}
+
+func TestRenewExpireTime(t *testing.T) {
+	now := int64(1000)
+	if got := renewExpireTime(5000, 100, now); got != 5100 {
+		t.Errorf("renewal should stack on the current expiry, got: %d", got)
+	}
+	if got := renewExpireTime(500, 100, now); got != 1100 {
+		t.Errorf("renewal of an expired account should start now, got: %d", got)
+	}
+}
+
+func TestProrateExpireTime(t *testing.T) {
+	now := int64(0)
+	oldPlan := newTestPlan(30, 300)
+	// 旧套餐剩余 15 天，价值 150，按新套餐单价 600/30 天折算为 7.5 天
+	newPlan := newTestPlan(30, 600)
+	expireTime := 15 * planDayMillis
+	expect := 30*planDayMillis + 15*planDayMillis/2
+	if got := prorateExpireTime(&oldPlan, newPlan, expireTime, now); got != expect {
+		t.Errorf("expected %d, got: %d", expect, got)
+	}
+	// 未设置价格时剩余时长原样保留
+	free := newTestPlan(30, 0)
+	if got := prorateExpireTime(&oldPlan, free, expireTime, now); got != 45*planDayMillis {
+		t.Errorf("expected %d, got: %d", 45*planDayMillis, got)
+	}
+	if got := prorateExpireTime(nil, newPlan, -planDayMillis, now); got != 30*planDayMillis {
+		t.Errorf("expired account should not get credit, got: %d", got)
+	}
+}
+
+func TestRenewAccountResetsTraffic(t *testing.T) {
+	initTestSqlite(t)
+	name, quota, duration, deviceNo, nodeAccess := "basic", int64(100), int64(30), int64(3), int64(1)
+	if err := SavePlan(dto.PlanSaveDto{Name: &name, Quota: &quota, Duration: &duration, DeviceNo: &deviceNo, NodeAccess: &nodeAccess}); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := dao.GetPlan("name = ?", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, pass, conPass := "planuser", "Plan-User-Pass1", "planconpass"
+	if err = SaveAccountFromPlan(dto.AccountSaveFromPlanDto{Username: &username, Pass: &pass, ConPass: &conPass, PlanId: plan.Id}); err != nil {
+		t.Fatal(err)
+	}
+	account, err := dao.GetAccount("username = ?", username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dao.UpdateAccount([]int64{*account.Id}, map[string]interface{}{"download": 80, "upload": 30}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = hysteria2AuthAccount(*account.ConPass); err == nil {
+		t.Fatal("account over quota should not authenticate")
+	}
+
+	if err = RenewAccount(*account.Id, 1); err != nil {
+		t.Fatal(err)
+	}
+	account, err = hysteria2AuthAccount(*account.ConPass)
+	if err != nil {
+		t.Fatalf("renewed account should authenticate: %v", err)
+	}
+	if *account.Download != 0 || *account.Upload != 0 || *account.Quota != quota {
+		t.Fatalf("unexpected traffic after renewal: %d %d %d", *account.Download, *account.Upload, *account.Quota)
+	}
+}
+
+func TestPlanSpeedTier(t *testing.T) {
+	initTestSqlite(t)
+	tierName, port, rules, up := "slow", int64(9443), "direct(all)", "10 mbps"
+	aclProfileId, err := dao.SaveAclProfile(entity.AclProfile{Name: &tierName, Port: &port, Rules: &rules, BandwidthUp: &up})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, quota, duration, deviceNo, nodeAccess := "trial", int64(100), int64(7), int64(1), int64(1)
+	unknown := "missing"
+	if err = SavePlan(dto.PlanSaveDto{Name: &name, Quota: &quota, Duration: &duration, DeviceNo: &deviceNo, NodeAccess: &nodeAccess, SpeedTier: &unknown}); err == nil {
+		t.Fatal("plan with an unknown speed tier should be rejected")
+	}
+	if err = SavePlan(dto.PlanSaveDto{Name: &name, Quota: &quota, Duration: &duration, DeviceNo: &deviceNo, NodeAccess: &nodeAccess, SpeedTier: &tierName}); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := dao.GetPlan("name = ?", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, pass, conPass := "tieruser", "Tier-User-Pass1", "tierconpass"
+	if err = SaveAccountFromPlan(dto.AccountSaveFromPlanDto{Username: &username, Pass: &pass, ConPass: &conPass, PlanId: plan.Id}); err != nil {
+		t.Fatal(err)
+	}
+	account, err := dao.GetAccount("username = ?", username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *account.AclProfileId != aclProfileId {
+		t.Fatalf("account should use the speed tier acl profile, got %d", *account.AclProfileId)
+	}
+	// 限速账号只能通过限速实例认证
+	if err = checkAccountAclProfile(account, 0); err == nil {
+		t.Fatal("speed tier account should not authenticate on the node")
+	}
+	if err = checkAccountAclProfile(account, aclProfileId); err != nil {
+		t.Fatalf("speed tier account should authenticate on its instance: %v", err)
+	}
+
+	if err = DeleteAclProfile(aclProfileId); err == nil {
+		t.Fatal("acl profile used as speed tier should not be deleted")
+	}
+	if err = updatePlanSpeedTier(tierName, "slower"); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = dao.GetPlan("id = ?", *plan.Id); err != nil || *plan.SpeedTier != "slower" {
+		t.Fatalf("plan speed tier should follow the acl profile name: %v", err)
+	}
+}
@@ -2,68 +2,513 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
 	"h-ui/util"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var server *http.Server
+const hysteria2AuthPath = "/hui/hysteria2/auth"
 
-func InitServer(addr string, handler http.Handler) {
-	server = &http.Server{
-		Addr:    addr,
-		Handler: handler,
-	}
+// panelServer 面板的一个监听
+type panelServer struct {
+	server   *http.Server
+	listener net.Listener
+	network  string
+	address  string
+	port     int64
+	tls      bool
+	// spec 为空时只给还在使用旧地址的 hysteria2 认证
+	spec atomic.Pointer[listenerSpec]
+}
+
+func (p *panelServer) key() string {
+	return p.network + "|" + p.address
+}
+
+var (
+	serverLock sync.Mutex
+	// servers 面板的所有监听，修改配置后换成新的监听，旧的监听在节点换成新的认证地址后关闭
+	servers    []*panelServer
+	serverDone chan error
+	// serverCert 当前使用的证书文件，文件被替换后重新加载
+	serverCert       [2]string
+	serverCertTime   time.Time
+	serverHandler    atomic.Value
+	serverWebContext atomic.Value
+	newServerHandler func(webContext *string) http.Handler
+)
+
+// InitServer 注册面板路由，修改访问路径后用它重建路由
+func InitServer(handler func(webContext *string) http.Handler) {
+	newServerHandler = handler
 }
 
-func StartServer(crtPath string, keyPath string) error {
-	if crtPath != "" && keyPath != "" {
-		return server.ListenAndServeTLS(crtPath, keyPath)
+// StartServer 启动面板，直到 StopServer 或面板端口监听出错才返回
+func StartServer() error {
+	serverLock.Lock()
+	serverDone = make(chan error, 1)
+	done := serverDone
+	serverLock.Unlock()
+	if err := ReloadServer(); err != nil {
+		return err
 	}
-	return server.ListenAndServe()
+	return <-done
 }
 
+// StopServer 停止面板和节点，随后面板会完整重启
 func StopServer() error {
 	if err := StopHysteria2(); err != nil {
 		return err
 	}
 
+	serverLock.Lock()
+	defer serverLock.Unlock()
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
+	var err error
+	for _, item := range servers {
+		item.spec.Store(nil)
+		if shutdownErr := item.server.Shutdown(ctx); shutdownErr != nil {
+			err = shutdownErr
+		}
+	}
+	servers = nil
+	select {
+	case serverDone <- http.ErrServerClosed:
+	default:
+	}
+	if err != nil {
 		logrus.Errorf("failed to shutdown server: %v", err)
 		return errors.New("failed to shutdown server")
 	}
+	return nil
+}
+
+// ReloadServer 按当前配置更新面板的路由、证书和监听，不重启节点，新的监听都启动后才替换路由和证书
+func ReloadServer() error {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+
+	mainRoutes, err := webRoutes()
+	if err != nil {
+		return err
+	}
+	listeners, err := dao.ListWebListener("enable = 1")
+	if err != nil {
+		return err
+	}
+	specs, err := listenerSpecs(mainRoutes, listeners)
+	if err != nil {
+		return err
+	}
 
+	crtPath, keyPath, err := serverCertPath()
+	if err != nil {
+		return err
+	}
+	var cert *tls.Certificate
+	for _, spec := range specs {
+		if !spec.tls {
+			continue
+		}
+		if crtPath == "" || keyPath == "" {
+			return fmt.Errorf("listen address: %s requires the panel certificate", spec.address)
+		}
+		if cert, err = parsePanelCert(crtPath, keyPath); err != nil {
+			return err
+		}
+		break
+	}
+
+	config, err := dao.GetConfig("key = ?", constant.HUIWebContext)
+	if err != nil {
+		return err
+	}
+	handler := newServerHandler(config.Value)
+
+	if err = switchServers(specs, func() {
+		if cert != nil {
+			panelCert.Store(cert)
+			serverCert = [2]string{crtPath, keyPath}
+			serverCertTime = certModTime(crtPath, keyPath)
+		}
+		serverHandler.Store(handler)
+		serverWebContext.Store(webContextPath(config.Value))
+	}); err != nil {
+		return err
+	}
+	closeDrainingServers()
 	return nil
 }
 
-func GetServerPortAndCert() (int64, string, string, error) {
-	port, crtPath, keyPath, err := GetPortAndCert()
+// switchServers 先启动新的监听再停用旧的监听，地址和协议不变的监听直接更新路由，
+// 新的监听都启动后调用 commit
+func switchServers(specs []listenerSpec, commit func()) error {
+	current := map[string]*panelServer{}
+	for _, item := range servers {
+		current[item.key()] = item
+	}
+	var authPorts map[int64]bool
+	restartNodes := false
+	next := make([]*panelServer, 0, len(specs))
+	var opened []*panelServer
+	var closed []closedServer
+	for i, spec := range specs {
+		item, ok := current[spec.key()]
+		if ok && item.tls == spec.tls {
+			delete(current, spec.key())
+			next = append(next, item)
+			continue
+		}
+		// 同一地址切换 http 和 https 只能先关闭旧的监听，使用它认证的节点需要重启
+		if ok {
+			delete(current, spec.key())
+			closed = append(closed, closedServer{item: item, spec: item.spec.Swap(nil)})
+			_ = item.listener.Close()
+			go shutdownServer(item)
+			if authPorts == nil {
+				authPorts = authPortsInUse()
+			}
+			restartNodes = restartNodes || authPorts[item.port]
+		}
+		listener, err := listen(spec.network, spec.address)
+		if err != nil {
+			// Serve 可能还没有开始，监听需要单独关闭，地址才能重新使用
+			for _, item := range opened {
+				_ = item.listener.Close()
+				_ = item.server.Close()
+			}
+			restoreServers(closed)
+			return err
+		}
+		item = servePanel(listener, spec, i == 0)
+		opened = append(opened, item)
+		next = append(next, item)
+	}
+
+	commit()
+	for i, item := range next {
+		spec := specs[i]
+		if item.spec.Swap(&spec) == nil {
+			logrus.Infof("panel listening on %s %s, tls: %v", spec.network, spec.address, spec.tls)
+		}
+	}
+	for _, item := range servers {
+		if _, ok := current[item.key()]; ok {
+			item.spec.Store(nil)
+			next = append(next, item)
+		}
+	}
+	servers = next
+
+	if restartNodes {
+		go restartNodesForAuth()
+	}
+	return nil
+}
+
+// closedServer 切换过程中为了换协议而关闭的监听
+type closedServer struct {
+	item *panelServer
+	spec *listenerSpec
+}
+
+// restoreServers 切换失败时在原来的地址上重新监听，servers 中换成新的监听，无法监听时移除
+func restoreServers(closed []closedServer) {
+	for _, item := range closed {
+		index := -1
+		for i, server := range servers {
+			if server == item.item {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			continue
+		}
+		listener, err := listen(item.item.network, item.item.address)
+		if err != nil {
+			servers = append(servers[:index], servers[index+1:]...)
+			continue
+		}
+		spec := listenerSpec{network: item.item.network, address: item.item.address, port: item.item.port, tls: item.item.tls}
+		if item.spec != nil {
+			spec = *item.spec
+		}
+		restored := servePanel(listener, spec, index == 0)
+		restored.spec.Store(item.spec)
+		servers[index] = restored
+	}
+}
+
+// listen unix socket 文件已存在且没有进程使用时先删除
+func listen(network string, address string) (net.Listener, error) {
+	if network == constant.ListenerNetworkUnix {
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if conn, err := net.DialTimeout("unix", address, time.Second); err == nil {
+				_ = conn.Close()
+			} else {
+				_ = os.Remove(address)
+			}
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		logrus.Errorf("listen %s %s err: %v", network, address, err)
+		return nil, fmt.Errorf("listen address: %s is used", address)
+	}
+	if network == constant.ListenerNetworkUnix {
+		_ = os.Chmod(address, 0660)
+	}
+	return listener, nil
+}
+
+// servePanel 面板端口出错时完整重启，其他监听只记录
+func servePanel(listener net.Listener, spec listenerSpec, main bool) *panelServer {
+	item := &panelServer{
+		listener: listener,
+		network:  spec.network,
+		address:  spec.address,
+		port:     spec.port,
+		tls:      spec.tls,
+	}
+	item.server = &http.Server{Handler: item}
+	if spec.tls {
+		item.server.TLSConfig = PanelTLSConfig()
+	}
+	done := serverDone
+	go func() {
+		var err error
+		if spec.tls {
+			err = item.server.ServeTLS(listener, "", "")
+		} else {
+			err = item.server.Serve(listener)
+		}
+		if err == nil || err == http.ErrServerClosed || item.spec.Load() == nil {
+			return
+		}
+		logrus.Errorf("serve %s %s err: %v", spec.network, spec.address, err)
+		if main {
+			select {
+			case done <- err:
+			default:
+			}
+		}
+	}()
+	return item
+}
+
+// ServeHTTP 本机发来的 hysteria2 认证转到当前的访问路径，其他请求只能访问监听开放的路由组
+func (p *panelServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	spec := p.spec.Load()
+	if !p.tls && strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+		panelAcmeHttpHandler(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, hysteria2AuthPath) && IsLocalRequest(r) {
+		r.URL.Path = serverWebContext.Load().(string) + hysteria2AuthPath
+		r.URL.RawPath = ""
+		r = withListenerRoutes(r, []string{constant.RouteGroupAuth})
+	} else if spec == nil {
+		http.NotFound(w, r)
+		return
+	} else if spec.redirectPort > 0 {
+		http.Redirect(w, r, httpsRedirectUrl(r, spec.redirectPort), http.StatusMovedPermanently)
+		return
+	} else {
+		r = withListenerRoutes(r, spec.routes)
+	}
+	serverHandler.Load().(http.Handler).ServeHTTP(w, r)
+}
+
+// httpsRedirectUrl 同一域名的 https 地址，443 端口不写端口号
+func httpsRedirectUrl(r *http.Request, port int64) string {
+	host := strings.Trim(r.Host, "[]")
+	if hostname, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = hostname
+	}
+	if port != 443 {
+		host = net.JoinHostPort(host, strconv.FormatInt(port, 10))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return "https://" + host + r.URL.RequestURI()
+}
+
+// listenerServing 该地址是否正在提供面板
+func listenerServing(key string) bool {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	for _, item := range servers {
+		if item.key() == key && item.spec.Load() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// panelServesPort 面板是否在该 tcp 端口上监听，ACME 验证时由面板响应
+func panelServesPort(port int, useTls bool) bool {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	for _, item := range servers {
+		if item.network == constant.ListenerNetworkTcp && item.port == int64(port) && item.tls == useTls && item.spec.Load() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocalRequest 本机直接发来的请求，同一台机器上的反向代理转发的请求带有转发头，不算本机请求
+func IsLocalRequest(r *http.Request) bool {
+	return isLoopback(r.RemoteAddr) && r.Header.Get("X-Forwarded-For") == "" &&
+		r.Header.Get("X-Real-IP") == "" && r.Header.Get("Forwarded") == ""
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
 	if err != nil {
-		return 0, "", "", err
+		return false
 	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
 
-	if !util.IsPortAvailable(uint(port), "tcp") {
-		errMsg := fmt.Sprintf("port %d is taken", port)
-		logrus.Errorf(errMsg)
-		return 0, "", "", errors.New(errMsg)
+func restartNodesForAuth() {
+	if Hysteria2IsRunning() {
+		if err := RestartHysteria2(); err != nil {
+			logrus.Errorf("restart hysteria2 err: %v", err)
+		}
+	}
+	if Hysteria2Node2IsRunning() {
+		if err := RestartHysteria2Node2(); err != nil {
+			logrus.Errorf("restart node2 err: %v", err)
+		}
+	}
+}
+
+// authPortsInUse 正在运行的节点配置里认证地址的端口
+func authPortsInUse() map[int64]bool {
+	paths := []string{constant.Hysteria2ConfigPath, constant.Hysteria2Node2ConfigPath}
+	aclPaths, _ := filepath.Glob(strings.Replace(constant.Hysteria2AclConfigPath, "%d", "*", 1))
+	paths = append(paths, aclPaths...)
+	ports := map[int64]bool{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var config bo.Hysteria2ServerConfig
+		if err = yaml.Unmarshal(content, &config); err != nil ||
+			config.Auth == nil || config.Auth.HTTP == nil || config.Auth.HTTP.URL == nil {
+			continue
+		}
+		authUrl, err := url.Parse(*config.Auth.HTTP.URL)
+		if err != nil {
+			continue
+		}
+		if port, err := strconv.ParseInt(authUrl.Port(), 10, 64); err == nil {
+			ports[port] = true
+		}
+	}
+	return ports
+}
+
+// closeDrainingServers 关闭没有节点使用的旧监听
+func closeDrainingServers() {
+	var authPorts map[int64]bool
+	next := servers[:0]
+	for _, item := range servers {
+		if item.spec.Load() != nil {
+			next = append(next, item)
+			continue
+		}
+		if authPorts == nil {
+			authPorts = authPortsInUse()
+		}
+		if item.network == constant.ListenerNetworkTcp && authPorts[item.port] {
+			next = append(next, item)
+			continue
+		}
+		go shutdownServer(item)
+		logrus.Infof("panel stopped listening on %s %s", item.network, item.address)
+	}
+	servers = next
+}
+
+// shutdownServer 等正在处理的请求结束后关闭
+func shutdownServer(item *panelServer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_ = item.server.Shutdown(ctx)
+}
+
+func certModTime(crtPath string, keyPath string) time.Time {
+	var modTime time.Time
+	for _, path := range []string{crtPath, keyPath} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime
+}
+
+// CronPanelServer 关闭不再使用的旧监听，证书文件被替换后重新加载
+func CronPanelServer() {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	closeDrainingServers()
+	if serverCert[0] == "" {
+		return
+	}
+	if modTime := certModTime(serverCert[0], serverCert[1]); modTime.After(serverCertTime) {
+		if err := loadPanelCert(serverCert[0], serverCert[1]); err != nil {
+			return
+		}
+		serverCertTime = modTime
+		logrus.Infof("panel cert %s reloaded", serverCert[0])
+	}
+}
+
+// serverCertPath 面板证书，启用面板 ACME 时使用申请到的证书，InitPanelAcme 保证文件存在
+func serverCertPath() (string, string, error) {
+	_, crtPath, keyPath, err := GetPortAndCert()
+	if err != nil {
+		return "", "", err
+	}
+
+	if PanelAcmeEnabled() {
+		crtPath = constant.PanelAcmeCrtPath
+		keyPath = constant.PanelAcmeKeyPath
 	}
 
 	if crtPath != "" && !util.Exists(crtPath) {
 		errMsg := fmt.Sprintf("crt path: %s does not exist", crtPath)
 		logrus.Errorf(errMsg)
-		return 0, "", "", errors.New(errMsg)
+		return "", "", errors.New(errMsg)
 	}
 
 	if keyPath != "" && !util.Exists(keyPath) {
 		errMsg := fmt.Sprintf("key path: %s does not exist", keyPath)
 		logrus.Errorf(errMsg)
-		return 0, "", "", errors.New(errMsg)
+		return "", "", errors.New(errMsg)
 	}
 
-	return port, crtPath, keyPath, nil
+	return crtPath, keyPath, nil
 }
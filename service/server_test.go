@@ -0,0 +1,212 @@
+package service
+
+import (
+	"h-ui/model/constant"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestPanelServerServeHTTP(t *testing.T) {
+	var path string
+	var allowed bool
+	serverHandler.Store(http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		allowed = ListenerAllowRoute(r, r.URL.Path)
+	})))
+	serverWebContext.Store("/new")
+
+	item := &panelServer{}
+	panel := &listenerSpec{routes: []string{constant.RouteGroupPanel}}
+	redirect := &listenerSpec{redirectPort: 8443}
+	cases := []struct {
+		spec       *listenerSpec
+		remoteAddr string
+		path       string
+		want       string
+		allowed    bool
+		code       int
+	}{
+		{panel, "192.0.2.1:1234", "/new/hui/login", "/new/hui/login", true, http.StatusOK},
+		{panel, "127.0.0.1:1234", "/old/hui/hysteria2/auth", "/new/hui/hysteria2/auth", true, http.StatusOK},
+		{panel, "192.0.2.1:1234", "/new/hui/hysteria2/auth", "/new/hui/hysteria2/auth", false, http.StatusOK},
+		{nil, "[::1]:1234", "/hui/hysteria2/auth", "/new/hui/hysteria2/auth", true, http.StatusOK},
+		{nil, "192.0.2.1:1234", "/new/hui/hysteria2/auth", "", false, http.StatusNotFound},
+		{nil, "127.0.0.1:1234", "/new/hui/login", "", false, http.StatusNotFound},
+		{redirect, "192.0.2.1:1234", "/new/hui/login", "", false, http.StatusMovedPermanently},
+	}
+	for _, c := range cases {
+		path, allowed = "", false
+		item.spec.Store(c.spec)
+		request := httptest.NewRequest(http.MethodPost, c.path, nil)
+		request.RemoteAddr = c.remoteAddr
+		recorder := httptest.NewRecorder()
+		item.ServeHTTP(recorder, request)
+		if recorder.Code != c.code || path != c.want || allowed != c.allowed {
+			t.Fatalf("%s from %s: got %d %q %v, want %d %q %v", c.path, c.remoteAddr, recorder.Code, path, allowed, c.code, c.want, c.allowed)
+		}
+	}
+}
+
+func TestHttpsRedirectUrl(t *testing.T) {
+	cases := []struct {
+		host string
+		port int64
+		want string
+	}{
+		{"example.com", 443, "https://example.com/a?b=1"},
+		{"example.com:80", 8443, "https://example.com:8443/a?b=1"},
+		{"[2001:db8::1]:80", 443, "https://[2001:db8::1]/a?b=1"},
+		{"[2001:db8::1]", 8443, "https://[2001:db8::1]:8443/a?b=1"},
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest(http.MethodGet, "/a?b=1", nil)
+		request.Host = c.host
+		if got := httpsRedirectUrl(request, c.port); got != c.want {
+			t.Fatalf("%s: got %s, want %s", c.host, got, c.want)
+		}
+	}
+}
+
+func TestReloadServerKeepsRoutesWhenListenFails(t *testing.T) {
+	initTestSqlite(t)
+	previousHandler := newServerHandler
+	t.Cleanup(func() {
+		newServerHandler = previousHandler
+		serverLock.Lock()
+		defer serverLock.Unlock()
+		for _, item := range servers {
+			_ = item.server.Close()
+		}
+		servers = nil
+	})
+	var served string
+	InitServer(func(webContext *string) http.Handler {
+		value := *webContext
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = value
+		})
+	})
+	freePort := func() string {
+		listener, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	}
+	serve := func() string {
+		served = ""
+		serverHandler.Load().(http.Handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		return served
+	}
+
+	for key, value := range map[string]string{constant.HUIWebPort: freePort(), constant.HUIWebContext: "/old"} {
+		if err := UpdateConfig(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ReloadServer(); err != nil {
+		t.Fatal(err)
+	}
+	if serve() != "/old" || serverWebContext.Load() != "/old" {
+		t.Fatalf("reload should install the new routes")
+	}
+
+	used, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer used.Close()
+	port := strconv.Itoa(used.Addr().(*net.TCPAddr).Port)
+	for key, value := range map[string]string{constant.HUIWebPort: port, constant.HUIWebContext: "/new"} {
+		if err = UpdateConfig(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ReloadServer(); err == nil {
+		t.Fatalf("reload should fail when the port is used")
+	}
+	if serve() != "/old" || serverWebContext.Load() != "/old" {
+		t.Fatalf("failed reload should keep the current routes, got %s %v", served, serverWebContext.Load())
+	}
+}
+
+func TestIsLocalRequest(t *testing.T) {
+	cases := []struct {
+		remoteAddr string
+		header     string
+		want       bool
+	}{
+		{"127.0.0.1:1234", "", true},
+		{"[::1]:1234", "", true},
+		{"192.0.2.1:1234", "", false},
+		{"127.0.0.1:1234", "X-Forwarded-For", false},
+		{"127.0.0.1:1234", "X-Real-IP", false},
+		{"127.0.0.1:1234", "Forwarded", false},
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest(http.MethodPost, "/hui/hysteria2/auth", nil)
+		request.RemoteAddr = c.remoteAddr
+		if c.header != "" {
+			request.Header.Set(c.header, "192.0.2.1")
+		}
+		if got := IsLocalRequest(request); got != c.want {
+			t.Errorf("%s with %q: got %v, want %v", c.remoteAddr, c.header, got, c.want)
+		}
+	}
+}
+
+func TestSwitchServersRestoresClosedListener(t *testing.T) {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	previous := servers
+	t.Cleanup(func() {
+		for _, item := range servers {
+			_ = item.server.Close()
+		}
+		servers = previous
+	})
+	serverHandler.Store(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serverWebContext.Store("/")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	spec := listenerSpec{network: constant.ListenerNetworkTcp, address: address, routes: []string{constant.RouteGroupPanel}}
+	item := servePanel(listener, spec, true)
+	item.spec.Store(&spec)
+	servers = []*panelServer{item}
+
+	used, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer used.Close()
+	httpsSpec := spec
+	httpsSpec.tls = true
+	usedSpec := listenerSpec{network: constant.ListenerNetworkTcp, address: used.Addr().String(), routes: []string{constant.RouteGroupPanel}}
+	if err = switchServers([]listenerSpec{httpsSpec, usedSpec}, func() {
+		t.Error("commit should not be called when listen fails")
+	}); err == nil {
+		t.Fatal("switch should fail when an address is used")
+	}
+
+	if len(servers) != 1 || servers[0].tls || servers[0].address != address || servers[0].spec.Load() == nil {
+		t.Fatalf("the closed http listener should be restored: %+v", servers)
+	}
+	response, err := http.Get("http://" + address + "/")
+	if err != nil {
+		t.Fatalf("restored listener should accept requests: %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected status: %d", response.StatusCode)
+	}
+}
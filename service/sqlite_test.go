@@ -0,0 +1,26 @@
+package service
+
+import (
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"os"
+	"testing"
+)
+
+// initTestSqlite 在临时目录中初始化数据库，测试结束后关闭
+func initTestSqlite(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir() + "/"
+	if err := os.MkdirAll(dir+constant.SqliteDBDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HUI_DATA", dir)
+	t.Setenv(constant.MasterKeyEnv, "")
+	t.Setenv(constant.MasterKeyFileEnv, "")
+	if err := dao.InitSql(""); err != nil {
+		t.Fatalf("init sqlite err: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dao.CloseSqliteDB()
+	})
+}
@@ -1,131 +1,400 @@
 package service
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
 	"h-ui/dao"
 	"h-ui/model/constant"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var bot *tgbotapi.BotAPI
+// telegramBot 重启时整体替换，读取方先 Load 再使用，避免并发读到 nil
+var telegramBot atomic.Pointer[tgbotapi.BotAPI]
 
-var done = make(chan bool)
+// telegramWebhookWorkers 限制 webhook 同时处理的消息数
+var telegramWebhookWorkers = make(chan struct{}, 16)
+
+type telegramConfig struct {
+	token      string
+	chatId     string
+	mode       string
+	secret     string
+	userEnable bool
+}
+
+// telegramSettings 处理消息时使用的会话权限，管理员会话或订阅变化时整体替换
+type telegramSettings struct {
+	adminChats map[int64][]string // chatId -> 订阅的通知
+	userEnable bool
+}
+
+var (
+	telegramMutex         sync.Mutex
+	telegramStop          chan struct{} // 轮询模式下停止接收消息
+	telegramMode          string
+	telegramWebhookSecret atomic.Value
+	telegramCurrent       atomic.Value
+)
 
 // 参数校验
-func valid() (string, string, error) {
-	enable, err := dao.GetConfig("key = ?", constant.TelegramEnable)
+func valid() (telegramConfig, error) {
+	configs, err := dao.ListConfig("key in ?", []string{
+		constant.TelegramEnable,
+		constant.TelegramToken,
+		constant.TelegramChatId,
+		constant.TelegramMode,
+		constant.TelegramWebhookSecret,
+		constant.TelegramUserEnable})
 	if err != nil {
-		return "", "", err
+		return telegramConfig{}, err
 	}
-	if enable.Value == nil || *enable.Value != "1" {
-		return "", "", errors.New("telegram not enable")
+	values := map[string]string{}
+	for _, item := range configs {
+		values[*item.Key] = *item.Value
 	}
-	token, err := dao.GetConfig("key = ?", constant.TelegramToken)
-	if err != nil {
-		return "", "", err
+	if values[constant.TelegramEnable] != "1" {
+		return telegramConfig{}, errors.New("telegram not enable")
 	}
-	if token.Value == nil || *token.Value == "" {
-		return "", "", errors.New("telegram token not set")
+	if values[constant.TelegramToken] == "" {
+		return telegramConfig{}, errors.New("telegram token not set")
 	}
-	chatId, err := dao.GetConfig("key = ?", constant.TelegramChatId)
-	if err != nil {
-		return "", "", err
+	config := telegramConfig{
+		token:      values[constant.TelegramToken],
+		chatId:     values[constant.TelegramChatId],
+		mode:       values[constant.TelegramMode],
+		secret:     values[constant.TelegramWebhookSecret],
+		userEnable: values[constant.TelegramUserEnable] == "1",
 	}
-	if chatId.Value == nil {
-		return "", "", errors.New("telegram chatId is nil")
+	if config.mode == constant.TelegramModeWebhook && config.secret == "" {
+		return telegramConfig{}, errors.New("telegram webhook secret not set")
+	}
+	return config, nil
+}
+
+// ErrTelegramBusy webhook 处理中的消息已达上限，Telegram 会稍后重试
+var ErrTelegramBusy = errors.New("telegram webhook busy")
+
+// telegramBotApi 返回当前运行的机器人
+func telegramBotApi() (*tgbotapi.BotAPI, error) {
+	bot := telegramBot.Load()
+	if bot == nil {
+		return nil, errors.New("telegram bot not initialized")
 	}
-	return *token.Value, *chatId.Value, nil
+	return bot, nil
 }
 
 func InitTelegramBot() error {
-	token, chatId, err := valid()
+	return StartTelegramBot()
+}
+
+// StartTelegramBot 按当前配置启动机器人，已在运行时先停止，未开启时只停止
+func StartTelegramBot() error {
+	telegramMutex.Lock()
+	defer telegramMutex.Unlock()
+
+	stopTelegramBot()
+	config, err := valid()
 	if err != nil {
 		if err.Error() == "telegram not enable" {
 			return nil
 		}
 		return err
 	}
-	bot, err = tgbotapi.NewBotAPI(token)
+	api, err := tgbotapi.NewBotAPI(config.token)
 	if err != nil {
 		logrus.Errorf("new bot api err: %v", err)
 		return err
 	}
-	bot.Debug = os.Getenv(constant.TelegramDebug) == "true"
-	logrus.Infof("Authorized on account %s", bot.Self.UserName)
-	// 初始化 menu
-	commands := []tgbotapi.BotCommand{
-		{Command: "status", Description: "System Status"},
-		{Command: "restart", Description: "System Restart"},
-	}
-	if chatId == "" {
-		commands = append(commands, tgbotapi.BotCommand{Command: "chatid", Description: "Get chatId"})
+	api.Debug = os.Getenv(constant.TelegramDebug) == "true"
+	logrus.Infof("Authorized on account %s", api.Self.UserName)
+	telegramBot.Store(api)
+
+	settings, err := loadTelegramSettings(config.chatId, config.userEnable)
+	if err != nil {
+		telegramBot.Store(nil)
+		return err
 	}
-	setCommands := tgbotapi.NewSetMyCommands(commands...)
-	if _, err := bot.Request(setCommands); err != nil {
+	telegramCurrent.Store(settings)
+	// 初始化 menu
+	if err = setTelegramMenu(settings); err != nil {
 		logrus.Errorf("unable to set commands err: %v", err)
+		telegramBot.Store(nil)
 		return err
 	}
-	// 处理消息
-	go func(done chan bool) {
-		updates := getUpdatesChan()
-		for {
-			select {
-			case update, ok := <-updates:
-				if !ok {
-					return
-				}
-				handleMsg(update, chatId)
-			case <-done:
-				break
-			}
+
+	if config.mode == constant.TelegramModeWebhook {
+		webhookUrl, err := telegramWebhookUrl()
+		if err != nil {
+			telegramBot.Store(nil)
+			return err
 		}
-	}(done)
+		if _, err = api.MakeRequest("setWebhook", tgbotapi.Params{
+			"url":          webhookUrl,
+			"secret_token": config.secret,
+		}); err != nil {
+			logrus.Errorf("tg api setWebhook err: %v", err)
+			telegramBot.Store(nil)
+			return err
+		}
+		telegramWebhookSecret.Store(config.secret)
+		telegramMode = constant.TelegramModeWebhook
+		return nil
+	}
+
+	// 轮询前删除 webhook，否则 getUpdates 会返回冲突
+	if _, err = api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		logrus.Errorf("tg api deleteWebhook err: %v", err)
+	}
+	stop := make(chan struct{})
+	telegramStop = stop
+	telegramMode = constant.TelegramModePolling
+	go pollTelegramUpdates(api, stop)
 	return nil
 }
 
-func getUpdatesChan() tgbotapi.UpdatesChannel {
+// StopTelegramBot 停止机器人，不影响面板其他功能
+func StopTelegramBot() {
+	telegramMutex.Lock()
+	defer telegramMutex.Unlock()
+	stopTelegramBot()
+}
+
+func stopTelegramBot() {
+	if telegramStop != nil {
+		close(telegramStop)
+		telegramStop = nil
+	}
+	telegramWebhookSecret.Store("")
+	telegramMode = ""
+	telegramBot.Store(nil)
+}
+
+func pollTelegramUpdates(api *tgbotapi.BotAPI, stop chan struct{}) {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
-	return bot.GetUpdatesChan(u)
+	updates := api.GetUpdatesChan(u)
+	for {
+		select {
+		case <-stop:
+			api.StopReceivingUpdates()
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			handleMsg(update)
+		}
+	}
 }
 
-func handleMsg(update tgbotapi.Update, chatId string) {
-	if update.Message != nil && update.Message.IsCommand() && (chatId == "" || strconv.FormatInt(update.Message.Chat.ID, 10) == chatId) {
-		switch update.Message.Command() {
-		case "chatid":
-			if err := handleChatId(update); err != nil {
-				logrus.Errorf("handleStatus err: %v", err)
-			}
-		case "status":
-			if err := handleStatus(update); err != nil {
-				logrus.Errorf("handleStatus err: %v", err)
-			}
-		case "restart":
-			if err := handleRestart(update); err != nil {
-				logrus.Errorf("handleRestart err: %v", err)
+// telegramWebhookUrl webhook 地址为 H_UI_PUBLIC_URL + H_UI_WEB_CONTEXT + /hui/telegram/webhook
+func telegramWebhookUrl() (string, error) {
+	configs, err := dao.ListConfig("key in ?", []string{constant.HUIPublicUrl, constant.HUIWebContext})
+	if err != nil {
+		return "", err
+	}
+	var publicUrl, webContext string
+	for _, item := range configs {
+		if *item.Key == constant.HUIPublicUrl {
+			publicUrl = *item.Value
+		} else if *item.Key == constant.HUIWebContext && *item.Value != "/" && strings.HasPrefix(*item.Value, "/") {
+			webContext = *item.Value
+		}
+	}
+	base, err := url.Parse(publicUrl)
+	if publicUrl == "" || err != nil || base.Scheme != "https" || base.Host == "" {
+		return "", errors.New("telegram webhook mode requires an https H_UI_PUBLIC_URL")
+	}
+	return fmt.Sprintf("https://%s%s/hui/telegram/webhook", base.Host, webContext), nil
+}
+
+// TelegramWebhookAuthorized 校验 Telegram 回传的 secret_token
+func TelegramWebhookAuthorized(token string) bool {
+	secret, _ := telegramWebhookSecret.Load().(string)
+	return secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+}
+
+// HandleTelegramWebhook 处理 webhook 推送的消息
+func HandleTelegramWebhook(body []byte) error {
+	var update tgbotapi.Update
+	if err := json.Unmarshal(body, &update); err != nil {
+		return err
+	}
+	select {
+	case telegramWebhookWorkers <- struct{}{}:
+	default:
+		return ErrTelegramBusy
+	}
+	go func() {
+		defer func() { <-telegramWebhookWorkers }()
+		handleMsg(update)
+	}()
+	return nil
+}
+
+func GetTelegramBotStatus() vo.TelegramBotStatusVo {
+	telegramMutex.Lock()
+	defer telegramMutex.Unlock()
+	bot := telegramBot.Load()
+	if bot == nil {
+		return vo.TelegramBotStatusVo{}
+	}
+	return vo.TelegramBotStatusVo{
+		Running:  true,
+		Mode:     telegramMode,
+		Username: bot.Self.UserName,
+	}
+}
+
+// loadTelegramSettings TELEGRAM_CHAT_ID 中的会话订阅全部通知，其余管理员会话来自 telegram_chat
+func loadTelegramSettings(chatId string, userEnable bool) (telegramSettings, error) {
+	settings := telegramSettings{adminChats: map[int64][]string{}, userEnable: userEnable}
+	if chatId != "" {
+		id, err := strconv.ParseInt(chatId, 10, 64)
+		if err != nil {
+			logrus.Errorf("parse chatId err: %v", err)
+			return settings, errors.New("telegram chatId is invalid")
+		}
+		settings.adminChats[id] = []string{constant.TelegramNotifyAll}
+	}
+	telegramChats, err := dao.ListTelegramChat("enable = 1")
+	if err != nil {
+		return settings, err
+	}
+	for _, item := range telegramChats {
+		subscriptions := settings.adminChats[*item.ChatId]
+		for _, subscription := range strings.Split(*item.Subscriptions, ",") {
+			if subscription != "" {
+				subscriptions = append(subscriptions, subscription)
 			}
-		default:
-			if err := handleDefault(update); err != nil {
-				logrus.Errorf("handleDefault err: %v", err)
+		}
+		settings.adminChats[*item.ChatId] = subscriptions
+	}
+	return settings, nil
+}
+
+func currentTelegramSettings() telegramSettings {
+	settings, _ := telegramCurrent.Load().(telegramSettings)
+	return settings
+}
+
+// ReloadTelegramChats 管理员会话变化后刷新权限和菜单，removed 为需要清除菜单的会话
+func ReloadTelegramChats(removed ...int64) error {
+	telegramMutex.Lock()
+	defer telegramMutex.Unlock()
+	bot := telegramBot.Load()
+	if bot == nil {
+		return nil
+	}
+	config, err := valid()
+	if err != nil {
+		return err
+	}
+	settings, err := loadTelegramSettings(config.chatId, config.userEnable)
+	if err != nil {
+		return err
+	}
+	telegramCurrent.Store(settings)
+	for _, chatId := range removed {
+		if _, exist := settings.adminChats[chatId]; !exist {
+			_, _ = bot.Request(tgbotapi.NewDeleteMyCommandsWithScope(tgbotapi.NewBotCommandScopeChat(chatId)))
+		}
+	}
+	return setTelegramMenu(settings)
+}
+
+// telegramSubscribed 会话是否订阅了该通知
+func telegramSubscribed(subscriptions []string, notify string) bool {
+	return util.ArrContain(subscriptions, constant.TelegramNotifyAll) || util.ArrContain(subscriptions, notify)
+}
+
+// sendTelegramNotify 发送通知到所有订阅了该通知的管理员会话
+func sendTelegramNotify(notify string, text string) error {
+	if _, err := telegramBotApi(); err != nil {
+		return err
+	}
+	var errs []error
+	sent := false
+	for chatId, subscriptions := range currentTelegramSettings().adminChats {
+		if !telegramSubscribed(subscriptions, notify) {
+			continue
+		}
+		sent = true
+		if err := SendWithMessage(chatId, text); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !sent {
+		return errors.New("no telegram chat subscribed")
+	}
+	return errors.Join(errs...)
+}
+
+// handleMsg 管理员会话使用管理命令，开启用户自助后其他私聊会话使用用户命令
+func handleMsg(update tgbotapi.Update) {
+	settings := currentTelegramSettings()
+	if update.CallbackQuery != nil && update.CallbackQuery.Message != nil {
+		messageChatId := update.CallbackQuery.Message.Chat.ID
+		if _, admin := settings.adminChats[messageChatId]; admin ||
+			settings.userEnable && telegramUserAllowed(messageChatId) {
+			if err := handleTelegramConfirm(update.CallbackQuery); err != nil {
+				logrus.Errorf("handleTelegramConfirm err: %v", err)
 			}
 		}
+		return
+	}
+	if update.Message == nil || !update.Message.IsCommand() {
+		return
+	}
+	name := update.Message.Command()
+	messageChatId := update.Message.Chat.ID
+	var commands *telegramCommandSet
+	if _, admin := settings.adminChats[messageChatId]; admin {
+		commands = telegramAdminCommands
+	} else if name == "chatid" {
+		// 任何会话都可以获取自己的 chatId，用于添加管理员会话
+		commands = telegramAdminCommands
+	} else if settings.userEnable && update.Message.Chat.IsPrivate() {
+		if !telegramUserAllowed(messageChatId) {
+			return
+		}
+		commands = telegramUserCommands
+	} else {
+		return
+	}
+	command, exist := commands.get(name)
+	if !exist {
+		if err := handleDefault(update, nil); err != nil {
+			logrus.Errorf("handleDefault err: %v", err)
+		}
+		return
+	}
+	if err := command.handler(update, strings.Fields(update.Message.CommandArguments())); err != nil {
+		logrus.Errorf("handle telegram command %s err: %v", name, err)
+		_ = SendWithMessage(messageChatId, err.Error())
 	}
 }
 
-func handleChatId(update tgbotapi.Update) error {
+func handleChatId(update tgbotapi.Update, args []string) error {
 	if err := SendWithMessage(update.Message.Chat.ID, fmt.Sprintf("chatId: %d", update.Message.Chat.ID)); err != nil {
 		return err
 	}
 	return nil
 }
 
-func handleStatus(update tgbotapi.Update) error {
+func handleStatus(update tgbotapi.Update, args []string) error {
 	systemMonitorVo, err := MonitorSystem()
 	if err != nil {
 		return err
@@ -155,20 +424,25 @@ func handleStatus(update tgbotapi.Update) error {
 	return nil
 }
 
-func handleRestart(update tgbotapi.Update) error {
-	_ = StopServer()
-	if err := SendWithMessage(update.Message.Chat.ID, "Restart successful"); err != nil {
-		return err
-	}
-	done <- true
-	return nil
+func handleRestart(update tgbotapi.Update, args []string) error {
+	return confirmTelegramAction(update.Message.Chat.ID, "Restart the panel?", func() (string, error) {
+		// 服务重启时会重新启动机器人
+		go func() {
+			_ = StopServer()
+		}()
+		return "Restart successful", nil
+	})
 }
 
-func handleDefault(update tgbotapi.Update) error {
-	return nil
+func handleDefault(update tgbotapi.Update, args []string) error {
+	return SendWithMessage(update.Message.Chat.ID, "Unknown command, send /help for the list of commands")
 }
 
 func GetMe() (tgbotapi.User, error) {
+	bot, err := telegramBotApi()
+	if err != nil {
+		return tgbotapi.User{}, err
+	}
 	user, err := bot.GetMe()
 	if err != nil {
 		logrus.Errorf("tg api GetMe err: %v", err)
@@ -178,6 +452,10 @@ func GetMe() (tgbotapi.User, error) {
 }
 
 func SendWithMessage(chatId int64, text string) error {
+	bot, err := telegramBotApi()
+	if err != nil {
+		return err
+	}
 	message := tgbotapi.NewMessage(chatId, text)
 	if _, err := bot.Send(message); err != nil {
 		logrus.Errorf("tg api SendMessage err: %v chatId: %d text: %s", err, chatId, text)
@@ -189,23 +467,17 @@ func SendWithMessage(chatId int64, text string) error {
 // TelegramLoginRemind 登录提醒
 func TelegramLoginRemind(username string, ip string) {
 	configs, err := dao.ListConfig("key in ?", []string{
-		constant.TelegramEnable,
-		constant.TelegramChatId,
 		constant.TelegramLoginJobEnable,
 		constant.TelegramLoginJobText})
 	if err != nil {
 		return
 	}
-	var telegramEnable, telegramChatId, telegramLoginJobEnable, telegramLoginJobText = "0", "", "0", ""
+	var telegramLoginJobEnable, telegramLoginJobText = "0", ""
 	for _, item := range configs {
 		if item.Value != nil {
 			key := *item.Key
 			value := *item.Value
-			if key == constant.TelegramEnable {
-				telegramEnable = value
-			} else if key == constant.TelegramChatId {
-				telegramChatId = value
-			} else if key == constant.TelegramLoginJobEnable {
+			if key == constant.TelegramLoginJobEnable {
 				telegramLoginJobEnable = value
 			} else if key == constant.TelegramLoginJobText {
 				telegramLoginJobText = value
@@ -213,13 +485,7 @@ func TelegramLoginRemind(username string, ip string) {
 		}
 	}
 
-	if telegramEnable != "1" || telegramChatId == "" || telegramLoginJobEnable != "1" || telegramLoginJobText == "" {
-		return
-	}
-
-	chatId, err := strconv.ParseInt(telegramChatId, 10, 64)
-	if err != nil {
-		logrus.Errorf("parse chatId err: %v", err)
+	if telegramBot.Load() == nil || telegramLoginJobEnable != "1" || telegramLoginJobText == "" {
 		return
 	}
 
@@ -227,7 +493,15 @@ func TelegramLoginRemind(username string, ip string) {
 	telegramLoginJobText = strings.ReplaceAll(telegramLoginJobText, "[username]", username)
 	telegramLoginJobText = strings.ReplaceAll(telegramLoginJobText, "[ip]", ip)
 
-	if err = SendWithMessage(chatId, fmt.Sprintf("【H UI】\n%s", telegramLoginJobText)); err != nil {
+	_ = sendTelegramNotify(constant.TelegramNotifyLogin, fmt.Sprintf("【H UI】\n%s", telegramLoginJobText))
+}
+
+// TelegramLoginLockRemind 登录锁定提醒
+func TelegramLoginLockRemind(username string, ip string) {
+	if telegramBot.Load() == nil {
 		return
 	}
+	text := fmt.Sprintf("【H UI】\n%s, login for [%s] from IP address %s has been locked for %s after repeated failures",
+		time.Now().Format("2006-01-02 15:04:05"), username, ip, loginLockDuration.String())
+	_ = sendTelegramNotify(constant.TelegramNotifyLoginLock, text)
 }
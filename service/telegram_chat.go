@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"h-ui/dao"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"strings"
+)
+
+func existTelegramChat(chatId int64, id int64) bool {
+	telegramChat, err := dao.GetTelegramChat("chat_id = ?", chatId)
+	return err == nil && *telegramChat.Id != id
+}
+
+func SaveTelegramChat(telegramChatSaveDto dto.TelegramChatSaveDto) error {
+	if existTelegramChat(*telegramChatSaveDto.ChatId, 0) {
+		return errors.New("chat id already exists")
+	}
+	subscriptions := strings.Join(telegramChatSaveDto.Subscriptions, ",")
+	if _, err := dao.SaveTelegramChat(entity.TelegramChat{
+		ChatId:        telegramChatSaveDto.ChatId,
+		Name:          telegramChatSaveDto.Name,
+		Subscriptions: &subscriptions,
+		Enable:        telegramChatSaveDto.Enable,
+	}); err != nil {
+		return err
+	}
+	return ReloadTelegramChats()
+}
+
+func UpdateTelegramChat(telegramChatUpdateDto dto.TelegramChatUpdateDto) error {
+	telegramChat, err := dao.GetTelegramChat("id = ?", *telegramChatUpdateDto.Id)
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{}
+	if telegramChatUpdateDto.ChatId != nil {
+		if existTelegramChat(*telegramChatUpdateDto.ChatId, *telegramChat.Id) {
+			return errors.New("chat id already exists")
+		}
+		updates["chat_id"] = *telegramChatUpdateDto.ChatId
+	}
+	if telegramChatUpdateDto.Name != nil {
+		updates["name"] = *telegramChatUpdateDto.Name
+	}
+	// 传入空数组表示取消全部订阅
+	if telegramChatUpdateDto.Subscriptions != nil {
+		updates["subscriptions"] = strings.Join(telegramChatUpdateDto.Subscriptions, ",")
+	}
+	if telegramChatUpdateDto.Enable != nil {
+		updates["enable"] = *telegramChatUpdateDto.Enable
+	}
+	if err = dao.UpdateTelegramChat([]int64{*telegramChat.Id}, updates); err != nil {
+		return err
+	}
+	return ReloadTelegramChats(*telegramChat.ChatId)
+}
+
+func DeleteTelegramChat(id int64) error {
+	telegramChat, err := dao.GetTelegramChat("id = ?", id)
+	if err != nil {
+		return err
+	}
+	if err = dao.DeleteTelegramChat([]int64{id}); err != nil {
+		return err
+	}
+	return ReloadTelegramChats(*telegramChat.ChatId)
+}
+
+func ListTelegramChat() ([]vo.TelegramChatVo, error) {
+	telegramChats, err := dao.ListTelegramChat(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	telegramChatVos := make([]vo.TelegramChatVo, 0, len(telegramChats))
+	for _, item := range telegramChats {
+		subscriptions := []string{}
+		if *item.Subscriptions != "" {
+			subscriptions = strings.Split(*item.Subscriptions, ",")
+		}
+		telegramChatVos = append(telegramChatVos, vo.TelegramChatVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			ChatId:        *item.ChatId,
+			Name:          *item.Name,
+			Subscriptions: subscriptions,
+			Enable:        *item.Enable,
+		})
+	}
+	return telegramChatVos, nil
+}
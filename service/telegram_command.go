@@ -0,0 +1,480 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/util"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	telegramConfirmTTL   = 2 * time.Minute
+	telegramConfirmData  = "confirm:"
+	telegramCancelData   = "cancel:"
+	telegramTopLimit     = 10
+	telegramDefaultKick  = 10 // /kick 默认禁止连接的分钟数
+	telegramBytesOfGiB   = 1024 * 1024 * 1024
+	telegramDefaultLimit = 3 // /adduser 默认设备数
+)
+
+type telegramCommandHandler func(update tgbotapi.Update, args []string) error
+
+type telegramCommand struct {
+	name        string
+	usage       string
+	description string
+	handler     telegramCommandHandler
+}
+
+// telegramCommandSet 按注册顺序生成菜单
+type telegramCommandSet struct {
+	commands   []telegramCommand
+	commandMap map[string]telegramCommand
+}
+
+func newTelegramCommandSet() *telegramCommandSet {
+	return &telegramCommandSet{commandMap: map[string]telegramCommand{}}
+}
+
+// register 注册命令，重复注册会覆盖
+func (s *telegramCommandSet) register(command telegramCommand) {
+	if _, exist := s.commandMap[command.name]; !exist {
+		s.commands = append(s.commands, command)
+	} else {
+		for i := range s.commands {
+			if s.commands[i].name == command.name {
+				s.commands[i] = command
+			}
+		}
+	}
+	s.commandMap[command.name] = command
+}
+
+func (s *telegramCommandSet) get(name string) (telegramCommand, bool) {
+	command, exist := s.commandMap[name]
+	return command, exist
+}
+
+func (s *telegramCommandSet) menu(exclude ...string) []tgbotapi.BotCommand {
+	var commands []tgbotapi.BotCommand
+	for _, item := range s.commands {
+		if util.ArrContain(exclude, item.name) {
+			continue
+		}
+		commands = append(commands, tgbotapi.BotCommand{Command: item.name, Description: item.description})
+	}
+	return commands
+}
+
+func (s *telegramCommandSet) help(exclude ...string) string {
+	text := ""
+	for _, item := range s.commands {
+		if util.ArrContain(exclude, item.name) {
+			continue
+		}
+		text += fmt.Sprintf("%s - %s\n", item.usage, item.description)
+	}
+	return text
+}
+
+// telegramAdminCommands 管理员会话可用的命令
+var telegramAdminCommands = newTelegramCommandSet()
+
+func init() {
+	telegramAdminCommands.register(telegramCommand{name: "status", usage: "/status", description: "System Status", handler: handleStatus})
+	telegramAdminCommands.register(telegramCommand{name: "restart", usage: "/restart", description: "System Restart", handler: handleRestart})
+	telegramAdminCommands.register(telegramCommand{name: "user", usage: "/user <username>", description: "Account details", handler: handleUser})
+	telegramAdminCommands.register(telegramCommand{name: "adduser", usage: "/adduser <username> <pass> <quota GiB, -1 unlimited> <days> [devices]", description: "Create an account", handler: handleAddUser})
+	telegramAdminCommands.register(telegramCommand{name: "renew", usage: "/renew <username> <days>", description: "Extend expiry", handler: handleRenew})
+	telegramAdminCommands.register(telegramCommand{name: "addquota", usage: "/addquota <username> <GiB>", description: "Add traffic quota", handler: handleAddQuota})
+	telegramAdminCommands.register(telegramCommand{name: "kick", usage: "/kick <username> [minutes]", description: "Kick an account offline", handler: handleKick})
+	telegramAdminCommands.register(telegramCommand{name: "resettraffic", usage: "/resettraffic <username>", description: "Reset traffic", handler: handleResetTraffic})
+	telegramAdminCommands.register(telegramCommand{name: "online", usage: "/online", description: "Online accounts", handler: handleOnline})
+	telegramAdminCommands.register(telegramCommand{name: "top", usage: "/top", description: "Top consumers today", handler: handleTop})
+	telegramAdminCommands.register(telegramCommand{name: "help", usage: "/help", description: "Command list", handler: handleHelp})
+	telegramAdminCommands.register(telegramCommand{name: "chatid", usage: "/chatid", description: "Get chatId", handler: handleChatId})
+}
+
+// setTelegramMenu 管理员会话展示管理命令，其他会话展示用户命令，没有管理员会话时额外展示 /chatid
+func setTelegramMenu(settings telegramSettings) error {
+	bot, err := telegramBotApi()
+	if err != nil {
+		return err
+	}
+	var commands []tgbotapi.BotCommand
+	if len(settings.adminChats) == 0 {
+		commands = append(commands, telegramAdminCommands.menu(telegramAdminMenuExclude(false)...)...)
+	}
+	if settings.userEnable {
+		commands = append(commands, telegramUserCommands.menu()...)
+	}
+	if _, err := bot.Request(tgbotapi.NewSetMyCommands(commands...)); err != nil {
+		return err
+	}
+	for chatId := range settings.adminChats {
+		scope := tgbotapi.NewBotCommandScopeChat(chatId)
+		if _, err := bot.Request(tgbotapi.NewSetMyCommandsWithScope(scope, telegramAdminCommands.menu(telegramAdminMenuExclude(true)...)...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// telegramAdminMenuExclude 管理员会话隐藏 /chatid，其他会话只展示 /chatid
+func telegramAdminMenuExclude(admin bool) []string {
+	if admin {
+		return []string{"chatid"}
+	}
+	var exclude []string
+	for _, item := range telegramAdminCommands.commands {
+		if item.name != "chatid" {
+			exclude = append(exclude, item.name)
+		}
+	}
+	return exclude
+}
+
+type telegramConfirm struct {
+	chatId   int64
+	expireAt time.Time
+	action   func() (string, error)
+}
+
+var telegramConfirmMutex sync.Mutex
+var telegramConfirms = map[string]telegramConfirm{}
+
+// confirmTelegramAction 发送带确认按钮的消息，确认后才执行 action
+func confirmTelegramAction(chatId int64, text string, action func() (string, error)) error {
+	bot, err := telegramBotApi()
+	if err != nil {
+		return err
+	}
+	token, err := util.RandomString(16)
+	if err != nil {
+		return errors.New(constant.SysError)
+	}
+	now := time.Now()
+	telegramConfirmMutex.Lock()
+	for key, item := range telegramConfirms {
+		if now.After(item.expireAt) {
+			delete(telegramConfirms, key)
+		}
+	}
+	telegramConfirms[token] = telegramConfirm{chatId: chatId, expireAt: now.Add(telegramConfirmTTL), action: action}
+	telegramConfirmMutex.Unlock()
+
+	message := tgbotapi.NewMessage(chatId, text)
+	message.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Confirm", telegramConfirmData+token),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", telegramCancelData+token),
+	))
+	_, err = bot.Send(message)
+	return err
+}
+
+// takeTelegramConfirm 取出并删除待确认操作，过期或不属于该会话时返回 false
+func takeTelegramConfirm(token string, chatId int64, now time.Time) (telegramConfirm, bool) {
+	telegramConfirmMutex.Lock()
+	defer telegramConfirmMutex.Unlock()
+	confirm, exist := telegramConfirms[token]
+	if !exist || confirm.chatId != chatId {
+		return telegramConfirm{}, false
+	}
+	delete(telegramConfirms, token)
+	return confirm, now.Before(confirm.expireAt)
+}
+
+func handleTelegramConfirm(query *tgbotapi.CallbackQuery) error {
+	chatId := query.Message.Chat.ID
+	var token string
+	confirmed := strings.HasPrefix(query.Data, telegramConfirmData)
+	if confirmed {
+		token = strings.TrimPrefix(query.Data, telegramConfirmData)
+	} else if strings.HasPrefix(query.Data, telegramCancelData) {
+		token = strings.TrimPrefix(query.Data, telegramCancelData)
+	} else {
+		return nil
+	}
+
+	text := "Cancelled"
+	confirm, ok := takeTelegramConfirm(token, chatId, time.Now())
+	if !ok {
+		text = "This confirmation has expired"
+	} else if confirmed {
+		result, err := confirm.action()
+		if err != nil {
+			text = err.Error()
+		} else {
+			text = result
+		}
+	}
+	bot, err := telegramBotApi()
+	if err != nil {
+		return err
+	}
+	_, _ = bot.Request(tgbotapi.NewCallback(query.ID, ""))
+	_, err = bot.Send(tgbotapi.NewEditMessageText(chatId, query.Message.MessageID, text))
+	return err
+}
+
+func telegramAccount(args []string, usage string) (entity.Account, error) {
+	if len(args) < 1 {
+		return entity.Account{}, fmt.Errorf("usage: %s", usage)
+	}
+	account, err := dao.GetAccount("username = ?", args[0])
+	if err != nil {
+		return entity.Account{}, fmt.Errorf("account %s not found", args[0])
+	}
+	return account, nil
+}
+
+func formatTelegramBytes(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(bytes)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
+
+func formatTelegramQuota(quota int64) string {
+	if quota < 0 {
+		return "unlimited"
+	}
+	return formatTelegramBytes(quota)
+}
+
+// telegramAccountText 账号的额度、用量、到期时间和在线设备
+func telegramAccountText(account entity.Account) string {
+	onlineUsers, err := Hysteria2Online()
+	if err != nil {
+		onlineUsers = map[string]int64{}
+	}
+	status := "enabled"
+	if *account.Deleted == 1 {
+		status = "disabled"
+	}
+	text := fmt.Sprintf("【%s】\n", *account.Username)
+	text += fmt.Sprintf("Status: %s\n", status)
+	text += fmt.Sprintf("Quota: %s\n", formatTelegramQuota(*account.Quota))
+	text += fmt.Sprintf("Used: %s (↓%s ↑%s)\n", formatTelegramBytes(*account.Download+*account.Upload),
+		formatTelegramBytes(*account.Download), formatTelegramBytes(*account.Upload))
+	text += fmt.Sprintf("Expire: %s\n", time.UnixMilli(*account.ExpireTime).Format("2006-01-02 15:04:05"))
+	text += fmt.Sprintf("Online devices: %d/%d\n", onlineUsers[*account.Username], *account.DeviceNo)
+	return text
+}
+
+func handleUser(update tgbotapi.Update, args []string) error {
+	account, err := telegramAccount(args, telegramAdminCommands.commandMap["user"].usage)
+	if err != nil {
+		return err
+	}
+	return SendWithMessage(update.Message.Chat.ID, telegramAccountText(account))
+}
+
+func handleAddUser(update tgbotapi.Update, args []string) error {
+	usage := telegramAdminCommands.commandMap["adduser"].usage
+	if len(args) < 4 {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	username, pass := args[0], args[1]
+	if !util.ValidateStr(username) || !util.ValidateStr(pass) {
+		return errors.New("username and pass must be 6-32 characters of letters, digits or !@#$%^&*()_+-=")
+	}
+	quotaGiB, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || quotaGiB < 0 && quotaGiB != -1 {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	days, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil || days <= 0 {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	var deviceNo int64 = telegramDefaultLimit
+	if len(args) > 4 {
+		if deviceNo, err = strconv.ParseInt(args[4], 10, 64); err != nil || deviceNo < 1 {
+			return fmt.Errorf("usage: %s", usage)
+		}
+	}
+	if ExistAccountUsername(username, 0) {
+		return fmt.Errorf("username %s already exists", username)
+	}
+	conPassSuffix, err := util.RandomString(16)
+	if err != nil {
+		return errors.New(constant.SysError)
+	}
+
+	quota := int64(-1)
+	if quotaGiB >= 0 {
+		quota = int64(quotaGiB * telegramBytesOfGiB)
+	}
+	expireTime := time.Now().Add(time.Duration(days) * 24 * time.Hour).UnixMilli()
+	if err = ValidateAccountPass(0, username, pass); err != nil {
+		return err
+	}
+	passEncrypt, err := hashPass(pass)
+	if err != nil {
+		return err
+	}
+	conPass := fmt.Sprintf("%s.%s", username, conPassSuffix)
+	var nodeAccess, deleted int64 = 1, 0
+	if err = SaveAccount(entity.Account{
+		Username:   &username,
+		Pass:       &passEncrypt,
+		ConPass:    &conPass,
+		Quota:      &quota,
+		ExpireTime: &expireTime,
+		DeviceNo:   &deviceNo,
+		NodeAccess: &nodeAccess,
+		Deleted:    &deleted,
+	}); err != nil {
+		return err
+	}
+	return SendWithMessage(update.Message.Chat.ID, fmt.Sprintf("Account %s created, quota %s, expires at %s",
+		username, formatTelegramQuota(quota), time.UnixMilli(expireTime).Format("2006-01-02 15:04:05")))
+}
+
+func handleRenew(update tgbotapi.Update, args []string) error {
+	usage := telegramAdminCommands.commandMap["renew"].usage
+	account, err := telegramAccount(args, usage)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	days, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || days <= 0 {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	// 未过期时在原到期时间上顺延
+	base := time.Now()
+	if expire := time.UnixMilli(*account.ExpireTime); expire.After(base) {
+		base = expire
+	}
+	expireTime := base.Add(time.Duration(days) * 24 * time.Hour).UnixMilli()
+	if err = UpdateAccount(entity.Account{
+		BaseEntity: entity.BaseEntity{Id: account.Id},
+		ExpireTime: &expireTime,
+	}); err != nil {
+		return err
+	}
+	return SendWithMessage(update.Message.Chat.ID, fmt.Sprintf("Account %s now expires at %s",
+		*account.Username, time.UnixMilli(expireTime).Format("2006-01-02 15:04:05")))
+}
+
+func handleAddQuota(update tgbotapi.Update, args []string) error {
+	usage := telegramAdminCommands.commandMap["addquota"].usage
+	account, err := telegramAccount(args, usage)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	gib, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || gib <= 0 {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	if *account.Quota < 0 {
+		return fmt.Errorf("account %s has unlimited quota", *account.Username)
+	}
+	quota := *account.Quota + int64(gib*telegramBytesOfGiB)
+	if err = UpdateAccount(entity.Account{
+		BaseEntity: entity.BaseEntity{Id: account.Id},
+		Quota:      &quota,
+	}); err != nil {
+		return err
+	}
+	return SendWithMessage(update.Message.Chat.ID, fmt.Sprintf("Account %s quota is now %s", *account.Username, formatTelegramQuota(quota)))
+}
+
+func handleKick(update tgbotapi.Update, args []string) error {
+	usage := telegramAdminCommands.commandMap["kick"].usage
+	account, err := telegramAccount(args, usage)
+	if err != nil {
+		return err
+	}
+	var minutes int64 = telegramDefaultKick
+	if len(args) > 1 {
+		if minutes, err = strconv.ParseInt(args[1], 10, 64); err != nil || minutes < 0 {
+			return fmt.Errorf("usage: %s", usage)
+		}
+	}
+	text := fmt.Sprintf("Kick %s offline and block connections for %d minutes?", *account.Username, minutes)
+	return confirmTelegramAction(update.Message.Chat.ID, text, func() (string, error) {
+		kickUtilTime := time.Now().Add(time.Duration(minutes) * time.Minute).UnixMilli()
+		if err := Hysteria2Kick([]int64{*account.Id}, kickUtilTime); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Account %s has been kicked", *account.Username), nil
+	})
+}
+
+func handleResetTraffic(update tgbotapi.Update, args []string) error {
+	account, err := telegramAccount(args, telegramAdminCommands.commandMap["resettraffic"].usage)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("Reset the traffic of %s (%s used)?", *account.Username, formatTelegramBytes(*account.Download+*account.Upload))
+	return confirmTelegramAction(update.Message.Chat.ID, text, func() (string, error) {
+		if err := ResetTraffic(*account.Id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Traffic of %s has been reset", *account.Username), nil
+	})
+}
+
+func handleOnline(update tgbotapi.Update, args []string) error {
+	onlineUsers, err := Hysteria2Online()
+	if err != nil {
+		return err
+	}
+	if len(onlineUsers) == 0 {
+		return SendWithMessage(update.Message.Chat.ID, "No online accounts")
+	}
+	usernames := make([]string, 0, len(onlineUsers))
+	var deviceTotal int64 = 0
+	for username, device := range onlineUsers {
+		usernames = append(usernames, username)
+		deviceTotal += device
+	}
+	sort.Strings(usernames)
+	text := fmt.Sprintf("Online accounts: %d, devices: %d\n", len(usernames), deviceTotal)
+	for _, username := range usernames {
+		text += fmt.Sprintf("%s: %d\n", username, onlineUsers[username])
+	}
+	return SendWithMessage(update.Message.Chat.ID, text)
+}
+
+func handleTop(update tgbotapi.Update, args []string) error {
+	tops, err := dao.TopAccountTrafficDaily(time.Now().Format("2006-01-02"), telegramTopLimit)
+	if err != nil {
+		return err
+	}
+	if len(tops) == 0 {
+		return SendWithMessage(update.Message.Chat.ID, "No traffic today")
+	}
+	text := "Top consumers today\n"
+	for i, item := range tops {
+		text += fmt.Sprintf("%d. %s: %s (↓%s ↑%s)\n", i+1, item.Username, formatTelegramBytes(item.Download+item.Upload),
+			formatTelegramBytes(item.Download), formatTelegramBytes(item.Upload))
+	}
+	return SendWithMessage(update.Message.Chat.ID, text)
+}
+
+func handleHelp(update tgbotapi.Update, args []string) error {
+	return SendWithMessage(update.Message.Chat.ID, telegramAdminCommands.help("chatid"))
+}
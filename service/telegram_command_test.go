@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTelegramCommandSet(t *testing.T) {
+	commands := newTelegramCommandSet()
+	commands.register(telegramCommand{name: "a", description: "first"})
+	commands.register(telegramCommand{name: "b", description: "second"})
+	commands.register(telegramCommand{name: "a", description: "override"})
+	menu := commands.menu()
+	if len(menu) != 2 || menu[0].Command != "a" || menu[0].Description != "override" || menu[1].Command != "b" {
+		t.Fatalf("unexpected menu: %+v", menu)
+	}
+	if menu = commands.menu("a"); len(menu) != 1 || menu[0].Command != "b" {
+		t.Errorf("unexpected menu with exclude: %+v", menu)
+	}
+}
+
+func TestTelegramAdminMenuExclude(t *testing.T) {
+	menu := telegramAdminCommands.menu(telegramAdminMenuExclude(false)...)
+	if len(menu) != 1 || menu[0].Command != "chatid" {
+		t.Errorf("only chatid should be shown outside admin chats, got: %+v", menu)
+	}
+	menu = telegramAdminCommands.menu(telegramAdminMenuExclude(true)...)
+	if len(menu) != len(telegramAdminCommands.commands)-1 {
+		t.Fatalf("expected %d commands, got: %d", len(telegramAdminCommands.commands)-1, len(menu))
+	}
+	for _, item := range menu {
+		if item.Command == "chatid" {
+			t.Errorf("chatid should be hidden in admin chats")
+		}
+	}
+}
+
+func TestTakeTelegramConfirm(t *testing.T) {
+	now := time.Now()
+	telegramConfirmMutex.Lock()
+	telegramConfirms["valid"] = telegramConfirm{chatId: 1, expireAt: now.Add(time.Minute)}
+	telegramConfirms["expired"] = telegramConfirm{chatId: 1, expireAt: now.Add(-time.Second)}
+	telegramConfirmMutex.Unlock()
+
+	if _, ok := takeTelegramConfirm("valid", 2, now); ok {
+		t.Errorf("confirm from another chat should be rejected")
+	}
+	if _, ok := takeTelegramConfirm("valid", 1, now); !ok {
+		t.Errorf("expected valid confirm")
+	}
+	if _, ok := takeTelegramConfirm("valid", 1, now); ok {
+		t.Errorf("confirm should only be used once")
+	}
+	if _, ok := takeTelegramConfirm("expired", 1, now); ok {
+		t.Errorf("expired confirm should be rejected")
+	}
+}
+
+func TestFormatTelegramBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                  "0 B",
+		1023:               "1023 B",
+		1536:               "1.50 KiB",
+		telegramBytesOfGiB: "1.00 GiB",
+	}
+	for bytes, expect := range tests {
+		if got := formatTelegramBytes(bytes); got != expect {
+			t.Errorf("formatTelegramBytes(%d) expected %s, got: %s", bytes, expect, got)
+		}
+	}
+	if got := formatTelegramQuota(-1); got != "unlimited" {
+		t.Errorf("expected unlimited, got: %s", got)
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"h-ui/model/constant"
+	"testing"
+)
+
+func TestTelegramSubscribed(t *testing.T) {
+	tests := []struct {
+		subscriptions []string
+		notify        string
+		expect        bool
+	}{
+		{[]string{constant.TelegramNotifyAll}, constant.TelegramNotifyAlert, true},
+		{[]string{constant.TelegramNotifyLogin}, constant.TelegramNotifyLogin, true},
+		{[]string{constant.TelegramNotifyLogin}, constant.TelegramNotifyLoginLock, false},
+		{nil, constant.TelegramNotifyAlert, false},
+	}
+	for _, tt := range tests {
+		if subscribed := telegramSubscribed(tt.subscriptions, tt.notify); subscribed != tt.expect {
+			t.Errorf("telegramSubscribed(%v, %s) expected %v, got: %v", tt.subscriptions, tt.notify, tt.expect, subscribed)
+		}
+	}
+}
+
+func TestTelegramWebhookAuthorized(t *testing.T) {
+	defer telegramWebhookSecret.Store("")
+
+	telegramWebhookSecret.Store("")
+	if TelegramWebhookAuthorized("") {
+		t.Errorf("empty secret should never be authorized")
+	}
+	telegramWebhookSecret.Store("0123456789abcdef")
+	if !TelegramWebhookAuthorized("0123456789abcdef") {
+		t.Errorf("expected matching secret to be authorized")
+	}
+	if TelegramWebhookAuthorized("0123456789abcdeF") {
+		t.Errorf("expected mismatched secret to be rejected")
+	}
+}
+
+func TestHandleTelegramWebhookBusy(t *testing.T) {
+	for i := 0; i < cap(telegramWebhookWorkers); i++ {
+		telegramWebhookWorkers <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < cap(telegramWebhookWorkers); i++ {
+			<-telegramWebhookWorkers
+		}
+	}()
+	if err := HandleTelegramWebhook([]byte(`{"update_id":1}`)); !errors.Is(err, ErrTelegramBusy) {
+		t.Fatalf("expected busy error, got %v", err)
+	}
+}
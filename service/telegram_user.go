@@ -0,0 +1,356 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	telegramBindCodeLength  = 10
+	telegramBindCodeTTL     = 30 * time.Minute
+	telegramUserRateLimit   = 20 // 每个会话每分钟最多处理的消息数
+	telegramBindRateLimit   = 5  // 每个会话每小时最多尝试绑定的次数
+	telegramRedeemRateLimit = 10 // 每个会话每小时最多尝试兑换的次数
+)
+
+// telegramUserCommands 用户私聊会话可用的命令
+var telegramUserCommands = newTelegramCommandSet()
+
+func init() {
+	telegramUserCommands.register(telegramCommand{name: "start", usage: "/start", description: "Start", handler: handleUserStart})
+	telegramUserCommands.register(telegramCommand{name: "bind", usage: "/bind <code>", description: "Bind your account with a binding code", handler: handleUserBind})
+	telegramUserCommands.register(telegramCommand{name: "me", usage: "/me", description: "Usage and expiry", handler: handleUserMe})
+	telegramUserCommands.register(telegramCommand{name: "sub", usage: "/sub", description: "Subscription link and QR code", handler: handleUserSub})
+	telegramUserCommands.register(telegramCommand{name: "redeem", usage: "/redeem <code>", description: "Redeem a voucher", handler: handleUserRedeem})
+	telegramUserCommands.register(telegramCommand{name: "portal", usage: "/portal", description: "One-time login link to the user portal", handler: handleUserPortal})
+	telegramUserCommands.register(telegramCommand{name: "unbind", usage: "/unbind", description: "Unbind your account", handler: handleUserUnbind})
+	telegramUserCommands.register(telegramCommand{name: "help", usage: "/help", description: "Command list", handler: handleUserHelp})
+}
+
+type telegramRateWindow struct {
+	start    time.Time
+	count    int
+	notified bool
+}
+
+// telegramRateLimiter 按会话的固定窗口计数
+type telegramRateLimiter struct {
+	mutex   sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[int64]*telegramRateWindow
+	now     func() time.Time
+}
+
+var telegramUserLimiter = newTelegramRateLimiter(telegramUserRateLimit, time.Minute, time.Now)
+var telegramBindLimiter = newTelegramRateLimiter(telegramBindRateLimit, time.Hour, time.Now)
+var telegramRedeemLimiter = newTelegramRateLimiter(telegramRedeemRateLimit, time.Hour, time.Now)
+
+func newTelegramRateLimiter(limit int, window time.Duration, now func() time.Time) *telegramRateLimiter {
+	return &telegramRateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[int64]*telegramRateWindow),
+		now:     now,
+	}
+}
+
+// allow 记录一次请求，notify 表示本窗口内第一次被拒绝，用于只提醒一次
+func (l *telegramRateLimiter) allow(chatId int64) (allowed bool, notify bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	window, exist := l.windows[chatId]
+	if !exist || now.Sub(window.start) >= l.window {
+		if len(l.windows) >= 1024 {
+			for key, item := range l.windows {
+				if now.Sub(item.start) >= l.window {
+					delete(l.windows, key)
+				}
+			}
+		}
+		window = &telegramRateWindow{start: now}
+		l.windows[chatId] = window
+	}
+	window.count++
+	if window.count <= l.limit {
+		return true, false
+	}
+	notify = !window.notified
+	window.notified = true
+	return false, notify
+}
+
+func telegramUserAllowed(chatId int64) bool {
+	allowed, notify := telegramUserLimiter.allow(chatId)
+	if notify {
+		_ = SendWithMessage(chatId, "Too many requests, please try again later")
+	}
+	return allowed
+}
+
+var telegramBindCodes = newOneTimeTokens(telegramBindCodeLength, telegramBindCodeTTL)
+
+// GenerateTelegramBindCode 生成一次性绑定码，同一账号只保留最新的绑定码
+func GenerateTelegramBindCode(accountId int64) (vo.TelegramBindCodeVo, error) {
+	if _, err := dao.GetAccount("id = ?", accountId); err != nil {
+		return vo.TelegramBindCodeVo{}, errors.New("account not found")
+	}
+	code, expireAt, err := telegramBindCodes.issue(accountId, time.Now())
+	if err != nil {
+		return vo.TelegramBindCodeVo{}, err
+	}
+	bindCodeVo := vo.TelegramBindCodeVo{
+		Code:       code,
+		ExpireTime: expireAt.UnixMilli(),
+	}
+	if bot := telegramBot.Load(); bot != nil {
+		bindCodeVo.Link = fmt.Sprintf("https://t.me/%s?start=%s", bot.Self.UserName, code)
+	}
+	return bindCodeVo, nil
+}
+
+func UnbindTelegram(accountId int64) error {
+	return dao.DeleteTelegramBinding("account_id = ?", accountId)
+}
+
+// telegramBoundAccount 当前会话绑定的账号
+func telegramBoundAccount(chatId int64) (entity.Account, error) {
+	binding, err := dao.GetTelegramBinding("chat_id = ?", chatId)
+	if err != nil {
+		return entity.Account{}, errors.New("this chat is not bound to any account, send /bind <code> to bind")
+	}
+	return dao.GetAccount("id = ?", *binding.AccountId)
+}
+
+func bindTelegram(chatId int64, code string) error {
+	if allowed, _ := telegramBindLimiter.allow(chatId); !allowed {
+		return errors.New("too many binding attempts, please try again later")
+	}
+	accountId, ok := telegramBindCodes.take(code, time.Now())
+	if !ok {
+		return errors.New("the binding code is invalid or has expired")
+	}
+	account, err := dao.GetAccount("id = ?", accountId)
+	if err != nil {
+		return err
+	}
+	if err = dao.SaveTelegramBinding(accountId, chatId); err != nil {
+		return err
+	}
+	return SendWithMessage(chatId, fmt.Sprintf("This chat is now bound to account %s, send /help for the list of commands", *account.Username))
+}
+
+func handleUserStart(update tgbotapi.Update, args []string) error {
+	// t.me/<bot>?start=<code> 打开时直接绑定
+	if len(args) > 0 {
+		return bindTelegram(update.Message.Chat.ID, args[0])
+	}
+	return handleUserHelp(update, args)
+}
+
+func handleUserBind(update tgbotapi.Update, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: %s", telegramUserCommands.commandMap["bind"].usage)
+	}
+	return bindTelegram(update.Message.Chat.ID, args[0])
+}
+
+func handleUserUnbind(update tgbotapi.Update, args []string) error {
+	account, err := telegramBoundAccount(update.Message.Chat.ID)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("Unbind account %s from this chat?", *account.Username)
+	return confirmTelegramAction(update.Message.Chat.ID, text, func() (string, error) {
+		if err := dao.DeleteTelegramBinding("chat_id = ?", update.Message.Chat.ID); err != nil {
+			return "", err
+		}
+		return "Unbound successfully", nil
+	})
+}
+
+func handleUserMe(update tgbotapi.Update, args []string) error {
+	account, err := telegramBoundAccount(update.Message.Chat.ID)
+	if err != nil {
+		return err
+	}
+	return SendWithMessage(update.Message.Chat.ID, telegramAccountText(account))
+}
+
+func handleUserSub(update tgbotapi.Update, args []string) error {
+	account, err := telegramBoundAccount(update.Message.Chat.ID)
+	if err != nil {
+		return err
+	}
+	publicUrl, err := dao.GetConfig("key = ?", constant.HUIPublicUrl)
+	if err != nil {
+		return err
+	}
+	base, err := url.Parse(*publicUrl.Value)
+	if *publicUrl.Value == "" || err != nil || base.Scheme == "" || base.Host == "" {
+		return errors.New("the subscription link is not available, please contact the administrator")
+	}
+	subscribeUrl, err := Hysteria2SubscribeUrl(*account.Id, base.Scheme+":", base.Host)
+	if err != nil {
+		return err
+	}
+	qrCode, err := qrcode.Encode(subscribeUrl, qrcode.Medium, 300)
+	if err != nil {
+		logrus.Errorf("qrcode encode err: %v", err)
+		return errors.New(constant.SysError)
+	}
+	photo := tgbotapi.NewPhoto(update.Message.Chat.ID, tgbotapi.FileBytes{Name: "subscribe.png", Bytes: qrCode})
+	photo.Caption = subscribeUrl
+	bot, err := telegramBotApi()
+	if err != nil {
+		return err
+	}
+	if _, err = bot.Send(photo); err != nil {
+		logrus.Errorf("tg api SendPhoto err: %v", err)
+		return err
+	}
+	nodeUrls, err := Hysteria2MultiNodeUrl(*account.Id, base.Hostname())
+	if err != nil || len(nodeUrls) == 0 {
+		return nil
+	}
+	text := ""
+	for _, item := range nodeUrls {
+		text += fmt.Sprintf("【%s】\n%s\n", item.NodeName, item.Url)
+	}
+	return SendWithMessage(update.Message.Chat.ID, text)
+}
+
+func handleUserRedeem(update tgbotapi.Update, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: %s", telegramUserCommands.commandMap["redeem"].usage)
+	}
+	account, err := telegramBoundAccount(update.Message.Chat.ID)
+	if err != nil {
+		return err
+	}
+	if allowed, _ := telegramRedeemLimiter.allow(update.Message.Chat.ID); !allowed {
+		return errors.New("too many redeem attempts, please try again later")
+	}
+	redemptionVo, err := RedeemVoucher(*account.Id, args[0])
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("Redeemed successfully, your account now expires at %s",
+		time.UnixMilli(redemptionVo.ExpireTimeAfter).Format("2006-01-02 15:04:05"))
+	return SendWithMessage(update.Message.Chat.ID, text)
+}
+
+func handleUserPortal(update tgbotapi.Update, args []string) error {
+	account, err := telegramBoundAccount(update.Message.Chat.ID)
+	if err != nil {
+		return err
+	}
+	loginLinkVo, err := GenerateUserLoginLink(*account.Id)
+	if err != nil {
+		return err
+	}
+	if loginLinkVo.Link == "" {
+		return errors.New("the user portal is not available, please contact the administrator")
+	}
+	text := fmt.Sprintf("%s\nThis link can only be used once and expires at %s", loginLinkVo.Link,
+		time.UnixMilli(loginLinkVo.ExpireTime).Format("2006-01-02 15:04:05"))
+	return SendWithMessage(update.Message.Chat.ID, text)
+}
+
+func handleUserHelp(update tgbotapi.Update, args []string) error {
+	return SendWithMessage(update.Message.Chat.ID, telegramUserCommands.help("start"))
+}
+
+// telegramQuotaWarn 用量达到额度的 percent% 时提醒，无限额度不提醒
+func telegramQuotaWarn(account entity.Account, percent int64) bool {
+	if *account.Quota <= 0 || percent <= 0 {
+		return false
+	}
+	return (*account.Download+*account.Upload)*100 >= *account.Quota*percent
+}
+
+// telegramExpireWarn 距离到期不足 days 天时提醒
+func telegramExpireWarn(account entity.Account, days int64, now time.Time) bool {
+	if days <= 0 {
+		return false
+	}
+	return time.UnixMilli(*account.ExpireTime).Sub(now) <= time.Duration(days)*24*time.Hour
+}
+
+// CronTelegramUserRemind 向已绑定的用户发送额度和到期提醒，每次越过阈值只提醒一次
+func CronTelegramUserRemind() {
+	if telegramBot.Load() == nil || !currentTelegramSettings().userEnable {
+		return
+	}
+	configs, err := dao.ListConfig("key in ?", []string{constant.TelegramUserQuotaWarn, constant.TelegramUserExpireWarn})
+	if err != nil {
+		return
+	}
+	var quotaWarn, expireWarn int64
+	for _, item := range configs {
+		value, _ := strconv.ParseInt(*item.Value, 10, 64)
+		if *item.Key == constant.TelegramUserQuotaWarn {
+			quotaWarn = value
+		} else if *item.Key == constant.TelegramUserExpireWarn {
+			expireWarn = value
+		}
+	}
+	bindings, err := dao.ListTelegramBinding(nil, nil)
+	if err != nil || len(bindings) == 0 {
+		return
+	}
+	var accountIds []int64
+	for _, item := range bindings {
+		accountIds = append(accountIds, *item.AccountId)
+	}
+	accounts, err := dao.ListAccount("id in ?", accountIds)
+	if err != nil {
+		return
+	}
+	accountMap := make(map[int64]entity.Account, len(accounts))
+	for _, item := range accounts {
+		accountMap[*item.Id] = item
+	}
+
+	now := time.Now()
+	for _, binding := range bindings {
+		account, exist := accountMap[*binding.AccountId]
+		if !exist {
+			continue
+		}
+		updates := map[string]interface{}{}
+		if telegramQuotaWarn(account, quotaWarn) {
+			if *binding.QuotaWarned == 0 {
+				text := fmt.Sprintf("【H UI】\nYou have used %s of your %s quota", formatTelegramBytes(*account.Download+*account.Upload),
+					formatTelegramQuota(*account.Quota))
+				if SendWithMessage(*binding.ChatId, text) == nil {
+					updates["quota_warned"] = 1
+				}
+			}
+		} else if *binding.QuotaWarned != 0 {
+			// 重置流量或增加额度后重新提醒
+			updates["quota_warned"] = 0
+		}
+		// 续期后到期时间变化，会再次提醒
+		if telegramExpireWarn(account, expireWarn, now) && *binding.ExpireWarned != *account.ExpireTime {
+			text := fmt.Sprintf("【H UI】\nYour account expires at %s", time.UnixMilli(*account.ExpireTime).Format("2006-01-02 15:04:05"))
+			if SendWithMessage(*binding.ChatId, text) == nil {
+				updates["expire_warned"] = *account.ExpireTime
+			}
+		}
+		_ = dao.UpdateTelegramBinding([]int64{*binding.Id}, updates)
+	}
+}
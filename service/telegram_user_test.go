@@ -0,0 +1,101 @@
+package service
+
+import (
+	"h-ui/model/entity"
+	"testing"
+	"time"
+)
+
+func TestTelegramRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newTelegramRateLimiter(2, time.Minute, func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.allow(1); !allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	if allowed, notify := limiter.allow(1); allowed || !notify {
+		t.Errorf("expected first rejection to notify, got allowed=%v notify=%v", allowed, notify)
+	}
+	if allowed, notify := limiter.allow(1); allowed || notify {
+		t.Errorf("expected silent rejection, got allowed=%v notify=%v", allowed, notify)
+	}
+	if allowed, _ := limiter.allow(2); !allowed {
+		t.Errorf("other chats should not be limited")
+	}
+
+	now = now.Add(time.Minute)
+	if allowed, _ := limiter.allow(1); !allowed {
+		t.Errorf("expected a new window after one minute")
+	}
+}
+
+func TestOneTimeTokens(t *testing.T) {
+	now := time.Now()
+	tokens := newOneTimeTokens(10, time.Minute)
+
+	first, _, err := tokens.issue(7, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, expireAt, err := tokens.issue(7, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 10 || !expireAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected token: %s %v", second, expireAt)
+	}
+	if _, ok := tokens.take(first, now); ok {
+		t.Errorf("previous token should be revoked when a new one is issued")
+	}
+	if accountId, ok := tokens.take(second, now); !ok || accountId != 7 {
+		t.Errorf("expected account 7, got: %d %v", accountId, ok)
+	}
+	if _, ok := tokens.take(second, now); ok {
+		t.Errorf("token should only be used once")
+	}
+
+	expired, _, _ := tokens.issue(8, now)
+	if _, ok := tokens.take(expired, now.Add(time.Minute)); ok {
+		t.Errorf("expired token should be rejected")
+	}
+}
+
+func TestTelegramQuotaWarn(t *testing.T) {
+	account := func(quota, download, upload int64) entity.Account {
+		return entity.Account{Quota: &quota, Download: &download, Upload: &upload}
+	}
+	tests := []struct {
+		account entity.Account
+		percent int64
+		expect  bool
+	}{
+		{account(100, 50, 29), 80, false},
+		{account(100, 50, 30), 80, true},
+		{account(-1, 1000, 1000), 80, false},
+		{account(100, 100, 0), 0, false},
+	}
+	for i, tt := range tests {
+		if warn := telegramQuotaWarn(tt.account, tt.percent); warn != tt.expect {
+			t.Errorf("case %d expected %v, got: %v", i, tt.expect, warn)
+		}
+	}
+}
+
+func TestTelegramExpireWarn(t *testing.T) {
+	now := time.Now()
+	account := func(expire time.Time) entity.Account {
+		expireTime := expire.UnixMilli()
+		return entity.Account{ExpireTime: &expireTime}
+	}
+	if !telegramExpireWarn(account(now.Add(47*time.Hour)), 2, now) {
+		t.Errorf("expected warning within 2 days")
+	}
+	if telegramExpireWarn(account(now.Add(49*time.Hour)), 2, now) {
+		t.Errorf("unexpected warning beyond 2 days")
+	}
+	if telegramExpireWarn(account(now.Add(time.Hour)), 0, now) {
+		t.Errorf("warning should be disabled when days is 0")
+	}
+}
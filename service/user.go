@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
+	"h-ui/dao"
+	"h-ui/model/bo"
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	userLoginTokenLength = 32
+	userLoginTokenTTL    = 15 * time.Minute
+	userConPassLength    = 16
+	userTrafficDays      = 30
+)
+
+// userLoginTokens 用户登录链接中的一次性凭证
+var userLoginTokens = newOneTimeTokens(userLoginTokenLength, userLoginTokenTTL)
+
+// genUserToken 用户自助页面的 token 只有 user 角色，不能访问管理接口
+func genUserToken(account entity.Account) (string, error) {
+	return GenToken(bo.AccountBo{
+		Id:       *account.Id,
+		Username: *account.Username,
+		Roles:    []string{"user"},
+		Deleted:  *account.Deleted,
+	})
+}
+
+// UserAccountAvailable 账号被删除或禁用后，已签发的 token 立即失效
+func UserAccountAvailable(accountId int64) bool {
+	_, err := dao.GetAccount("id = ? and deleted = 0", accountId)
+	return err == nil
+}
+
+func UserLogin(username string, pass string) (string, error) {
+	account, err := dao.GetAccount("username = ? and deleted = 0", username)
+	if err != nil {
+		return "", err
+	}
+	if !verifyAccountPass(account, pass) {
+		return "", errors.New(constant.WrongPassword)
+	}
+	return genUserToken(account)
+}
+
+// UserLoginByToken 使用登录链接登录，凭证只能使用一次
+func UserLoginByToken(token string) (string, error) {
+	accountId, ok := userLoginTokens.take(token, time.Now())
+	if !ok {
+		return "", errors.New("the login link is invalid or has expired")
+	}
+	account, err := dao.GetAccount("id = ? and deleted = 0", accountId)
+	if err != nil {
+		return "", errors.New("this account has been disabled")
+	}
+	return genUserToken(account)
+}
+
+// GenerateUserLoginLink 生成登录链接，同一账号只保留最新的链接
+func GenerateUserLoginLink(accountId int64) (vo.UserLoginLinkVo, error) {
+	if _, err := dao.GetAccount("id = ? and deleted = 0", accountId); err != nil {
+		return vo.UserLoginLinkVo{}, errors.New("account not found")
+	}
+	token, expireAt, err := userLoginTokens.issue(accountId, time.Now())
+	if err != nil {
+		return vo.UserLoginLinkVo{}, err
+	}
+	link, _ := userLoginLink(token)
+	return vo.UserLoginLinkVo{
+		Token:      token,
+		Link:       link,
+		ExpireTime: expireAt.UnixMilli(),
+	}, nil
+}
+
+// userLoginLink 登录链接为 H_UI_PUBLIC_URL + H_UI_WEB_CONTEXT + /#/portal?token=
+func userLoginLink(token string) (string, error) {
+	configs, err := dao.ListConfig("key in ?", []string{constant.HUIPublicUrl, constant.HUIWebContext})
+	if err != nil {
+		return "", err
+	}
+	var publicUrl, webContext string
+	for _, item := range configs {
+		if *item.Key == constant.HUIPublicUrl {
+			publicUrl = *item.Value
+		} else if *item.Key == constant.HUIWebContext && *item.Value != "/" && strings.HasPrefix(*item.Value, "/") {
+			webContext = *item.Value
+		}
+	}
+	base, err := url.Parse(publicUrl)
+	if publicUrl == "" || err != nil || base.Scheme == "" || base.Host == "" {
+		return "", errors.New("H_UI_PUBLIC_URL is not configured")
+	}
+	return fmt.Sprintf("%s://%s%s/#/portal?token=%s", base.Scheme, base.Host, webContext, token), nil
+}
+
+func GetUserInfo(accountId int64) (vo.UserInfoVo, error) {
+	account, err := dao.GetAccount("id = ?", accountId)
+	if err != nil {
+		return vo.UserInfoVo{}, errors.New("account not found")
+	}
+	userInfoVo := vo.UserInfoVo{
+		Id:         *account.Id,
+		Username:   *account.Username,
+		Quota:      *account.Quota,
+		Download:   *account.Download,
+		Upload:     *account.Upload,
+		ExpireTime: *account.ExpireTime,
+		DeviceNo:   *account.DeviceNo,
+		NodeAccess: *account.NodeAccess,
+	}
+	onlineUsers, err := Hysteria2Online()
+	if err == nil {
+		if device, exist := onlineUsers[*account.Username]; exist {
+			userInfoVo.Online = true
+			userInfoVo.Device = device
+		}
+	}
+	return userInfoVo, nil
+}
+
+// ListUserTrafficDaily 最近 days 天的每日流量，没有流量的日期补 0
+func ListUserTrafficDaily(accountId int64, days int64) ([]vo.UserTrafficDailyVo, error) {
+	if days <= 0 {
+		days = userTrafficDays
+	}
+	now := time.Now()
+	start := now.AddDate(0, 0, int(1-days)).Format("2006-01-02")
+	trafficDailies, err := dao.ListAccountTrafficDaily("account_id = ? and day >= ?", accountId, start)
+	if err != nil {
+		return nil, err
+	}
+	return fillUserTrafficDaily(trafficDailies, days, now), nil
+}
+
+func fillUserTrafficDaily(trafficDailies []entity.AccountTrafficDaily, days int64, now time.Time) []vo.UserTrafficDailyVo {
+	trafficMap := make(map[string]entity.AccountTrafficDaily, len(trafficDailies))
+	for _, item := range trafficDailies {
+		trafficMap[*item.Day] = item
+	}
+	trafficDailyVos := make([]vo.UserTrafficDailyVo, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		day := now.AddDate(0, 0, int(-i)).Format("2006-01-02")
+		trafficDailyVo := vo.UserTrafficDailyVo{Day: day}
+		if item, exist := trafficMap[day]; exist {
+			trafficDailyVo.Download = *item.Download
+			trafficDailyVo.Upload = *item.Upload
+		}
+		trafficDailyVos = append(trafficDailyVos, trafficDailyVo)
+	}
+	return trafficDailyVos
+}
+
+// GetUserSubscribe 订阅链接和有权限的各节点链接
+func GetUserSubscribe(accountId int64, protocol string, host string) (vo.UserSubscribeVo, error) {
+	subscribeUrl, err := Hysteria2SubscribeUrl(accountId, protocol, host)
+	if err != nil {
+		return vo.UserSubscribeVo{}, err
+	}
+	qrCode, err := qrcode.Encode(subscribeUrl, qrcode.Medium, 300)
+	if err != nil {
+		logrus.Errorf("qrcode encode err: %v", err)
+		return vo.UserSubscribeVo{}, errors.New(constant.SysError)
+	}
+	hostname := host
+	if parsed, err := url.Parse(protocol + "//" + host); err == nil && parsed.Hostname() != "" {
+		hostname = parsed.Hostname()
+	}
+	nodes, err := Hysteria2MultiNodeUrl(accountId, hostname)
+	if err != nil {
+		return vo.UserSubscribeVo{}, err
+	}
+	return vo.UserSubscribeVo{
+		Url:    subscribeUrl,
+		QrCode: qrCode,
+		Nodes:  nodes,
+	}, nil
+}
+
+// RotateUserConPass 重新生成连接密码，并断开使用旧密码的连接
+func RotateUserConPass(accountId int64) (string, error) {
+	account, err := dao.GetAccount("id = ?", accountId)
+	if err != nil {
+		return "", errors.New("account not found")
+	}
+	conPass, err := util.RandomString(userConPassLength)
+	if err != nil {
+		return "", errors.New(constant.SysError)
+	}
+	if err = UpdateAccount(entity.Account{
+		BaseEntity: entity.BaseEntity{Id: account.Id},
+		Username:   account.Username,
+		ConPass:    &conPass,
+	}); err != nil {
+		return "", err
+	}
+	if Hysteria2IsRunning() {
+		if err = kickUserSessions(accountId); err != nil {
+			logrus.Errorf("kick account %d after rotating conPass err: %v", accountId, err)
+		}
+	}
+	// 保存的连接密码带有用户名前缀
+	return fmt.Sprintf("%s.%s", *account.Username, conPass), nil
+}
+
+// KickUserSessions 断开当前账号的所有连接
+func KickUserSessions(accountId int64) error {
+	if !Hysteria2IsRunning() {
+		return errors.New("hysteria2 is not running")
+	}
+	return kickUserSessions(accountId)
+}
+
+// kickUserSessions 断开连接后账号立即可以重新连接
+func kickUserSessions(accountId int64) error {
+	return Hysteria2Kick([]int64{accountId}, time.Now().UnixMilli())
+}
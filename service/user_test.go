@@ -0,0 +1,43 @@
+package service
+
+import (
+	"h-ui/model/entity"
+	"testing"
+	"time"
+)
+
+func TestFillUserTrafficDaily(t *testing.T) {
+	now := time.Date(2024, 3, 2, 12, 0, 0, 0, time.Local)
+	day, download, upload := "2024-03-01", int64(10), int64(20)
+	trafficDailyVos := fillUserTrafficDaily([]entity.AccountTrafficDaily{
+		{Day: &day, Download: &download, Upload: &upload},
+	}, 3, now)
+	if len(trafficDailyVos) != 3 {
+		t.Fatalf("expected 3 days, got: %d", len(trafficDailyVos))
+	}
+	expects := []string{"2024-02-29", "2024-03-01", "2024-03-02"}
+	for i, item := range trafficDailyVos {
+		if item.Day != expects[i] {
+			t.Errorf("expected day %s, got: %s", expects[i], item.Day)
+		}
+	}
+	if trafficDailyVos[0].Download != 0 || trafficDailyVos[1].Download != 10 || trafficDailyVos[1].Upload != 20 {
+		t.Errorf("unexpected traffic: %+v", trafficDailyVos)
+	}
+}
+
+func TestRotateUserConPass(t *testing.T) {
+	initTestSqlite(t)
+
+	conPass, err := RotateUserConPass(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := hysteria2AuthAccount(conPass)
+	if err != nil || *account.Id != 1 {
+		t.Fatalf("rotated conPass %s cannot authenticate: %v", conPass, err)
+	}
+	if _, err = hysteria2AuthAccount("sysadmin.sysadmin"); err == nil {
+		t.Fatal("old conPass should no longer authenticate")
+	}
+}
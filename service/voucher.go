@@ -0,0 +1,282 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	voucherCodeCharset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // 去掉易混淆的 I O 0 1
+	voucherCodeLength  = 16
+	voucherCodeGroup   = 4
+)
+
+// voucherMutex 串行化兑换，保证读取账号和更新账号之间不被其他兑换插入
+var voucherMutex sync.Mutex
+
+// newVoucherCode 生成形如 ABCD-EFGH-JKLM-NPQR 的兑换码
+func newVoucherCode() (string, error) {
+	bytes := make([]byte, voucherCodeLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	for i := range bytes {
+		if i > 0 && i%voucherCodeGroup == 0 {
+			builder.WriteByte('-')
+		}
+		builder.WriteByte(voucherCodeCharset[int(bytes[i])%len(voucherCodeCharset)])
+	}
+	return builder.String(), nil
+}
+
+// normalizeVoucherCode 忽略大小写、空格和分隔符
+func normalizeVoucherCode(code string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		}
+	}
+	code = builder.String()
+	if len(code) != voucherCodeLength {
+		return code
+	}
+	var grouped []string
+	for i := 0; i < len(code); i += voucherCodeGroup {
+		grouped = append(grouped, code[i:i+voucherCodeGroup])
+	}
+	return strings.Join(grouped, "-")
+}
+
+func GenerateVoucherBatch(voucherBatchSaveDto dto.VoucherBatchSaveDto) (int64, error) {
+	var planId, quota, duration, expireTime int64
+	if voucherBatchSaveDto.PlanId != nil {
+		planId = *voucherBatchSaveDto.PlanId
+	}
+	if voucherBatchSaveDto.Quota != nil {
+		quota = *voucherBatchSaveDto.Quota
+	}
+	if voucherBatchSaveDto.Duration != nil {
+		duration = *voucherBatchSaveDto.Duration
+	}
+	if voucherBatchSaveDto.ExpireTime != nil {
+		expireTime = *voucherBatchSaveDto.ExpireTime
+	}
+	if planId > 0 {
+		if quota > 0 || duration > 0 {
+			return 0, errors.New("a voucher is either tied to a plan or to a quota/duration amount")
+		}
+		if _, err := dao.GetPlan("id = ?", planId); err != nil {
+			return 0, err
+		}
+	} else if quota <= 0 && duration <= 0 {
+		return 0, errors.New("either plan or quota/duration is required")
+	}
+
+	count := *voucherBatchSaveDto.Count
+	codeSet := make(map[string]struct{}, count)
+	codes := make([]string, 0, count)
+	for int64(len(codes)) < count {
+		code, err := newVoucherCode()
+		if err != nil {
+			return 0, errors.New(constant.SysError)
+		}
+		if _, exist := codeSet[code]; exist {
+			continue
+		}
+		codeSet[code] = struct{}{}
+		codes = append(codes, code)
+	}
+	return dao.SaveVoucherBatch(entity.VoucherBatch{
+		Name:       voucherBatchSaveDto.Name,
+		PlanId:     &planId,
+		Quota:      &quota,
+		Duration:   &duration,
+		MaxUses:    voucherBatchSaveDto.MaxUses,
+		ExpireTime: &expireTime,
+		Count:      &count,
+	}, codes)
+}
+
+func DeleteVoucherBatch(id int64) error {
+	if _, err := dao.GetVoucherBatch("id = ?", id); err != nil {
+		return err
+	}
+	return dao.DeleteVoucherBatch([]int64{id})
+}
+
+func GetVoucherBatch(id int64) (entity.VoucherBatch, error) {
+	return dao.GetVoucherBatch("id = ?", id)
+}
+
+func ListVoucherBatch() ([]vo.VoucherBatchVo, error) {
+	voucherBatches, err := dao.ListVoucherBatch(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	voucherBatchVos := make([]vo.VoucherBatchVo, 0, len(voucherBatches))
+	for _, item := range voucherBatches {
+		voucherBatchVos = append(voucherBatchVos, vo.VoucherBatchVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Name:       *item.Name,
+			PlanId:     *item.PlanId,
+			Quota:      *item.Quota,
+			Duration:   *item.Duration,
+			MaxUses:    *item.MaxUses,
+			ExpireTime: *item.ExpireTime,
+			Count:      *item.Count,
+		})
+	}
+	return voucherBatchVos, nil
+}
+
+func ListVoucher(batchId int64) ([]vo.VoucherVo, error) {
+	vouchers, err := dao.ListVoucher("batch_id = ?", batchId)
+	if err != nil {
+		return nil, err
+	}
+	voucherVos := make([]vo.VoucherVo, 0, len(vouchers))
+	for _, item := range vouchers {
+		voucherVos = append(voucherVos, vo.VoucherVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Code:       *item.Code,
+			MaxUses:    *item.MaxUses,
+			Used:       *item.Used,
+			ExpireTime: *item.ExpireTime,
+		})
+	}
+	return voucherVos, nil
+}
+
+// voucherAmountUpdates 额度叠加到当前额度上，无限额度的账号不变；时长与续期一样叠加
+func voucherAmountUpdates(account entity.Account, quota int64, duration int64, now int64) map[string]interface{} {
+	updates := map[string]interface{}{}
+	if quota > 0 && *account.Quota >= 0 {
+		updates["quota"] = *account.Quota + quota
+	}
+	if duration > 0 {
+		updates["expire_time"] = renewExpireTime(*account.ExpireTime, duration*planDayMillis, now)
+	}
+	return updates
+}
+
+// RedeemVoucher 兑换后写入兑换记录，套餐兑换码同时记录套餐历史
+func RedeemVoucher(accountId int64, code string) (vo.VoucherRedemptionVo, error) {
+	voucherMutex.Lock()
+	defer voucherMutex.Unlock()
+
+	now := time.Now().UnixMilli()
+	voucher, err := dao.GetVoucher("code = ?", normalizeVoucherCode(code))
+	if err != nil {
+		return vo.VoucherRedemptionVo{}, err
+	}
+	if *voucher.ExpireTime != 0 && *voucher.ExpireTime <= now {
+		return vo.VoucherRedemptionVo{}, errors.New("the voucher has expired")
+	}
+	if *voucher.Used >= *voucher.MaxUses {
+		return vo.VoucherRedemptionVo{}, errors.New("the voucher has been used up")
+	}
+	voucherBatch, err := dao.GetVoucherBatch("id = ?", *voucher.BatchId)
+	if err != nil {
+		return vo.VoucherRedemptionVo{}, err
+	}
+	account, err := dao.GetAccount("id = ? and deleted = 0", accountId)
+	if err != nil {
+		return vo.VoucherRedemptionVo{}, errors.New("account not found")
+	}
+	if dao.ExistVoucherRedemption(*voucher.Id, accountId) {
+		return vo.VoucherRedemptionVo{}, errors.New("the voucher has already been redeemed by this account")
+	}
+
+	var updates map[string]interface{}
+	var history *entity.AccountPlanHistory
+	if *voucherBatch.PlanId > 0 {
+		plan, err := dao.GetPlan("id = ?", *voucherBatch.PlanId)
+		if err != nil {
+			return vo.VoucherRedemptionVo{}, err
+		}
+		var planHistory entity.AccountPlanHistory
+		updates, planHistory = accountPlanUpdates(account, plan, 1, now)
+		history = &planHistory
+	} else {
+		updates = voucherAmountUpdates(account, *voucherBatch.Quota, *voucherBatch.Duration, now)
+	}
+	expireTimeAfter := *account.ExpireTime
+	if value, exist := updates["expire_time"]; exist {
+		expireTimeAfter = value.(int64)
+	}
+	redemption := entity.VoucherRedemption{
+		VoucherId:        voucher.Id,
+		BatchId:          voucher.BatchId,
+		Code:             voucher.Code,
+		AccountId:        account.Id,
+		Username:         account.Username,
+		PlanId:           voucherBatch.PlanId,
+		Quota:            voucherBatch.Quota,
+		Duration:         voucherBatch.Duration,
+		ExpireTimeBefore: account.ExpireTime,
+		ExpireTimeAfter:  &expireTimeAfter,
+	}
+	if err = dao.RedeemVoucher(*voucher.Id, now, accountId, updates, &redemption, history); err != nil {
+		return vo.VoucherRedemptionVo{}, err
+	}
+	kickSpeedTierChange(account, updates)
+	emitAccountWebhookEventByIds(constant.WebhookEventAccountUpdated, []int64{accountId})
+	return toVoucherRedemptionVo(redemption), nil
+}
+
+func toVoucherRedemptionVo(item entity.VoucherRedemption) vo.VoucherRedemptionVo {
+	redemptionVo := vo.VoucherRedemptionVo{
+		VoucherId:        *item.VoucherId,
+		BatchId:          *item.BatchId,
+		Code:             *item.Code,
+		AccountId:        *item.AccountId,
+		Username:         *item.Username,
+		PlanId:           *item.PlanId,
+		Quota:            *item.Quota,
+		Duration:         *item.Duration,
+		ExpireTimeBefore: *item.ExpireTimeBefore,
+		ExpireTimeAfter:  *item.ExpireTimeAfter,
+	}
+	if item.Id != nil {
+		redemptionVo.Id = *item.Id
+	}
+	if item.CreateTime != nil {
+		redemptionVo.CreateTime = *item.CreateTime
+	}
+	return redemptionVo
+}
+
+func PageVoucherRedemption(voucherRedemptionPageDto dto.VoucherRedemptionPageDto) (vo.VoucherRedemptionPageVo, error) {
+	if voucherRedemptionPageDto.Code != nil {
+		code := normalizeVoucherCode(*voucherRedemptionPageDto.Code)
+		voucherRedemptionPageDto.Code = &code
+	}
+	redemptions, total, err := dao.PageVoucherRedemption(voucherRedemptionPageDto)
+	if err != nil {
+		return vo.VoucherRedemptionPageVo{}, err
+	}
+	redemptionVos := make([]vo.VoucherRedemptionVo, 0, len(redemptions))
+	for _, item := range redemptions {
+		redemptionVos = append(redemptionVos, toVoucherRedemptionVo(item))
+	}
+	return vo.VoucherRedemptionPageVo{
+		VoucherRedemptionVos: redemptionVos,
+		Total:                total,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"h-ui/model/entity"
+	"strings"
+	"testing"
+)
+
+func TestNewVoucherCode(t *testing.T) {
+	code, err := newVoucherCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := strings.Split(code, "-")
+	if len(groups) != voucherCodeLength/voucherCodeGroup {
+		t.Fatalf("unexpected code: %s", code)
+	}
+	for _, r := range strings.ReplaceAll(code, "-", "") {
+		if !strings.ContainsRune(voucherCodeCharset, r) {
+			t.Errorf("unexpected character %c in code: %s", r, code)
+		}
+	}
+	if normalizeVoucherCode(code) != code {
+		t.Errorf("generated code should already be normalized: %s", code)
+	}
+}
+
+func TestNormalizeVoucherCode(t *testing.T) {
+	tests := map[string]string{
+		"abcd-efgh-jklm-npqr": "ABCD-EFGH-JKLM-NPQR",
+		" ABCDEFGHJKLMNPQR ":  "ABCD-EFGH-JKLM-NPQR",
+		"abcd efgh jklm npqr": "ABCD-EFGH-JKLM-NPQR",
+		"short-code":          "SHORTCODE",
+	}
+	for code, expect := range tests {
+		if got := normalizeVoucherCode(code); got != expect {
+			t.Errorf("normalizeVoucherCode(%q) expected %s, got: %s", code, expect, got)
+		}
+	}
+}
+
+func TestVoucherAmountUpdates(t *testing.T) {
+	quota, expireTime := int64(100), int64(5000)
+	account := entity.Account{Quota: &quota, ExpireTime: &expireTime}
+	updates := voucherAmountUpdates(account, 50, 1, 1000)
+	if updates["quota"] != int64(150) || updates["expire_time"] != 5000+planDayMillis {
+		t.Errorf("unexpected updates: %v", updates)
+	}
+
+	unlimited := int64(-1)
+	account.Quota = &unlimited
+	updates = voucherAmountUpdates(account, 50, 0, 1000)
+	if len(updates) != 0 {
+		t.Errorf("unlimited quota and zero duration should not be changed: %v", updates)
+	}
+}
@@ -0,0 +1,460 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"h-ui/util"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenerSpec 面板的一个监听，redirectPort 大于 0 时只做 https 重定向
+type listenerSpec struct {
+	network      string
+	address      string
+	port         int64
+	tls          bool
+	routes       []string
+	redirectPort int64
+}
+
+func (s listenerSpec) key() string {
+	return s.network + "|" + s.address
+}
+
+type listenerRoutesKey struct{}
+
+// ParseWebRoutes 解析逗号分隔的路由组
+func ParseWebRoutes(value string) ([]string, error) {
+	var routes []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !util.ArrContain(constant.RouteGroups, item) {
+			return nil, fmt.Errorf("route group: %s is invalid", item)
+		}
+		if !util.ArrContain(routes, item) {
+			routes = append(routes, item)
+		}
+	}
+	return routes, nil
+}
+
+// webRoutes 面板端口开放的路由组
+func webRoutes() ([]string, error) {
+	config, err := dao.GetConfig("key = ?", constant.HUIWebRoutes)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWebRoutes(*config.Value)
+}
+
+func listenerPort(network string, address string) (int64, error) {
+	if network != constant.ListenerNetworkTcp {
+		return 0, nil
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, fmt.Errorf("listen address: %s is invalid", address)
+	}
+	value, err := strconv.ParseInt(port, 10, 64)
+	if err != nil || value <= 0 || value > 65535 {
+		return 0, fmt.Errorf("listen address: %s is invalid", address)
+	}
+	return value, nil
+}
+
+func toListenerSpec(item entity.WebListener) (listenerSpec, error) {
+	port, err := listenerPort(*item.Network, *item.Address)
+	if err != nil {
+		return listenerSpec{}, err
+	}
+	routes, err := ParseWebRoutes(*item.Routes)
+	if err != nil {
+		return listenerSpec{}, err
+	}
+	return listenerSpec{
+		network:      *item.Network,
+		address:      *item.Address,
+		port:         port,
+		tls:          *item.Tls == 1,
+		routes:       routes,
+		redirectPort: *item.RedirectPort,
+	}, nil
+}
+
+// listenerSpecs 面板端口和启用的监听，面板端口在第一个
+func listenerSpecs(mainRoutes []string, listeners []entity.WebListener) ([]listenerSpec, error) {
+	port, crtPath, keyPath, err := GetPortAndCert()
+	if err != nil {
+		return nil, err
+	}
+	specs := []listenerSpec{{
+		network: constant.ListenerNetworkTcp,
+		address: fmt.Sprintf(":%d", port),
+		port:    port,
+		tls:     crtPath != "" && keyPath != "" || PanelAcmeEnabled(),
+		routes:  mainRoutes,
+	}}
+	for _, item := range listeners {
+		if *item.Enable != 1 {
+			continue
+		}
+		spec, err := toListenerSpec(item)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// checkListenerSpecs 地址不能重复，面板和 hysteria2 认证至少要有一个监听
+func checkListenerSpecs(specs []listenerSpec) error {
+	var panel, auth bool
+	for i, spec := range specs {
+		for _, other := range specs[:i] {
+			if spec.key() == other.key() || spec.port != 0 && spec.port == other.port {
+				return fmt.Errorf("listen address: %s is used by another listener", spec.address)
+			}
+		}
+		if spec.redirectPort > 0 {
+			continue
+		}
+		if util.ArrContain(spec.routes, constant.RouteGroupPanel) {
+			panel = true
+		}
+		// hysteria2 只能通过 tcp 回调认证
+		if spec.network == constant.ListenerNetworkTcp && util.ArrContain(spec.routes, constant.RouteGroupAuth) {
+			auth = true
+		}
+	}
+	if !panel {
+		return errors.New("at least one listener must serve the panel")
+	}
+	if !auth {
+		return errors.New("at least one tcp listener must serve the hysteria2 auth callback")
+	}
+	return nil
+}
+
+func checkWebListener(item entity.WebListener) error {
+	if _, err := toListenerSpec(item); err != nil {
+		return err
+	}
+	if *item.RedirectPort > 0 {
+		if *item.Network != constant.ListenerNetworkTcp || *item.Tls == 1 {
+			return errors.New("redirect listener must be a plain tcp listener")
+		}
+	} else if *item.Routes == "" {
+		return errors.New("routes of the listener is empty")
+	}
+	if *item.Tls == 1 && !PanelAcmeEnabled() {
+		if _, crtPath, keyPath, err := GetPortAndCert(); err != nil || crtPath == "" || keyPath == "" {
+			return errors.New("tls listener requires the panel certificate")
+		}
+	}
+	if *item.Enable == 1 {
+		return checkListenAddr(*item.Network, *item.Address)
+	}
+	return nil
+}
+
+// checkListenAddr 地址没有被面板使用时检查是否可以监听
+func checkListenAddr(network string, address string) error {
+	if listenerServing(network + "|" + address) {
+		return nil
+	}
+	if network == constant.ListenerNetworkUnix {
+		info, err := os.Stat(address)
+		if err != nil {
+			return nil
+		}
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("listen address: %s is not a socket", address)
+		}
+		if conn, err := net.DialTimeout("unix", address, time.Second); err == nil {
+			_ = conn.Close()
+			return fmt.Errorf("listen address: %s is used", address)
+		}
+		return nil
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("listen address: %s is used", address)
+	}
+	return listener.Close()
+}
+
+// checkWebListeners 用修改后的监听列表检查
+func checkWebListeners(mainRoutes []string, listeners []entity.WebListener) error {
+	specs, err := listenerSpecs(mainRoutes, listeners)
+	if err != nil {
+		return err
+	}
+	return checkListenerSpecs(specs)
+}
+
+// CheckWebRoutes 修改面板端口的路由组前检查
+func CheckWebRoutes(value string) error {
+	routes, err := ParseWebRoutes(value)
+	if err != nil {
+		return err
+	}
+	listeners, err := dao.ListWebListener(nil, nil)
+	if err != nil {
+		return err
+	}
+	return checkWebListeners(routes, listeners)
+}
+
+// replaceWebListener 去掉 id 对应的监听，item 不为空时加入修改后的监听
+func replaceWebListener(listeners []entity.WebListener, id int64, item *entity.WebListener) []entity.WebListener {
+	result := make([]entity.WebListener, 0, len(listeners)+1)
+	for _, listener := range listeners {
+		if *listener.Id != id {
+			result = append(result, listener)
+		}
+	}
+	if item != nil {
+		result = append(result, *item)
+	}
+	return result
+}
+
+func existWebListenerName(name string, id int64) bool {
+	_, err := dao.GetWebListener("name = ? and id != ?", name, id)
+	return err == nil
+}
+
+// reloadWebListeners 切换监听后按新的端口和路由组更新防火墙
+func reloadWebListeners() error {
+	if err := ReloadServer(); err != nil {
+		return err
+	}
+	return InitFirewall()
+}
+
+func SaveWebListener(webListenerSaveDto dto.WebListenerSaveDto) error {
+	if existWebListenerName(*webListenerSaveDto.Name, 0) {
+		return fmt.Errorf("web listener %s already exists", *webListenerSaveDto.Name)
+	}
+	var zero int64
+	var one int64 = 1
+	orDefault := func(value *int64, defaultValue *int64) *int64 {
+		if value == nil {
+			return defaultValue
+		}
+		return value
+	}
+	routes := strings.Join(webListenerSaveDto.Routes, ",")
+	remark := ""
+	if webListenerSaveDto.Remark != nil {
+		remark = *webListenerSaveDto.Remark
+	}
+	webListener := entity.WebListener{
+		Name:         webListenerSaveDto.Name,
+		Network:      webListenerSaveDto.Network,
+		Address:      webListenerSaveDto.Address,
+		Tls:          orDefault(webListenerSaveDto.Tls, &zero),
+		Routes:       &routes,
+		RedirectPort: orDefault(webListenerSaveDto.RedirectPort, &zero),
+		Enable:       orDefault(webListenerSaveDto.Enable, &one),
+		Remark:       &remark,
+	}
+	if err := checkWebListener(webListener); err != nil {
+		return err
+	}
+	mainRoutes, err := webRoutes()
+	if err != nil {
+		return err
+	}
+	listeners, err := dao.ListWebListener(nil, nil)
+	if err != nil {
+		return err
+	}
+	if err = checkWebListeners(mainRoutes, replaceWebListener(listeners, 0, &webListener)); err != nil {
+		return err
+	}
+	if _, err = dao.SaveWebListener(webListener); err != nil {
+		return err
+	}
+	return reloadWebListeners()
+}
+
+func UpdateWebListener(webListenerUpdateDto dto.WebListenerUpdateDto) error {
+	webListener, err := dao.GetWebListener("id = ?", *webListenerUpdateDto.Id)
+	if err != nil {
+		return err
+	}
+	if webListenerUpdateDto.Name != nil && existWebListenerName(*webListenerUpdateDto.Name, *webListener.Id) {
+		return fmt.Errorf("web listener %s already exists", *webListenerUpdateDto.Name)
+	}
+	updates := map[string]interface{}{}
+	setString := func(column string, dst **string, src *string) {
+		if src != nil && *src != **dst {
+			updates[column] = *src
+			*dst = src
+		}
+	}
+	setInt := func(column string, dst **int64, src *int64) {
+		if src != nil && *src != **dst {
+			updates[column] = *src
+			*dst = src
+		}
+	}
+	setString("name", &webListener.Name, webListenerUpdateDto.Name)
+	setString("network", &webListener.Network, webListenerUpdateDto.Network)
+	setString("address", &webListener.Address, webListenerUpdateDto.Address)
+	if webListenerUpdateDto.Routes != nil {
+		routes := strings.Join(webListenerUpdateDto.Routes, ",")
+		setString("routes", &webListener.Routes, &routes)
+	}
+	setInt("tls", &webListener.Tls, webListenerUpdateDto.Tls)
+	setInt("redirect_port", &webListener.RedirectPort, webListenerUpdateDto.RedirectPort)
+	setInt("enable", &webListener.Enable, webListenerUpdateDto.Enable)
+	setString("remark", &webListener.Remark, webListenerUpdateDto.Remark)
+	if len(updates) == 0 {
+		return nil
+	}
+	if err = checkWebListener(webListener); err != nil {
+		return err
+	}
+	mainRoutes, err := webRoutes()
+	if err != nil {
+		return err
+	}
+	listeners, err := dao.ListWebListener(nil, nil)
+	if err != nil {
+		return err
+	}
+	if err = checkWebListeners(mainRoutes, replaceWebListener(listeners, *webListener.Id, &webListener)); err != nil {
+		return err
+	}
+	if err = dao.UpdateWebListener([]int64{*webListener.Id}, updates); err != nil {
+		return err
+	}
+	return reloadWebListeners()
+}
+
+func DeleteWebListener(id int64) error {
+	if _, err := dao.GetWebListener("id = ?", id); err != nil {
+		return err
+	}
+	mainRoutes, err := webRoutes()
+	if err != nil {
+		return err
+	}
+	listeners, err := dao.ListWebListener(nil, nil)
+	if err != nil {
+		return err
+	}
+	if err = checkWebListeners(mainRoutes, replaceWebListener(listeners, id, nil)); err != nil {
+		return err
+	}
+	if err = dao.DeleteWebListener([]int64{id}); err != nil {
+		return err
+	}
+	return reloadWebListeners()
+}
+
+func ListWebListener() ([]vo.WebListenerVo, error) {
+	listeners, err := dao.ListWebListener(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	webListenerVos := make([]vo.WebListenerVo, 0, len(listeners))
+	for _, item := range listeners {
+		routes := []string{}
+		if *item.Routes != "" {
+			routes = strings.Split(*item.Routes, ",")
+		}
+		webListenerVos = append(webListenerVos, vo.WebListenerVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Name:         *item.Name,
+			Network:      *item.Network,
+			Address:      *item.Address,
+			Tls:          *item.Tls,
+			Routes:       routes,
+			RedirectPort: *item.RedirectPort,
+			Enable:       *item.Enable,
+			Remark:       *item.Remark,
+			Listening:    *item.Enable == 1 && listenerServing(*item.Network+"|"+*item.Address),
+		})
+	}
+	return webListenerVos, nil
+}
+
+// authListenAddr hysteria2 认证回调使用的监听，优先使用面板端口
+func authListenAddr() (string, int64, bool, error) {
+	mainRoutes, err := webRoutes()
+	if err != nil {
+		return "", 0, false, err
+	}
+	listeners, err := dao.ListWebListener("enable = 1")
+	if err != nil {
+		return "", 0, false, err
+	}
+	specs, err := listenerSpecs(mainRoutes, listeners)
+	if err != nil {
+		return "", 0, false, err
+	}
+	for _, spec := range specs {
+		if spec.network != constant.ListenerNetworkTcp || spec.redirectPort > 0 ||
+			!util.ArrContain(spec.routes, constant.RouteGroupAuth) {
+			continue
+		}
+		host, _, _ := net.SplitHostPort(spec.address)
+		ip := net.ParseIP(host)
+		if host == "" || ip != nil && ip.IsUnspecified() {
+			host = "127.0.0.1"
+		}
+		return host, spec.port, spec.tls, nil
+	}
+	return "", 0, false, errors.New("no listener serves the hysteria2 auth callback")
+}
+
+// routeGroup 按 gin 的路由判断所属的路由组
+func routeGroup(fullPath string) string {
+	path := fullPath
+	if webContext, ok := serverWebContext.Load().(string); ok {
+		path = strings.TrimPrefix(fullPath, webContext)
+	}
+	switch path {
+	case hysteria2AuthPath:
+		return constant.RouteGroupAuth
+	case "/hui/:conPass":
+		return constant.RouteGroupSubscribe
+	case "/hui/telegram/webhook":
+		return constant.RouteGroupTelegram
+	}
+	return constant.RouteGroupPanel
+}
+
+func withListenerRoutes(r *http.Request, routes []string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), listenerRoutesKey{}, routes))
+}
+
+// ListenerAllowRoute 请求所在的监听是否开放了该路由，不经过监听的请求不限制
+func ListenerAllowRoute(r *http.Request, fullPath string) bool {
+	routes, ok := r.Context().Value(listenerRoutesKey{}).([]string)
+	if !ok {
+		return true
+	}
+	return util.ArrContain(routes, routeGroup(fullPath))
+}
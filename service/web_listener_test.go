@@ -0,0 +1,68 @@
+package service
+
+import (
+	"h-ui/model/constant"
+	"testing"
+)
+
+func TestCheckListenerSpecs(t *testing.T) {
+	all := constant.RouteGroups
+	subscribe := []string{constant.RouteGroupSubscribe}
+	admin := []string{constant.RouteGroupPanel, constant.RouteGroupAuth}
+	cases := []struct {
+		specs []listenerSpec
+		ok    bool
+	}{
+		{[]listenerSpec{{network: "tcp", address: ":8081", port: 8081, routes: all}}, true},
+		{[]listenerSpec{
+			{network: "tcp", address: ":443", port: 443, tls: true, routes: subscribe},
+			{network: "tcp", address: "127.0.0.1:8081", port: 8081, routes: admin},
+			{network: "tcp", address: ":80", port: 80, redirectPort: 443},
+		}, true},
+		// 认证只能通过 tcp 回调
+		{[]listenerSpec{
+			{network: "tcp", address: ":443", port: 443, routes: subscribe},
+			{network: "unix", address: "/run/h-ui.sock", routes: admin},
+		}, false},
+		// 重定向的监听不提供面板
+		{[]listenerSpec{
+			{network: "tcp", address: ":443", port: 443, routes: []string{constant.RouteGroupAuth}},
+			{network: "tcp", address: ":80", port: 80, routes: all, redirectPort: 443},
+		}, false},
+		{[]listenerSpec{
+			{network: "tcp", address: ":8081", port: 8081, routes: all},
+			{network: "tcp", address: "127.0.0.1:8081", port: 8081, routes: all},
+		}, false},
+	}
+	for i, c := range cases {
+		if err := checkListenerSpecs(c.specs); (err == nil) != c.ok {
+			t.Fatalf("case %d: unexpected result %v", i, err)
+		}
+	}
+}
+
+func TestParseWebRoutes(t *testing.T) {
+	routes, err := ParseWebRoutes(" panel, auth,panel,")
+	if err != nil || len(routes) != 2 || routes[0] != constant.RouteGroupPanel || routes[1] != constant.RouteGroupAuth {
+		t.Fatalf("unexpected routes: %v %v", routes, err)
+	}
+	if _, err = ParseWebRoutes("panel,admin"); err == nil {
+		t.Fatal("unknown route group should fail")
+	}
+}
+
+func TestRouteGroup(t *testing.T) {
+	serverWebContext.Store("/ctx")
+	cases := map[string]string{
+		"/ctx/hui/hysteria2/auth":    constant.RouteGroupAuth,
+		"/ctx/hui/:conPass":          constant.RouteGroupSubscribe,
+		"/ctx/hui/telegram/webhook":  constant.RouteGroupTelegram,
+		"/ctx/hui/config/listConfig": constant.RouteGroupPanel,
+		"":                           constant.RouteGroupPanel,
+	}
+	for path, want := range cases {
+		if got := routeGroup(path); got != want {
+			t.Fatalf("%s: got %s, want %s", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"h-ui/dao"
+	"h-ui/model/constant"
+	"h-ui/model/dto"
+	"h-ui/model/entity"
+	"h-ui/model/vo"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhookMaxAttempts       = 5
+	webhookTimeout           = 10 * time.Second
+	webhookConcurrency       = 8                   // 同时进行的 HTTP 请求数
+	webhookDeliveryRetention = 30 * 24 * time.Hour // 投递记录保留时间
+	webhookErrorMaxLength    = 512
+)
+
+// webhookPayload 推送的请求体
+type webhookPayload struct {
+	Event string      `json:"event"`
+	Time  int64       `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+type webhookTask struct {
+	deliveryId int64
+	url        string
+	secret     string
+	event      string
+	body       []byte
+}
+
+// webhookAttempt 单次投递的结果，done 表示不再重试
+type webhookAttempt struct {
+	attempt int
+	code    int
+	err     error
+	done    bool
+}
+
+type webhookSender struct {
+	client      *http.Client
+	backoff     func(attempt int) time.Duration
+	maxAttempts int
+	semaphore   chan struct{}
+}
+
+var defaultWebhookSender = newWebhookSender(&http.Client{Timeout: webhookTimeout}, webhookBackoff)
+
+func newWebhookSender(client *http.Client, backoff func(attempt int) time.Duration) *webhookSender {
+	return &webhookSender{
+		client:      client,
+		backoff:     backoff,
+		maxAttempts: webhookMaxAttempts,
+		semaphore:   make(chan struct{}, webhookConcurrency),
+	}
+}
+
+// webhookBackoff 第 attempt 次失败后的等待时间：5s 15s 45s 135s
+func webhookBackoff(attempt int) time.Duration {
+	delay := 5 * time.Second
+	for i := 1; i < attempt; i++ {
+		delay *= 3
+	}
+	return delay
+}
+
+// WebhookSignature 签名为 hex(HMAC-SHA256(secret, timestamp + "." + body))
+func WebhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookSender) send(task webhookTask) (int, error) {
+	s.semaphore <- struct{}{}
+	defer func() { <-s.semaphore }()
+
+	req, err := http.NewRequest(http.MethodPost, task.url, bytes.NewReader(task.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "h-ui-webhook")
+	req.Header.Set(constant.WebhookEventHeader, task.event)
+	req.Header.Set(constant.WebhookDeliveryHeader, strconv.FormatInt(task.deliveryId, 10))
+	req.Header.Set(constant.WebhookTimestampHeader, timestamp)
+	req.Header.Set(constant.WebhookSignatureHeader, "sha256="+WebhookSignature(task.secret, timestamp, task.body))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// deliver 投递并在失败时按退避重试，每次尝试后回调 record
+func (s *webhookSender) deliver(task webhookTask, record func(webhookAttempt)) {
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		code, err := s.send(task)
+		done := err == nil || attempt == s.maxAttempts
+		record(webhookAttempt{attempt: attempt, code: code, err: err, done: done})
+		if done {
+			return
+		}
+		time.Sleep(s.backoff(attempt))
+	}
+}
+
+func recordWebhookDelivery(deliveryId int64) func(webhookAttempt) {
+	return func(result webhookAttempt) {
+		status := constant.WebhookDeliveryPending
+		errMsg := ""
+		if result.err == nil {
+			status = constant.WebhookDeliverySuccess
+		} else {
+			errMsg = result.err.Error()
+			if len(errMsg) > webhookErrorMaxLength {
+				errMsg = errMsg[:webhookErrorMaxLength]
+			}
+			if result.done {
+				status = constant.WebhookDeliveryFail
+			}
+		}
+		_ = dao.UpdateWebhookDelivery(deliveryId, map[string]interface{}{
+			"status":        status,
+			"attempts":      result.attempt,
+			"response_code": result.code,
+			"error":         errMsg,
+		})
+	}
+}
+
+func webhookSubscribed(webhook entity.Webhook, event string) bool {
+	if event == constant.WebhookEventPing {
+		return true
+	}
+	for _, item := range strings.Split(*webhook.Events, ",") {
+		if item == constant.WebhookEventAll || item == event {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatchWebhook 写入投递记录并异步投递
+func dispatchWebhook(webhook entity.Webhook, event string, body []byte) (int64, error) {
+	status := constant.WebhookDeliveryPending
+	payload := string(body)
+	deliveryId, err := dao.SaveWebhookDelivery(entity.WebhookDelivery{
+		WebhookId: webhook.Id,
+		Event:     &event,
+		Payload:   &payload,
+		Status:    &status,
+	})
+	if err != nil {
+		return 0, err
+	}
+	go defaultWebhookSender.deliver(webhookTask{
+		deliveryId: deliveryId,
+		url:        *webhook.Url,
+		secret:     *webhook.Secret,
+		event:      event,
+		body:       body,
+	}, recordWebhookDelivery(deliveryId))
+	return deliveryId, nil
+}
+
+func marshalWebhookPayload(event string, data interface{}) ([]byte, error) {
+	body, err := json.Marshal(webhookPayload{
+		Event: event,
+		Time:  time.Now().UnixMilli(),
+		Data:  data,
+	})
+	if err != nil {
+		logrus.Errorf("webhook marshal payload err: %v", err)
+		return nil, errors.New(constant.SysError)
+	}
+	return body, nil
+}
+
+// EmitWebhookEvent 异步推送事件到所有订阅了该事件的 Webhook
+func EmitWebhookEvent(event string, data interface{}) {
+	emitWebhookEvents(event, []interface{}{data})
+}
+
+// EmitAccountWebhookEvent 每个账号推送一条事件
+func EmitAccountWebhookEvent(event string, accounts []entity.Account) {
+	if len(accounts) == 0 {
+		return
+	}
+	datas := make([]interface{}, 0, len(accounts))
+	for _, item := range accounts {
+		datas = append(datas, map[string]interface{}{"account": toAccountVo(item)})
+	}
+	emitWebhookEvents(event, datas)
+}
+
+func emitWebhookEvents(event string, datas []interface{}) {
+	go func() {
+		webhooks, err := dao.ListWebhook("enable = 1")
+		if err != nil {
+			return
+		}
+		var subscribed []entity.Webhook
+		for _, item := range webhooks {
+			if webhookSubscribed(item, event) {
+				subscribed = append(subscribed, item)
+			}
+		}
+		if len(subscribed) == 0 {
+			return
+		}
+		for _, data := range datas {
+			body, err := marshalWebhookPayload(event, data)
+			if err != nil {
+				continue
+			}
+			for _, item := range subscribed {
+				if _, err = dispatchWebhook(item, event, body); err != nil {
+					logrus.Errorf("webhook dispatch event %s to %d err: %v", event, *item.Id, err)
+				}
+			}
+		}
+	}()
+}
+
+func emitAccountWebhookEventByIds(event string, ids []int64) {
+	if len(ids) == 0 {
+		return
+	}
+	accounts, err := dao.ListAccount("id in ?", ids)
+	if err != nil {
+		return
+	}
+	EmitAccountWebhookEvent(event, accounts)
+}
+
+func EmitNodeWebhookEvent(event string, node string, err error) {
+	data := map[string]interface{}{"node": node}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	EmitWebhookEvent(event, data)
+}
+
+func EmitConfigWebhookEvent(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	EmitWebhookEvent(constant.WebhookEventConfigChanged, map[string]interface{}{"keys": keys})
+}
+
+func SaveWebhook(webhookSaveDto dto.WebhookSaveDto) error {
+	events := strings.Join(webhookSaveDto.Events, ",")
+	_, err := dao.SaveWebhook(entity.Webhook{
+		Name:   webhookSaveDto.Name,
+		Url:    webhookSaveDto.Url,
+		Secret: webhookSaveDto.Secret,
+		Events: &events,
+		Enable: webhookSaveDto.Enable,
+	})
+	return err
+}
+
+func UpdateWebhook(webhookUpdateDto dto.WebhookUpdateDto) error {
+	if _, err := dao.GetWebhook("id = ?", *webhookUpdateDto.Id); err != nil {
+		return err
+	}
+	updates := map[string]interface{}{}
+	if webhookUpdateDto.Name != nil {
+		updates["name"] = *webhookUpdateDto.Name
+	}
+	if webhookUpdateDto.Url != nil {
+		updates["url"] = *webhookUpdateDto.Url
+	}
+	if webhookUpdateDto.Secret != nil {
+		updates["secret"] = *webhookUpdateDto.Secret
+	}
+	if len(webhookUpdateDto.Events) > 0 {
+		updates["events"] = strings.Join(webhookUpdateDto.Events, ",")
+	}
+	if webhookUpdateDto.Enable != nil {
+		updates["enable"] = *webhookUpdateDto.Enable
+	}
+	return dao.UpdateWebhook([]int64{*webhookUpdateDto.Id}, updates)
+}
+
+func DeleteWebhook(id int64) error {
+	return dao.DeleteWebhook([]int64{id})
+}
+
+func ListWebhook() ([]vo.WebhookVo, error) {
+	webhooks, err := dao.ListWebhook(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	webhookVos := make([]vo.WebhookVo, 0, len(webhooks))
+	for _, item := range webhooks {
+		webhookVos = append(webhookVos, vo.WebhookVo{
+			BaseVo: vo.BaseVo{
+				Id:         *item.Id,
+				CreateTime: *item.CreateTime,
+			},
+			Name:   *item.Name,
+			Url:    *item.Url,
+			Events: strings.Split(*item.Events, ","),
+			Enable: *item.Enable,
+		})
+	}
+	return webhookVos, nil
+}
+
+// TestWebhook 向指定 Webhook 发送一条 ping 事件，返回投递记录 id
+func TestWebhook(id int64) (int64, error) {
+	webhook, err := dao.GetWebhook("id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	body, err := marshalWebhookPayload(constant.WebhookEventPing, map[string]interface{}{"webhookId": id})
+	if err != nil {
+		return 0, err
+	}
+	return dispatchWebhook(webhook, constant.WebhookEventPing, body)
+}
+
+// RedeliverWebhook 重新投递一条历史记录
+func RedeliverWebhook(deliveryId int64) (int64, error) {
+	delivery, err := dao.GetWebhookDelivery("id = ?", deliveryId)
+	if err != nil {
+		return 0, err
+	}
+	webhook, err := dao.GetWebhook("id = ?", *delivery.WebhookId)
+	if err != nil {
+		return 0, err
+	}
+	return dispatchWebhook(webhook, *delivery.Event, []byte(*delivery.Payload))
+}
+
+func PageWebhookDelivery(deliveryPageDto dto.WebhookDeliveryPageDto) ([]entity.WebhookDelivery, int64, error) {
+	return dao.PageWebhookDelivery(deliveryPageDto)
+}
+
+// CronCleanWebhookDelivery 清理过期的投递记录
+func CronCleanWebhookDelivery() {
+	_ = dao.DeleteWebhookDeliveryBefore(time.Now().Add(-webhookDeliveryRetention))
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"h-ui/model/constant"
+	"h-ui/model/entity"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookDeliverRetry(t *testing.T) {
+	secret := "0123456789abcdef"
+	body := []byte(`{"event":"account.created","time":1,"data":{}}`)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		expect := "sha256=" + WebhookSignature(secret, r.Header.Get(constant.WebhookTimestampHeader), content)
+		if r.Header.Get(constant.WebhookSignatureHeader) != expect {
+			t.Errorf("unexpected signature: %s", r.Header.Get(constant.WebhookSignatureHeader))
+		}
+		if r.Header.Get(constant.WebhookEventHeader) != "account.created" || r.Header.Get(constant.WebhookDeliveryHeader) != "7" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		// 前两次返回 500
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := newWebhookSender(server.Client(), func(int) time.Duration { return time.Millisecond })
+	var results []webhookAttempt
+	sender.deliver(webhookTask{
+		deliveryId: 7,
+		url:        server.URL,
+		secret:     secret,
+		event:      "account.created",
+		body:       body,
+	}, func(result webhookAttempt) {
+		results = append(results, result)
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 attempts, got: %d", len(results))
+	}
+	if results[0].code != http.StatusInternalServerError || results[0].err == nil || results[0].done {
+		t.Errorf("unexpected first attempt: %+v", results[0])
+	}
+	if last := results[2]; last.code != http.StatusNoContent || last.err != nil || !last.done {
+		t.Errorf("unexpected last attempt: %+v", last)
+	}
+}
+
+func TestWebhookDeliverGiveUp(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	sender := newWebhookSender(server.Client(), func(int) time.Duration { return time.Millisecond })
+	var last webhookAttempt
+	sender.deliver(webhookTask{url: server.URL, secret: "0123456789abcdef", event: "ping", body: []byte(`{}`)}, func(result webhookAttempt) {
+		last = result
+	})
+
+	if calls != webhookMaxAttempts {
+		t.Errorf("expected %d calls, got: %d", webhookMaxAttempts, calls)
+	}
+	if !last.done || last.err == nil || last.attempt != webhookMaxAttempts {
+		t.Errorf("unexpected last attempt: %+v", last)
+	}
+}
+
+func TestWebhookSubscribed(t *testing.T) {
+	events := "account.created,node.crashed"
+	all := constant.WebhookEventAll
+	tests := []struct {
+		events string
+		event  string
+		expect bool
+	}{
+		{events, constant.WebhookEventAccountCreated, true},
+		{events, constant.WebhookEventNodeCrashed, true},
+		{events, constant.WebhookEventAccountDeleted, false},
+		{events, constant.WebhookEventPing, true},
+		{all, constant.WebhookEventConfigChanged, true},
+	}
+	for _, tt := range tests {
+		events := tt.events
+		if subscribed := webhookSubscribed(entity.Webhook{Events: &events}, tt.event); subscribed != tt.expect {
+			t.Errorf("webhookSubscribed(%s, %s) expected %v, got: %v", tt.events, tt.event, tt.expect, subscribed)
+		}
+	}
+}
@@ -30,3 +30,26 @@ func SplitArr[T any](arr []T, num int) [][]T {
 
 	return segments
 }
+
+// DiffArr 返回 desired 中缺少的元素和 current 中多余的元素，current 中重复的元素只保留一个
+func DiffArr[T comparable](current []T, desired []T) ([]T, []T) {
+	want := make(map[T]bool, len(desired))
+	for _, item := range desired {
+		want[item] = true
+	}
+	var add, del []T
+	seen := make(map[T]bool, len(current))
+	for _, item := range current {
+		if !want[item] || seen[item] {
+			del = append(del, item)
+		}
+		seen[item] = true
+	}
+	for _, item := range desired {
+		if !seen[item] {
+			add = append(add, item)
+			seen[item] = true
+		}
+	}
+	return add, del
+}
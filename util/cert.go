@@ -0,0 +1,61 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"time"
+)
+
+// GetCertNotAfter 读取 PEM 证书文件中第一张证书的过期时间
+func GetCertNotAfter(certPath string) (time.Time, error) {
+	content, err := os.ReadFile(certPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, errors.New("invalid certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// ParseCertFile 读取 PEM 文件中的所有证书，第一张为叶子证书
+func ParseCertFile(certPath string) ([]*x509.Certificate, error) {
+	content, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("invalid certificate")
+	}
+	return certs, nil
+}
+
+// CheckCertKeyPair 检查私钥与证书是否匹配
+func CheckCertKeyPair(certPath string, keyPath string) error {
+	_, err := tls.LoadX509KeyPair(certPath, keyPath)
+	return err
+}
@@ -0,0 +1,163 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	envelopePrefix  = "enc:v1:"
+	MasterKeyLength = 32
+)
+
+// Envelope 信封加密：每个值使用随机的数据密钥加密，数据密钥再由主密钥加密后与密文一起保存
+// 格式为 enc:v1:<主密钥 id>:<加密的数据密钥>:<密文>，更换主密钥时只需重新加密数据密钥
+type Envelope struct {
+	masterKey []byte
+	keyId     string
+}
+
+func NewEnvelope(masterKey []byte) (*Envelope, error) {
+	if len(masterKey) != MasterKeyLength {
+		return nil, fmt.Errorf("the master key must be %d bytes", MasterKeyLength)
+	}
+	sum := sha256.Sum256(masterKey)
+	return &Envelope{masterKey: masterKey, keyId: hex.EncodeToString(sum[:4])}, nil
+}
+
+// GenerateMasterKey 随机生成 base64 编码的主密钥
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, MasterKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseMasterKey 解析 base64 或 hex 编码的主密钥
+func ParseMasterKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == MasterKeyLength {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(value); err == nil && len(key) == MasterKeyLength {
+		return key, nil
+	}
+	return nil, fmt.Errorf("the master key must be %d bytes encoded in base64 or hex", MasterKeyLength)
+}
+
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+func (e *Envelope) KeyId() string {
+	return e.keyId
+}
+
+func (e *Envelope) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, MasterKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := gcmSeal(e.masterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := gcmSeal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return e.format(wrappedKey, ciphertext), nil
+}
+
+func (e *Envelope) Decrypt(value string) (string, error) {
+	dataKey, ciphertext, err := e.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcmOpen(dataKey, ciphertext)
+	if err != nil {
+		return "", errors.New("envelope decrypt err")
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap 使用新的主密钥重新加密数据密钥，密文不变
+func (e *Envelope) Rewrap(value string, target *Envelope) (string, error) {
+	dataKey, ciphertext, err := e.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := gcmSeal(target.masterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return target.format(wrappedKey, ciphertext), nil
+}
+
+func (e *Envelope) format(wrappedKey []byte, ciphertext []byte) string {
+	return envelopePrefix + e.keyId + ":" + base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext)
+}
+
+func (e *Envelope) unwrap(value string) ([]byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if !IsEnvelope(value) || len(parts) != 3 {
+		return nil, nil, errors.New("invalid envelope")
+	}
+	if parts[0] != e.keyId {
+		return nil, nil, fmt.Errorf("the value was encrypted by master key %s, current master key is %s", parts[0], e.keyId)
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, errors.New("invalid envelope")
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, errors.New("invalid envelope")
+	}
+	dataKey, err := gcmOpen(e.masterKey, wrappedKey)
+	if err != nil {
+		return nil, nil, errors.New("envelope data key decrypt err")
+	}
+	return dataKey, ciphertext, nil
+}
+
+// gcmSeal 返回 nonce + 密文
+func gcmSeal(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func gcmOpen(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestEnvelope(t *testing.T) *Envelope {
+	value, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("generate err: %v", err)
+	}
+	masterKey, err := ParseMasterKey(value)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	envelope, err := NewEnvelope(masterKey)
+	if err != nil {
+		t.Fatalf("new envelope err: %v", err)
+	}
+	return envelope
+}
+
+func TestEnvelope(t *testing.T) {
+	envelope := newTestEnvelope(t)
+	encrypted, err := envelope.Encrypt("telegram-token")
+	if err != nil {
+		t.Fatalf("encrypt err: %v", err)
+	}
+	if !IsEnvelope(encrypted) || strings.Contains(encrypted, "telegram-token") {
+		t.Errorf("unexpected envelope: %s", encrypted)
+	}
+	if again, _ := envelope.Encrypt("telegram-token"); again == encrypted {
+		t.Error("each encryption should use a new data key")
+	}
+	if plaintext, err := envelope.Decrypt(encrypted); err != nil || plaintext != "telegram-token" {
+		t.Errorf("expected telegram-token, got: %s %v", plaintext, err)
+	}
+
+	other := newTestEnvelope(t)
+	if _, err = other.Decrypt(encrypted); err == nil {
+		t.Error("decrypt with another master key should fail")
+	}
+	rewrapped, err := envelope.Rewrap(encrypted, other)
+	if err != nil {
+		t.Fatalf("rewrap err: %v", err)
+	}
+	if plaintext, err := other.Decrypt(rewrapped); err != nil || plaintext != "telegram-token" {
+		t.Errorf("expected telegram-token after rewrap, got: %s %v", plaintext, err)
+	}
+	// 密文不变，只替换了加密的数据密钥
+	if encrypted[strings.LastIndex(encrypted, ":"):] != rewrapped[strings.LastIndex(rewrapped, ":"):] {
+		t.Error("rewrap should keep the ciphertext")
+	}
+
+	tampered := encrypted[:len(encrypted)-2] + "AA"
+	if _, err = envelope.Decrypt(tampered); err == nil {
+		t.Error("tampered ciphertext should fail")
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	if _, err := ParseMasterKey(strings.Repeat("ab", 32)); err != nil {
+		t.Errorf("hex key should be accepted: %v", err)
+	}
+	for _, value := range []string{"", "short", strings.Repeat("ab", 16)} {
+		if _, err := ParseMasterKey(value); err == nil {
+			t.Errorf("%q should be rejected", value)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package util
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
 	"gopkg.in/yaml.v3"
 	"h-ui/model/constant"
 	"os"
@@ -38,3 +40,49 @@ func ExportFile(filePath string, data any, t int) error {
 	}
 	return nil
 }
+
+// ExportCsv 导出 csv 文件，header 为表头
+func ExportCsv(filePath string, header []string, rows [][]string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		logrus.Errorf("ExportCsv create file err filePath: %s err: %v", filePath, err)
+		return errors.New(constant.SysError)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err = writer.Write(header); err != nil {
+		logrus.Errorf("ExportCsv write header err filePath: %s err: %v", filePath, err)
+		return errors.New(constant.SysError)
+	}
+	if err = writer.WriteAll(rows); err != nil {
+		logrus.Errorf("ExportCsv write rows err filePath: %s err: %v", filePath, err)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
+
+// ExportXlsx 导出 xlsx 文件，header 为表头
+func ExportXlsx(filePath string, header []string, rows [][]string) error {
+	file := excelize.NewFile()
+	defer file.Close()
+	sheet := file.GetSheetName(0)
+	for i, row := range append([][]string{header}, rows...) {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return errors.New(constant.SysError)
+		}
+		values := make([]interface{}, 0, len(row))
+		for _, value := range row {
+			values = append(values, value)
+		}
+		if err = file.SetSheetRow(sheet, cell, &values); err != nil {
+			logrus.Errorf("ExportXlsx write row err filePath: %s err: %v", filePath, err)
+			return errors.New(constant.SysError)
+		}
+	}
+	if err := file.SaveAs(filePath); err != nil {
+		logrus.Errorf("ExportXlsx save err filePath: %s err: %v", filePath, err)
+		return errors.New(constant.SysError)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"errors"
+	"google.golang.org/protobuf/encoding/protowire"
+	"strings"
+)
+
+// geo 数据文件是 v2ray 的 protobuf 格式：
+// GeoIPList { repeated GeoIP entry = 1 }，GeoIP { string country_code = 1 }
+// GeoSiteList { repeated GeoSite entry = 1 }，GeoSite { string country_code = 1; repeated Domain domain = 2 }
+// Domain { repeated Attribute attribute = 3 }，Attribute { string key = 1 }
+
+// ParseGeoIP geoip.dat 中的分类，小写
+func ParseGeoIP(data []byte) (map[string][]string, error) {
+	return parseGeoList(data, false)
+}
+
+// ParseGeoSite geosite.dat 中的分类及其域名的属性，小写
+func ParseGeoSite(data []byte) (map[string][]string, error) {
+	return parseGeoList(data, true)
+}
+
+func parseGeoList(data []byte, withAttributes bool) (map[string][]string, error) {
+	categories := map[string][]string{}
+	err := rangeGeoFields(data, func(num protowire.Number, value []byte) error {
+		if num != 1 {
+			return nil
+		}
+		var code string
+		var attributes []string
+		err := rangeGeoFields(value, func(num protowire.Number, value []byte) error {
+			if num == 1 {
+				code = strings.ToLower(string(value))
+			} else if num == 2 && withAttributes {
+				return rangeGeoFields(value, func(num protowire.Number, value []byte) error {
+					if num != 3 {
+						return nil
+					}
+					return rangeGeoFields(value, func(num protowire.Number, value []byte) error {
+						if key := strings.ToLower(string(value)); num == 1 && !ArrContain(attributes, key) {
+							attributes = append(attributes, key)
+						}
+						return nil
+					})
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if code == "" {
+			return errors.New("geo entry without code")
+		}
+		categories[code] = append(categories[code], attributes...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) == 0 {
+		return nil, errors.New("geo data is empty")
+	}
+	return categories, nil
+}
+
+// rangeGeoFields 遍历消息中的 bytes 字段，其他类型的字段跳过
+func rangeGeoFields(data []byte, handle func(num protowire.Number, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return errors.New("invalid geo data")
+		}
+		data = data[n:]
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, data); n < 0 {
+				return errors.New("invalid geo data")
+			}
+			data = data[n:]
+			continue
+		}
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return errors.New("invalid geo data")
+		}
+		data = data[n:]
+		if err := handle(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
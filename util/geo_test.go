@@ -0,0 +1,65 @@
+package util
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"reflect"
+	"testing"
+)
+
+func appendGeoBytes(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func geoSiteEntry(code string, attributes ...string) []byte {
+	entry := appendGeoBytes(nil, 1, []byte(code))
+	for _, key := range attributes {
+		var attribute []byte
+		attribute = appendGeoBytes(attribute, 1, []byte(key))
+		attribute = protowire.AppendTag(attribute, 2, protowire.VarintType)
+		attribute = protowire.AppendVarint(attribute, 1)
+		domain := protowire.AppendTag(nil, 1, protowire.VarintType)
+		domain = protowire.AppendVarint(domain, 2)
+		domain = appendGeoBytes(domain, 2, []byte("example.com"))
+		domain = appendGeoBytes(domain, 3, attribute)
+		entry = appendGeoBytes(entry, 2, domain)
+	}
+	return entry
+}
+
+func TestParseGeoSite(t *testing.T) {
+	var data []byte
+	data = appendGeoBytes(data, 1, geoSiteEntry("GOOGLE", "cn", "ads", "cn"))
+	data = appendGeoBytes(data, 1, geoSiteEntry("netflix"))
+	categories, err := ParseGeoSite(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string][]string{"google": {"cn", "ads"}, "netflix": nil}
+	if !reflect.DeepEqual(categories, expect) {
+		t.Errorf("expected %v, got: %v", expect, categories)
+	}
+}
+
+func TestParseGeoIP(t *testing.T) {
+	cidr := appendGeoBytes(nil, 1, []byte{10, 0, 0, 0})
+	cidr = protowire.AppendTag(cidr, 2, protowire.VarintType)
+	cidr = protowire.AppendVarint(cidr, 8)
+	entry := appendGeoBytes(nil, 1, []byte("CN"))
+	entry = appendGeoBytes(entry, 2, cidr)
+	data := appendGeoBytes(nil, 1, entry)
+	data = appendGeoBytes(data, 1, appendGeoBytes(nil, 1, []byte("private")))
+	categories, err := ParseGeoIP(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := categories["cn"]; !ok || len(categories) != 2 {
+		t.Errorf("unexpected categories: %v", categories)
+	}
+
+	for _, invalid := range [][]byte{nil, []byte("not a geo file"), data[:len(data)-1]} {
+		if _, err = ParseGeoIP(invalid); err == nil {
+			t.Errorf("%q should be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+// argon2id 参数，修改后旧参数的哈希会在下次登录时重新生成
+const (
+	argon2Memory  uint32 = 19 * 1024
+	argon2Time    uint32 = 2
+	argon2Threads uint8  = 1
+	argon2KeyLen  uint32 = 32
+	argon2SaltLen        = 16
+)
+
+// HashPassword 生成 $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash> 格式的哈希，每个密码使用随机盐
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// IsLegacyPasswordHash 旧版本不加盐的 SHA224 哈希
+func IsLegacyPasswordHash(hash string) bool {
+	if len(hash) != 56 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// IsPasswordHash 是否为 HashPassword 或旧版本生成的哈希
+func IsPasswordHash(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$") || IsLegacyPasswordHash(hash)
+}
+
+// VerifyPassword 校验密码，兼容旧版本的 SHA224 哈希。rehash 为 true 时应使用 HashPassword 重新生成哈希
+func VerifyPassword(password string, hash string) (ok bool, rehash bool) {
+	if IsLegacyPasswordHash(hash) {
+		ok = subtle.ConstantTimeCompare([]byte(SHA224String(password)), []byte(strings.ToLower(hash))) == 1
+		return ok, ok
+	}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, false
+	}
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil || threads == 0 || memory > 1024*1024 {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false
+	}
+	actual := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return false, false
+	}
+	return true, memory != argon2Memory || time != argon2Time || threads != argon2Threads || uint32(len(key)) != argon2KeyLen
+}
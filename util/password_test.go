@@ -0,0 +1,48 @@
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash1, err := HashPassword("sysadmin")
+	if err != nil {
+		t.Fatalf("hash err: %v", err)
+	}
+	hash2, _ := HashPassword("sysadmin")
+	if !strings.HasPrefix(hash1, "$argon2id$v=19$") || hash1 == hash2 {
+		t.Errorf("hash should be salted argon2id: %s %s", hash1, hash2)
+	}
+	if ok, rehash := VerifyPassword("sysadmin", hash1); !ok || rehash {
+		t.Errorf("expected ok without rehash, got: %v %v", ok, rehash)
+	}
+	if ok, _ := VerifyPassword("sysadmin1", hash1); ok {
+		t.Error("wrong password should fail")
+	}
+}
+
+func TestVerifyPasswordLegacy(t *testing.T) {
+	legacy := SHA224String("sysadmin")
+	if ok, rehash := VerifyPassword("sysadmin", legacy); !ok || !rehash {
+		t.Errorf("legacy hash should verify and be rehashed, got: %v %v", ok, rehash)
+	}
+	if ok, rehash := VerifyPassword("sysadmin1", legacy); ok || rehash {
+		t.Errorf("wrong password should fail, got: %v %v", ok, rehash)
+	}
+	// 参数与当前不一致时需要重新生成
+	salt := []byte("saltsaltsaltsalt")
+	weaker := fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s", base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("sysadmin"), salt, 1, 1024, 1, 32)))
+	if ok, rehash := VerifyPassword("sysadmin", weaker); !ok || !rehash {
+		t.Errorf("weaker hash should verify and be rehashed, got: %v %v", ok, rehash)
+	}
+	for _, hash := range []string{"", "plain", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5"} {
+		if ok, _ := VerifyPassword("sysadmin", hash); ok {
+			t.Errorf("invalid hash %q should fail", hash)
+		}
+	}
+}
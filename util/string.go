@@ -1,6 +1,16 @@
 package util
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+)
+
+// 字符串必须6-32位是字母或者数字或部分特殊字符的组合
+var validateStrRegexp = regexp.MustCompile("^[a-zA-Z0-9!@#$%^&*()_+-=]{6,32}$")
+
+func ValidateStr(str string) bool {
+	return validateStrRegexp.MatchString(str)
+}
 
 func CompareVersion(version1, version2 string) int {
 	v1 := strings.Split(version1, ".")
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"strings"
+)
+
+// ReadTable 读取 csv 或 xlsx 第一个工作表的所有行，第一行为表头
+func ReadTable(reader io.Reader, format string) ([][]string, error) {
+	var rows [][]string
+	switch format {
+	case "csv":
+		csvReader := csv.NewReader(reader)
+		csvReader.FieldsPerRecord = -1
+		records, err := csvReader.ReadAll()
+		if err != nil {
+			return nil, errors.New("csv file read err")
+		}
+		rows = records
+	case "xlsx":
+		file, err := excelize.OpenReader(reader)
+		if err != nil {
+			return nil, errors.New("xlsx file read err")
+		}
+		defer file.Close()
+		if rows, err = file.GetRows(file.GetSheetName(0)); err != nil {
+			return nil, errors.New("xlsx file read err")
+		}
+	default:
+		return nil, errors.New("file format not supported")
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		// 去掉 Excel 导出 csv 时带的 BOM
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	return rows, nil
+}